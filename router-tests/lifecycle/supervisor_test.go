@@ -2,11 +2,22 @@ package integration
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/cosmo/router-tests/freeport"
 	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	"github.com/wundergraph/cosmo/router/pkg/config"
 	"go.uber.org/goleak"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func TestRouterSupervisor(t *testing.T) {
@@ -43,3 +54,66 @@ func TestRouterSupervisor(t *testing.T) {
 
 	<-stopped
 }
+
+func TestRouterSupervisorAdminReload(t *testing.T) {
+	t.Parallel()
+
+	writeExecutionConfig := func(t *testing.T, path, version string) {
+		t.Helper()
+
+		var routerConfig nodev1.RouterConfig
+		require.NoError(t, protojson.Unmarshal([]byte(testenv.ConfigJSONTemplate), &routerConfig))
+		routerConfig.Version = version
+		data, err := protojson.Marshal(&routerConfig)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+	}
+
+	executionConfigPath := filepath.Join(t.TempDir(), "config.json")
+	writeExecutionConfig(t, executionConfigPath, "initial")
+
+	adminAddr := fmt.Sprintf("localhost:%d", freeport.GetOne(t))
+
+	xEnv, err := testenv.CreateTestSupervisorEnv(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			core.WithConfigVersionHeader(true),
+			core.WithExecutionConfig(&core.ExecutionConfig{Path: executionConfigPath}),
+			core.WithAdminAPI(config.AdminAPIConfiguration{Enabled: true, ListenAddr: adminAddr, Token: "admin-token"}),
+		},
+	})
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = xEnv.RouterSupervisor.Start()
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		xEnv.RouterSupervisor.Stop()
+		xEnv.Shutdown()
+		<-stopped
+	})
+
+	err = xEnv.WaitForServer(context.Background(), xEnv.RouterURL+"/health/ready", 2000, 30)
+	require.NoError(t, err)
+
+	res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ __typename }`})
+	require.Equal(t, "initial", res.Response.Header.Get("X-Router-Config-Version"))
+
+	// The execution config file isn't watched, the reload has to read it again
+	writeExecutionConfig(t, executionConfigPath, "reloaded")
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+adminAddr+"/reload", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	reloadRes, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, reloadRes.Body.Close())
+	require.Equal(t, http.StatusAccepted, reloadRes.StatusCode)
+
+	require.EventuallyWithT(t, func(t *assert.CollectT) {
+		res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{Query: `{ __typename }`})
+		require.NoError(t, err)
+		assert.Equal(t, "reloaded", res.Response.Header.Get("X-Router-Config-Version"))
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -2309,6 +2309,7 @@ func TestFlakyAccessLogs(t *testing.T) {
 						"query":                 "",
 						"ip":                    "[REDACTED]",
 						"feature_flag":          "myff",
+						"feature_flag_reason":   "header",
 						"url_method_expression": "POST", // From expression
 					}
 					additionalExpectedKeys := []string{
@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+const employeesIDData = `{"data":{"employees":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":7},{"id":8},{"id":10},{"id":11},{"id":12}]}}`
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serves cached, not modified and stale responses", func(t *testing.T) {
+		t.Parallel()
+
+		// blockSubgraph holds the subgraph requests until it is closed, so a request stays in revalidation
+		var block atomic.Bool
+		blockSubgraph := make(chan struct{})
+		subgraphBlocked := make(chan struct{}, 1)
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithResponseCache(&config.ResponseCacheConfiguration{
+					Enabled:  true,
+					InMemory: config.ResponseCacheInMemoryConfiguration{MaxEntries: 100},
+				}),
+			},
+			CacheControlPolicy: config.CacheControlPolicy{
+				Enabled: true,
+				Value:   "max-age=1, stale-while-revalidate=60",
+			},
+			ModifyEngineExecutionConfiguration: func(cfg *config.EngineExecutionConfiguration) {
+				cfg.Debug.EnableCacheResponseHeaders = true
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: func(handler http.Handler) http.Handler {
+						return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							if block.Load() {
+								subgraphBlocked <- struct{}{}
+								<-blockSubgraph
+							}
+							// The most restrictive policy wins, so the subgraph has to allow stale responses too
+							w.Header().Set("Cache-Control", "max-age=1, stale-while-revalidate=60")
+							handler.ServeHTTP(w, r)
+						})
+					},
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			query := testenv.GraphQLRequest{Query: `{ employees { id } }`}
+
+			res := xEnv.MakeGraphQLRequestOK(query)
+			require.Equal(t, "MISS", res.Response.Header.Get(core.ResponseCacheHeader))
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			res = xEnv.MakeGraphQLRequestOK(query)
+			require.Equal(t, "HIT", res.Response.Header.Get(core.ResponseCacheHeader))
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Employees.Load())
+
+			etag := res.Response.Header.Get("ETag")
+			require.NotEmpty(t, etag)
+
+			res, err := xEnv.MakeGraphQLRequestWithHeaders(query, map[string]string{"If-None-Match": etag})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNotModified, res.Response.StatusCode)
+			require.Empty(t, res.Body)
+
+			// Let the entry become stale and keep the revalidating request at the subgraph
+			time.Sleep(1100 * time.Millisecond)
+			block.Store(true)
+
+			revalidated := make(chan *testenv.TestResponse, 1)
+			go func() {
+				res, err := xEnv.MakeGraphQLRequest(query)
+				if err != nil {
+					res = nil
+				}
+				revalidated <- res
+			}()
+
+			select {
+			case <-subgraphBlocked:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the stale entry was not revalidated")
+			}
+			block.Store(false)
+
+			res = xEnv.MakeGraphQLRequestOK(query)
+			require.Equal(t, "STALE", res.Response.Header.Get(core.ResponseCacheHeader))
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			close(blockSubgraph)
+
+			res = <-revalidated
+			require.NotNil(t, res)
+			require.Equal(t, "REVALIDATED", res.Response.Header.Get(core.ResponseCacheHeader))
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			res = xEnv.MakeGraphQLRequestOK(query)
+			require.Equal(t, "HIT", res.Response.Header.Get(core.ResponseCacheHeader))
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/andybalholm/brotli"
 	"github.com/buger/jsonparser"
+	"github.com/klauspost/compress/zstd"
 
 	"github.com/stretchr/testify/require"
 	"github.com/wundergraph/cosmo/router-tests/testenv"
@@ -28,6 +30,21 @@ func decompressGzip(t *testing.T, body io.Reader) []byte {
 	return data
 }
 
+func decompressZstd(t *testing.T, body io.Reader) []byte {
+	zr, err := zstd.NewReader(body)
+	require.NoError(t, err)
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return data
+}
+
+func decompressBrotli(t *testing.T, body io.Reader) []byte {
+	data, err := io.ReadAll(brotli.NewReader(body))
+	require.NoError(t, err)
+	return data
+}
+
 func decompressNone(t *testing.T, body io.Reader) []byte {
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
@@ -49,7 +66,9 @@ func TestResponseCompression(t *testing.T) {
 		{"gzip with min size", "gzip", decompressGzip, true, employeesIdDataMinSizeGzip},     // Gzip Encoding with min size
 		{"no gzip because request is too small", "", decompressGzip, false, employeesIdData}, // No Gzip Encoding because of min size
 		{"identity", "identity", decompressNone, false, employeesIdData},                     // NO Encoding
-		{"zstd", "zstd", decompressNone, false, employeesIdData},                             // Unsuported Encoding
+		{"zstd", "zstd", decompressNone, false, employeesIdData},                             // No Zstd Encoding because of min size
+		{"zstd with min size", "zstd", decompressZstd, true, employeesIdDataMinSizeGzip},     // Zstd Encoding with min size
+		{"br with min size", "br", decompressBrotli, true, employeesIdDataMinSizeGzip},       // Brotli Encoding with min size
 	}
 
 	for _, tc := range testCases {
@@ -181,3 +200,71 @@ func TestResponseCompressionWithCustomMinSize(t *testing.T) {
 		})
 	}
 }
+
+func TestSubgraphResponseDecompression(t *testing.T) {
+	t.Parallel()
+
+	compressors := map[string]func(w io.Writer) io.WriteCloser{
+		"zstd": func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(w)
+			return zw
+		},
+		"br": func(w io.Writer) io.WriteCloser {
+			return brotli.NewWriter(w)
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		encoding    string
+		maxSize     config.BytesString
+		expectError bool
+	}{
+		{"zstd", "zstd", 0, false},
+		{"br", "br", 0, false},
+		{"zstd response exceeding the maximum size", "zstd", config.BytesString(len(employeesIdData) - 1), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			testenv.Run(t, &testenv.Config{
+				RouterOptions: []core.Option{
+					core.WithSubgraphCompressionOptions(core.NewSubgraphCompressionOptions(config.TrafficShapingRules{
+						All: config.GlobalSubgraphRequestRule{
+							Compression: config.SubgraphCompression{
+								Enabled:             true,
+								Encoding:            "gzip",
+								MaxDecompressedSize: tc.maxSize,
+							},
+						},
+					})),
+				},
+				Subgraphs: testenv.SubgraphsConfig{
+					Employees: testenv.SubgraphConfig{
+						Middleware: func(handler http.Handler) http.Handler {
+							return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+								require.Contains(t, r.Header.Get("Accept-Encoding"), tc.encoding)
+								w.Header().Set("Content-Type", "application/json")
+								w.Header().Set("Content-Encoding", tc.encoding)
+								cw := compressors[tc.encoding](w)
+								_, _ = io.WriteString(cw, employeesIdData)
+								require.NoError(t, cw.Close())
+							})
+						},
+					},
+				},
+			}, func(t *testing.T, xEnv *testenv.Environment) {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `query { employees { id } }`,
+				})
+				if tc.expectError {
+					require.Contains(t, res.Body, "Failed to fetch from Subgraph 'employees'")
+					require.Contains(t, res.Body, `"data":{"employees":null}`)
+					return
+				}
+				require.JSONEq(t, employeesIdData, res.Body)
+			})
+		})
+	}
+}
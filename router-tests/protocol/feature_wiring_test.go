@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router-tests/testutils"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+const employeesIDData = `{"data":{"employees":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":7},{"id":8},{"id":10},{"id":11},{"id":12}]}}`
+
+// subgraphProxy forwards the requests to a subgraph server of the test environment. It is started
+// before the environment, so its URL can be configured as a subgraph endpoint.
+type subgraphProxy struct {
+	*httptest.Server
+	target   atomic.Pointer[url.URL]
+	requests atomic.Int64
+	// unhealthy fails the health probes of the load balancer
+	unhealthy atomic.Bool
+	probes    atomic.Int64
+}
+
+func newSubgraphProxy(t *testing.T) *subgraphProxy {
+	t.Helper()
+
+	p := &subgraphProxy{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			p.probes.Add(1)
+			if p.unhealthy.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		p.requests.Add(1)
+		httputil.NewSingleHostReverseProxy(p.target.Load()).ServeHTTP(w, r)
+	}))
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *subgraphProxy) forwardTo(t *testing.T, server *httptest.Server) {
+	t.Helper()
+
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	p.target.Store(target)
+}
+
+// TestRouterFeatureWiring makes sure that the features set up when the router is bootstrapped are
+// active for the requests. The features are covered in detail by their own tests.
+func TestRouterFeatureWiring(t *testing.T) {
+	t.Parallel()
+
+	t.Run("response cache", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithResponseCache(&config.ResponseCacheConfiguration{
+					Enabled:  true,
+					InMemory: config.ResponseCacheInMemoryConfiguration{MaxEntries: 100},
+				}),
+			},
+			CacheControlPolicy: config.CacheControlPolicy{
+				Enabled: true,
+				Value:   "max-age=60",
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for range 2 {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ employees { id } }`})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("subgraph load balancing", func(t *testing.T) {
+		t.Parallel()
+
+		proxy := newSubgraphProxy(t)
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphLoadBalancing(&config.SubgraphLoadBalancingConfiguration{
+					Subgraphs: map[string]config.SubgraphLoadBalancingSettings{
+						"employees": {
+							Strategy:  "round_robin",
+							Endpoints: []config.SubgraphLoadBalancingEndpoint{{URL: proxy.URL + "/graphql"}},
+						},
+					},
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			proxy.forwardTo(t, xEnv.Servers[0])
+
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ employees { id } }`})
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(1), proxy.requests.Load())
+		})
+	})
+
+	t.Run("subgraph hedging", func(t *testing.T) {
+		t.Parallel()
+
+		var requests atomic.Int64
+		metricReader := metric.NewManualReader()
+
+		testenv.Run(t, &testenv.Config{
+			MetricReader: metricReader,
+			RouterOptions: []core.Option{
+				core.WithSubgraphHedgingOptions(core.NewSubgraphHedgingOptions(config.TrafficShapingRules{
+					Subgraphs: map[string]config.GlobalSubgraphRequestRule{
+						"employees": {
+							Hedging: config.SubgraphHedging{Enabled: true, Delay: 20 * time.Millisecond, MaxInFlight: 10},
+						},
+					},
+				})),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: func(handler http.Handler) http.Handler {
+						return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							// The first request is slow, so that it is hedged
+							if requests.Add(1) == 1 {
+								time.Sleep(500 * time.Millisecond)
+							}
+							handler.ServeHTTP(w, r)
+						})
+					},
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ employees { id } }`})
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(2), requests.Load())
+
+			rm := metricdata.ResourceMetrics{}
+			require.NoError(t, metricReader.Collect(context.Background(), &rm))
+			scopeMetric := testutils.GetMetricScopeByName(rm.ScopeMetrics, "cosmo.router.subgraph.hedge")
+			require.NotNil(t, scopeMetric)
+			require.NotNil(t, testutils.GetMetricByName(scopeMetric, "router.subgraph.hedged_requests"))
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestSubgraphLoadBalancing(t *testing.T) {
+	t.Parallel()
+
+	t.Run("does not route requests to an unhealthy endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		healthy := newSubgraphProxy(t)
+		unhealthy := newSubgraphProxy(t)
+		unhealthy.unhealthy.Store(true)
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphLoadBalancing(&config.SubgraphLoadBalancingConfiguration{
+					Subgraphs: map[string]config.SubgraphLoadBalancingSettings{
+						"employees": {
+							Strategy: "round_robin",
+							Endpoints: []config.SubgraphLoadBalancingEndpoint{
+								{URL: healthy.URL + "/graphql"},
+								{URL: unhealthy.URL + "/graphql"},
+							},
+							HealthCheck: config.SubgraphLoadBalancingHealthCheck{
+								Enabled:            true,
+								Interval:           50 * time.Millisecond,
+								Timeout:            time.Second,
+								UnhealthyThreshold: 1,
+								HealthyThreshold:   1,
+							},
+						},
+					},
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			healthy.forwardTo(t, xEnv.Servers[0])
+			unhealthy.forwardTo(t, xEnv.Servers[0])
+
+			// The endpoint is ejected after the first failed probe, so it is out once the second one arrives
+			require.Eventually(t, func() bool {
+				return unhealthy.probes.Load() >= 2
+			}, 5*time.Second, 10*time.Millisecond)
+
+			for range 6 {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ employees { id } }`})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(6), healthy.requests.Load())
+			require.Equal(t, int64(0), unhealthy.requests.Load())
+
+			// The recovered endpoint gets its share of the requests again
+			unhealthy.unhealthy.Store(false)
+			require.Eventually(t, func() bool {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ employees { id } }`})
+				return res.Response.StatusCode == 200 && unhealthy.requests.Load() > 0
+			}, 5*time.Second, 50*time.Millisecond)
+		})
+	})
+}
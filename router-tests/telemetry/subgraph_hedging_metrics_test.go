@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router-tests/testutils"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	otelattrs "github.com/wundergraph/cosmo/router/pkg/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestSubgraphHedgingMetrics(t *testing.T) {
+	t.Parallel()
+
+	// run sends one employees request. The first subgraph request is the original, the second one
+	// the hedged request. Every request is delayed by the duration for its position.
+	run := func(t *testing.T, delays []time.Duration, check func(t *testing.T, sum metricdata.Sum[int64])) {
+		t.Helper()
+
+		var requests atomic.Int64
+		metricReader := metric.NewManualReader()
+
+		testenv.Run(t, &testenv.Config{
+			MetricReader: metricReader,
+			RouterOptions: []core.Option{
+				core.WithSubgraphHedgingOptions(core.NewSubgraphHedgingOptions(config.TrafficShapingRules{
+					Subgraphs: map[string]config.GlobalSubgraphRequestRule{
+						"employees": {
+							Hedging: config.SubgraphHedging{Enabled: true, Delay: 20 * time.Millisecond, MaxInFlight: 10},
+						},
+					},
+				})),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: func(handler http.Handler) http.Handler {
+						return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							if n := int(requests.Add(1)); n <= len(delays) {
+								select {
+								case <-time.After(delays[n-1]):
+								case <-r.Context().Done():
+									return
+								}
+							}
+							handler.ServeHTTP(w, r)
+						})
+					},
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{Query: `{ employees { id } }`})
+			require.JSONEq(t, `{"data":{"employees":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":7},{"id":8},{"id":10},{"id":11},{"id":12}]}}`, res.Body)
+			require.Equal(t, int64(2), requests.Load())
+
+			rm := metricdata.ResourceMetrics{}
+			require.NoError(t, metricReader.Collect(context.Background(), &rm))
+
+			scopeMetric := testutils.GetMetricScopeByName(rm.ScopeMetrics, "cosmo.router.subgraph.hedge")
+			require.NotNil(t, scopeMetric)
+			hedgedRequests := testutils.GetMetricByName(scopeMetric, "router.subgraph.hedged_requests")
+			require.NotNil(t, hedgedRequests)
+
+			sum, ok := hedgedRequests.Data.(metricdata.Sum[int64])
+			require.True(t, ok)
+			check(t, sum)
+		})
+	}
+
+	t.Run("counts a hedged request that won", func(t *testing.T) {
+		t.Parallel()
+
+		run(t, []time.Duration{time.Second}, func(t *testing.T, sum metricdata.Sum[int64]) {
+			require.Len(t, sum.DataPoints, 1)
+			require.Equal(t, int64(1), sum.DataPoints[0].Value)
+			require.Equal(t, attribute.NewSet(
+				otelattrs.WgSubgraphName.String("employees"),
+				otelattrs.WgSubgraphHedgeWon.Bool(true),
+			), sum.DataPoints[0].Attributes)
+		})
+	})
+
+	t.Run("counts a hedged request that lost", func(t *testing.T) {
+		t.Parallel()
+
+		run(t, []time.Duration{100 * time.Millisecond, time.Second}, func(t *testing.T, sum metricdata.Sum[int64]) {
+			require.Len(t, sum.DataPoints, 1)
+			require.Equal(t, int64(1), sum.DataPoints[0].Value)
+			require.Equal(t, attribute.NewSet(
+				otelattrs.WgSubgraphName.String("employees"),
+				otelattrs.WgSubgraphHedgeWon.Bool(false),
+			), sum.DataPoints[0].Attributes)
+		})
+	})
+}
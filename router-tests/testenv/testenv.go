@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -722,7 +723,11 @@ func CreateTestSupervisorEnv(t testing.TB, cfg *Config) (*Environment, error) {
 			return &config.Config{}, nil
 		},
 		RouterFactory: func(ctx context.Context, res *core.RouterResources) (*core.Router, error) {
-			rr, err := configureRouter(ctx, listenerAddr, cfg, &routerConfig, cdnServer, natsSetup)
+			// Let the admin API reload the router through the supervisor
+			routerCfg := *cfg
+			routerCfg.RouterOptions = append(slices.Clone(cfg.RouterOptions), core.WithReloadFunc(res.Reload))
+
+			rr, err := configureRouter(ctx, listenerAddr, &routerCfg, &routerConfig, cdnServer, natsSetup)
 			if err != nil {
 				cancel(err)
 				return nil, err
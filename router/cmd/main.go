@@ -87,11 +87,33 @@ func Main() {
 
 	logLevelAtomic := zap.NewAtomicLevelAt(result.Config.LogLevel)
 
-	baseLogger := logging.New(!result.Config.JSONLog, result.Config.DevelopmentMode, result.Config.AccessLogs.AddStacktrace, logLevelAtomic).
-		With(
-			zap.String("service", result.Config.LogServiceName),
-			zap.String("service_version", core.Version),
-		)
+	baseLogger := logging.New(!result.Config.JSONLog, result.Config.DevelopmentMode, result.Config.AccessLogs.AddStacktrace, logLevelAtomic)
+
+	// Export the application log over OTLP in addition to stdout
+	if result.Config.Telemetry.Logs.Enabled {
+		otlpOptions := core.LogsConfigFromTelemetry(&result.Config.Telemetry)
+		if otlpOptions == nil {
+			log.Fatal("Could not export logs: telemetry.logs requires at least one exporter")
+		}
+		otlpOptions.ServiceInstanceID = result.Config.InstanceID
+
+		otlpLogger, err := logging.NewOTLPLogger(context.Background(), *otlpOptions)
+		if err != nil {
+			log.Fatalf("Could not create OTLP logger: %s", err)
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = otlpLogger.Shutdown(shutdownCtx)
+		}()
+
+		baseLogger = otlpLogger.Tee(baseLogger, "wundergraph/cosmo/router", logLevelAtomic)
+	}
+
+	baseLogger = baseLogger.With(
+		zap.String("service", result.Config.LogServiceName),
+		zap.String("service_version", core.Version),
+	)
 
 	if *pprofListenAddr != "" && result.Config.Pyroscope.Enabled {
 		baseLogger.Fatal("Cannot use pprof and pyroscope at the same time")
@@ -200,7 +222,7 @@ func Main() {
 			Callback: func() {
 				ll.Info("Configuration changed, triggering reload")
 
-				rs.Reload()
+				rs.ReloadOnConfigChange()
 			},
 		})
 		if err != nil {
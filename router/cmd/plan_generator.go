@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/KimMachineGun/automemlimit/memlimit"
 	"github.com/dustin/go-humanize"
@@ -22,6 +23,7 @@ import (
 
 func PlanGenerator(args []string) {
 	var planHelp bool
+	var diffCfg plan_generator.PlanDiffConfig
 
 	cfg := plan_generator.QueryPlanConfig{
 		OutputFormat: core.PlanOutputFormatText,
@@ -51,6 +53,12 @@ func PlanGenerator(args []string) {
 		return fmt.Errorf("must be one of: text, json (got %q)", s)
 	})
 	f.UintVar(&cfg.MaxDataSourceCollectorsConcurrency, "max-collectors", 0, "max number of concurrent data source collectors, if unset or 0, no limit will be enforced")
+	f.StringVar(&diffCfg.TargetExecutionConfig, "diff-execution-config", "", "compare the plans of the operations with this execution config and write a diff report instead of the plans")
+	f.StringVar(&diffCfg.FeatureFlag, "diff-feature-flag", "", "compare the plans of the operations with this feature flag of the diff (or base) execution config")
+	f.StringVar(&diffCfg.Manifest, "manifest", "", "PQL manifest file to compare the operations of, instead of the operations folder")
+	f.Float64Var(&diffCfg.PlanTimeRegressionFactor, "plan-time-regression-factor", 2, "report a regression when the planning time of the diff is higher than the base planning time times the factor, 0 disables it")
+	f.DurationVar(&diffCfg.PlanTimeRegressionMinDelta, "plan-time-regression-min-delta", 5*time.Millisecond, "ignore planning time increases below the delta")
+	f.BoolVar(&diffCfg.FailOnDiff, "fail-on-diff", false, "if at least one operation plans differently, fails to plan or regressed, the command exit code will be 1")
 
 	if err := f.Parse(args[1:]); err != nil {
 		f.PrintDefaults()
@@ -61,7 +69,16 @@ func PlanGenerator(args []string) {
 		f.PrintDefaults()
 		return
 	}
-	if cfg.ExecutionConfig == "" || cfg.SourceDir == "" || cfg.OutDir == "" {
+	diffMode := diffCfg.TargetExecutionConfig != "" || diffCfg.FeatureFlag != ""
+	if cfg.ExecutionConfig == "" || cfg.OutDir == "" {
+		f.PrintDefaults()
+		log.Fatalf("missing required flags")
+	}
+	if diffMode && (cfg.SourceDir == "") == (diffCfg.Manifest == "") {
+		f.PrintDefaults()
+		log.Fatalf("either -operations or -manifest is required")
+	}
+	if !diffMode && cfg.SourceDir == "" {
 		f.PrintDefaults()
 		log.Fatalf("missing required flags")
 	}
@@ -117,6 +134,23 @@ func PlanGenerator(args []string) {
 		}
 	}
 
+	if diffMode {
+		diffCfg.BaseExecutionConfig = cfg.ExecutionConfig
+		diffCfg.SourceDir = cfg.SourceDir
+		diffCfg.OutDir = cfg.OutDir
+		diffCfg.Concurrency = cfg.Concurrency
+		diffCfg.Filter = cfg.Filter
+		diffCfg.Timeout = cfg.Timeout
+		diffCfg.Logger = logger
+		diffCfg.MaxDataSourceCollectorsConcurrency = cfg.MaxDataSourceCollectorsConcurrency
+
+		err = plan_generator.PlanDiff(ctxNotify, diffCfg)
+		if err != nil {
+			logger.Fatal("Error during command plan-generator diff", zap.Error(err))
+		}
+		return
+	}
+
 	err = plan_generator.PlanGenerator(ctxNotify, cfg)
 	if err != nil {
 		logger.Fatal("Error during command plan-generator: %s", zap.Error(err))
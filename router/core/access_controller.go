@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"crypto/subtle"
 	"errors"
 	"net/http"
@@ -46,11 +47,43 @@ func NewAccessController(opts AccessControllerOptions) (*AccessController, error
 	}, nil
 }
 
+type authenticationResultKey struct{}
+
+// authenticationResult is the outcome of authenticating a request before it reaches a graph mux
+type authenticationResult struct {
+	auth authentication.Authentication
+	err  error
+}
+
+// preAuthenticate authenticates the request and stores the result in the context of the returned
+// request, so that Access reuses it instead of calling the authenticators again.
+func (a *AccessController) preAuthenticate(r *http.Request) (*http.Request, authentication.Authentication) {
+	auth, err := a.authenticate(r)
+	ctx := context.WithValue(r.Context(), authenticationResultKey{}, &authenticationResult{auth: auth, err: err})
+	return r.WithContext(ctx), auth
+}
+
+// withoutAuthenticationResult drops a stored authentication result, e.g. when the request is
+// authenticated with other credentials like the initial payload of a websocket connection
+func withoutAuthenticationResult(ctx context.Context) context.Context {
+	if ctx.Value(authenticationResultKey{}) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, authenticationResultKey{}, (*authenticationResult)(nil))
+}
+
+func (a *AccessController) authenticate(r *http.Request) (authentication.Authentication, error) {
+	if res, ok := r.Context().Value(authenticationResultKey{}).(*authenticationResult); ok && res != nil {
+		return res.auth, res.err
+	}
+	return authentication.AuthenticateHTTPRequest(r.Context(), a.authenticators, r, a.scopeClaim)
+}
+
 // Access performs authorization and authentication, returning an error if the request
 // should not proceed. If it succeeds, a new http.Request with an updated context.Context
 // is returned.
 func (a *AccessController) Access(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
-	auth, err := authentication.AuthenticateHTTPRequest(r.Context(), a.authenticators, r, a.scopeClaim)
+	auth, err := a.authenticate(r)
 	if err != nil {
 		return nil, errors.Join(err, ErrUnauthorized)
 	}
@@ -0,0 +1,506 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/circuit"
+)
+
+// adminAPIServer serves runtime introspection and control endpoints on a separate listener.
+// Every request reads the state of the currently active graph server, so the responses always
+// reflect the latest execution config.
+type adminAPIServer struct {
+	router *Router
+	logger *zap.Logger
+	token  []byte
+	server *http.Server
+}
+
+// adminCache is implemented by all ristretto caches of a graph mux
+type adminCache interface {
+	MaxCost() int64
+	RemainingCost() int64
+	Clear()
+}
+
+type adminConfigResponse struct {
+	ConfigVersion string             `json:"config_version"`
+	FeatureFlags  []adminFeatureFlag `json:"feature_flags"`
+}
+
+type adminFeatureFlag struct {
+	Name          string `json:"name"`
+	ConfigVersion string `json:"config_version"`
+}
+
+type adminModule struct {
+	ID       string `json:"id"`
+	Priority int    `json:"priority"`
+}
+
+type adminGraphCaches struct {
+	// FeatureFlag is empty for the base graph
+	FeatureFlag string              `json:"feature_flag"`
+	Caches      []adminCacheSummary `json:"caches"`
+}
+
+type adminCacheSummary struct {
+	Name       string `json:"name"`
+	Entries    int64  `json:"entries"`
+	MaxEntries int64  `json:"max_entries"`
+}
+
+type adminGraphSlowPlans struct {
+	FeatureFlag string          `json:"feature_flag"`
+	Plans       []adminSlowPlan `json:"plans"`
+}
+
+type adminSlowPlan struct {
+	OperationName    string `json:"operation_name"`
+	Content          string `json:"content"`
+	PlanningDuration string `json:"planning_duration"`
+}
+
+type adminPubSubProvider struct {
+	FeatureFlag string `json:"feature_flag"`
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	// Status is one of "starting", "running" or "failed"
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type adminCircuitRequest struct {
+	Force circuit.ForceMode `json:"force"`
+}
+
+type adminErrorResponse struct {
+	Error string `json:"error"`
+}
+
+const (
+	slowPlanCacheName = "slow_plan"
+	// responseCacheName is shared by all graphs, so it is not flushed per feature flag
+	responseCacheName = "response"
+)
+
+func newAdminAPIServer(r *Router) *adminAPIServer {
+	a := &adminAPIServer{
+		router: r,
+		logger: r.logger.With(zap.String("component", "admin_api")),
+		token:  []byte(r.adminAPI.Token),
+	}
+
+	a.server = &http.Server{
+		Addr:              r.adminAPI.ListenAddr,
+		Handler:           a.handler(),
+		ReadTimeout:       1 * time.Minute,
+		WriteTimeout:      1 * time.Minute,
+		ReadHeaderTimeout: 2 * time.Second,
+		IdleTimeout:       30 * time.Second,
+		ErrorLog:          zap.NewStdLog(a.logger),
+	}
+
+	return a
+}
+
+func (a *adminAPIServer) handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Use(a.authenticate)
+
+	r.Get("/config", a.handleConfig)
+	r.Get("/modules", a.handleModules)
+	r.Get("/circuits", a.handleCircuits)
+	r.Put("/circuits/{subgraph}", a.handleForceCircuit)
+	r.Get("/caches", a.handleCaches)
+	r.Delete("/caches", a.handleFlushCaches)
+	r.Delete("/caches/{cache}", a.handleFlushCaches)
+	r.Get("/caches/slow_plan/entries", a.handleSlowPlans)
+	r.Get("/pubsub/providers", a.handlePubSubProviders)
+	r.Post("/reload", a.handleReload)
+
+	return r
+}
+
+// listen binds the listen address, so the router fails to start if the address is not available
+func (a *adminAPIServer) listen() error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.server.Addr, err)
+	}
+
+	go func() {
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("Failed to serve admin API", zap.Error(err))
+		}
+	}()
+
+	a.logger.Info("Admin API enabled", zap.String("listen_addr", listener.Addr().String()))
+
+	return nil
+}
+
+func (a *adminAPIServer) Shutdown(ctx context.Context) error {
+	return a.server.Shutdown(ctx)
+}
+
+func (a *adminAPIServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), a.token) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAdminError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// graphServer returns the active graph server or nil if no execution config was loaded yet
+func (a *adminAPIServer) graphServer() *graphServer {
+	if a.router.httpServer == nil {
+		return nil
+	}
+
+	state := a.router.httpServer.state.Load()
+	if state == nil {
+		return nil
+	}
+
+	return state.graphServer
+}
+
+// graphMuxes returns the graph muxes of the active graph server sorted by feature flag name,
+// starting with the base graph
+func (a *adminAPIServer) graphMuxes() ([]string, map[string]*graphMux) {
+	muxes := currentGraphMuxes(a.router)
+	return slices.Sorted(maps.Keys(muxes)), muxes
+}
+
+func (a *adminAPIServer) handleConfig(w http.ResponseWriter, _ *http.Request) {
+	gs := a.graphServer()
+	if gs == nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "no execution config loaded")
+		return
+	}
+
+	resp := adminConfigResponse{
+		ConfigVersion: gs.baseRouterConfigVersion,
+		FeatureFlags:  []adminFeatureFlag{},
+	}
+
+	names, muxes := a.graphMuxes()
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		resp.FeatureFlags = append(resp.FeatureFlags, adminFeatureFlag{
+			Name:          name,
+			ConfigVersion: muxes[name].routerConfigVersion,
+		})
+	}
+
+	writeAdminJSON(w, http.StatusOK, resp)
+}
+
+func (a *adminAPIServer) handleModules(w http.ResponseWriter, _ *http.Request) {
+	modules := make([]adminModule, 0, len(a.router.loadedModules))
+	for _, info := range a.router.loadedModules {
+		modules = append(modules, adminModule{
+			ID:       string(info.ID),
+			Priority: info.Priority,
+		})
+	}
+
+	writeAdminJSON(w, http.StatusOK, modules)
+}
+
+func (a *adminAPIServer) handleCircuits(w http.ResponseWriter, _ *http.Request) {
+	states := []circuit.CircuitState{}
+	if gs := a.graphServer(); gs != nil && gs.circuitBreakerManager != nil {
+		states = append(states, gs.circuitBreakerManager.States()...)
+	}
+
+	writeAdminJSON(w, http.StatusOK, states)
+}
+
+func (a *adminAPIServer) handleForceCircuit(w http.ResponseWriter, r *http.Request) {
+	var req adminCircuitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return
+	}
+
+	gs := a.graphServer()
+	if gs == nil || gs.circuitBreakerManager == nil {
+		writeAdminError(w, http.StatusNotFound, circuit.ErrCircuitNotFound.Error())
+		return
+	}
+
+	subgraph := chi.URLParam(r, "subgraph")
+
+	if err := gs.circuitBreakerManager.Force(subgraph, req.Force); err != nil {
+		if errors.Is(err, circuit.ErrCircuitNotFound) {
+			writeAdminError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.logger.Info("Circuit breaker forced", zap.String("subgraph_name", subgraph), zap.String("force", string(req.Force)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminAPIServer) handleCaches(w http.ResponseWriter, _ *http.Request) {
+	names, muxes := a.graphMuxes()
+
+	resp := make([]adminGraphCaches, 0, len(names))
+	for _, name := range names {
+		gm := muxes[name]
+
+		caches := gm.adminCaches()
+		summaries := make([]adminCacheSummary, 0, len(caches)+1)
+		for _, cacheName := range slices.Sorted(maps.Keys(caches)) {
+			cache := caches[cacheName]
+			summaries = append(summaries, adminCacheSummary{
+				Name:       cacheName,
+				Entries:    cache.MaxCost() - cache.RemainingCost(),
+				MaxEntries: cache.MaxCost(),
+			})
+		}
+
+		if gm.planFallbackCache != nil {
+			var entries int64
+			for range gm.planFallbackCache.Values() {
+				entries++
+			}
+			summaries = append(summaries, adminCacheSummary{
+				Name:       slowPlanCacheName,
+				Entries:    entries,
+				MaxEntries: a.router.engineExecutionConfiguration.SlowPlanCacheSize,
+			})
+		}
+
+		resp = append(resp, adminGraphCaches{
+			FeatureFlag: name,
+			Caches:      summaries,
+		})
+	}
+
+	writeAdminJSON(w, http.StatusOK, resp)
+}
+
+// handleFlushCaches flushes a single cache or, without a cache name, all caches. The feature_flag
+// query parameter limits the flush to the graph of the feature flag, "base" selects the base graph.
+// The response cache is flushed for all graphs, the operation_name query parameter limits the
+// flush to the responses of the operation.
+func (a *adminAPIServer) handleFlushCaches(w http.ResponseWriter, r *http.Request) {
+	cacheName := chi.URLParam(r, "cache")
+
+	if cacheName == responseCacheName {
+		a.flushResponseCache(w, r)
+		return
+	}
+
+	featureFlag, onlyOneGraph := r.URL.Query().Get("feature_flag"), r.URL.Query().Has("feature_flag")
+	if featureFlag == "base" {
+		featureFlag = ""
+	}
+
+	names, muxes := a.graphMuxes()
+	if onlyOneGraph {
+		if _, ok := muxes[featureFlag]; !ok {
+			writeAdminError(w, http.StatusNotFound, fmt.Sprintf("feature flag %q not found", featureFlag))
+			return
+		}
+		names = []string{featureFlag}
+	}
+
+	known := cacheName == "" || cacheName == slowPlanCacheName
+	for _, name := range names {
+		gm := muxes[name]
+
+		for n, cache := range gm.adminCaches() {
+			if cacheName == "" || cacheName == n {
+				cache.Clear()
+				known = true
+			}
+		}
+
+		if cacheName == "" || cacheName == slowPlanCacheName {
+			gm.planFallbackCache.Clear()
+		}
+	}
+
+	if !known {
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("cache %q not found", cacheName))
+		return
+	}
+
+	if cacheName == "" && !onlyOneGraph {
+		if err := a.router.responseCacheStore.Clear(r.Context()); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	a.logger.Info("Caches flushed", zap.String("cache", cmp.Or(cacheName, "all")), zap.Strings("feature_flags", names))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminAPIServer) flushResponseCache(w http.ResponseWriter, r *http.Request) {
+	if a.router.responseCacheStore == nil {
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("cache %q not found", responseCacheName))
+		return
+	}
+
+	var err error
+	operationName, onlyOneOperation := r.URL.Query().Get("operation_name"), r.URL.Query().Has("operation_name")
+	if onlyOneOperation {
+		err = a.router.responseCacheStore.InvalidateOperation(r.Context(), operationName)
+	} else {
+		err = a.router.responseCacheStore.Clear(r.Context())
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if onlyOneOperation {
+		a.logger.Info("Response cache invalidated", zap.String("operation_name", operationName))
+	} else {
+		a.logger.Info("Caches flushed", zap.String("cache", responseCacheName))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminAPIServer) handleSlowPlans(w http.ResponseWriter, _ *http.Request) {
+	names, muxes := a.graphMuxes()
+
+	resp := make([]adminGraphSlowPlans, 0, len(names))
+	for _, name := range names {
+		plans := []adminSlowPlan{}
+		for p := range muxes[name].planFallbackCache.Values() {
+			plans = append(plans, adminSlowPlan{
+				OperationName:    p.operationName,
+				Content:          p.content,
+				PlanningDuration: p.planningDuration.String(),
+			})
+		}
+
+		resp = append(resp, adminGraphSlowPlans{
+			FeatureFlag: name,
+			Plans:       plans,
+		})
+	}
+
+	writeAdminJSON(w, http.StatusOK, resp)
+}
+
+func (a *adminAPIServer) handlePubSubProviders(w http.ResponseWriter, _ *http.Request) {
+	names, muxes := a.graphMuxes()
+
+	providers := []adminPubSubProvider{}
+	for _, name := range names {
+		providers = append(providers, muxes[name].pubSubProviderStates(name)...)
+	}
+
+	writeAdminJSON(w, http.StatusOK, providers)
+}
+
+func (a *adminAPIServer) handleReload(w http.ResponseWriter, _ *http.Request) {
+	if a.router.reloadFunc == nil {
+		writeAdminError(w, http.StatusNotImplemented, "the router is not running under a supervisor and can't be reloaded")
+		return
+	}
+
+	a.logger.Info("Reload requested through admin API")
+
+	// The reload restarts the router, which reads the execution config again. It shuts down this
+	// router including the admin API, so it must not block the request.
+	go a.router.reloadFunc()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// adminCaches returns the enabled ristretto caches of s keyed by name
+func (s *graphMux) adminCaches() map[string]adminCache {
+	caches := make(map[string]adminCache)
+
+	add := func(name string, cache adminCache, enabled bool) {
+		if enabled {
+			caches[name] = cache
+		}
+	}
+
+	add("plan", s.planCache, s.planCache != nil)
+	add("persisted_query_normalization", s.persistedOperationCache, s.persistedOperationCache != nil)
+	add("query_normalization", s.normalizationCache, s.normalizationCache != nil)
+	add("complexity_calculation", s.complexityCalculationCache, s.complexityCalculationCache != nil)
+	add("variables_normalization", s.variablesNormalizationCache, s.variablesNormalizationCache != nil)
+	add("remap_variables", s.remapVariablesCache, s.remapVariablesCache != nil)
+	add("validation", s.validationCache, s.validationCache != nil)
+	add("operation_hash", s.operationHashCache, s.operationHashCache != nil)
+
+	return caches
+}
+
+// pubSubProviderStates returns the startup state of the pubsub providers of s
+func (s *graphMux) pubSubProviderStates(featureFlag string) []adminPubSubProvider {
+	s.pubSubProviderErrorsLock.Lock()
+	defer s.pubSubProviderErrorsLock.Unlock()
+
+	states := make([]adminPubSubProvider, 0, len(s.pubSubProviders))
+	for _, provider := range s.pubSubProviders {
+		state := adminPubSubProvider{
+			FeatureFlag: featureFlag,
+			ID:          provider.ID(),
+			Type:        provider.TypeID(),
+			Status:      "starting",
+		}
+
+		if err, ok := s.pubSubProviderErrors[provider]; ok {
+			if err != nil {
+				state.Status = "failed"
+				state.Error = err.Error()
+			} else {
+				state.Status = "running"
+			}
+		}
+
+		states = append(states, state)
+	}
+
+	return states
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, adminErrorResponse{Error: message})
+}
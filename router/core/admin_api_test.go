@@ -0,0 +1,270 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/circuit"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/cosmo/router/pkg/pubsub/datasource"
+	"github.com/wundergraph/cosmo/router/pkg/responsecache"
+	"github.com/wundergraph/cosmo/router/pkg/slowplancache"
+)
+
+const testAdminToken = "admin-token"
+
+func newTestAdminAPI(t *testing.T) (*Router, http.Handler) {
+	t.Helper()
+
+	planCache, err := ristretto.NewCache(&ristretto.Config[uint64, *planWithMetaData]{
+		MaxCost:            10,
+		NumCounters:        100,
+		BufferItems:        64,
+		IgnoreInternalCost: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(planCache.Close)
+
+	slowPlanCache, err := slowplancache.New[*planWithMetaData](5, 0)
+	require.NoError(t, err)
+	t.Cleanup(slowPlanCache.Close)
+
+	planCache.Set(1, &planWithMetaData{}, 1)
+	planCache.Set(2, &planWithMetaData{}, 1)
+	planCache.Wait()
+	slowPlanCache.Set(1, &planWithMetaData{content: "query { slow }", operationName: "Slow", planningDuration: time.Second}, time.Second)
+	slowPlanCache.Wait()
+
+	baseMux := &graphMux{
+		planCache:           planCache,
+		planFallbackCache:   slowPlanCache,
+		routerConfigVersion: "base-version",
+		pubSubProviders:     []datasource.Provider{failingStartupProvider(t)},
+		logger:              zap.NewNop(),
+	}
+	require.Error(t, baseMux.startPubsubProviders(context.Background()))
+
+	manager, err := circuit.NewManager(circuit.CircuitBreakerConfig{
+		Enabled:         true,
+		RollingDuration: 10 * time.Second,
+		NumBuckets:      10,
+	})
+	require.NoError(t, err)
+	require.NoError(t, manager.Initialize(circuit.ManagerOpts{
+		AllGroupings: map[string]map[string]bool{
+			"http://employees": {"employees": true},
+		},
+	}))
+
+	r := &Router{
+		Config: Config{
+			logger:   zap.NewNop(),
+			adminAPI: config.AdminAPIConfiguration{Token: testAdminToken},
+			loadedModules: []ModuleInfo{
+				{ID: "first", Priority: 1},
+				{ID: "second"},
+			},
+		},
+		httpServer: &server{},
+	}
+	r.httpServer.state.Store(&serverState{
+		graphServer: &graphServer{
+			baseRouterConfigVersion: "base-version",
+			graphMuxList: map[string]*graphMux{
+				"":     baseMux,
+				"beta": {routerConfigVersion: "beta-version"},
+			},
+			circuitBreakerManager: manager,
+		},
+	})
+
+	return r, newAdminAPIServer(r).handler()
+}
+
+func adminRequest(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestAdminAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rejects requests without the token", func(t *testing.T) {
+		t.Parallel()
+
+		_, handler := newTestAdminAPI(t)
+
+		for _, authorization := range []string{"", "Bearer wrong", testAdminToken} {
+			req := httptest.NewRequest(http.MethodGet, "/config", nil)
+			req.Header.Set("Authorization", authorization)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+		}
+	})
+
+	t.Run("returns the execution config versions", func(t *testing.T) {
+		t.Parallel()
+
+		_, handler := newTestAdminAPI(t)
+
+		rec := adminRequest(t, handler, http.MethodGet, "/config", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"config_version":"base-version","feature_flags":[{"name":"beta","config_version":"beta-version"}]}`, rec.Body.String())
+	})
+
+	t.Run("returns the modules in provisioning order", func(t *testing.T) {
+		t.Parallel()
+
+		_, handler := newTestAdminAPI(t)
+
+		rec := adminRequest(t, handler, http.MethodGet, "/modules", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"id":"first","priority":1},{"id":"second","priority":0}]`, rec.Body.String())
+	})
+
+	t.Run("forces a circuit open", func(t *testing.T) {
+		t.Parallel()
+
+		_, handler := newTestAdminAPI(t)
+
+		rec := adminRequest(t, handler, http.MethodPut, "/circuits/employees", `{"force":"open"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = adminRequest(t, handler, http.MethodGet, "/circuits", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var states []circuit.CircuitState
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &states))
+		require.Len(t, states, 1)
+		assert.True(t, states[0].Open)
+		assert.Equal(t, circuit.ForceOpen, states[0].Forced)
+
+		rec = adminRequest(t, handler, http.MethodPut, "/circuits/unknown", `{"force":"open"}`)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = adminRequest(t, handler, http.MethodPut, "/circuits/employees", `{"force":"half-open"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("returns and flushes caches", func(t *testing.T) {
+		t.Parallel()
+
+		r, handler := newTestAdminAPI(t)
+		r.engineExecutionConfiguration.SlowPlanCacheSize = 5
+
+		rec := adminRequest(t, handler, http.MethodGet, "/caches", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[
+			{"feature_flag":"","caches":[{"name":"plan","entries":2,"max_entries":10},{"name":"slow_plan","entries":1,"max_entries":5}]},
+			{"feature_flag":"beta","caches":[]}
+		]`, rec.Body.String())
+
+		rec = adminRequest(t, handler, http.MethodGet, "/caches/slow_plan/entries", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[
+			{"feature_flag":"","plans":[{"operation_name":"Slow","content":"query { slow }","planning_duration":"1s"}]},
+			{"feature_flag":"beta","plans":[]}
+		]`, rec.Body.String())
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches/unknown", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches/plan?feature_flag=unknown", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches/plan?feature_flag=base", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		gm := currentGraphMuxes(r)[""]
+		assert.Equal(t, int64(0), gm.planCache.MaxCost()-gm.planCache.RemainingCost())
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		gm.planFallbackCache.Wait()
+		_, ok := gm.planFallbackCache.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run("flushes the response cache", func(t *testing.T) {
+		t.Parallel()
+
+		r, handler := newTestAdminAPI(t)
+
+		rec := adminRequest(t, handler, http.MethodDelete, "/caches/response", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		store, err := responsecache.NewMemoryStore(10)
+		require.NoError(t, err)
+		r.responseCacheStore = &ResponseCache{store: store}
+
+		entry := responsecache.NewEntry([]byte(`{"data":{}}`), nil, responsecache.Directives{MaxAge: time.Minute}, time.Now())
+		for _, key := range []string{responsecache.Key("Employees", "1"), responsecache.Key("Teams", "1")} {
+			require.NoError(t, store.Set(t.Context(), key, entry))
+		}
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches/response?operation_name=Employees", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, 1, store.Len())
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches?feature_flag=base", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, 1, store.Len())
+
+		rec = adminRequest(t, handler, http.MethodDelete, "/caches", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, 0, store.Len())
+	})
+
+	t.Run("returns the pubsub provider status", func(t *testing.T) {
+		t.Parallel()
+
+		_, handler := newTestAdminAPI(t)
+
+		rec := adminRequest(t, handler, http.MethodGet, "/pubsub/providers", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"feature_flag":"","id":"redis-1","type":"redis","status":"failed","error":"connection refused"}]`, rec.Body.String())
+	})
+
+	t.Run("triggers a reload", func(t *testing.T) {
+		t.Parallel()
+
+		r, handler := newTestAdminAPI(t)
+
+		rec := adminRequest(t, handler, http.MethodPost, "/reload", "")
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+
+		reloaded := make(chan struct{})
+		r.reloadFunc = func() {
+			close(reloaded)
+		}
+
+		rec = adminRequest(t, handler, http.MethodPost, "/reload", "")
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		select {
+		case <-reloaded:
+		case <-time.After(2 * time.Second):
+			t.Fatal("reload was not triggered")
+		}
+	})
+}
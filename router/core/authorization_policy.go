@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/ast"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/plan"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+// authorizationPolicy is a compiled config.AuthorizationPolicy
+type authorizationPolicy struct {
+	name        string
+	coordinates []policyCoordinate
+	condition   *vm.Program
+}
+
+// policyCoordinate is a type or, when fieldName is set, a field the policy applies to
+type policyCoordinate struct {
+	typeName  string
+	fieldName string
+}
+
+func (c policyCoordinate) matches(coordinate resolve.GraphCoordinate) bool {
+	return c.typeName == coordinate.TypeName && (c.fieldName == "" || c.fieldName == coordinate.FieldName)
+}
+
+func (p *authorizationPolicy) appliesTo(coordinate resolve.GraphCoordinate) bool {
+	return slices.ContainsFunc(p.coordinates, func(c policyCoordinate) bool {
+		return c.matches(coordinate)
+	})
+}
+
+// allows evaluates the condition of the policy. Conditions that fail to evaluate deny the access.
+func (p *authorizationPolicy) allows(exprCtx expr.Context) bool {
+	ok, err := expr.ResolveBoolExpression(p.condition, exprCtx)
+	return err == nil && ok
+}
+
+func parsePolicyCoordinate(coordinate string) (policyCoordinate, error) {
+	typeName, fieldName, _ := strings.Cut(coordinate, ".")
+	if typeName == "" || strings.Contains(fieldName, ".") {
+		return policyCoordinate{}, fmt.Errorf("invalid coordinate '%s', expected a type or a field like Type.field", coordinate)
+	}
+	return policyCoordinate{typeName: typeName, fieldName: fieldName}, nil
+}
+
+// newAuthorizationPolicies compiles the conditions of the policies
+func newAuthorizationPolicies(exprManager *expr.Manager, policies []config.AuthorizationPolicy) ([]*authorizationPolicy, error) {
+	out := make([]*authorizationPolicy, 0, len(policies))
+
+	for _, policy := range policies {
+		if len(policy.Coordinates) == 0 {
+			return nil, fmt.Errorf("authorization policy '%s' has no coordinates", policy.Name)
+		}
+
+		coordinates := make([]policyCoordinate, 0, len(policy.Coordinates))
+		for _, c := range policy.Coordinates {
+			coordinate, err := parsePolicyCoordinate(c)
+			if err != nil {
+				return nil, fmt.Errorf("authorization policy '%s': %w", policy.Name, err)
+			}
+			coordinates = append(coordinates, coordinate)
+		}
+
+		condition, err := exprManager.CompileExpression(policy.Condition, reflect.Bool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile condition of authorization policy '%s': %w", policy.Name, err)
+		}
+
+		out = append(out, &authorizationPolicy{
+			name:        policy.Name,
+			coordinates: coordinates,
+			condition:   condition,
+		})
+	}
+
+	return out, nil
+}
+
+// applyAuthorizationPolicies marks the fields protected by a policy as having an authorization rule,
+// so that the engine asks the authorizer about them. Coordinates that don't exist in the schema
+// are logged and skipped.
+func applyAuthorizationPolicies(logger *zap.Logger, planConfig *plan.Configuration, schema *ast.Document, policies []config.AuthorizationPolicy) error {
+	for _, policy := range policies {
+		for _, c := range policy.Coordinates {
+			coordinate, err := parsePolicyCoordinate(c)
+			if err != nil {
+				return fmt.Errorf("authorization policy '%s': %w", policy.Name, err)
+			}
+
+			node, ok := schema.Index.FirstNodeByNameStr(coordinate.typeName)
+			if !ok || (node.Kind != ast.NodeKindObjectTypeDefinition && node.Kind != ast.NodeKindInterfaceTypeDefinition) {
+				logger.Warn("Authorization policy coordinate is not an object or interface type of the schema",
+					zap.String("policy", policy.Name),
+					zap.String("coordinate", c),
+				)
+				continue
+			}
+
+			if coordinate.fieldName != "" {
+				if _, ok := schema.NodeFieldDefinitionByName(node, []byte(coordinate.fieldName)); !ok {
+					logger.Warn("Authorization policy coordinate is not a field of the schema",
+						zap.String("policy", policy.Name),
+						zap.String("coordinate", c),
+					)
+					continue
+				}
+				markFieldAuthorizationRule(planConfig, coordinate.typeName, coordinate.fieldName)
+				continue
+			}
+
+			for _, ref := range schema.NodeFieldDefinitions(node) {
+				fieldName := schema.FieldDefinitionNameString(ref)
+				// The introspection fields are never protected
+				if strings.HasPrefix(fieldName, "__") {
+					continue
+				}
+				markFieldAuthorizationRule(planConfig, coordinate.typeName, fieldName)
+			}
+		}
+	}
+
+	return nil
+}
+
+func markFieldAuthorizationRule(planConfig *plan.Configuration, typeName, fieldName string) {
+	for i := range planConfig.Fields {
+		if planConfig.Fields[i].TypeName == typeName && planConfig.Fields[i].FieldName == fieldName {
+			planConfig.Fields[i].HasAuthorizationRule = true
+			return
+		}
+	}
+
+	planConfig.Fields = append(planConfig.Fields, plan.FieldConfiguration{
+		TypeName:             typeName,
+		FieldName:            fieldName,
+		HasAuthorizationRule: true,
+	})
+}
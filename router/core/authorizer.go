@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	"github.com/wundergraph/cosmo/router/internal/expr"
 	"github.com/wundergraph/cosmo/router/pkg/authentication"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
 )
@@ -22,6 +23,9 @@ type CosmoAuthorizerOptions struct {
 	// EnablePreFetchFieldAuthorization authorizes protected fields in a single batch before any
 	// subgraph fetch runs, instead of filtering them out of the response afterwards.
 	EnablePreFetchFieldAuthorization bool
+	// Policies are the authorization policies of the router config. A field is denied when a policy
+	// that applies to it does not allow the request.
+	Policies []*authorizationPolicy
 }
 
 func NewCosmoAuthorizer(opts *CosmoAuthorizerOptions) *CosmoAuthorizer {
@@ -29,16 +33,19 @@ func NewCosmoAuthorizer(opts *CosmoAuthorizerOptions) *CosmoAuthorizer {
 		fieldConfigurations:              opts.FieldConfigurations,
 		rejectUnauthorized:               opts.RejectOperationIfUnauthorized,
 		enablePreFetchFieldAuthorization: opts.EnablePreFetchFieldAuthorization,
+		policies:                         opts.Policies,
 	}
 }
 
 // CosmoAuthorizer enforces field-level authorization (@authenticated and @requiresScopes) against
-// the scopes of the authenticated request. It implements resolve.Authorizer and, when pre-fetch
-// field authorization is enabled, resolve.BatchAuthorizer.
+// the scopes of the authenticated request, and the authorization policies of the router config.
+// It implements resolve.Authorizer and, when pre-fetch field authorization is enabled,
+// resolve.BatchAuthorizer.
 type CosmoAuthorizer struct {
 	fieldConfigurations              []*nodev1.FieldConfiguration
 	rejectUnauthorized               bool
 	enablePreFetchFieldAuthorization bool
+	policies                         []*authorizationPolicy
 }
 
 // IsPreFetchFieldAuthorizationEnabled reports whether the engine should authorize protected fields
@@ -47,11 +54,11 @@ func (a *CosmoAuthorizer) IsPreFetchFieldAuthorizationEnabled() bool {
 	return a.enablePreFetchFieldAuthorization
 }
 
-// HasResponseExtensionData reports whether any missing scopes were collected during resolution and
-// should be rendered into the response extensions.
+// HasResponseExtensionData reports whether any missing scopes or denied policies were collected
+// during resolution and should be rendered into the response extensions.
 func (a *CosmoAuthorizer) HasResponseExtensionData(ctx *resolve.Context) bool {
 	extension := a.getAuthorizationExtension(ctx)
-	return extension != nil && len(extension.MissingScopes) > 0
+	return extension != nil && (len(extension.MissingScopes) > 0 || len(extension.DeniedPolicies) > 0)
 }
 
 // RenderResponseExtension writes the collected authorization extension (missing and actual scopes)
@@ -96,16 +103,14 @@ func (a *CosmoAuthorizer) handleRejectUnauthorized(result *resolve.Authorization
 // which matters for mutations where filtering the response afterwards would not stop the write.
 func (a *CosmoAuthorizer) AuthorizePreFetch(ctx *resolve.Context, dataSourceID string, input json.RawMessage, coordinate resolve.GraphCoordinate) (result *resolve.AuthorizationDeny, err error) {
 	isAuthenticated, actual := a.getAuth(ctx.Context())
-	required := a.requiredScopesForField(coordinate)
-	return a.handleRejectUnauthorized(a.validateScopes(ctx, coordinate, required, isAuthenticated, actual))
+	return a.handleRejectUnauthorized(a.authorizeField(ctx, coordinate, isAuthenticated, actual))
 }
 
 // AuthorizeObjectField authorizes a field against the already-fetched response object. A deny filters
 // the field out of the response but cannot prevent the fetch.
 func (a *CosmoAuthorizer) AuthorizeObjectField(ctx *resolve.Context, dataSourceID string, object json.RawMessage, coordinate resolve.GraphCoordinate) (result *resolve.AuthorizationDeny, err error) {
 	isAuthenticated, actual := a.getAuth(ctx.Context())
-	required := a.requiredScopesForField(coordinate)
-	return a.handleRejectUnauthorized(a.validateScopes(ctx, coordinate, required, isAuthenticated, actual))
+	return a.handleRejectUnauthorized(a.authorizeField(ctx, coordinate, isAuthenticated, actual))
 }
 
 // AuthorizeFields authorizes every protected field coordinate of an operation in one call, before any
@@ -116,8 +121,7 @@ func (a *CosmoAuthorizer) AuthorizeFields(ctx *resolve.Context, coordinates []re
 	isAuthenticated, actual := a.getAuth(ctx.Context())
 
 	for i, coordinate := range coordinates {
-		required := a.requiredScopesForField(coordinate)
-		deny, err := a.handleRejectUnauthorized(a.validateScopes(ctx, coordinate, required, isAuthenticated, actual))
+		deny, err := a.handleRejectUnauthorized(a.authorizeField(ctx, coordinate, isAuthenticated, actual))
 		if err != nil {
 			return nil, err
 		}
@@ -138,6 +142,19 @@ func (a *CosmoAuthorizer) AuthorizeFields(ctx *resolve.Context, coordinates []re
 	return decisions, nil
 }
 
+// authorizeField checks the required scopes of a field and the policies that apply to it. Fields that
+// are only protected by policies don't require the request to be authenticated.
+func (a *CosmoAuthorizer) authorizeField(ctx *resolve.Context, coordinate resolve.GraphCoordinate, isAuthenticated bool, actual []string) *resolve.AuthorizationDeny {
+	policies := a.policiesForField(coordinate)
+	if len(policies) == 0 || a.hasFieldAuthorizationRule(coordinate) {
+		required := a.requiredScopesForField(coordinate)
+		if deny := a.validateScopes(ctx, coordinate, required, isAuthenticated, actual); deny != nil {
+			return deny
+		}
+	}
+	return a.validatePolicies(ctx, coordinate, policies)
+}
+
 // validateScopes checks the actual scopes against a field's required scopes. requiredOrScopes is a
 // disjunction: the field is authorized if all scopes of any one entry are present (OR of ANDs). An
 // unauthenticated request is always denied; a field with no required scopes is allowed. Denials are
@@ -166,6 +183,51 @@ WithNext:
 	}
 }
 
+// validatePolicies evaluates the policies against the expression context of the request. The field
+// is denied when any policy does not allow it. Denials are recorded via addDeniedPolicy.
+func (a *CosmoAuthorizer) validatePolicies(ctx *resolve.Context, coordinate resolve.GraphCoordinate, policies []*authorizationPolicy) *resolve.AuthorizationDeny {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	var exprCtx expr.Context
+	if reqCtx := getRequestContext(ctx.Context()); reqCtx != nil {
+		exprCtx = reqCtx.expressionContext
+	}
+
+	for _, policy := range policies {
+		if !policy.allows(exprCtx) {
+			a.addDeniedPolicy(ctx, coordinate, policy.name)
+			return &resolve.AuthorizationDeny{
+				Reason: "denied by authorization policy",
+			}
+		}
+	}
+	return nil
+}
+
+// addDeniedPolicy records a field denied by a policy on the authorization extension context,
+// deduplicating by coordinate and policy. It is a no-op when no extension context is attached.
+func (a *CosmoAuthorizer) addDeniedPolicy(ctx *resolve.Context, coordinate resolve.GraphCoordinate, policy string) {
+	extensionCtx := ctx.Context().Value(authorizationExtensionKey{})
+	if extensionCtx == nil {
+		return
+	}
+	extension := extensionCtx.(*authorizationExtensionCtx)
+	extension.mux.Lock()
+	if !slices.ContainsFunc(extension.extension.DeniedPolicies, func(existing DeniedPolicyError) bool {
+		return existing.Policy == policy &&
+			existing.Coordinate.TypeName == coordinate.TypeName &&
+			existing.Coordinate.FieldName == coordinate.FieldName
+	}) {
+		extension.extension.DeniedPolicies = append(extension.extension.DeniedPolicies, DeniedPolicyError{
+			Coordinate: coordinate,
+			Policy:     policy,
+		})
+	}
+	extension.mux.Unlock()
+}
+
 // addMissingScopes records a denied field and the request's actual scopes on the authorization
 // extension context, deduplicating by coordinate. It is a no-op when no extension context is attached.
 func (a *CosmoAuthorizer) addMissingScopes(ctx *resolve.Context, coordinate resolve.GraphCoordinate, requiredOrScopes []*nodev1.Scopes, actual []string) {
@@ -221,8 +283,9 @@ func WithAuthorizationExtension(ctx *resolve.Context) *resolve.Context {
 
 // AuthorizationExtension is the authorization payload rendered into the response extensions.
 type AuthorizationExtension struct {
-	MissingScopes []MissingScopesError `json:"missingScopes,omitempty"`
-	ActualScopes  []string             `json:"actualScopes"`
+	MissingScopes  []MissingScopesError `json:"missingScopes,omitempty"`
+	DeniedPolicies []DeniedPolicyError  `json:"deniedPolicies,omitempty"`
+	ActualScopes   []string             `json:"actualScopes"`
 }
 
 // MissingScopesError reports a field that was denied and the scopes it required (an OR of ANDs).
@@ -231,6 +294,12 @@ type MissingScopesError struct {
 	RequiredOrScopes [][]string              `json:"required"`
 }
 
+// DeniedPolicyError reports a field that was denied by an authorization policy of the router config.
+type DeniedPolicyError struct {
+	Coordinate resolve.GraphCoordinate `json:"coordinate"`
+	Policy     string                  `json:"policy"`
+}
+
 type RequiredAndScopes struct {
 	RequiredAndScopes []string `json:"and"`
 }
@@ -262,3 +331,26 @@ func (a *CosmoAuthorizer) requiredScopesForField(coordinate resolve.GraphCoordin
 	}
 	return nil
 }
+
+// hasFieldAuthorizationRule reports whether the schema requires authentication or scopes for a field
+// coordinate.
+func (a *CosmoAuthorizer) hasFieldAuthorizationRule(coordinate resolve.GraphCoordinate) bool {
+	for i := range a.fieldConfigurations {
+		if a.fieldConfigurations[i].TypeName == coordinate.TypeName && a.fieldConfigurations[i].FieldName == coordinate.FieldName {
+			authorization := a.fieldConfigurations[i].GetAuthorizationConfiguration()
+			return authorization.GetRequiresAuthentication() || len(authorization.GetRequiredOrScopes()) > 0
+		}
+	}
+	return false
+}
+
+// policiesForField returns the authorization policies that apply to a field coordinate.
+func (a *CosmoAuthorizer) policiesForField(coordinate resolve.GraphCoordinate) []*authorizationPolicy {
+	var policies []*authorizationPolicy
+	for _, policy := range a.policies {
+		if policy.appliesTo(coordinate) {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
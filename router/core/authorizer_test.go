@@ -4,13 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+	"github.com/wundergraph/cosmo/router/internal/expr"
 	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/astparser"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/plan"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
 )
 
@@ -270,3 +277,112 @@ func TestCosmoAuthorizer_IsPreFetchFieldAuthorizationEnabled(t *testing.T) {
 	disabled := NewCosmoAuthorizer(&CosmoAuthorizerOptions{})
 	assert.False(t, disabled.IsPreFetchFieldAuthorizationEnabled())
 }
+
+// policyContext builds a resolve.Context for a request of the tenant with the X-Tenant header
+func policyContext(tenant, headerTenant string) *resolve.Context {
+	header := http.Header{}
+	header.Set("X-Tenant", headerTenant)
+	rc := &requestContext{
+		expressionContext: expr.Context{
+			Request: expr.Request{
+				Auth:   expr.RequestAuth{IsAuthenticated: true, Claims: map[string]any{"tenant": tenant}},
+				Header: expr.Headers{Header: header},
+			},
+		},
+	}
+	return resolve.NewContext(context.WithValue(context.Background(), rcontext.RequestContextKey, rc))
+}
+
+func TestCosmoAuthorizer_Policies(t *testing.T) {
+	t.Parallel()
+
+	policies, err := newAuthorizationPolicies(expr.CreateNewExprManager(), []config.AuthorizationPolicy{
+		{
+			Name:        "tenant_isolation",
+			Coordinates: []string{"Employee", "Query.employees"},
+			Condition:   `request.auth.claims.tenant == request.header.Get("X-Tenant")`,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("allows when the condition is met", func(t *testing.T) {
+		t.Parallel()
+		a := NewCosmoAuthorizer(&CosmoAuthorizerOptions{Policies: policies})
+		deny, err := a.AuthorizeObjectField(policyContext("acme", "acme"), "ds", nil, coordinate("Employee", "name"))
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+	})
+
+	t.Run("denies and records the policy when the condition is not met", func(t *testing.T) {
+		t.Parallel()
+		a := NewCosmoAuthorizer(&CosmoAuthorizerOptions{Policies: policies})
+		ctx := WithAuthorizationExtension(policyContext("acme", "globex"))
+
+		decisions, err := a.AuthorizeFields(ctx, []resolve.GraphCoordinate{coordinate("Query", "employees")})
+		require.NoError(t, err)
+		assert.False(t, decisions[0].Allowed)
+		assert.Equal(t, "denied by authorization policy", decisions[0].Reason)
+
+		require.True(t, a.HasResponseExtensionData(ctx))
+		var buf bytes.Buffer
+		require.NoError(t, a.RenderResponseExtension(ctx, &buf))
+		var ext AuthorizationExtension
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &ext))
+		require.Equal(t, []DeniedPolicyError{{Coordinate: coordinate("Query", "employees"), Policy: "tenant_isolation"}}, ext.DeniedPolicies)
+	})
+
+	t.Run("checks the required scopes before the policies", func(t *testing.T) {
+		t.Parallel()
+		a := NewCosmoAuthorizer(&CosmoAuthorizerOptions{
+			FieldConfigurations: []*nodev1.FieldConfiguration{fieldConfig("Employee", "salary", []string{"read:salary"})},
+			Policies:            policies,
+		})
+		deny, err := a.AuthorizePreFetch(policyContext("acme", "acme"), "ds", nil, coordinate("Employee", "salary"))
+		require.NoError(t, err)
+		require.NotNil(t, deny)
+		assert.Equal(t, "not authenticated", deny.Reason)
+	})
+
+	t.Run("rejects the operation when enabled", func(t *testing.T) {
+		t.Parallel()
+		a := NewCosmoAuthorizer(&CosmoAuthorizerOptions{Policies: policies, RejectOperationIfUnauthorized: true})
+		_, err := a.AuthorizeObjectField(policyContext("acme", "globex"), "ds", nil, coordinate("Employee", "id"))
+		require.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("rejects invalid coordinates", func(t *testing.T) {
+		t.Parallel()
+		_, err := newAuthorizationPolicies(expr.CreateNewExprManager(), []config.AuthorizationPolicy{
+			{Name: "invalid", Coordinates: []string{"Query.employees.id"}, Condition: "true"},
+		})
+		require.ErrorContains(t, err, "invalid coordinate 'Query.employees.id'")
+	})
+}
+
+func TestApplyAuthorizationPolicies(t *testing.T) {
+	t.Parallel()
+
+	schema, report := astparser.ParseGraphqlDocumentString(`
+		type Query { employees(tenant: String): [Employee!]! products: [String!]! }
+		type Employee { id: ID! name: String! }
+	`)
+	require.False(t, report.HasErrors())
+
+	planConfig := &plan.Configuration{
+		Fields: plan.FieldConfigurations{
+			{TypeName: "Query", FieldName: "employees", Arguments: plan.ArgumentsConfigurations{{Name: "tenant"}}},
+		},
+	}
+
+	err := applyAuthorizationPolicies(zap.NewNop(), planConfig, &schema, []config.AuthorizationPolicy{
+		{Name: "tenant_isolation", Coordinates: []string{"Employee", "Query.employees", "Query.unknown"}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, planConfig.Fields, 3)
+	require.True(t, planConfig.Fields.ForTypeField("Query", "employees").HasAuthorizationRule)
+	require.Len(t, planConfig.Fields.ForTypeField("Query", "employees").Arguments, 1)
+	require.True(t, planConfig.Fields.ForTypeField("Employee", "id").HasAuthorizationRule)
+	require.True(t, planConfig.Fields.ForTypeField("Employee", "name").HasAuthorizationRule)
+	require.Nil(t, planConfig.Fields.ForTypeField("Query", "products"))
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wundergraph/cosmo/router/internal/stringsx"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/cosmo/router/pkg/cors"
+	"github.com/wundergraph/cosmo/router/pkg/routerconfig"
+)
+
+// liveConfig holds the router settings that can be changed without restarting the router.
+// See config.Diff for the config fields they are built from.
+type liveConfig struct {
+	headerRules           *config.HeaderRules
+	headerPropagation     *HeaderPropagation
+	cacheControlPolicy    config.CacheControlPolicy
+	corsOptions           *cors.Config
+	securityConfiguration config.SecurityConfiguration
+	rateLimit             *config.RateLimitConfiguration
+	accessLogsConfig      *AccessLogsConfig
+}
+
+func (r *Router) liveConfig() liveConfig {
+	return liveConfig{
+		headerRules:           r.headerRules,
+		headerPropagation:     r.headerPropagation,
+		cacheControlPolicy:    r.cacheControlPolicy,
+		corsOptions:           r.corsOptions,
+		securityConfiguration: r.securityConfiguration,
+		rateLimit:             r.rateLimit,
+		accessLogsConfig:      r.accessLogsConfig,
+	}
+}
+
+// liveConfigOptions returns the options of the live reloadable settings of the config
+func liveConfigOptions(cfg *config.Config) []Option {
+	return []Option{
+		WithHeaderRules(cfg.Headers),
+		WithCors(&cors.Config{
+			Enabled:          cfg.CORS.Enabled,
+			AllowOrigins:     cfg.CORS.AllowOrigins,
+			AllowMethods:     cfg.CORS.AllowMethods,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			AllowHeaders:     cfg.CORS.AllowHeaders,
+			MaxAge:           cfg.CORS.MaxAge,
+		}),
+		WithCacheControlPolicy(cfg.CacheControl),
+		WithSecurityConfig(cfg.SecurityConfiguration),
+		WithRateLimitConfig(&cfg.RateLimit),
+	}
+}
+
+// prepareLiveConfig applies the defaults to the live reloadable settings and validates them.
+// It is called on startup and for every config that is applied to the running router.
+func (r *Router) prepareLiveConfig(ctx context.Context) error {
+	if r.corsOptions == nil {
+		r.corsOptions = CorsDefaultOptions()
+	}
+
+	postRules := CreateCacheControlPolicyHeaderRules(r.cacheControlPolicy)
+	var err error
+	r.headerPropagation, err = NewHeaderPropagation(ctx, r.logger, r.headerRules, postRules)
+	if err != nil {
+		return err
+	}
+
+	defaultCorsHeaders := []string{
+		// Common headers
+		"authorization",
+		"origin",
+		"content-length",
+		"content-type",
+		// Semi standard client info headers
+		"graphql-client-name",
+		"graphql-client-version",
+		// Apollo client info headers
+		"apollographql-client-name",
+		"apollographql-client-version",
+		// Required for WunderGraph ART
+		"x-wg-trace",
+		"x-wg-disable-tracing",
+		"x-wg-token",
+		"x-wg-skip-loader",
+		"x-wg-include-query-plan",
+		// Required for Trace Context propagation
+		"traceparent",
+		"tracestate",
+		// Required for feature flags
+		"x-feature-flag",
+	}
+
+	if r.clientHeader.Name != "" {
+		defaultCorsHeaders = append(defaultCorsHeaders, r.clientHeader.Name)
+	}
+	if r.clientHeader.Version != "" {
+		defaultCorsHeaders = append(defaultCorsHeaders, r.clientHeader.Version)
+	}
+
+	defaultMethods := []string{
+		"HEAD", "GET", "POST",
+	}
+	r.corsOptions.AllowHeaders = stringsx.RemoveDuplicates(append(r.corsOptions.AllowHeaders, defaultCorsHeaders...))
+	r.corsOptions.AllowMethods = stringsx.RemoveDuplicates(append(r.corsOptions.AllowMethods, defaultMethods...))
+
+	if r.securityConfiguration.BlockPersistedOperations.Enabled &&
+		r.securityConfiguration.BlockNonPersistedOperations.Enabled {
+
+		// Both have no condition, unusable state
+		if r.securityConfiguration.BlockPersistedOperations.Condition == "" &&
+			r.securityConfiguration.BlockNonPersistedOperations.Condition == "" {
+			return errors.New("persisted and non-persisted operations are both unconditionally blocked")
+		}
+
+		// One or both have a condition, could be intentional for edge cases
+		r.logger.Warn("The security configuration fields 'block_persisted_operations' and 'block_non_persisted_operations' are both enabled. Take care to ensure this is intentional.")
+	}
+
+	if r.persistedOperationsConfig.Safelist.Enabled && r.securityConfiguration.BlockPersistedOperations.Enabled {
+		// Both have no condition, unusable state
+		if r.securityConfiguration.BlockPersistedOperations.Condition == "" {
+			return errors.New("safelist cannot be enabled while persisted operations are unconditionally blocked")
+		}
+
+		// Has a condition, could be intentional for edge cases
+		r.logger.Warn("The security configuration field 'block_persisted_operations' is enabled alongside the persisted operations safelist. Take care to ensure this is intentional. Misconfiguration will result in safelisted queries being blocked.")
+	}
+
+	if r.securityConfiguration.DepthLimit != nil {
+		r.logger.Warn("The security configuration field 'depth_limit' is deprecated, and will be removed. Use 'security.complexity_limits.depth' instead.")
+
+		if r.securityConfiguration.ComplexityCalculationCache == nil {
+			r.securityConfiguration.ComplexityCalculationCache = &config.ComplexityCalculationCache{
+				Enabled:   true,
+				CacheSize: r.securityConfiguration.DepthLimit.CacheSize,
+			}
+		}
+
+		if r.securityConfiguration.ComplexityLimits == nil {
+			r.securityConfiguration.ComplexityLimits = &config.ComplexityLimits{
+				Mode: config.ComplexityLimitsModeEnforce,
+			}
+		}
+		if r.securityConfiguration.ComplexityLimits.Depth == nil {
+			r.securityConfiguration.ComplexityLimits.Depth = &config.ComplexityLimit{
+				Enabled:                   r.securityConfiguration.DepthLimit.Enabled,
+				Limit:                     r.securityConfiguration.DepthLimit.Limit,
+				IgnorePersistedOperations: r.securityConfiguration.DepthLimit.IgnorePersistedOperations,
+			}
+		} else {
+			r.logger.Warn("Ignoring deprecated security configuration field 'depth_limit', in favor of the `security.complexity_limits.depth` configuration")
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) setLiveConfig(c liveConfig) {
+	r.headerRules = c.headerRules
+	r.headerPropagation = c.headerPropagation
+	r.cacheControlPolicy = c.cacheControlPolicy
+	r.corsOptions = c.corsOptions
+	r.securityConfiguration = c.securityConfiguration
+	r.rateLimit = c.rateLimit
+	r.accessLogsConfig = c.accessLogsConfig
+}
+
+// ApplyConfig applies the live reloadable fields of the config to the running router, as reported
+// by config.Diff. Changes to other fields are ignored. The graph server is rebuilt with the new
+// settings, while the open subscriptions of the previous graph server are kept until their
+// clients disconnect.
+func (r *Router) ApplyConfig(ctx context.Context, cfg *config.Config) error {
+	// Only the live settings are taken from the config. Everything else, e.g. the authenticators
+	// and the telemetry providers, stays the one of the running router.
+	next := &Router{}
+	next.logger = r.logger
+	next.clientHeader = r.clientHeader
+	next.persistedOperationsConfig = r.persistedOperationsConfig
+	for _, opt := range liveConfigOptions(cfg) {
+		opt(next)
+	}
+
+	if err := next.prepareLiveConfig(ctx); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if err := validateCostRateLimit(next.rateLimit, next.securityConfiguration); err != nil {
+		return err
+	}
+
+	updated := next.liveConfig()
+	if r.accessLogsConfig != nil {
+		// The access logger itself can't be changed live, only the logged fields
+		accessLogsConfig := *r.accessLogsConfig
+		accessLogsConfig.Attributes = cfg.AccessLogs.Router.Fields
+		accessLogsConfig.IgnoreQueryParamsList = cfg.AccessLogs.Router.IgnoreQueryParamsList
+		accessLogsConfig.SubgraphEnabled = cfg.AccessLogs.Subgraphs.Enabled
+		accessLogsConfig.SubgraphAttributes = cfg.AccessLogs.Subgraphs.Fields
+		updated.accessLogsConfig = &accessLogsConfig
+	}
+
+	r.graphServerLock.Lock()
+	defer r.graphServerLock.Unlock()
+
+	state := r.httpServer.state.Load()
+	if state == nil || state.graphServer == nil {
+		return errors.New("the router is not serving a graph yet")
+	}
+
+	previous := r.liveConfig()
+	r.setLiveConfig(updated)
+
+	server, err := newGraphServer(ctx, r, &routerconfig.Response{Config: state.graphServer.executionConfig}, r.proxy)
+	if err != nil {
+		r.setLiveConfig(previous)
+		return fmt.Errorf("failed to create graph server: %w", err)
+	}
+
+	state.graphServer.drainStreams.Store(true)
+	r.httpServer.SwapGraphServer(ctx, server)
+
+	return nil
+}
+
+// reopenAccessLogFile reopens the access log file, so that the access logs are written to a new
+// file after an external tool moved it
+func (r *Router) reopenAccessLogFile() error {
+	if r.accessLogsConfig == nil || r.accessLogsConfig.File == nil {
+		return nil
+	}
+	return r.accessLogsConfig.File.Reopen()
+}
+
+// recordConfigReload records a reload of the router config in the config reload metrics
+func (r *Router) recordConfigReload(ctx context.Context, mode string, success bool) {
+	if r.configReloadMetrics == nil {
+		return
+	}
+	r.configReloadMetrics.MeasureReload(ctx, mode, success)
+}
+
+func validateCostRateLimit(rateLimit *config.RateLimitConfiguration, security config.SecurityConfiguration) error {
+	if rateLimit != nil && rateLimit.Enabled && rateLimit.Strategy == "cost" &&
+		(security.CostControl == nil || !security.CostControl.Enabled) {
+		return errors.New("the cost rate limit strategy requires security.cost_control to be enabled")
+	}
+	return nil
+}
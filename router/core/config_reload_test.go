@@ -0,0 +1,46 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestRouterApplyConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rejects an invalid config without changing the router", func(t *testing.T) {
+		t.Parallel()
+
+		r := &Router{}
+		r.logger = zap.NewNop()
+		r.corsOptions = CorsDefaultOptions()
+
+		cfg := &config.Config{}
+		cfg.SecurityConfiguration.BlockPersistedOperations.Enabled = true
+		cfg.SecurityConfiguration.BlockNonPersistedOperations.Enabled = true
+
+		err := r.ApplyConfig(t.Context(), cfg)
+		require.ErrorContains(t, err, "persisted and non-persisted operations are both unconditionally blocked")
+		require.False(t, r.securityConfiguration.BlockPersistedOperations.Enabled)
+	})
+
+	t.Run("applies the defaults of the live settings", func(t *testing.T) {
+		t.Parallel()
+
+		next := &Router{}
+		next.logger = zap.NewNop()
+		next.clientHeader = config.ClientHeader{Name: "x-client"}
+		for _, opt := range liveConfigOptions(&config.Config{CORS: config.CORS{AllowOrigins: []string{"https://example.com"}}}) {
+			opt(next)
+		}
+		require.NoError(t, next.prepareLiveConfig(t.Context()))
+
+		require.Equal(t, []string{"https://example.com"}, next.corsOptions.AllowOrigins)
+		require.Contains(t, next.corsOptions.AllowHeaders, "x-client")
+		require.NotNil(t, next.headerPropagation)
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/wundergraph/cosmo/router/internal/expr"
 
 	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+	"github.com/wundergraph/cosmo/router/internal/loadbalancer"
 	"github.com/wundergraph/cosmo/router/internal/requestlogger"
 	"github.com/wundergraph/cosmo/router/internal/traceclient"
 	"github.com/wundergraph/cosmo/router/internal/unique"
@@ -105,6 +106,7 @@ func (f *engineLoaderHooks) OnLoad(ctx context.Context, ds resolve.DataSourceInf
 	duration := atomic.Int64{}
 	ctx = context.WithValue(ctx, rcontext.FetchTimingKey, &duration)
 	ctx = traceclient.WithClientTraceResults(ctx)
+	ctx = loadbalancer.WithSelection(ctx)
 
 	reqContext := getRequestContext(ctx)
 	if reqContext == nil {
@@ -244,6 +246,12 @@ func (f *engineLoaderHooks) OnFinished(ctx context.Context, ds resolve.DataSourc
 			zap.Int("status", responseInfo.StatusCode),
 			zap.Duration("latency", latency),
 		}
+		if selection := loadbalancer.SelectionFromContext(ctx); selection != nil && selection.Endpoint != "" {
+			fields = append(fields,
+				zap.String("subgraph_endpoint", selection.Endpoint),
+				zap.Strings("subgraph_ejected_endpoints", selection.Ejected),
+			)
+		}
 		path := ds.Name
 		if responseInfo.Request != nil {
 			fields = append(fields, f.accessLogger.RequestFields(responseInfo, exprCtx)...)
@@ -163,6 +163,13 @@ func (b *ExecutorConfigurationBuilder) Build(ctx context.Context, opts *Executor
 		return nil, providers, fmt.Errorf("failed to merge graphql schema with base schema: %w", err)
 	}
 
+	// the fields protected by the authorization policies of the router config must be authorized
+	// by the engine, even when the schema doesn't require any scopes for them
+	err = applyAuthorizationPolicies(b.logger, planConfig, &routerSchemaDefinition, opts.RouterEngineConfig.AuthorizationPolicies)
+	if err != nil {
+		return nil, providers, fmt.Errorf("failed to apply authorization policies: %w", err)
+	}
+
 	if clientSchemaStr := opts.EngineConfig.GetGraphqlClientSchema(); clientSchemaStr != "" {
 		// The client schema is a subset of the router schema that does not include @inaccessible fields.
 		// The client schema only exists if the federated schema includes @inaccessible directives or @tag directives
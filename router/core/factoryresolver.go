@@ -247,6 +247,7 @@ type RouterEngineConfiguration struct {
 	SubgraphExtensionPropagation config.SubgraphExtensionPropagationConfiguration
 	StreamMetricStore            rmetric.StreamMetricStore
 	CostControl                  *config.CostControl
+	AuthorizationPolicies        []config.AuthorizationPolicy
 }
 
 func mapProtoFilterToPlanFilter(input *nodev1.SubscriptionFilterCondition, output *plan.SubscriptionFilterCondition) *plan.SubscriptionFilterCondition {
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/expr-lang/expr/vm"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	ctrace "github.com/wundergraph/cosmo/router/pkg/trace"
+)
+
+const (
+	featureFlagReasonHeader     = "header"
+	featureFlagReasonCookie     = "cookie"
+	featureFlagReasonExpression = "expression"
+	featureFlagReasonPercentage = "percentage"
+
+	// featureFlagBuckets is the resolution of the percentage split, 10000 buckets allow
+	// percentages with two decimal places.
+	featureFlagBuckets = 10000
+)
+
+type featureFlagReasonKey struct{}
+
+// withFeatureFlagReason stores the reason the feature flag was selected for the request.
+func withFeatureFlagReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, featureFlagReasonKey{}, reason)
+}
+
+// featureFlagReasonFromContext returns the reason the feature flag of the request was selected,
+// or an empty string if the request is served by the base graph.
+func featureFlagReasonFromContext(ctx context.Context) string {
+	reason, _ := ctx.Value(featureFlagReasonKey{}).(string)
+	return reason
+}
+
+// requestedFeatureFlag returns the feature flag the client asked for
+// 1. From the request header
+// 2. From the cookie
+func requestedFeatureFlag(r *http.Request) (string, string) {
+	if ff := strings.TrimSpace(r.Header.Get(featureFlagHeader)); ff != "" {
+		return ff, featureFlagReasonHeader
+	}
+	cookie, err := r.Cookie(featureFlagCookie)
+	if err == nil && cookie != nil {
+		if ff := strings.TrimSpace(cookie.Value); ff != "" {
+			return ff, featureFlagReasonCookie
+		}
+	}
+	return "", ""
+}
+
+type featureFlagRoutingRule struct {
+	featureFlag string
+	expression  *vm.Program
+	// threshold is the percentage scaled to featureFlagBuckets
+	threshold uint64
+	sticky    config.FeatureFlagRoutingStickiness
+}
+
+// featureFlagRouter selects a feature flag for requests that don't ask for one.
+type featureFlagRouter struct {
+	rules            []featureFlagRoutingRule
+	accessController *AccessController
+	clientHeader     config.ClientHeader
+	// needsAuth is set when a rule depends on the claims of the request
+	needsAuth bool
+}
+
+type featureFlagRouterOptions struct {
+	logger           *zap.Logger
+	rules            []config.FeatureFlagRoutingRule
+	featureFlags     map[string]struct{}
+	accessController *AccessController
+	clientHeader     config.ClientHeader
+}
+
+// newFeatureFlagRouter compiles the routing rules. Rules pointing to a feature flag that is not part
+// of the current execution config are skipped. It returns nil if no rule is left.
+func newFeatureFlagRouter(opts featureFlagRouterOptions) (*featureFlagRouter, error) {
+	router := &featureFlagRouter{
+		clientHeader: opts.clientHeader,
+	}
+
+	exprManager := expr.CreateNewExprManager()
+
+	for i, rule := range opts.rules {
+		if rule.Expression == "" && rule.Percentage <= 0 {
+			return nil, fmt.Errorf("feature flag routing rule %d: either an expression or a percentage is required", i)
+		}
+		if rule.Percentage > 100 {
+			return nil, fmt.Errorf("feature flag routing rule %d: percentage must be between 0 and 100", i)
+		}
+
+		compiled := featureFlagRoutingRule{
+			featureFlag: rule.FeatureFlag,
+			sticky:      rule.Sticky,
+			threshold:   featureFlagBuckets,
+		}
+
+		if rule.Expression != "" {
+			program, err := exprManager.CompileExpression(rule.Expression, reflect.Bool)
+			if err != nil {
+				return nil, fmt.Errorf("feature flag routing rule %d: failed to compile expression: %w", i, err)
+			}
+			compiled.expression = program
+			// Expressions can access the claims of the request
+			router.needsAuth = true
+		}
+
+		if rule.Percentage > 0 {
+			compiled.threshold = uint64(rule.Percentage * featureFlagBuckets / 100)
+		}
+
+		if rule.Sticky.Claim != "" {
+			router.needsAuth = true
+		}
+
+		if _, ok := opts.featureFlags[rule.FeatureFlag]; !ok {
+			opts.logger.Warn("Feature flag of routing rule is not part of the execution config, skipping rule",
+				zap.String("flag", rule.FeatureFlag),
+			)
+			continue
+		}
+
+		router.rules = append(router.rules, compiled)
+	}
+
+	if len(router.rules) == 0 {
+		return nil, nil
+	}
+
+	router.accessController = opts.accessController
+
+	return router, nil
+}
+
+// selectFeatureFlag returns the feature flag of the first matching rule and the reason it matched.
+// It returns empty strings if no rule matches. When a rule needed the claims of the request, the
+// returned request carries the authentication result, so that the graph mux doesn't authenticate again.
+func (f *featureFlagRouter) selectFeatureFlag(r *http.Request) (string, string, *http.Request) {
+	var exprCtx *expr.Context
+
+	for i := range f.rules {
+		rule := &f.rules[i]
+
+		if rule.expression != nil {
+			if exprCtx == nil {
+				exprCtx, r = f.expressionContext(r)
+			}
+			matches, err := expr.ResolveBoolExpression(rule.expression, *exprCtx)
+			if err != nil || !matches {
+				continue
+			}
+		}
+
+		if rule.expression != nil && rule.threshold >= featureFlagBuckets {
+			return rule.featureFlag, featureFlagReasonExpression, r
+		}
+
+		if exprCtx == nil && rule.sticky.Claim != "" {
+			exprCtx, r = f.expressionContext(r)
+		}
+
+		if f.bucket(rule, r, exprCtx) < rule.threshold {
+			return rule.featureFlag, featureFlagReasonPercentage, r
+		}
+	}
+
+	return "", "", r
+}
+
+// bucket places the request in one of the featureFlagBuckets. The same sticky value always
+// ends up in the same bucket of a rule. Requests without a sticky value are placed randomly.
+func (f *featureFlagRouter) bucket(rule *featureFlagRoutingRule, r *http.Request, exprCtx *expr.Context) uint64 {
+	var key string
+
+	switch {
+	case rule.sticky.Claim != "":
+		if v, ok := exprCtx.Request.Auth.Claims[rule.sticky.Claim]; ok && v != nil {
+			key = fmt.Sprint(v)
+		}
+	case rule.sticky.Header != "":
+		key = r.Header.Get(rule.sticky.Header)
+	case rule.sticky.ClientName:
+		if clientName, _ := ctrace.GetClientDetails(r, f.clientHeader); clientName != "unknown" {
+			key = clientName
+		}
+	}
+
+	if key == "" {
+		return rand.Uint64N(featureFlagBuckets)
+	}
+
+	// Hash the flag together with the key so every rule splits the traffic independently
+	return xxhash.Sum64String(rule.featureFlag+":"+key) % featureFlagBuckets
+}
+
+// expressionContext builds the part of the expression context that is known before the request
+// reaches a graph mux. Authentication failures are ignored here, the mux rejects the request later
+// with the authentication result stored in the returned request.
+func (f *featureFlagRouter) expressionContext(r *http.Request) (*expr.Context, *http.Request) {
+	exprCtx := &expr.Context{
+		Request: expr.LoadRequest(r),
+	}
+
+	clientName, clientVersion := ctrace.GetClientDetails(r, f.clientHeader)
+	exprCtx.Request.Client.Name = clientName
+	exprCtx.Request.Client.Version = clientVersion
+
+	if f.needsAuth && f.accessController != nil && len(f.accessController.authenticators) > 0 {
+		var auth authentication.Authentication
+		r, auth = f.accessController.preAuthenticate(r)
+		if auth != nil {
+			exprCtx.Request.Auth = expr.LoadAuth(authentication.NewContext(r.Context(), auth))
+		}
+	}
+
+	return exprCtx, r
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+type countingAuthenticator struct {
+	calls int
+}
+
+func (a *countingAuthenticator) Name() string {
+	return "counting"
+}
+
+func (a *countingAuthenticator) Authenticate(_ context.Context, p authentication.Provider) (authentication.Claims, error) {
+	a.calls++
+	return authentication.Claims{"sub": p.AuthenticationHeaders().Get("X-User")}, nil
+}
+
+func TestRequestedFeatureFlag(t *testing.T) {
+	t.Parallel()
+
+	t.Run("header wins over cookie", func(t *testing.T) {
+		t.Parallel()
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set(featureFlagHeader, " myff ")
+		r.AddCookie(&http.Cookie{Name: featureFlagCookie, Value: "other"})
+
+		ff, reason := requestedFeatureFlag(r)
+		require.Equal(t, "myff", ff)
+		require.Equal(t, featureFlagReasonHeader, reason)
+	})
+
+	t.Run("cookie", func(t *testing.T) {
+		t.Parallel()
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.AddCookie(&http.Cookie{Name: featureFlagCookie, Value: "myff"})
+
+		ff, reason := requestedFeatureFlag(r)
+		require.Equal(t, "myff", ff)
+		require.Equal(t, featureFlagReasonCookie, reason)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		ff, reason := requestedFeatureFlag(httptest.NewRequest(http.MethodPost, "/graphql", nil))
+		require.Empty(t, ff)
+		require.Empty(t, reason)
+	})
+}
+
+func TestFeatureFlagRouter(t *testing.T) {
+	t.Parallel()
+
+	featureFlags := map[string]struct{}{"canary": {}, "beta": {}}
+
+	newRouter := func(t *testing.T, rules ...config.FeatureFlagRoutingRule) *featureFlagRouter {
+		t.Helper()
+		router, err := newFeatureFlagRouter(featureFlagRouterOptions{
+			logger:       zap.NewNop(),
+			rules:        rules,
+			featureFlags: featureFlags,
+		})
+		require.NoError(t, err)
+		return router
+	}
+
+	t.Run("expression selects the feature flag", func(t *testing.T) {
+		t.Parallel()
+		router := newRouter(t, config.FeatureFlagRoutingRule{
+			FeatureFlag: "beta",
+			Expression:  `request.client.name == "mobile"`,
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set("graphql-client-name", "mobile")
+		ff, reason, _ := router.selectFeatureFlag(r)
+		require.Equal(t, "beta", ff)
+		require.Equal(t, featureFlagReasonExpression, reason)
+
+		r = httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set("graphql-client-name", "web")
+		ff, reason, _ = router.selectFeatureFlag(r)
+		require.Empty(t, ff)
+		require.Empty(t, reason)
+	})
+
+	t.Run("first matching rule wins", func(t *testing.T) {
+		t.Parallel()
+		router := newRouter(t,
+			config.FeatureFlagRoutingRule{FeatureFlag: "beta", Expression: `request.header.Get("X-Beta") == "true"`},
+			config.FeatureFlagRoutingRule{FeatureFlag: "canary", Percentage: 100},
+		)
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set("X-Beta", "true")
+		ff, _, _ := router.selectFeatureFlag(r)
+		require.Equal(t, "beta", ff)
+
+		ff, reason, _ := router.selectFeatureFlag(httptest.NewRequest(http.MethodPost, "/graphql", nil))
+		require.Equal(t, "canary", ff)
+		require.Equal(t, featureFlagReasonPercentage, reason)
+	})
+
+	t.Run("sticky percentage is deterministic", func(t *testing.T) {
+		t.Parallel()
+		router := newRouter(t, config.FeatureFlagRoutingRule{
+			FeatureFlag: "canary",
+			Percentage:  50,
+			Sticky:      config.FeatureFlagRoutingStickiness{Header: "X-User-Id"},
+		})
+
+		selected := 0
+		for i := range 1000 {
+			userID := strconv.Itoa(i)
+			r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			r.Header.Set("X-User-Id", userID)
+			first, _, _ := router.selectFeatureFlag(r)
+			for range 5 {
+				again, _, _ := router.selectFeatureFlag(r)
+				require.Equal(t, first, again, "user %s switched sides", userID)
+			}
+			if first == "canary" {
+				selected++
+			}
+		}
+
+		require.InDelta(t, 500, selected, 100)
+	})
+
+	t.Run("authentication result is reused by the access controller", func(t *testing.T) {
+		t.Parallel()
+		authenticator := &countingAuthenticator{}
+		accessController, err := NewAccessController(AccessControllerOptions{
+			Authenticators: []authentication.Authenticator{authenticator},
+		})
+		require.NoError(t, err)
+
+		router, err := newFeatureFlagRouter(featureFlagRouterOptions{
+			logger:           zap.NewNop(),
+			rules:            []config.FeatureFlagRoutingRule{{FeatureFlag: "beta", Expression: `request.auth.claims.sub == "alice"`}},
+			featureFlags:     featureFlags,
+			accessController: accessController,
+		})
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set("X-User", "alice")
+		ff, _, r := router.selectFeatureFlag(r)
+		require.Equal(t, "beta", ff)
+		require.Equal(t, 1, authenticator.calls)
+
+		r, err = accessController.Access(httptest.NewRecorder(), r)
+		require.NoError(t, err)
+		require.Equal(t, "alice", authentication.FromContext(r.Context()).Claims()["sub"])
+		require.Equal(t, 1, authenticator.calls)
+	})
+
+	t.Run("rules for unknown feature flags are skipped", func(t *testing.T) {
+		t.Parallel()
+		router := newRouter(t, config.FeatureFlagRoutingRule{FeatureFlag: "unknown", Percentage: 100})
+		require.Nil(t, router)
+	})
+
+	t.Run("invalid rules are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newFeatureFlagRouter(featureFlagRouterOptions{
+			logger:       zap.NewNop(),
+			rules:        []config.FeatureFlagRoutingRule{{FeatureFlag: "canary"}},
+			featureFlags: featureFlags,
+		})
+		require.ErrorContains(t, err, "either an expression or a percentage is required")
+
+		_, err = newFeatureFlagRouter(featureFlagRouterOptions{
+			logger:       zap.NewNop(),
+			rules:        []config.FeatureFlagRoutingRule{{FeatureFlag: "canary", Expression: `request.client.name`}},
+			featureFlags: featureFlags,
+		})
+		require.ErrorContains(t, err, "failed to compile expression")
+	})
+}
@@ -13,7 +13,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,8 +23,6 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/klauspost/compress/gzhttp"
-	"github.com/klauspost/compress/gzip"
 	"github.com/wundergraph/cosmo/router/pkg/routerconfig"
 	"go.opentelemetry.io/otel/attribute"
 	otelmetric "go.opentelemetry.io/otel/metric"
@@ -107,6 +104,15 @@ type (
 		connector               *grpcconnector.Connector
 		circuitBreakerManager   *circuit.Manager
 		headerPropagation       *HeaderPropagation
+		// executionConfig is the execution config the server was built from. It is used to rebuild
+		// the server when the router config is reloaded live.
+		executionConfig *nodev1.RouterConfig
+		// drainStreams keeps the muxes with open subscriptions alive on shutdown until the clients
+		// disconnect, so a live config reload does not close them.
+		drainStreams atomic.Bool
+		// streamDrainTimeout bounds how long the draining muxes wait for their subscriptions.
+		// Defaults to defaultStreamDrainTimeout.
+		streamDrainTimeout time.Duration
 	}
 )
 
@@ -215,6 +221,7 @@ func newGraphServer(routerCtx context.Context, r *Router, response *routerconfig
 		subgraphTransports:      subgraphTransports,
 		playgroundHandler:       r.playgroundHandler,
 		baseRouterConfigVersion: response.Config.GetVersion(),
+		executionConfig:         response.Config,
 		graphMuxList:            make(map[string]*graphMux, 1),
 		instanceData: InstanceData{
 			HostName:      r.hostName,
@@ -328,6 +335,14 @@ func newGraphServer(routerCtx context.Context, r *Router, response *routerconfig
 		return nil, err
 	}
 
+	// Load balanced subgraphs get a circuit breaker per endpoint from their load balancer, so an
+	// unhealthy endpoint is ejected instead of opening the circuit of the whole subgraph
+	for _, sgNames := range routingUrlGroupings {
+		for sgName := range s.subgraphLoadBalancers {
+			delete(sgNames, sgName)
+		}
+	}
+
 	// reusedMuxes accumulates the muxes that the new server intends to inherit from
 	// the previous server. The reuse bookkeeping (gm.reused flag and s.graphMuxList
 	// entries) is committed at the end of this function, after every fallible step
@@ -388,13 +403,18 @@ func newGraphServer(routerCtx context.Context, r *Router, response *routerconfig
 	}
 	reusedMuxes = append(reusedMuxes, ffReusedMuxes...)
 
-	wrapper, err := gzhttp.NewWrapper(
-		gzhttp.MinSize(int(s.routerTrafficConfig.ResponseCompressionMinSize)),
-		gzhttp.CompressionLevel(gzip.DefaultCompression),
-		gzhttp.ContentTypes(CompressibleContentTypes),
-	)
+	compressionEncodings := s.routerTrafficConfig.ResponseCompressionEncodings
+	if len(compressionEncodings) == 0 {
+		compressionEncodings = DefaultResponseCompressionEncodings
+	}
+
+	wrapper, err := rmiddleware.ResponseCompression(rmiddleware.ResponseCompressionOptions{
+		Encodings:    compressionEncodings,
+		MinSize:      int(s.routerTrafficConfig.ResponseCompressionMinSize),
+		ContentTypes: CompressibleContentTypes,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gzip wrapper: %w", err)
+		return nil, fmt.Errorf("failed to create response compression middleware: %w", err)
 	}
 
 	if s.traceConfig.Enabled {
@@ -616,24 +636,43 @@ func (s *graphServer) buildMultiGraphHandler(
 		featureFlagToMux[featureFlagName] = gm.mux
 	}
 
+	var ffRouter *featureFlagRouter
+	if s.featureFlagRouting != nil && len(s.featureFlagRouting.Rules) > 0 {
+		featureFlags := make(map[string]struct{}, len(featureFlagToMux))
+		for name := range featureFlagToMux {
+			featureFlags[name] = struct{}{}
+		}
+
+		var err error
+		ffRouter, err = newFeatureFlagRouter(featureFlagRouterOptions{
+			logger:           s.logger,
+			rules:            s.featureFlagRouting.Rules,
+			featureFlags:     featureFlags,
+			accessController: s.accessController,
+			clientHeader:     s.clientHeader,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build feature flag routing: %w", err)
+		}
+	}
+
 	baseMux := opts.baseMux // Capture only baseMux so the closure does not hold the whole opts struct
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract the feature flag and run the corresponding mux
-		// 1. From the request header
-		// 2. From the cookie
-
-		ff := strings.TrimSpace(r.Header.Get(featureFlagHeader))
-		if ff == "" {
-			cookie, err := r.Cookie(featureFlagCookie)
-			if err == nil && cookie != nil {
-				ff = strings.TrimSpace(cookie.Value)
-			}
+		// Extract the feature flag and run the corresponding mux.
+		// A flag requested by the client always wins over the routing rules.
+		ff, reason := requestedFeatureFlag(r)
+		if ff == "" && ffRouter != nil {
+			ff, reason, r = ffRouter.selectFeatureFlag(r)
 		}
 
 		if mux, ok := featureFlagToMux[ff]; ok {
 			w.Header().Set(featureFlagHeader, ff)
-			mux.ServeHTTP(w, r)
+			oteltrace.SpanFromContext(r.Context()).SetAttributes(
+				otel.WgFeatureFlag.String(ff),
+				otel.WgFeatureFlagReason.String(reason),
+			)
+			mux.ServeHTTP(w, r.WithContext(withFeatureFlagReason(r.Context(), reason)))
 			return
 		}
 
@@ -687,6 +726,9 @@ type graphMux struct {
 	// the count belongs to the mux and is only drained by the server that
 	// tears the mux down.
 	inFlightRequests atomic.Int64
+	// activeStreams tracks the number of open subscriptions over SSE and websocket connections
+	// served by this mux. They keep the mux alive while a graph server drains its streams.
+	activeStreams atomic.Int64
 
 	planCache                   *ristretto.Cache[uint64, *planWithMetaData]
 	planFallbackCache           *slowplancache.Cache[*planWithMetaData]
@@ -707,6 +749,12 @@ type graphMux struct {
 
 	pubSubProviders          []datasource.Provider
 	skipUnavailableProviders bool
+	// pubSubProviderErrors holds the startup result of every provider that finished starting
+	pubSubProviderErrors     map[datasource.Provider]error
+	pubSubProviderErrorsLock sync.Mutex
+
+	// routerConfigVersion is the version of the execution config the mux was built from
+	routerConfigVersion string
 
 	logger *zap.Logger
 }
@@ -977,7 +1025,16 @@ func (s *graphMux) addPubsubProviders(providers []datasource.Provider) {
 // startPubsubProviders starts all pubsub providers of s.
 func (s *graphMux) startPubsubProviders(ctx context.Context) error {
 	return providersActionWithTimeout(ctx, s.pubSubProviders, func(ctx context.Context, provider datasource.Provider) error {
-		return provider.Startup(ctx)
+		err := provider.Startup(ctx)
+
+		s.pubSubProviderErrorsLock.Lock()
+		if s.pubSubProviderErrors == nil {
+			s.pubSubProviderErrors = make(map[datasource.Provider]error, len(s.pubSubProviders))
+		}
+		s.pubSubProviderErrors[provider] = err
+		s.pubSubProviderErrorsLock.Unlock()
+
+		return err
 	}, providerTimeout, "pubsub provider startup timed out", s.logger, s.skipUnavailableProviders)
 }
 
@@ -1065,6 +1122,7 @@ func (s *graphServer) buildGraphMux(
 		metricStore:              rmetric.NewNoopMetrics(),
 		streamMetricStore:        rmetric.NewNoopStreamMetricStore(),
 		skipUnavailableProviders: s.Config.eventsConfig.SkipUnavailableProviders,
+		routerConfigVersion:      opts.RouterConfigVersion,
 		logger:                   s.logger,
 	}
 
@@ -1287,6 +1345,8 @@ func (s *graphServer) buildGraphMux(
 				r:                             r,
 			})
 
+			reqContext.expressionContext.Request.FeatureFlag = opts.FeatureFlagName
+
 			r = r.WithContext(withRequestContext(r.Context(), reqContext))
 
 			// For debugging purposes, we can validate from what version of the config the request is coming from
@@ -1362,11 +1422,19 @@ func (s *graphServer) buildGraphMux(
 	})
 
 	if s.traceConfig.Enabled {
+		var samplingRules []traceSamplingRule
+		if s.deferredSampler != nil {
+			samplingRules, err = newTraceSamplingRules(exprManager, s.traceConfig.Sampling.Rules)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		f := func(h http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				reqContext := getRequestContext(r.Context())
 				traceID := rtrace.GetTraceID(r.Context())
-				requestLogger := reqContext.Logger().With(logging.WithTraceID(traceID))
+				requestLogger := reqContext.Logger().With(logging.WithTraceID(traceID), logging.WithContext(r.Context()))
 
 				reqContext.logger = requestLogger
 
@@ -1383,6 +1451,20 @@ func (s *graphServer) buildGraphMux(
 				}
 
 				h.ServeHTTP(w, r)
+
+				// The sampling decision of a recorded trace is made when the request span ends,
+				// when the operation is known
+				if len(samplingRules) == 0 || isSampled || !span.IsRecording() {
+					return
+				}
+				ratio, matched, err := matchTraceSamplingRule(samplingRules, &reqContext.expressionContext)
+				if err != nil {
+					requestLogger.Debug("Failed to evaluate trace sampling rules", zap.Error(err))
+					return
+				}
+				if matched {
+					s.deferredSampler.SetRatio(span.SpanContext().TraceID(), ratio)
+				}
 			})
 		}
 		httpRouter.Use(f)
@@ -1469,6 +1551,10 @@ func (s *graphServer) buildGraphMux(
 		CostControl:                  s.securityConfiguration.CostControl,
 	}
 
+	if s.authorization != nil {
+		routerEngineConfig.AuthorizationPolicies = s.authorization.Policies
+	}
+
 	// map[string]*http.Transport cannot be coerced into map[string]http.RoundTripper, unfortunately
 	subgraphTippers := map[string]http.RoundTripper{}
 	for subgraph, subgraphTransport := range s.subgraphTransports {
@@ -1530,6 +1616,9 @@ func (s *graphServer) buildGraphMux(
 			Logger:                        s.logger,
 			EnableTraceClient:             enableTraceClient,
 			CircuitBreaker:                s.circuitBreakerManager,
+			LoadBalancers:                 s.subgraphLoadBalancers,
+			Hedging:                       s.buildHedgingConfig(),
+			Compression:                   s.buildCompressionConfig(),
 		},
 		subscriptionHooks: s.subscriptionHooks,
 	}
@@ -1780,6 +1869,11 @@ func (s *graphServer) buildGraphMux(
 	if s.authorization != nil {
 		authorizerOptions.RejectOperationIfUnauthorized = s.authorization.RejectOperationIfUnauthorized
 		authorizerOptions.EnablePreFetchFieldAuthorization = s.authorization.EnablePreFetchFieldAuthorization
+
+		authorizerOptions.Policies, err = newAuthorizationPolicies(exprManager, s.authorization.Policies)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create authorization policies: %w", err)
+		}
 	}
 
 	loaderHooks := NewEngineRequestHooks(
@@ -1808,21 +1902,54 @@ func (s *graphServer) buildGraphMux(
 		HeaderPropagation:               s.headerPropagation,
 	}
 
-	if s.redisClient != nil {
+	if s.rateLimit != nil && s.rateLimit.Enabled {
 		handlerOpts.RateLimitConfig = s.rateLimit
 		handlerOpts.RateLimiter, err = NewCosmoRateLimiter(&CosmoRateLimiterOptions{
 			RedisClient:         s.redisClient,
+			Store:               s.rateLimitStore,
 			Debug:               s.rateLimit.Debug,
 			RejectStatusCode:    s.rateLimit.SimpleStrategy.RejectStatusCode,
 			KeySuffixExpression: s.rateLimit.KeySuffixExpression,
 			ExprManager:         exprManager,
 			Overrides:           s.rateLimit.SimpleStrategy.Overrides,
+			ScopedLimits:        s.rateLimit.ScopedLimits,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create rate limiter: %w", err)
 		}
 	}
 
+	if s.rateLimit != nil && s.rateLimit.Enabled && s.rateLimit.Strategy == "cost" {
+		var errorExtensionCode string
+		if s.rateLimit.ErrorExtensionCode.Enabled {
+			errorExtensionCode = s.rateLimit.CostStrategy.ErrorExtensionCode
+		}
+		handlerOpts.CostRateLimiter, err = NewCostRateLimiter(&CostRateLimiterOptions{
+			RedisClient:         s.redisClient,
+			Store:               s.rateLimitStore,
+			Debug:               s.rateLimit.Debug,
+			KeyPrefix:           s.rateLimit.Storage.KeyPrefix + ":cost",
+			KeySuffixExpression: s.rateLimit.KeySuffixExpression,
+			ExprManager:         exprManager,
+			Budget:              s.rateLimit.CostStrategy.Budget,
+			Period:              s.rateLimit.CostStrategy.Period,
+			UseActualCost:       s.rateLimit.CostStrategy.UseActualCost,
+			RejectStatusCode:    s.rateLimit.CostStrategy.RejectStatusCode,
+			ErrorExtensionCode:  errorExtensionCode,
+			ResponseHeaders:     s.rateLimit.ResponseHeaders,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cost rate limiter: %w", err)
+		}
+	}
+
+	if s.responseCacheStore != nil {
+		handlerOpts.ResponseCache, err = newGraphResponseCache(s.responseCacheStore, exprManager, s.responseCache.KeyExpression, opts.RouterConfigVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if s.apolloCompatibilityFlags.SubscriptionMultipartPrintBoundary.Enabled {
 		handlerOpts.ApolloSubscriptionMultipartPrintBoundary = s.apolloCompatibilityFlags.SubscriptionMultipartPrintBoundary.Enabled
 	}
@@ -1869,6 +1996,7 @@ func (s *graphServer) buildGraphMux(
 		Planner:                                operationPlanner,
 		AccessController:                       s.accessController,
 		OperationBlocker:                       operationBlocker,
+		OperationRecorder:                      s.pqlRecorder,
 		RouterPublicKey:                        s.publicKey,
 		EnableRequestTracing:                   s.engineExecutionConfiguration.EnableRequestTracing,
 		ForceUnauthenticatedRequestTracing:     s.engineExecutionConfiguration.ForceUnauthenticatedRequestTracing,
@@ -1922,6 +2050,7 @@ func (s *graphServer) buildGraphMux(
 			ClientHeader:              s.clientHeader,
 			DisableVariablesRemapping: s.engineExecutionConfiguration.DisableVariablesRemapping,
 			ApolloCompatibilityFlags:  s.apolloCompatibilityFlags,
+			ActiveConnections:         &gm.activeStreams,
 		})
 
 		// When the playground path is equal to the graphql path, we need to handle
@@ -1948,6 +2077,9 @@ func (s *graphServer) buildGraphMux(
 					// Counting like this is safe because according to the go http.ServeHTTP documentation
 					// the requests is guaranteed to be finished when ServeHTTP returns
 					defer gm.inFlightRequests.Add(-1)
+				} else if requestContext != nil && requestContext.operation != nil {
+					gm.activeStreams.Add(1)
+					defer gm.activeStreams.Add(-1)
 				}
 
 				handler.ServeHTTP(w, r)
@@ -2191,6 +2323,74 @@ func (s *graphServer) wait(ctx context.Context) error {
 	}
 }
 
+// defaultStreamDrainTimeout bounds how long the muxes of a replaced graph server are kept alive
+// for their open subscriptions
+const defaultStreamDrainTimeout = 15 * time.Minute
+
+// shutdownAfterStreams waits until the subscriptions of the draining muxes are closed by their
+// clients, the drain timeout elapsed or the router is shut down. Afterwards, it releases the
+// remaining resources of the server, which closes the subscriptions that are still open.
+func (s *graphServer) shutdownAfterStreams(muxes map[string]*graphMux) {
+	defer s.graphServerCancel()
+
+	drainTimeout := s.streamDrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultStreamDrainTimeout
+	}
+	drainCtx, cancelDrain := context.WithTimeout(s.routerCtx, drainTimeout)
+	defer cancelDrain()
+
+	b := backoff.New(5*time.Second, 100*time.Millisecond)
+
+	timer := time.NewTimer(b.Duration())
+	defer timer.Stop()
+
+	activeStreams := func() int64 {
+		var n int64
+		for _, mux := range muxes {
+			n += mux.activeStreams.Load()
+		}
+		return n
+	}
+
+wait:
+	for activeStreams() > 0 {
+		select {
+		case <-drainCtx.Done():
+			if errors.Is(drainCtx.Err(), context.DeadlineExceeded) {
+				s.logger.Warn("Closing the subscriptions of a replaced graph server after the drain timeout",
+					zap.Int64("subscriptions", activeStreams()),
+					zap.Duration("timeout", drainTimeout),
+				)
+			}
+			break wait
+		case <-timer.C:
+			timer.Reset(b.Duration())
+		}
+	}
+
+	ctx := context.Background()
+	if s.routerGracePeriod > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.routerGracePeriod)
+		defer cancel()
+	}
+
+	for name, mux := range muxes {
+		s.logger.Debug("shutting down drained graph mux", zap.String("mux", name))
+		if err := mux.Shutdown(ctx); err != nil {
+			s.logger.Error("Failed to shutdown drained graph mux", zap.String("mux", name), zap.Error(err))
+		}
+	}
+
+	if s.connector != nil {
+		s.logger.Debug("Stopping old plugins")
+		if err := s.connector.StopAllProviders(); err != nil {
+			s.logger.Error("Failed to stop old plugins", zap.Error(err))
+		}
+	}
+}
+
 // metricsFlushTimeout bounds the single, central flush of the shared meter
 // providers during graph server shutdown.
 const metricsFlushTimeout = 30 * time.Second
@@ -2228,8 +2428,13 @@ func (s *graphServer) flushMeterProviders(ctx context.Context) error {
 // Shutdown does cancel the context after all non-hijacked requests such as WebSockets has been handled.
 func (s *graphServer) Shutdown(ctx context.Context) error {
 	// Cancel the context after the graceful shutdown is done
-	// to clean up resources.
-	defer s.graphServerCancel()
+	// to clean up resources. Draining muxes still need it and cancel it once they are done.
+	var draining map[string]*graphMux
+	defer func() {
+		if len(draining) == 0 {
+			s.graphServerCancel()
+		}
+	}()
 
 	s.logger.Debug("Shutdown of graph server initiated. Waiting for in-flight requests to finish.",
 		zap.String("config_version", s.baseRouterConfigVersion),
@@ -2295,6 +2500,15 @@ func (s *graphServer) Shutdown(ctx context.Context) error {
 				zap.String("mux", name))
 			continue
 		}
+		if s.drainStreams.Load() && mux.activeStreams.Load() > 0 {
+			s.logger.Debug("graph mux has open subscriptions, shutting down after they are closed",
+				zap.String("mux", name), zap.Int64("streams", mux.activeStreams.Load()))
+			if draining == nil {
+				draining = make(map[string]*graphMux)
+			}
+			draining[name] = mux
+			continue
+		}
 		s.logger.Debug("shutting down graph mux", zap.String("mux", name))
 		if err := mux.Shutdown(ctx); err != nil {
 			finalErr = errors.Join(finalErr, err)
@@ -2307,6 +2521,11 @@ func (s *graphServer) Shutdown(ctx context.Context) error {
 		subgraphTransport.CloseIdleConnections()
 	}
 
+	if len(draining) > 0 {
+		go s.shutdownAfterStreams(draining)
+		return finalErr
+	}
+
 	if s.connector != nil {
 		s.logger.Debug("Stopping old plugins")
 		if err := s.connector.StopAllProviders(); err != nil {
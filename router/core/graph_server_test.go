@@ -991,6 +991,67 @@ func TestGraphServerShutdown(t *testing.T) {
 		require.True(t, provider.shutdown.Load(),
 			"provider for a non-reused mux must be shut down when the server shuts down")
 	})
+
+	// A live config reload replaces the graph server, but must not close the subscriptions
+	// that are still served by the muxes of the previous server.
+	t.Run("keeps a mux with open subscriptions alive until they are closed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		provider := &reuseTrackingProvider{}
+		mux := &graphMux{mux: chi.NewMux(), pubSubProviders: []datasource.Provider{provider}, cancel: func() {}}
+		mux.activeStreams.Store(1)
+
+		srvCtx, srvCancel := context.WithCancel(ctx)
+		srv := &graphServer{
+			Config:            &Config{logger: zap.NewNop()},
+			routerCtx:         ctx,
+			graphServerCtx:    srvCtx,
+			graphServerCancel: srvCancel,
+			baseTransport:     &http.Transport{},
+			graphMuxList:      map[string]*graphMux{"": mux},
+		}
+		srv.drainStreams.Store(true)
+
+		require.NoError(t, srv.Shutdown(ctx))
+
+		require.False(t, mux.finalized.Load(), "mux with open subscriptions must not be shut down")
+		require.NoError(t, srvCtx.Err(), "graph server context must stay alive while draining")
+
+		mux.activeStreams.Store(0)
+
+		require.Eventually(t, func() bool {
+			return provider.shutdown.Load() && srvCtx.Err() != nil
+		}, 5*time.Second, 10*time.Millisecond, "drained mux must be shut down")
+	})
+
+	t.Run("shuts down a mux with open subscriptions after the drain timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		provider := &reuseTrackingProvider{}
+		mux := &graphMux{mux: chi.NewMux(), pubSubProviders: []datasource.Provider{provider}, cancel: func() {}}
+		mux.activeStreams.Store(1)
+
+		srvCtx, srvCancel := context.WithCancel(ctx)
+		srv := &graphServer{
+			Config:             &Config{logger: zap.NewNop()},
+			routerCtx:          ctx,
+			graphServerCtx:     srvCtx,
+			graphServerCancel:  srvCancel,
+			baseTransport:      &http.Transport{},
+			graphMuxList:       map[string]*graphMux{"": mux},
+			streamDrainTimeout: 50 * time.Millisecond,
+		}
+		srv.drainStreams.Store(true)
+
+		require.NoError(t, srv.Shutdown(ctx))
+
+		require.Eventually(t, func() bool {
+			return provider.shutdown.Load() && srvCtx.Err() != nil
+		}, 5*time.Second, 10*time.Millisecond, "mux must be shut down after the drain timeout")
+		require.Equal(t, int64(1), mux.activeStreams.Load())
+	})
 }
 
 func toSet[T comparable](slice ...T) map[T]bool {
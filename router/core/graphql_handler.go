@@ -77,6 +77,8 @@ type HandlerOptions struct {
 	TracerProvider trace.TracerProvider
 	Authorizer     *CosmoAuthorizer
 	RateLimiter    *CosmoRateLimiter
+	// CostRateLimiter limits the cost of the operations with the cost rate limit strategy
+	CostRateLimiter *CostRateLimiter
 
 	RateLimitConfig          *config.RateLimitConfiguration
 	SubgraphErrorPropagation config.SubgraphErrorPropagationConfiguration
@@ -88,6 +90,8 @@ type HandlerOptions struct {
 
 	ApolloSubscriptionMultipartPrintBoundary bool
 	HeaderPropagation                        *HeaderPropagation
+	// ResponseCache is nil when the response cache is disabled
+	ResponseCache *graphResponseCache
 }
 
 func NewGraphQLHandler(opts HandlerOptions) *GraphQLHandler {
@@ -105,11 +109,13 @@ func NewGraphQLHandler(opts HandlerOptions) *GraphQLHandler {
 		tracer:                                   tracer,
 		authorizer:                               opts.Authorizer,
 		rateLimiter:                              opts.RateLimiter,
+		costRateLimiter:                          opts.CostRateLimiter,
 		rateLimitConfig:                          opts.RateLimitConfig,
 		subgraphErrorPropagation:                 opts.SubgraphErrorPropagation,
 		engineLoaderHooks:                        opts.EngineLoaderHooks,
 		apolloSubscriptionMultipartPrintBoundary: opts.ApolloSubscriptionMultipartPrintBoundary,
 		headerPropagation:                        opts.HeaderPropagation,
+		responseCache:                            opts.ResponseCache,
 	}
 	return graphQLHandler
 }
@@ -133,10 +139,13 @@ type GraphQLHandler struct {
 	authorizer  *CosmoAuthorizer
 	rateLimiter *CosmoRateLimiter
 
+	costRateLimiter *CostRateLimiter
+
 	rateLimitConfig          *config.RateLimitConfiguration
 	subgraphErrorPropagation config.SubgraphErrorPropagationConfiguration
 	engineLoaderHooks        resolve.LoaderHooks
 	headerPropagation        *HeaderPropagation
+	responseCache            *graphResponseCache
 
 	enableCacheResponseHeaders      bool
 	enableResponseHeaderPropagation bool
@@ -187,7 +196,26 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if h.engineLoaderHooks != nil {
 		resolveCtx.SetEngineLoaderHooks(h.engineLoaderHooks)
 	}
+	// Cached responses are served before the rate limits apply, as they don't reach the subgraphs
+	var responseCacheLookup *responseCacheLookup
+	if _, ok := reqCtx.operation.preparedPlan.preparedPlan.(*plan.SynchronousResponsePlan); ok && h.responseCache != nil {
+		var served bool
+		responseCacheLookup, served = h.responseCache.serve(w, r, reqCtx, h.enableCacheResponseHeaders)
+		if served {
+			return
+		}
+		defer responseCacheLookup.release()
+	}
+
 	resolveCtx = h.configureRateLimiting(resolveCtx)
+	if h.costRateLimiter != nil {
+		if err := h.costRateLimiter.Reserve(r.Context(), reqCtx, w.Header()); err != nil {
+			trackFinalResponseError(r.Context(), err)
+			writeOperationError(r, w, reqCtx.logger, err, h.headerPropagation)
+			return
+		}
+		defer h.costRateLimiter.Settle(r.Context(), reqCtx)
+	}
 	if reqCtx.customFieldValueRenderer != nil {
 		resolveCtx.SetFieldValueRenderer(reqCtx.customFieldValueRenderer)
 	}
@@ -250,16 +278,33 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					}
 				}
 			}
+			if h.rateLimitConfig != nil && h.rateLimitConfig.ResponseHeaders && h.rateLimiter != nil {
+				// The stats are complete once all fetches are done, which is before the response is written
+				pw.rateLimitHeaderSetter = func() {
+					h.rateLimiter.SetResponseHeaders(resolveCtx, pw.writer.Header())
+				}
+			}
+		}
+
+		var responseCacheWriter *responseCacheWriter
+		if responseCacheLookup != nil {
+			responseCacheWriter = newResponseCacheWriter(w.Header(), hpw)
+			hpw = responseCacheWriter
 		}
 
 		info, err := h.executor.Resolver.ArenaResolveGraphQLResponse(resolveCtx, p.Response, hpw)
 		reqCtx.dataSourceNames = getSubgraphNames(p.Response.DataSources)
+		reqCtx.expressionContext.Request.Operation.Subgraphs = reqCtx.dataSourceNames
 		if err != nil {
 			trackFinalResponseError(resolveCtx.Context(), err)
 			h.WriteError(resolveCtx, err, p.Response, w)
 			return
 		}
 
+		if responseCacheLookup != nil {
+			responseCacheLookup.store(r.Context(), reqCtx.logger, responseCacheWriter, resolveCtx.SubgraphErrors() != nil)
+		}
+
 		// Compute actual cost for metrics/telemetry if not already set by the header callback
 		if !reqCtx.operation.costActualSet && resolveCtx.TypeNameStats != nil &&
 			reqCtx.operation.preparedPlan != nil && reqCtx.operation.preparedPlan.preparedPlan != nil {
@@ -312,6 +357,7 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		err := h.executor.Resolver.ResolveGraphQLSubscription(resolveCtx, p.Response, writer)
 		reqCtx.dataSourceNames = getSubgraphNames(p.Response.Response.DataSources)
+		reqCtx.expressionContext.Request.Operation.Subgraphs = reqCtx.dataSourceNames
 
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -373,6 +419,7 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		_, err := h.executor.Resolver.ResolveGraphQLDeferResponse(resolveCtx, p.Response, writer)
 		reqCtx.dataSourceNames = getSubgraphNames(p.Response.Response.DataSources)
+		reqCtx.expressionContext.Request.Operation.Subgraphs = reqCtx.dataSourceNames
 
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -514,6 +561,9 @@ func (h *GraphQLHandler) writeError(ctx *resolve.Context, err error, res *resolv
 			}
 		}
 		if isHttpResponseWriter {
+			if h.rateLimitConfig.ResponseHeaders {
+				h.rateLimiter.SetResponseHeaders(ctx, httpWriter.Header())
+			}
 			httpWriter.WriteHeader(h.rateLimiter.RejectStatusCode())
 		}
 	case errorTypeUnauthorized:
@@ -28,6 +28,7 @@ import (
 
 	"github.com/wundergraph/cosmo/router/internal/expr"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation/pqlmanifest"
 	"github.com/wundergraph/cosmo/router/pkg/art"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/cosmo/router/pkg/otel"
@@ -47,6 +48,7 @@ type PreHandlerOptions struct {
 	Planner            *OperationPlanner
 	AccessController   *AccessController
 	OperationBlocker   *OperationBlocker
+	OperationRecorder  *pqlmanifest.Recorder
 	RouterPublicKey    *ecdsa.PublicKey
 	TracerProvider     *sdktrace.TracerProvider
 	ComplexityLimits   *config.ComplexityLimits
@@ -91,6 +93,7 @@ type PreHandler struct {
 	planner                                *OperationPlanner
 	accessController                       *AccessController
 	operationBlocker                       *OperationBlocker
+	operationRecorder                      *pqlmanifest.Recorder
 	headerPropagation                      *HeaderPropagation
 	developmentMode                        bool
 	forceUnauthenticatedRequestTracing     bool
@@ -158,6 +161,7 @@ func NewPreHandler(opts *PreHandlerOptions) *PreHandler {
 		planner:                            opts.Planner,
 		accessController:                   opts.AccessController,
 		operationBlocker:                   opts.OperationBlocker,
+		operationRecorder:                  opts.OperationRecorder,
 		routerPublicKey:                    opts.RouterPublicKey,
 		developmentMode:                    opts.DevelopmentMode,
 		enableRequestTracing:               opts.EnableRequestTracing,
@@ -515,8 +519,9 @@ func (h *PreHandler) shouldComputeOperationSha256(operationKit *OperationKit, re
 	}
 
 	// If it already has a persisted hash attached to the request, then there is no need for us to compute it anew.
-	// Otherwise, we only want to compute the hash (an expensive operation) if we're safelisting or logging unknown persisted operations
-	if !hasPersistedHash && (h.operationBlocker.safelistEnabled || h.operationBlocker.logUnknownOperationsEnabled) {
+	// Otherwise, we only want to compute the hash (an expensive operation) if we're safelisting, logging unknown persisted operations
+	// or recording the operations
+	if !hasPersistedHash && (h.operationBlocker.safelistEnabled || h.operationBlocker.logUnknownOperationsEnabled || h.operationRecorder != nil) {
 		return true
 	}
 
@@ -1126,6 +1131,17 @@ func (h *PreHandler) handleOperation(req *http.Request, httpOperation *httpOpera
 
 	engineValidateSpan.End()
 
+	// Record the valid operations sent with a body to the learned manifest. The body is recorded as sent,
+	// because the safelist matches the hash of the body as sent by the client.
+	if h.operationRecorder != nil && operationKit.parsedOperation.Request.Query != "" && operationKit.parsedOperation.Sha256Hash != "" {
+		h.operationRecorder.Record(
+			operationKit.parsedOperation.Sha256Hash,
+			operationKit.parsedOperation.Request.Query,
+			requestContext.operation.clientInfo.Name,
+			requestContext.operation.clientInfo.Version,
+		)
+	}
+
 	/**
 	* Plan the operation
 	 */
@@ -111,6 +111,8 @@ type headerPropagationWriter struct {
 	didApplyRouterRespHeaders bool
 	costHeaderSetter          func(typeStats map[string]resolve.TypeNameStats)
 	didSetCostHeaders         bool
+	rateLimitHeaderSetter     func()
+	didSetRateLimitHeaders    bool
 }
 
 func (h *headerPropagationWriter) Write(p []byte) (n int, err error) {
@@ -145,6 +147,10 @@ func (h *headerPropagationWriter) Write(p []byte) (n int, err error) {
 		h.didSetCostHeaders = true
 		h.costHeaderSetter(h.resolveCtx.TypeNameStats)
 	}
+	if h.rateLimitHeaderSetter != nil && !h.didSetRateLimitHeaders {
+		h.didSetRateLimitHeaders = true
+		h.rateLimitHeaderSetter()
+	}
 	return h.writer.Write(p)
 }
 
@@ -970,6 +976,8 @@ func createMostRestrictivePolicy(policies []*cachedirective.Object) (*cachedirec
 		RespDirectives: &cachedirective.ResponseCacheDirectives{},
 	}
 	var minMaxAge cachedirective.DeltaSeconds = -1
+	// stale-while-revalidate is only kept when every policy allows serving stale responses
+	var minStaleWhileRevalidate cachedirective.DeltaSeconds = -1
 	isPrivate := false
 	isPublic := false
 
@@ -988,6 +996,12 @@ func createMostRestrictivePolicy(policies []*cachedirective.Object) (*cachedirec
 			minMaxAge = policy.RespDirectives.MaxAge
 		}
 
+		if policy.RespDirectives.StaleWhileRevalidate <= 0 {
+			minStaleWhileRevalidate = 0
+		} else if minStaleWhileRevalidate == -1 || policy.RespDirectives.StaleWhileRevalidate < minStaleWhileRevalidate {
+			minStaleWhileRevalidate = policy.RespDirectives.StaleWhileRevalidate
+		}
+
 		// Track if any policy specifies "private"
 		if policy.RespDirectives.PrivatePresent {
 			isPrivate = true
@@ -1005,6 +1019,9 @@ func createMostRestrictivePolicy(policies []*cachedirective.Object) (*cachedirec
 	if minMaxAge > 0 {
 		result.RespDirectives.MaxAge = minMaxAge
 	}
+	if minStaleWhileRevalidate > 0 {
+		result.RespDirectives.StaleWhileRevalidate = minStaleWhileRevalidate
+	}
 	result.RespDirectives.PrivatePresent = isPrivate
 
 	// Format the final Cache-Control header
@@ -1013,6 +1030,9 @@ func createMostRestrictivePolicy(policies []*cachedirective.Object) (*cachedirec
 		headerParts = append(headerParts, noCache)
 	} else if minMaxAge > 0 {
 		headerParts = append(headerParts, fmt.Sprintf("max-age=%d", minMaxAge))
+		if minStaleWhileRevalidate > 0 {
+			headerParts = append(headerParts, fmt.Sprintf("stale-while-revalidate=%d", minStaleWhileRevalidate))
+		}
 	}
 	if isPrivate {
 		headerParts = append(headerParts, "private")
@@ -85,6 +85,22 @@ func TestCreateMostRestrictivePolicy(t *testing.T) {
 			},
 			expectedHeader: "max-age=300, public",
 		},
+		{
+			name: "shortest stale-while-revalidate wins",
+			policies: []*cachedirective.Object{
+				{RespDirectives: &cachedirective.ResponseCacheDirectives{MaxAge: 600, StaleWhileRevalidate: 60}},
+				{RespDirectives: &cachedirective.ResponseCacheDirectives{MaxAge: 300, StaleWhileRevalidate: 30}},
+			},
+			expectedHeader: "max-age=300, stale-while-revalidate=30",
+		},
+		{
+			name: "stale-while-revalidate is dropped when a policy does not allow it",
+			policies: []*cachedirective.Object{
+				{RespDirectives: &cachedirective.ResponseCacheDirectives{MaxAge: 600, StaleWhileRevalidate: 60}},
+				{RespDirectives: &cachedirective.ResponseCacheDirectives{MaxAge: 300}},
+			},
+			expectedHeader: "max-age=300",
+		},
 		{
 			name: "no-cache with private",
 			policies: []*cachedirective.Object{
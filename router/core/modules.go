@@ -173,6 +173,9 @@ type ModuleContext struct {
 	stdContext.Context
 	Module Module
 	Logger *zap.Logger
+	// ResponseCache invalidates cached responses, e.g. after a mutation changed the data.
+	// It is nil when the response cache is disabled.
+	ResponseCache *ResponseCache
 }
 
 // WriteResponseError writes the given error as a GraphQL error response to the http.ResponseWriter
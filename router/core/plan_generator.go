@@ -27,6 +27,7 @@ import (
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/datasource/introspection_datasource"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/plan"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/postprocess"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/operationreport"
 )
 
@@ -123,7 +124,17 @@ func (pl *Planner) PlanOperation(operationFilePath string, outputFormat PlanOutp
 func (pl *Planner) ParseAndPrepareOperation(operationFilePath string) (*ast.Document, OperationTimes, error) {
 	start := time.Now()
 	operation, err := pl.parseOperation(operationFilePath)
-	parseTime := time.Since(start)
+	return pl.prepareParsedOperation(operation, time.Since(start), err)
+}
+
+// ParseAndPrepareOperationContent parses, normalizes and validates the operation content
+func (pl *Planner) ParseAndPrepareOperationContent(content []byte) (*ast.Document, OperationTimes, error) {
+	start := time.Now()
+	operation, err := pl.parseOperationContent(content)
+	return pl.prepareParsedOperation(operation, time.Since(start), err)
+}
+
+func (pl *Planner) prepareParsedOperation(operation *ast.Document, parseTime time.Duration, err error) (*ast.Document, OperationTimes, error) {
 	if err != nil {
 		return nil, OperationTimes{ParseTime: parseTime}, &PlannerOperationValidationError{err: err}
 	}
@@ -265,6 +276,20 @@ func (p *PlanWrapper) Marshal() ([]byte, error) {
 	return nil, nil
 }
 
+// QueryPlan returns the fetch tree of the plan
+func (p *PlanWrapper) QueryPlan() (*resolve.FetchTreeQueryPlanNode, error) {
+	switch p := p.Plan.(type) {
+	case *plan.SynchronousResponsePlan:
+		return p.Response.Fetches.QueryPlan(), nil
+	case *plan.SubscriptionResponsePlan:
+		return p.Response.Response.Fetches.QueryPlan(), nil
+	case *plan.DeferResponsePlan:
+		return nil, errors.New("defer query plan unsupported yet")
+	}
+
+	return nil, nil
+}
+
 // PlanPreparedOperation creates a query plan from a normalized and validated operation
 func (pl *Planner) PlanPreparedOperation(operation *ast.Document) (planNode *PlanWrapper, opTimes OperationTimes, err error) {
 	defer func() {
@@ -321,6 +346,10 @@ func (pl *Planner) parseOperation(operationFilePath string) (*ast.Document, erro
 		return nil, err
 	}
 
+	return pl.parseOperationContent(content)
+}
+
+func (pl *Planner) parseOperationContent(content []byte) (*ast.Document, error) {
 	doc, report := astparser.ParseGraphqlDocumentBytes(content)
 	if report.HasErrors() {
 		return nil, errors.New(report.Error())
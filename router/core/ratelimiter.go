@@ -6,15 +6,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"reflect"
 	"regexp"
+	"strconv"
 	"sync"
+	"time"
 
 	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
 
 	"github.com/expr-lang/expr/vm"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
 )
@@ -25,14 +30,17 @@ var (
 
 type CosmoRateLimiterOptions struct {
 	RedisClient rd.RDCloser
-	Debug       bool
+	// Store keeps the rate limit state. If not set, the limits are stored in Redis.
+	Store RateLimitStore
+	Debug bool
 
 	RejectStatusCode int
 
 	KeySuffixExpression string
 	ExprManager         *expr.Manager
 
-	Overrides []config.RateLimitOverride
+	Overrides    []config.RateLimitOverride
+	ScopedLimits []config.RateLimitScopedLimit
 }
 
 type compiledOverride struct {
@@ -40,8 +48,37 @@ type compiledOverride struct {
 	limit   redis_rate.Limit
 }
 
+type compiledScopedLimit struct {
+	subgraph  string
+	field     string
+	operation string
+	// key is appended to the rate limit key, so that every scoped limit has its own bucket
+	key   string
+	limit redis_rate.Limit
+}
+
+func (s *compiledScopedLimit) matches(info *resolve.FetchInfo, operationName string) bool {
+	switch {
+	case s.subgraph != "":
+		return info.DataSourceName == s.subgraph
+	case s.field != "":
+		for _, rootField := range info.RootFields {
+			if rootField.TypeName+"."+rootField.FieldName == s.field {
+				return true
+			}
+		}
+		return false
+	case s.operation != "":
+		return operationName == s.operation
+	}
+	return false
+}
+
 func NewCosmoRateLimiter(opts *CosmoRateLimiterOptions) (rl *CosmoRateLimiter, err error) {
-	limiter := redis_rate.NewLimiter(opts.RedisClient)
+	var limiter RateLimitStore = opts.Store
+	if limiter == nil {
+		limiter = redis_rate.NewLimiter(opts.RedisClient)
+	}
 
 	rl = &CosmoRateLimiter{
 		client:           opts.RedisClient,
@@ -76,18 +113,43 @@ func NewCosmoRateLimiter(opts *CosmoRateLimiterOptions) (rl *CosmoRateLimiter, e
 		})
 	}
 
+	for _, l := range opts.ScopedLimits {
+		scoped := compiledScopedLimit{
+			subgraph:  l.Subgraph,
+			field:     l.Field,
+			operation: l.Operation,
+			limit: redis_rate.Limit{
+				Rate:   l.Rate,
+				Burst:  l.Burst,
+				Period: l.Period,
+			},
+		}
+		switch {
+		case l.Subgraph != "":
+			scoped.key = "subgraph:" + l.Subgraph
+		case l.Field != "":
+			scoped.key = "field:" + l.Field
+		case l.Operation != "":
+			scoped.key = "operation:" + l.Operation
+		default:
+			return nil, errors.New("scoped rate limit must define a subgraph, field or operation")
+		}
+		rl.scopedLimits = append(rl.scopedLimits, scoped)
+	}
+
 	return rl, nil
 }
 
 type CosmoRateLimiter struct {
 	client  rd.RDCloser
-	limiter *redis_rate.Limiter
+	limiter RateLimitStore
 	debug   bool
 
 	rejectStatusCode int
 
 	keySuffixProgram *vm.Program
 	overrides        []compiledOverride
+	scopedLimits     []compiledScopedLimit
 }
 
 func (c *CosmoRateLimiter) resolveLimit(key string, defaultLimit redis_rate.Limit) redis_rate.Limit {
@@ -118,18 +180,66 @@ func (c *CosmoRateLimiter) RateLimitPreFetch(ctx *resolve.Context, info *resolve
 	}
 
 	limit := c.resolveLimit(suffix, defaultLimit)
+	if apiKeyLimit, apiKeyName, ok := apiKeyRateLimit(ctx.Context()); ok {
+		limit = apiKeyLimit
+		// Every API key has its own bucket, otherwise the keys with a limit would share
+		// the bucket of the configured key, e.g. all requests without a key suffix expression
+		key += ":api_key:" + apiKeyName
+	}
+
+	scopedLimits := c.matchingScopedLimits(ctx, info)
+
+	// A scoped limit that is exhausted denies the fetch before any bucket is consumed,
+	// otherwise every denied fetch would still count against the request limit
+	for _, scoped := range scopedLimits {
+		scopedKey := key + ":" + scoped.key
+		res, err := c.limiter.AllowN(ctx.Context(), scopedKey, scoped.limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		if res.Remaining < requestRate {
+			c.setRateLimitStats(ctx, scopedKey, requestRate, deniedResult(res, requestRate))
+			return c.deny(ctx)
+		}
+	}
 
 	allow, err := c.limiter.AllowN(ctx.Context(), key, limit, requestRate)
 	if err != nil {
 		return nil, err
 	}
 
-	c.setRateLimitStats(ctx, key, requestRate, allow.Remaining, allow.RetryAfter.Milliseconds(), allow.ResetAfter.Milliseconds())
+	// The stats describe the most restrictive limit: the one that denied the fetch
+	// or the one with the fewest remaining requests
+	statsKey, stats := key, allow
 
 	if allow.Allowed >= requestRate {
+		// The scoped buckets were checked above, only a concurrent fetch of the same key
+		// can take their last tokens in between. The request limit is not refunded then.
+		for _, scoped := range scopedLimits {
+			scopedKey := key + ":" + scoped.key
+			res, err := c.limiter.AllowN(ctx.Context(), scopedKey, scoped.limit, requestRate)
+			if err != nil {
+				return nil, err
+			}
+			if res.Allowed < requestRate || res.Remaining < stats.Remaining {
+				statsKey, stats = scopedKey, res
+			}
+			if res.Allowed < requestRate {
+				break
+			}
+		}
+	}
+
+	c.setRateLimitStats(ctx, statsKey, requestRate, stats)
+
+	if stats.Allowed >= requestRate {
 		return nil, nil
 	}
 
+	return c.deny(ctx)
+}
+
+func (c *CosmoRateLimiter) deny(ctx *resolve.Context) (*resolve.RateLimitDeny, error) {
 	if ctx.RateLimitOptions.RejectExceedingRequests {
 		return nil, ErrRateLimitExceeded
 	}
@@ -137,6 +247,61 @@ func (c *CosmoRateLimiter) RateLimitPreFetch(ctx *resolve.Context, info *resolve
 	return &resolve.RateLimitDeny{}, nil
 }
 
+// matchingScopedLimits returns the scoped limits that apply to the fetch
+func (c *CosmoRateLimiter) matchingScopedLimits(ctx *resolve.Context, info *resolve.FetchInfo) []*compiledScopedLimit {
+	if len(c.scopedLimits) == 0 {
+		return nil
+	}
+
+	var operationName string
+	if rc := getRequestContext(ctx.Context()); rc != nil {
+		operationName = rc.operation.name
+	}
+
+	var matching []*compiledScopedLimit
+	for i := range c.scopedLimits {
+		if c.scopedLimits[i].matches(info, operationName) {
+			matching = append(matching, &c.scopedLimits[i])
+		}
+	}
+
+	return matching
+}
+
+// deniedResult turns the result of checking a bucket without taking tokens into the result
+// of a denied fetch, so that the Retry-After header tells when enough tokens are available again
+func deniedResult(res *redis_rate.Result, requestRate int) *redis_rate.Result {
+	denied := *res
+	denied.Allowed = 0
+	if res.Limit.Rate > 0 {
+		denied.RetryAfter = time.Duration(requestRate-res.Remaining) * res.Limit.Period / time.Duration(res.Limit.Rate)
+	}
+	return &denied
+}
+
+// apiKeyRateLimit returns the rate limit and the name of the API key the request was authenticated
+// with. The limit takes precedence over the overrides.
+func apiKeyRateLimit(ctx context.Context) (redis_rate.Limit, string, bool) {
+	auth := authentication.FromContext(ctx)
+	if auth == nil {
+		return redis_rate.Limit{}, "", false
+	}
+
+	// Only the API key authenticator sets a typed rate limit, it can't be set by the claims of a token
+	limit, ok := auth.Claims()[authentication.APIKeyRateLimitClaim].(*authentication.APIKeyRateLimit)
+	if !ok || limit == nil {
+		return redis_rate.Limit{}, "", false
+	}
+
+	name, _ := auth.Claims()[authentication.APIKeyNameClaim].(string)
+
+	return redis_rate.Limit{
+		Rate:   limit.Rate,
+		Burst:  limit.Burst,
+		Period: limit.Period,
+	}, name, true
+}
+
 // generateKey returns the full Redis key and the suffix used for override matching.
 // When no key_suffix_expression is configured, the suffix equals the full key.
 func (c *CosmoRateLimiter) generateKey(ctx *resolve.Context) (fullKey, suffix string, err error) {
@@ -170,6 +335,39 @@ type RateLimitStats struct {
 	ResetAfterMilliseconds int64  `json:"resetAfterMs"`
 }
 
+// SetResponseHeaders sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+// and, if a fetch was rate limited, the Retry-After header. Values are in seconds.
+func (c *CosmoRateLimiter) SetResponseHeaders(ctx *resolve.Context, header http.Header) {
+	v := ctx.Context().Value(rateLimitStatsCtxKey{})
+	if v == nil {
+		return
+	}
+
+	statsCtx := v.(*rateLimitStatsCtx)
+	statsCtx.mux.Lock()
+	stats, limit, retryAfter := c.displayStats(statsCtx.stats), statsCtx.limit, statsCtx.retryAfter
+	statsCtx.mux.Unlock()
+
+	if stats.RequestRate == 0 {
+		// no fetch was rate limited
+		return
+	}
+
+	header.Set("RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(stats.Remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(secondsCeil(stats.ResetAfterMilliseconds), 10))
+	if retryAfter > 0 {
+		if c.debug {
+			retryAfter = 1234 * time.Millisecond
+		}
+		header.Set("Retry-After", strconv.FormatInt(secondsCeil(retryAfter.Milliseconds()), 10))
+	}
+}
+
+func secondsCeil(milliseconds int64) int64 {
+	return int64(math.Ceil(float64(milliseconds) / 1000))
+}
+
 func (c *CosmoRateLimiter) RenderResponseExtension(ctx *resolve.Context, out io.Writer) error {
 	data, err := c.statsJSON(ctx)
 	if err != nil {
@@ -202,18 +400,20 @@ func (c *CosmoRateLimiter) calculateRate() int {
 }
 
 func (c *CosmoRateLimiter) statsJSON(ctx *resolve.Context) ([]byte, error) {
-	stats := c.getRateLimitStats(ctx)
+	return json.Marshal(c.displayStats(c.getRateLimitStats(ctx)))
+}
+
+func (c *CosmoRateLimiter) displayStats(stats RateLimitStats) RateLimitStats {
 	if c.debug {
 		stats.ResetAfterMilliseconds = 1234
 		stats.RetryAfterMilliseconds = 1234
 	} else {
 		stats.Key = "" // hide key when not in debug mode
 	}
-
-	return json.Marshal(stats)
+	return stats
 }
 
-func (c *CosmoRateLimiter) setRateLimitStats(ctx *resolve.Context, key string, requestRate, remaining int, retryAfter, resetAfter int64) {
+func (c *CosmoRateLimiter) setRateLimitStats(ctx *resolve.Context, key string, requestRate int, res *redis_rate.Result) {
 	v := ctx.Context().Value(rateLimitStatsCtxKey{})
 	if v == nil {
 		return
@@ -223,9 +423,12 @@ func (c *CosmoRateLimiter) setRateLimitStats(ctx *resolve.Context, key string, r
 	statsCtx.mux.Lock()
 	statsCtx.stats.Key = key
 	statsCtx.stats.RequestRate = statsCtx.stats.RequestRate + requestRate
-	statsCtx.stats.Remaining = remaining
-	statsCtx.stats.RetryAfterMilliseconds = retryAfter
-	statsCtx.stats.ResetAfterMilliseconds = resetAfter
+	statsCtx.stats.Remaining = res.Remaining
+	statsCtx.stats.RetryAfterMilliseconds = res.RetryAfter.Milliseconds()
+	statsCtx.stats.ResetAfterMilliseconds = res.ResetAfter.Milliseconds()
+	statsCtx.limit = res.Limit.Burst
+	// Keep the longest wait of all rate limited fetches for the Retry-After header
+	statsCtx.retryAfter = max(statsCtx.retryAfter, res.RetryAfter)
 	statsCtx.mux.Unlock()
 }
 
@@ -243,8 +446,10 @@ func (c *CosmoRateLimiter) getRateLimitStats(ctx *resolve.Context) RateLimitStat
 }
 
 type rateLimitStatsCtx struct {
-	stats RateLimitStats
-	mux   sync.Mutex
+	stats      RateLimitStats
+	limit      int
+	retryAfter time.Duration
+	mux        sync.Mutex
 }
 
 type rateLimitStatsCtxKey struct{}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/go-redis/redis_rate/v10"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
+)
+
+const (
+	CostBudgetLimitHeader     = "X-WG-Cost-Budget-Limit"
+	CostBudgetRemainingHeader = "X-WG-Cost-Budget-Remaining"
+	CostBudgetResetHeader     = "X-WG-Cost-Budget-Reset"
+)
+
+type CostRateLimiterOptions struct {
+	RedisClient rd.RDCloser
+	// Store keeps the budgets. If not set, the budgets are stored in Redis.
+	Store RateLimitStore
+	Debug bool
+
+	// KeyPrefix is the prefix of the budget keys, the key suffix expression is appended to it
+	KeyPrefix           string
+	KeySuffixExpression string
+	ExprManager         *expr.Manager
+
+	Budget        int
+	Period        time.Duration
+	UseActualCost bool
+
+	RejectStatusCode int
+	// ErrorExtensionCode is added to the error of rejected operations, if not empty
+	ErrorExtensionCode string
+	ResponseHeaders    bool
+}
+
+// CostRateLimiter limits the cost of the operations a client can execute per period.
+// The cost of every operation is subtracted from a budget per key, which is refilled over the period.
+type CostRateLimiter struct {
+	limiter RateLimitStore
+	debug   bool
+
+	keyPrefix        string
+	keySuffixProgram *vm.Program
+
+	limit         redis_rate.Limit
+	useActualCost bool
+
+	rejectStatusCode   int
+	errorExtensionCode string
+	responseHeaders    bool
+}
+
+func NewCostRateLimiter(opts *CostRateLimiterOptions) (*CostRateLimiter, error) {
+	if opts.Budget <= 0 {
+		return nil, errors.New("cost rate limit budget must be greater than 0")
+	}
+	if opts.Period <= 0 {
+		return nil, errors.New("cost rate limit period must be greater than 0")
+	}
+
+	var limiter RateLimitStore = opts.Store
+	if limiter == nil {
+		limiter = redis_rate.NewLimiter(opts.RedisClient)
+	}
+
+	c := &CostRateLimiter{
+		limiter:   limiter,
+		debug:     opts.Debug,
+		keyPrefix: opts.KeyPrefix,
+		limit: redis_rate.Limit{
+			Rate:   opts.Budget,
+			Burst:  opts.Budget,
+			Period: opts.Period,
+		},
+		useActualCost:      opts.UseActualCost,
+		rejectStatusCode:   opts.RejectStatusCode,
+		errorExtensionCode: opts.ErrorExtensionCode,
+		responseHeaders:    opts.ResponseHeaders,
+	}
+	if c.rejectStatusCode == 0 {
+		c.rejectStatusCode = http.StatusTooManyRequests
+	}
+
+	if opts.KeySuffixExpression != "" {
+		var err error
+		c.keySuffixProgram, err = opts.ExprManager.CompileExpression(opts.KeySuffixExpression, reflect.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Reserve charges the estimated cost of the operation before it is executed. If the actual cost
+// is charged instead, it only checks that the budget is not exhausted yet. It returns an error
+// to send to the client if the operation must be rejected. The budget headers are set on header,
+// which can be nil.
+func (c *CostRateLimiter) Reserve(ctx context.Context, reqCtx *requestContext, header http.Header) error {
+	if !reqCtx.operation.costEstimatedSet {
+		return nil
+	}
+
+	key, err := c.generateKey(reqCtx)
+	if err != nil {
+		return err
+	}
+
+	cost := reqCtx.operation.costEstimated
+	if c.useActualCost {
+		cost = 0
+	}
+
+	res, err := c.limiter.AllowN(ctx, key, c.limit, cost)
+	if err != nil {
+		return err
+	}
+
+	allowed := res.Allowed >= cost
+	if c.useActualCost {
+		allowed = res.Remaining > 0
+		if !allowed {
+			// Nothing was requested from the limiter, so the wait is until one unit of the budget is refilled
+			res.RetryAfter = c.limit.Period / time.Duration(c.limit.Rate)
+		}
+	}
+
+	if header != nil && c.responseHeaders {
+		c.setResponseHeaders(header, res, !allowed)
+	}
+
+	if allowed {
+		return nil
+	}
+
+	message := fmt.Sprintf("The cost budget is exhausted. The estimated query cost %d exceeds the remaining budget %d", reqCtx.operation.costEstimated, res.Remaining)
+	if c.useActualCost {
+		message = "The cost budget is exhausted"
+	}
+
+	return NewHttpGraphqlError(message, c.errorExtensionCode, c.rejectStatusCode)
+}
+
+// Settle charges the actual cost of the operation after it was executed, if configured.
+// As the response is already sent, the cost is charged as far as the budget allows.
+func (c *CostRateLimiter) Settle(ctx context.Context, reqCtx *requestContext) {
+	if !c.useActualCost || !reqCtx.operation.costActualSet || reqCtx.operation.costActual <= 0 {
+		return
+	}
+
+	key, err := c.generateKey(reqCtx)
+	if err != nil {
+		reqCtx.logger.Error("unable to generate cost rate limit key", zap.Error(err))
+		return
+	}
+
+	// The client might have disconnected already, but the cost must still be charged
+	_, err = c.limiter.AllowAtMost(context.WithoutCancel(ctx), key, c.limit, reqCtx.operation.costActual)
+	if err != nil {
+		reqCtx.logger.Error("unable to charge the actual operation cost", zap.Error(err))
+	}
+}
+
+func (c *CostRateLimiter) generateKey(reqCtx *requestContext) (string, error) {
+	if c.keySuffixProgram == nil {
+		return c.keyPrefix, nil
+	}
+
+	suffix, err := expr.ResolveStringExpression(c.keySuffixProgram, reqCtx.expressionContext)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve key suffix expression: %w", err)
+	}
+
+	return c.keyPrefix + ":" + suffix, nil
+}
+
+func (c *CostRateLimiter) setResponseHeaders(header http.Header, res *redis_rate.Result, exceeded bool) {
+	resetAfter, retryAfter := res.ResetAfter, res.RetryAfter
+	if c.debug {
+		resetAfter, retryAfter = 1234*time.Millisecond, 1234*time.Millisecond
+	}
+
+	header.Set(CostBudgetLimitHeader, strconv.Itoa(c.limit.Burst))
+	header.Set(CostBudgetRemainingHeader, strconv.Itoa(res.Remaining))
+	header.Set(CostBudgetResetHeader, strconv.FormatInt(secondsCeil(resetAfter.Milliseconds()), 10))
+	if exceeded && retryAfter > 0 {
+		header.Set("Retry-After", strconv.FormatInt(secondsCeil(retryAfter.Milliseconds()), 10))
+	}
+}
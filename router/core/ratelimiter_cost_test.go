@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+)
+
+func costRequestContext(t *testing.T, header http.Header, estimated int) *requestContext {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:3002/graphql", nil)
+	require.NoError(t, err)
+	if header != nil {
+		req.Header = header
+	}
+
+	reqCtx := buildRequestContext(requestContextOptions{
+		r:             req,
+		requestLogger: zap.NewNop(),
+	})
+	reqCtx.operation = &operationContext{
+		costEstimated:    estimated,
+		costEstimatedSet: true,
+	}
+
+	return reqCtx
+}
+
+func TestCostRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	newLimiter := func(t *testing.T, useActualCost bool) *CostRateLimiter {
+		t.Helper()
+
+		c, err := NewCostRateLimiter(&CostRateLimiterOptions{
+			Store:               NewMemoryRateLimitStore(),
+			KeyPrefix:           "cost",
+			KeySuffixExpression: "request.header.Get('X-Api-Key')",
+			ExprManager:         expr.CreateNewExprManager(),
+			Budget:              100,
+			Period:              time.Minute,
+			UseActualCost:       useActualCost,
+			ErrorExtensionCode:  "COST_BUDGET_EXCEEDED",
+			ResponseHeaders:     true,
+		})
+		require.NoError(t, err)
+		return c
+	}
+
+	t.Run("charges the estimated cost", func(t *testing.T) {
+		t.Parallel()
+
+		c := newLimiter(t, false)
+		apiKey := http.Header{"X-Api-Key": []string{"a"}}
+
+		header := http.Header{}
+		require.NoError(t, c.Reserve(context.Background(), costRequestContext(t, apiKey, 60), header))
+		assert.Equal(t, "100", header.Get(CostBudgetLimitHeader))
+		assert.Equal(t, "40", header.Get(CostBudgetRemainingHeader))
+		assert.Equal(t, "36", header.Get(CostBudgetResetHeader))
+
+		header = http.Header{}
+		err := c.Reserve(context.Background(), costRequestContext(t, apiKey, 60), header)
+		require.Error(t, err)
+
+		var httpErr HttpError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode())
+		assert.Equal(t, "COST_BUDGET_EXCEEDED", httpErr.ExtensionCode())
+		assert.Equal(t, "The cost budget is exhausted. The estimated query cost 60 exceeds the remaining budget 40", httpErr.Message())
+		assert.Equal(t, "12", header.Get("Retry-After"))
+
+		// Every key has its own budget
+		require.NoError(t, c.Reserve(context.Background(), costRequestContext(t, http.Header{"X-Api-Key": []string{"b"}}, 60), nil))
+	})
+
+	t.Run("charges the actual cost", func(t *testing.T) {
+		t.Parallel()
+
+		c := newLimiter(t, true)
+
+		reqCtx := costRequestContext(t, nil, 500)
+		require.NoError(t, c.Reserve(context.Background(), reqCtx, nil))
+
+		reqCtx.operation.costActual = 150
+		reqCtx.operation.costActualSet = true
+		c.Settle(context.Background(), reqCtx)
+
+		header := http.Header{}
+		err := c.Reserve(context.Background(), costRequestContext(t, nil, 500), header)
+		require.Error(t, err)
+		assert.Equal(t, "0", header.Get(CostBudgetRemainingHeader))
+		assert.Equal(t, "1", header.Get("Retry-After"))
+	})
+
+	t.Run("skips operations without an estimated cost", func(t *testing.T) {
+		t.Parallel()
+
+		c := newLimiter(t, false)
+		reqCtx := costRequestContext(t, nil, 1000)
+		reqCtx.operation.costEstimatedSet = false
+
+		header := http.Header{}
+		require.NoError(t, c.Reserve(context.Background(), reqCtx, header))
+		assert.Empty(t, header)
+	})
+
+	t.Run("requires a budget", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewCostRateLimiter(&CostRateLimiterOptions{Period: time.Minute})
+		require.Error(t, err)
+	})
+}
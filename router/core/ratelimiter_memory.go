@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+)
+
+// memoryRateLimitSweepInterval is the minimum time between two sweeps of the idle buckets
+const memoryRateLimitSweepInterval = time.Minute
+
+// RateLimitStore enforces a limit for a key. It is implemented by the Redis limiter, which shares
+// the limits between router instances, and by the MemoryRateLimitStore.
+type RateLimitStore interface {
+	AllowN(ctx context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error)
+	AllowAtMost(ctx context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error)
+}
+
+// MemoryRateLimitStore is a RateLimitStore that keeps a token bucket per key in memory.
+// A bucket holds up to Burst tokens and is refilled with Rate tokens per Period.
+// It is safe for concurrent use.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+
+	// burst and refillRate of the limit the bucket was last used with
+	burst      float64
+	refillRate float64
+}
+
+// refill returns the tokens the bucket holds at the given time
+func (b *tokenBucket) refill(now time.Time) float64 {
+	return math.Min(b.burst, b.tokens+float64(now.Sub(b.lastSeen))*b.refillRate)
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// AllowN takes n tokens from the bucket of the key if it holds enough of them.
+// The result has the same semantics as the one of the Redis limiter.
+func (s *MemoryRateLimitStore) AllowN(_ context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error) {
+	return s.take(key, limit, n, false), nil
+}
+
+// AllowAtMost takes up to n tokens from the bucket of the key, as many as it holds.
+// The result has the same semantics as the one of the Redis limiter.
+func (s *MemoryRateLimitStore) AllowAtMost(_ context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error) {
+	return s.take(key, limit, n, true), nil
+}
+
+func (s *MemoryRateLimitStore) take(key string, limit redis_rate.Limit, n int, atMost bool) *redis_rate.Result {
+	burst := float64(max(limit.Burst, 1))
+	// tokens refilled per nanosecond
+	refillRate := float64(limit.Rate) / float64(limit.Period)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst}
+		s.buckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(burst, bucket.refill(now))
+	}
+	bucket.lastSeen = now
+	bucket.burst = burst
+	bucket.refillRate = refillRate
+
+	res := &redis_rate.Result{
+		Limit:      limit,
+		RetryAfter: -1,
+	}
+
+	if atMost {
+		n = min(n, int(bucket.tokens))
+	}
+
+	if bucket.tokens >= float64(n) {
+		bucket.tokens -= float64(n)
+		res.Allowed = n
+	} else {
+		res.RetryAfter = durationUntil(float64(n)-bucket.tokens, refillRate)
+	}
+
+	res.Remaining = int(bucket.tokens)
+	res.ResetAfter = durationUntil(burst-bucket.tokens, refillRate)
+
+	return res
+}
+
+// sweep removes the buckets that are full again, as they are equal to a new bucket.
+// The caller must hold the lock.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memoryRateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if bucket.refill(now) >= bucket.burst {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// durationUntil returns the time it takes to refill the given number of tokens
+func durationUntil(tokens, refillRate float64) time.Duration {
+	if tokens <= 0 || refillRate <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / refillRate))
+}
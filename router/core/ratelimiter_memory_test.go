@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	t.Parallel()
+
+	limit := redis_rate.Limit{Rate: 2, Burst: 2, Period: time.Second}
+
+	newStore := func() (*MemoryRateLimitStore, *time.Time) {
+		now := time.Unix(1000, 0)
+		store := NewMemoryRateLimitStore()
+		store.now = func() time.Time { return now }
+		return store, &now
+	}
+
+	t.Run("allows requests up to the burst", func(t *testing.T) {
+		t.Parallel()
+
+		store, _ := newStore()
+
+		res, err := store.AllowN(context.Background(), "key", limit, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Allowed)
+		assert.Equal(t, 1, res.Remaining)
+		assert.Equal(t, time.Duration(-1), res.RetryAfter)
+		assert.Equal(t, 500*time.Millisecond, res.ResetAfter)
+
+		res, err = store.AllowN(context.Background(), "key", limit, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+		assert.Equal(t, time.Second, res.ResetAfter)
+
+		res, err = store.AllowN(context.Background(), "key", limit, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+		assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	})
+
+	t.Run("refills the bucket over time", func(t *testing.T) {
+		t.Parallel()
+
+		store, now := newStore()
+
+		res, err := store.AllowN(context.Background(), "key", limit, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Allowed)
+
+		*now = now.Add(500 * time.Millisecond)
+
+		res, err = store.AllowN(context.Background(), "key", limit, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Allowed)
+
+		res, err = store.AllowN(context.Background(), "key", limit, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, res.Allowed)
+	})
+
+	t.Run("keeps a bucket per key", func(t *testing.T) {
+		t.Parallel()
+
+		store, _ := newStore()
+
+		res, err := store.AllowN(context.Background(), "a", limit, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Allowed)
+
+		res, err = store.AllowN(context.Background(), "b", limit, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Allowed)
+	})
+
+	t.Run("takes at most the remaining tokens", func(t *testing.T) {
+		t.Parallel()
+
+		store, _ := newStore()
+
+		res, err := store.AllowAtMost(context.Background(), "key", limit, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+
+		res, err = store.AllowAtMost(context.Background(), "key", limit, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 0, res.Allowed)
+	})
+
+	t.Run("removes full buckets", func(t *testing.T) {
+		t.Parallel()
+
+		store, now := newStore()
+
+		_, err := store.AllowN(context.Background(), "a", limit, 1)
+		require.NoError(t, err)
+		_, err = store.AllowN(context.Background(), "b", redis_rate.Limit{Rate: 1, Burst: 2, Period: time.Hour}, 1)
+		require.NoError(t, err)
+
+		*now = now.Add(memoryRateLimitSweepInterval)
+
+		_, err = store.AllowN(context.Background(), "c", limit, 1)
+		require.NoError(t, err)
+		assert.NotContains(t, store.buckets, "a")
+		assert.Contains(t, store.buckets, "b")
+		assert.Contains(t, store.buckets, "c")
+	})
+}
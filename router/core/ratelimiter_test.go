@@ -254,6 +254,77 @@ func TestRateLimiterResolveLimit(t *testing.T) {
 	})
 }
 
+func TestAPIKeyRateLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the rate limit of the API key", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := authentication.NewContext(context.Background(), &FakeAuthenticator{claims: map[string]any{
+			authentication.APIKeyNameClaim:      "mobile",
+			authentication.APIKeyRateLimitClaim: &authentication.APIKeyRateLimit{Rate: 100, Burst: 200, Period: time.Minute},
+		}})
+
+		limit, name, ok := apiKeyRateLimit(ctx)
+		require.True(t, ok)
+		assert.Equal(t, redis_rate.Limit{Rate: 100, Burst: 200, Period: time.Minute}, limit)
+		assert.Equal(t, "mobile", name)
+	})
+
+	t.Run("every API key has its own bucket", func(t *testing.T) {
+		t.Parallel()
+
+		rl, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{Store: NewMemoryRateLimitStore()})
+		require.NoError(t, err)
+
+		withAPIKey := func(name string) *resolve.Context {
+			ctx := expressionResolveContext(t, nil, nil)
+			ctx.RateLimitOptions.Rate = 10
+			ctx.RateLimitOptions.Burst = 10
+			ctx.RateLimitOptions.Period = time.Minute
+			return WithRateLimiterStats(ctx.WithContext(authentication.NewContext(ctx.Context(), &FakeAuthenticator{claims: map[string]any{
+				authentication.APIKeyNameClaim:      name,
+				authentication.APIKeyRateLimitClaim: &authentication.APIKeyRateLimit{Rate: 1, Burst: 1, Period: time.Minute},
+			}})))
+		}
+		fetch := &resolve.FetchInfo{
+			DataSourceName: "employees",
+			RootFields:     []resolve.GraphCoordinate{{TypeName: "Query", FieldName: "employees"}},
+		}
+
+		mobile := withAPIKey("mobile")
+		deny, err := rl.RateLimitPreFetch(mobile, fetch, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+		assert.Equal(t, "test:api_key:mobile", rl.getRateLimitStats(mobile).Key)
+
+		deny, err = rl.RateLimitPreFetch(mobile, fetch, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, deny)
+
+		// The limit of the mobile key doesn't affect the web key
+		web := withAPIKey("web")
+		deny, err = rl.RateLimitPreFetch(web, fetch, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+		assert.Equal(t, "test:api_key:web", rl.getRateLimitStats(web).Key)
+	})
+
+	t.Run("ignores a rate limit claim of a token", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := authentication.NewContext(context.Background(), &FakeAuthenticator{claims: map[string]any{
+			authentication.APIKeyRateLimitClaim: map[string]any{"rate": 100, "burst": 200, "period": "1m"},
+		}})
+
+		_, _, ok := apiKeyRateLimit(ctx)
+		require.False(t, ok)
+
+		_, _, ok = apiKeyRateLimit(context.Background())
+		require.False(t, ok)
+	})
+}
+
 func TestNewCosmoRateLimiter(t *testing.T) {
 	t.Parallel()
 
@@ -268,3 +339,215 @@ func TestNewCosmoRateLimiter(t *testing.T) {
 		require.ErrorContains(t, err, "invalid regex '[invalid' for rate limit override")
 	})
 }
+
+func TestRateLimiterScopedLimits(t *testing.T) {
+	t.Parallel()
+
+	newLimiter := func(t *testing.T, scoped ...config.RateLimitScopedLimit) *CosmoRateLimiter {
+		t.Helper()
+
+		rl, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{
+			Store:        NewMemoryRateLimitStore(),
+			ScopedLimits: scoped,
+		})
+		require.NoError(t, err)
+		return rl
+	}
+
+	newResolveContext := func(t *testing.T, operationName string) *resolve.Context {
+		t.Helper()
+
+		ctx := expressionResolveContext(t, nil, nil)
+		getRequestContext(ctx.Context()).operation = &operationContext{name: operationName}
+		ctx.RateLimitOptions.Rate = 10
+		ctx.RateLimitOptions.Burst = 10
+		ctx.RateLimitOptions.Period = time.Second
+		return WithRateLimiterStats(ctx)
+	}
+
+	employees := &resolve.FetchInfo{
+		DataSourceName: "employees",
+		RootFields:     []resolve.GraphCoordinate{{TypeName: "Query", FieldName: "employees"}},
+	}
+	products := &resolve.FetchInfo{
+		DataSourceName: "products",
+		RootFields:     []resolve.GraphCoordinate{{TypeName: "Query", FieldName: "products"}},
+	}
+
+	t.Run("limits the fetches of a subgraph", func(t *testing.T) {
+		t.Parallel()
+
+		rl := newLimiter(t, config.RateLimitScopedLimit{Subgraph: "employees", Rate: 1, Burst: 1, Period: time.Minute})
+		ctx := newResolveContext(t, "")
+
+		deny, err := rl.RateLimitPreFetch(ctx, employees, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+
+		deny, err = rl.RateLimitPreFetch(ctx, employees, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, deny)
+		assert.Equal(t, "test:subgraph:employees", rl.getRateLimitStats(ctx).Key)
+
+		deny, err = rl.RateLimitPreFetch(ctx, products, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+	})
+
+	t.Run("limits the fetches of a root field", func(t *testing.T) {
+		t.Parallel()
+
+		rl := newLimiter(t, config.RateLimitScopedLimit{Field: "Query.products", Rate: 1, Burst: 1, Period: time.Minute})
+		ctx := newResolveContext(t, "")
+
+		for range 2 {
+			deny, err := rl.RateLimitPreFetch(ctx, employees, nil)
+			require.NoError(t, err)
+			assert.Nil(t, deny)
+		}
+
+		deny, err := rl.RateLimitPreFetch(ctx, products, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+
+		deny, err = rl.RateLimitPreFetch(ctx, products, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, deny)
+	})
+
+	t.Run("limits the fetches of an operation", func(t *testing.T) {
+		t.Parallel()
+
+		rl := newLimiter(t, config.RateLimitScopedLimit{Operation: "Expensive", Rate: 1, Burst: 1, Period: time.Minute})
+
+		deny, err := rl.RateLimitPreFetch(newResolveContext(t, "Cheap"), employees, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+
+		deny, err = rl.RateLimitPreFetch(newResolveContext(t, "Expensive"), employees, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+
+		deny, err = rl.RateLimitPreFetch(newResolveContext(t, "Expensive"), employees, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, deny)
+	})
+
+	t.Run("a denied scoped limit does not consume the request limit", func(t *testing.T) {
+		t.Parallel()
+
+		rl := newLimiter(t, config.RateLimitScopedLimit{Subgraph: "employees", Rate: 1, Burst: 1, Period: time.Minute})
+		ctx := newResolveContext(t, "")
+		ctx.RateLimitOptions.Burst = 2
+		ctx.RateLimitOptions.Period = time.Minute
+
+		deny, err := rl.RateLimitPreFetch(ctx, employees, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+
+		for range 3 {
+			deny, err = rl.RateLimitPreFetch(ctx, employees, nil)
+			require.NoError(t, err)
+			assert.NotNil(t, deny)
+		}
+		stats := rl.getRateLimitStats(ctx)
+		assert.Equal(t, "test:subgraph:employees", stats.Key)
+		assert.Positive(t, stats.RetryAfterMilliseconds)
+
+		// The request limit still holds the token the denied fetches didn't take
+		deny, err = rl.RateLimitPreFetch(ctx, products, nil)
+		require.NoError(t, err)
+		assert.Nil(t, deny)
+
+		deny, err = rl.RateLimitPreFetch(ctx, products, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, deny)
+		assert.Equal(t, "test", rl.getRateLimitStats(ctx).Key)
+	})
+
+	t.Run("reports the most restrictive limit", func(t *testing.T) {
+		t.Parallel()
+
+		rl := newLimiter(t, config.RateLimitScopedLimit{Subgraph: "employees", Rate: 3, Burst: 3, Period: time.Minute})
+		ctx := newResolveContext(t, "")
+
+		_, err := rl.RateLimitPreFetch(ctx, employees, nil)
+		require.NoError(t, err)
+
+		stats := rl.getRateLimitStats(ctx)
+		assert.Equal(t, "test:subgraph:employees", stats.Key)
+		assert.Equal(t, 2, stats.Remaining)
+	})
+
+	t.Run("requires a scope", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{
+			ScopedLimits: []config.RateLimitScopedLimit{{Rate: 1, Burst: 1, Period: time.Second}},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestRateLimiterSetResponseHeaders(t *testing.T) {
+	t.Parallel()
+
+	newContext := func(t *testing.T) *resolve.Context {
+		t.Helper()
+
+		ctx := expressionResolveContext(t, nil, nil)
+		ctx.RateLimitOptions.Rate = 1
+		ctx.RateLimitOptions.Burst = 2
+		ctx.RateLimitOptions.Period = 10 * time.Second
+		return WithRateLimiterStats(ctx)
+	}
+
+	info := &resolve.FetchInfo{DataSourceName: "employees"}
+
+	t.Run("sets no headers without rate limited fetches", func(t *testing.T) {
+		t.Parallel()
+
+		rl, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{Store: NewMemoryRateLimitStore()})
+		require.NoError(t, err)
+
+		header := http.Header{}
+		rl.SetResponseHeaders(newContext(t), header)
+		assert.Empty(t, header)
+	})
+
+	t.Run("sets the limit headers", func(t *testing.T) {
+		t.Parallel()
+
+		rl, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{Store: NewMemoryRateLimitStore()})
+		require.NoError(t, err)
+		ctx := newContext(t)
+
+		_, err = rl.RateLimitPreFetch(ctx, info, nil)
+		require.NoError(t, err)
+
+		header := http.Header{}
+		rl.SetResponseHeaders(ctx, header)
+		assert.Equal(t, "2", header.Get("RateLimit-Limit"))
+		assert.Equal(t, "1", header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "10", header.Get("RateLimit-Reset"))
+		assert.Empty(t, header.Get("Retry-After"))
+	})
+
+	t.Run("sets retry after when a fetch was rate limited", func(t *testing.T) {
+		t.Parallel()
+
+		rl, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{Store: NewMemoryRateLimitStore()})
+		require.NoError(t, err)
+		ctx := newContext(t)
+
+		for range 3 {
+			_, err = rl.RateLimitPreFetch(ctx, info, nil)
+			require.NoError(t, err)
+		}
+
+		header := http.Header{}
+		rl.SetResponseHeaders(ctx, header)
+		assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "10", header.Get("Retry-After"))
+	})
+}
@@ -147,6 +147,10 @@ func processRequestIDField(request *http.Request, resFields []zapcore.Field) (*r
 		resFields = append(resFields, logging.WithBatchedRequestOperationID(batchedOperationId))
 	}
 
+	if reason := featureFlagReasonFromContext(request.Context()); reason != "" {
+		resFields = append(resFields, zap.String("feature_flag_reason", reason))
+	}
+
 	return reqContext, resFields
 }
 
@@ -0,0 +1,315 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/cespare/xxhash/v2"
+	"github.com/expr-lang/expr/vm"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/responsecache"
+)
+
+// ResponseCacheHeader reports whether a response was served from the response cache.
+// It is only set when the cache response headers are enabled for debugging.
+const ResponseCacheHeader = "X-WG-Response-Cache"
+
+// cachedResponseHeaders are the response headers that are stored with a cached response
+var cachedResponseHeaders = []string{"Content-Type", "Cache-Control"}
+
+// ResponseCache caches the complete responses of query operations. The entries are shared by all
+// graphs of the router and survive config changes, because the config version is part of the key.
+// Modules get it through the ModuleContext to invalidate cached responses.
+type ResponseCache struct {
+	store responsecache.Store
+	// revalidating holds the keys of the stale entries that a request is currently refreshing,
+	// so concurrent requests keep getting the stale entry instead of hitting the subgraphs
+	revalidating sync.Map
+}
+
+// buildResponseCache creates the response cache. With a storage provider the entries are
+// written to Redis and shared between router instances, otherwise they are kept in memory.
+func (r *Router) buildResponseCache(ctx context.Context) error {
+	cfg := r.responseCache
+	logger := r.logger.With(zap.String("component", "response_cache"))
+
+	// The key only contains the authentication state and the scopes of the request. Policies can
+	// deny fields by any claim, so the responses have to be scoped by the key expression.
+	if cfg.KeyExpression == "" && r.authorization != nil && len(r.authorization.Policies) > 0 {
+		return errors.New("response_cache.key_expression is required when authorization.policies are configured")
+	}
+
+	var store responsecache.Store
+
+	if cfg.Storage.ProviderID != "" {
+		provider, ok := r.providerRegistry.Redis(cfg.Storage.ProviderID)
+		if !ok {
+			return fmt.Errorf("redis storage provider with id '%s' for response_cache not found", cfg.Storage.ProviderID)
+		}
+
+		var err error
+		r.responseCacheRedisClient, err = rd.NewRedisCloser(&rd.RedisCloserOptions{
+			URLs:           provider.URLs,
+			ClusterEnabled: provider.ClusterEnabled,
+			Logger:         r.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create redis client for response caching: %w", err)
+		}
+
+		prefix := cfg.Storage.KeyPrefix
+		if prefix != "" {
+			prefix += ":"
+		}
+
+		store, err = responsecache.NewRedisStore(ctx, r.responseCacheRedisClient, prefix)
+		if err != nil {
+			return fmt.Errorf("failed to create redis response cache: %w", err)
+		}
+
+		logger.Debug("Using redis response cache", zap.String("provider_id", cfg.Storage.ProviderID))
+	} else {
+		var err error
+		store, err = responsecache.NewMemoryStore(cfg.InMemory.MaxEntries)
+		if err != nil {
+			return err
+		}
+
+		logger.Debug("Using in-memory response cache", zap.Int("max_entries", cfg.InMemory.MaxEntries))
+	}
+
+	r.responseCacheStore = &ResponseCache{store: store}
+
+	return nil
+}
+
+// InvalidateOperation removes the cached responses of every operation with the given name.
+// An empty name removes the responses of anonymous operations. It does nothing when the
+// response cache is disabled.
+func (c *ResponseCache) InvalidateOperation(ctx context.Context, operationName string) error {
+	if c == nil {
+		return nil
+	}
+	return c.store.InvalidateOperation(ctx, operationName)
+}
+
+// Clear removes all cached responses. It does nothing when the response cache is disabled.
+func (c *ResponseCache) Clear(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	return c.store.Clear(ctx)
+}
+
+// graphResponseCache is the response cache as used by the GraphQL handler of a single graph
+type graphResponseCache struct {
+	cache *ResponseCache
+	// keyProgram scopes the entries, e.g. per user. It is nil when no key expression is configured.
+	keyProgram *vm.Program
+	// configVersion is part of every key, so a new execution config doesn't serve outdated responses
+	configVersion string
+	now           func() time.Time
+}
+
+func newGraphResponseCache(cache *ResponseCache, exprManager *expr.Manager, keyExpression, configVersion string) (*graphResponseCache, error) {
+	c := &graphResponseCache{
+		cache:         cache,
+		configVersion: configVersion,
+		now:           time.Now,
+	}
+
+	if keyExpression != "" {
+		var err error
+		c.keyProgram, err = exprManager.CompileExpression(keyExpression, reflect.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile response cache key expression: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// responseCacheLookup is the response cache state of a single request
+type responseCacheLookup struct {
+	cache  *graphResponseCache
+	key    string
+	scoped bool
+	// revalidating is true if the request refreshes a stale entry
+	revalidating bool
+}
+
+// cacheable reports whether the response of the operation can be cached. Only queries are
+// cached, and never when the response contains a trace or query plan of the request.
+func (c *graphResponseCache) cacheable(op *operationContext) bool {
+	return op.opType == OperationTypeQuery &&
+		!op.traceOptions.Enable &&
+		!op.executionOptions.SkipLoader &&
+		!op.executionOptions.IncludeQueryPlanInResponse
+}
+
+// key returns the cache key of the operation. The key is made of the normalized operation, the
+// variables, the authorization state of the request and the value of the key expression.
+func (c *graphResponseCache) key(ctx context.Context, reqCtx *requestContext) (key string, scoped bool, err error) {
+	var scope string
+	if c.keyProgram != nil {
+		scope, err = expr.ResolveStringExpression(c.keyProgram, reqCtx.expressionContext)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to resolve response cache key expression: %w", err)
+		}
+	}
+
+	d := xxhash.New()
+	_, _ = d.WriteString(c.configVersion)
+	_, _ = d.WriteString(strconv.FormatUint(reqCtx.operation.internalHash, 10))
+	_, _ = d.WriteString(strconv.FormatUint(reqCtx.operation.variablesHash, 10))
+	_, _ = d.WriteString(scope)
+
+	// The field authorizer removes the fields the request isn't allowed to see from the response.
+	// It decides by the authentication state and the scopes of the request, so requests that differ
+	// in them must not share a cached response, even without a key expression.
+	if auth := authentication.FromContext(ctx); auth != nil {
+		scopes := slices.Clone(auth.Scopes())
+		slices.Sort(scopes)
+		_, _ = d.WriteString("\x00authenticated")
+		for _, s := range scopes {
+			_, _ = d.WriteString("\x00")
+			_, _ = d.WriteString(s)
+		}
+	}
+
+	return responsecache.Key(reqCtx.operation.name, strconv.FormatUint(d.Sum64(), 16)), c.keyProgram != nil, nil
+}
+
+// serve writes the cached response of the request if there is one. Otherwise, it returns the
+// lookup used to store the response after it was resolved. A stale entry is served while one
+// request refreshes it. The lookup is nil if the response can't be cached.
+func (c *graphResponseCache) serve(w http.ResponseWriter, r *http.Request, reqCtx *requestContext, debugHeaders bool) (*responseCacheLookup, bool) {
+	if !c.cacheable(reqCtx.operation) {
+		return nil, false
+	}
+
+	key, scoped, err := c.key(r.Context(), reqCtx)
+	if err != nil {
+		reqCtx.logger.Warn("Response cache skipped", zap.Error(err))
+		return nil, false
+	}
+
+	lookup := &responseCacheLookup{cache: c, key: key, scoped: scoped}
+
+	entry, ok, err := c.cache.store.Get(r.Context(), key)
+	if err != nil {
+		reqCtx.logger.Warn("Failed to read from response cache", zap.Error(err))
+		return lookup, false
+	}
+
+	now := c.now()
+	status := "HIT"
+
+	switch {
+	case !ok:
+		if debugHeaders {
+			w.Header().Set(ResponseCacheHeader, "MISS")
+		}
+		return lookup, false
+	case !entry.Fresh(now):
+		if _, refreshing := c.cache.revalidating.LoadOrStore(key, struct{}{}); !refreshing {
+			lookup.revalidating = true
+			if debugHeaders {
+				w.Header().Set(ResponseCacheHeader, "REVALIDATED")
+			}
+			return lookup, false
+		}
+		status = "STALE"
+	}
+
+	header := w.Header()
+	for name, values := range entry.Header {
+		header[name] = values
+	}
+	header.Set("ETag", entry.ETag)
+	header.Set("Age", strconv.Itoa(int(entry.Age(now).Seconds())))
+	if debugHeaders {
+		header.Set(ResponseCacheHeader, status)
+	}
+
+	if responsecache.MatchesETag(r.Header.Get("If-None-Match"), entry.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil, true
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	if _, err := w.Write(entry.Body); err != nil {
+		reqCtx.logger.Debug("Failed to write cached response", zap.Error(err))
+	}
+
+	return nil, true
+}
+
+// store caches the resolved response if its Cache-Control header allows it. Responses with errors
+// are never cached.
+func (l *responseCacheLookup) store(ctx context.Context, logger *zap.Logger, rw *responseCacheWriter, hasSubgraphErrors bool) {
+	if rw.body == nil || hasSubgraphErrors {
+		return
+	}
+
+	directives := responsecache.ParseCacheControl(rw.header.Get("Cache-Control"))
+	if !directives.Cacheable(l.scoped) {
+		return
+	}
+
+	if _, _, _, err := jsonparser.Get(rw.body, "errors"); err == nil {
+		return
+	}
+
+	header := make(http.Header, len(cachedResponseHeaders))
+	for _, name := range cachedResponseHeaders {
+		if values := rw.header.Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+
+	entry := responsecache.NewEntry(rw.body, header, directives, l.cache.now())
+	if err := l.cache.cache.store.Set(ctx, l.key, entry); err != nil {
+		logger.Warn("Failed to write to response cache", zap.Error(err))
+	}
+}
+
+// release ends the revalidation of a stale entry
+func (l *responseCacheLookup) release() {
+	if l != nil && l.revalidating {
+		l.cache.cache.revalidating.Delete(l.key)
+	}
+}
+
+// responseCacheWriter records the response body written by the resolver. The resolver writes the
+// whole body at once, so the ETag header can be set before the body is passed on.
+type responseCacheWriter struct {
+	header http.Header
+	next   io.Writer
+	body   []byte
+}
+
+func newResponseCacheWriter(header http.Header, next io.Writer) *responseCacheWriter {
+	return &responseCacheWriter{header: header, next: next}
+}
+
+func (w *responseCacheWriter) Write(p []byte) (int, error) {
+	if w.body == nil {
+		w.header.Set("ETag", responsecache.ETag(p))
+	}
+	w.body = append(w.body, p...)
+	return w.next.Write(p)
+}
@@ -0,0 +1,284 @@
+package core
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/cosmo/router/pkg/responsecache"
+)
+
+func newTestGraphResponseCache(t *testing.T, keyExpression string) *graphResponseCache {
+	t.Helper()
+
+	store, err := responsecache.NewMemoryStore(100)
+	require.NoError(t, err)
+
+	c, err := newGraphResponseCache(&ResponseCache{store: store}, expr.CreateNewExprManager(), keyExpression, "config-version")
+	require.NoError(t, err)
+
+	return c
+}
+
+func newTestResponseCacheRequest(subject string) *requestContext {
+	return &requestContext{
+		logger: zap.NewNop(),
+		operation: &operationContext{
+			name:          "Employees",
+			opType:        OperationTypeQuery,
+			internalHash:  1,
+			variablesHash: 2,
+		},
+		expressionContext: expr.Context{
+			Request: expr.Request{
+				Auth: expr.RequestAuth{Claims: map[string]any{"sub": subject}},
+			},
+		},
+	}
+}
+
+// resolveThroughResponseCache stands in for the GraphQL handler: it serves the request from the
+// cache or writes and stores the given response
+func resolveThroughResponseCache(t *testing.T, c *graphResponseCache, reqCtx *requestContext, req *http.Request, cacheControl, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+
+	lookup, served := c.serve(rec, req, reqCtx, true)
+	if served {
+		return rec
+	}
+	defer lookup.release()
+
+	rec.Header().Set("Cache-Control", cacheControl)
+
+	if lookup == nil {
+		_, err := rec.Write([]byte(body))
+		require.NoError(t, err)
+		return rec
+	}
+
+	rw := newResponseCacheWriter(rec.Header(), rec)
+	_, err := rw.Write([]byte(body))
+	require.NoError(t, err)
+	lookup.store(req.Context(), zap.NewNop(), rw, false)
+
+	return rec
+}
+
+func TestGraphResponseCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serves a cached response and answers conditional requests", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "")
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+
+		rec := resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, "max-age=60", `{"data":{"a":1}}`)
+		assert.Equal(t, "MISS", rec.Header().Get(ResponseCacheHeader))
+		etag := rec.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		rec = resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, "max-age=60", `{"data":{"a":2}}`)
+		assert.Equal(t, "HIT", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"a":1}}`, rec.Body.String())
+		assert.Equal(t, "max-age=60", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+		assert.Equal(t, "0", rec.Header().Get("Age"))
+
+		req.Header.Set("If-None-Match", etag)
+		rec = resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, "max-age=60", `{"data":{"a":2}}`)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("does not cache responses that forbid it or contain errors", func(t *testing.T) {
+		t.Parallel()
+
+		responses := map[string]string{
+			"no-store":              `{"data":{}}`,
+			"max-age=0":             `{"data":{}}`,
+			"private, max-age=60":   `{"data":{}}`,
+			"max-age=60, no-cache":  `{"data":{}}`,
+			"public, max-age=60":    `{"errors":[{"message":"failed"}],"data":null}`,
+			"max-age=60, s-maxage=": `{"data":{}}`,
+		}
+
+		for cacheControl, body := range responses {
+			c := newTestGraphResponseCache(t, "")
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+
+			resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, cacheControl, body)
+			rec := resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, cacheControl, body)
+			assert.Equal(t, "MISS", rec.Header().Get(ResponseCacheHeader), cacheControl)
+		}
+	})
+
+	t.Run("does not cache responses with subgraph errors", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "")
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Cache-Control", "max-age=60")
+
+		lookup, served := c.serve(rec, req, newTestResponseCacheRequest(""), false)
+		require.False(t, served)
+
+		rw := newResponseCacheWriter(rec.Header(), &bytes.Buffer{})
+		_, err := rw.Write([]byte(`{"data":{}}`))
+		require.NoError(t, err)
+		lookup.store(req.Context(), zap.NewNop(), rw, true)
+
+		_, ok, err := c.cache.store.Get(req.Context(), lookup.key)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("does not cache mutations", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "")
+		reqCtx := newTestResponseCacheRequest("")
+		reqCtx.operation.opType = OperationTypeMutation
+
+		lookup, served := c.serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil), reqCtx, false)
+		assert.Nil(t, lookup)
+		assert.False(t, served)
+	})
+
+	t.Run("scopes the cached responses with the key expression", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "request.auth.claims.sub")
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+
+		resolveThroughResponseCache(t, c, newTestResponseCacheRequest("alice"), req, "private, max-age=60", `{"data":{"me":"alice"}}`)
+
+		rec := resolveThroughResponseCache(t, c, newTestResponseCacheRequest("bob"), req, "private, max-age=60", `{"data":{"me":"bob"}}`)
+		assert.Equal(t, "MISS", rec.Header().Get(ResponseCacheHeader))
+
+		rec = resolveThroughResponseCache(t, c, newTestResponseCacheRequest("alice"), req, "private, max-age=60", "")
+		assert.Equal(t, "HIT", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"me":"alice"}}`, rec.Body.String())
+	})
+
+	t.Run("does not serve the response of an authorized request to other requests", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "")
+		authenticated := func(scopes ...string) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			return req.WithContext(authentication.NewContext(req.Context(), &FakeAuthenticator{scopes: scopes}))
+		}
+
+		resolveThroughResponseCache(t, c, newTestResponseCacheRequest("alice"), authenticated("read:salary", "read:employee"), "public, max-age=60", `{"data":{"salary":100}}`)
+
+		// An unauthenticated request resolves its own response, without the authorized fields
+		rec := resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), httptest.NewRequest(http.MethodPost, "/graphql", nil), "public, max-age=60", `{"data":{"salary":null}}`)
+		assert.Equal(t, "MISS", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"salary":null}}`, rec.Body.String())
+
+		rec = resolveThroughResponseCache(t, c, newTestResponseCacheRequest("bob"), authenticated("read:employee"), "public, max-age=60", `{"data":{"salary":null}}`)
+		assert.Equal(t, "MISS", rec.Header().Get(ResponseCacheHeader))
+
+		// The same authorization state shares the cached response, regardless of the order of the scopes
+		rec = resolveThroughResponseCache(t, c, newTestResponseCacheRequest("carol"), authenticated("read:employee", "read:salary"), "public, max-age=60", "")
+		assert.Equal(t, "HIT", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"salary":100}}`, rec.Body.String())
+	})
+
+	t.Run("does not share responses between requests with the same scopes but different claims", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "request.auth.claims.sub")
+		authenticated := func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			return req.WithContext(authentication.NewContext(req.Context(), &FakeAuthenticator{scopes: []string{"read:employee"}}))
+		}
+
+		// A policy allows alice to see the field, but not bob
+		resolveThroughResponseCache(t, c, newTestResponseCacheRequest("alice"), authenticated(), "public, max-age=60", `{"data":{"salary":100}}`)
+
+		rec := resolveThroughResponseCache(t, c, newTestResponseCacheRequest("bob"), authenticated(), "public, max-age=60", `{"data":{"salary":null}}`)
+		assert.Equal(t, "MISS", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"salary":null}}`, rec.Body.String())
+	})
+
+	t.Run("serves a stale response while one request revalidates it", func(t *testing.T) {
+		t.Parallel()
+
+		c := newTestGraphResponseCache(t, "")
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+
+		resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, "max-age=10, stale-while-revalidate=60", `{"data":{"a":1}}`)
+
+		c.now = func() time.Time { return time.Now().Add(30 * time.Second) }
+
+		revalidating := httptest.NewRecorder()
+		lookup, served := c.serve(revalidating, req, newTestResponseCacheRequest(""), true)
+		require.False(t, served)
+		require.NotNil(t, lookup)
+		assert.Equal(t, "REVALIDATED", revalidating.Header().Get(ResponseCacheHeader))
+
+		rec := resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, "max-age=10", `{"data":{"a":2}}`)
+		assert.Equal(t, "STALE", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"a":1}}`, rec.Body.String())
+
+		revalidating.Header().Set("Cache-Control", "max-age=10")
+		rw := newResponseCacheWriter(revalidating.Header(), revalidating)
+		_, err := rw.Write([]byte(`{"data":{"a":2}}`))
+		require.NoError(t, err)
+		lookup.store(req.Context(), zap.NewNop(), rw, false)
+		lookup.release()
+
+		rec = resolveThroughResponseCache(t, c, newTestResponseCacheRequest(""), req, "max-age=10", `{"data":{"a":3}}`)
+		assert.Equal(t, "HIT", rec.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, `{"data":{"a":2}}`, rec.Body.String())
+	})
+}
+
+func TestBuildResponseCache(t *testing.T) {
+	t.Parallel()
+
+	newRouter := func(keyExpression string) *Router {
+		return &Router{Config: Config{
+			logger:        zap.NewNop(),
+			responseCache: &config.ResponseCacheConfiguration{Enabled: true, KeyExpression: keyExpression, InMemory: config.ResponseCacheInMemoryConfiguration{MaxEntries: 10}},
+			authorization: &config.AuthorizationConfiguration{
+				Policies: []config.AuthorizationPolicy{{Name: "tenant", Coordinates: []string{"Employee"}, Condition: `request.auth.claims.tenant == "a"`}},
+			},
+		}}
+	}
+
+	t.Run("requires a key expression with authorization policies", func(t *testing.T) {
+		t.Parallel()
+		err := newRouter("").buildResponseCache(t.Context())
+		require.ErrorContains(t, err, "response_cache.key_expression is required")
+	})
+
+	t.Run("scopes the responses of policies with the key expression", func(t *testing.T) {
+		t.Parallel()
+		r := newRouter("request.auth.claims.sub")
+		require.NoError(t, r.buildResponseCache(t.Context()))
+		require.NotNil(t, r.responseCacheStore)
+	})
+}
+
+func TestResponseCacheIsNilSafe(t *testing.T) {
+	t.Parallel()
+
+	var c *ResponseCache
+	require.NoError(t, c.InvalidateOperation(t.Context(), "Employees"))
+	require.NoError(t, c.Clear(t.Context()))
+}
@@ -4,7 +4,6 @@ import (
 	"cmp"
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
 	"maps"
@@ -15,6 +14,7 @@ import (
 	"path"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,11 +34,13 @@ import (
 	"github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/graphqlmetrics/v1/graphqlmetricsv1connect"
 	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
 	"github.com/wundergraph/cosmo/router/internal/circuit"
+	"github.com/wundergraph/cosmo/router/internal/compression"
 	"github.com/wundergraph/cosmo/router/internal/debug"
 	"github.com/wundergraph/cosmo/router/internal/docker"
 	"github.com/wundergraph/cosmo/router/internal/exporter"
 	"github.com/wundergraph/cosmo/router/internal/graphiql"
 	"github.com/wundergraph/cosmo/router/internal/graphqlmetrics"
+	"github.com/wundergraph/cosmo/router/internal/hedgetransport"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation/apq"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation/operationstorage/cdn"
@@ -47,9 +49,9 @@ import (
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation/pqlmanifest"
 	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
 	"github.com/wundergraph/cosmo/router/internal/retrytransport"
-	"github.com/wundergraph/cosmo/router/internal/stringsx"
 	"github.com/wundergraph/cosmo/router/internal/track"
 	"github.com/wundergraph/cosmo/router/internal/versioninfo"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/cosmo/router/pkg/connectrpc"
 	"github.com/wundergraph/cosmo/router/pkg/controlplane/configpoller"
@@ -57,6 +59,7 @@ import (
 	"github.com/wundergraph/cosmo/router/pkg/cors"
 	"github.com/wundergraph/cosmo/router/pkg/execution_config"
 	"github.com/wundergraph/cosmo/router/pkg/health"
+	"github.com/wundergraph/cosmo/router/pkg/logging"
 	"github.com/wundergraph/cosmo/router/pkg/mcpserver"
 	rmetric "github.com/wundergraph/cosmo/router/pkg/metric"
 	"github.com/wundergraph/cosmo/router/pkg/otel/otelconfig"
@@ -75,6 +78,9 @@ const (
 	Redact IPAnonymizationMethod = "redact"
 )
 
+// DefaultResponseCompressionEncodings are the encodings of compressed responses in order of preference
+var DefaultResponseCompressionEncodings = []string{compression.Zstd, compression.Brotli, compression.Gzip}
+
 var CompressibleContentTypes = []string{
 	"text/html",
 	"text/css",
@@ -89,6 +95,9 @@ var CompressibleContentTypes = []string{
 	"application/graphql",
 	"application/graphql-response+json",
 	"application/graphql+json",
+	// Streamed responses are flushed through the compressor, see rmiddleware.ResponseCompression
+	"text/event-stream",
+	"multipart/mixed",
 }
 
 type (
@@ -107,6 +116,9 @@ type (
 		connectionMetricsLock sync.Mutex
 		connectionMetrics     *rmetric.ConnectionMetrics
 		traceDialer           *TraceDialer
+		// graphServerLock serializes the creation of graph servers by config updates and reloads
+		graphServerLock     sync.Mutex
+		configReloadMetrics *rmetric.ConfigReloadMetrics
 	}
 
 	UsageTracker interface {
@@ -159,6 +171,8 @@ type (
 		// It's used for the routers http server.
 		// It's created once during bootstrap and reused during server swap.
 		compiledServerConfig *tls.Config
+		// serverCertificates provides the certificates of compiledServerConfig, which can be reloaded
+		serverCertificates *serverTLSCertificates
 	}
 
 	RouterConfigPollerConfig struct {
@@ -189,6 +203,12 @@ type (
 		SubgraphEnabled       bool
 		SubgraphAttributes    []config.CustomAttribute
 		IgnoreQueryParamsList []string
+		// OTLPLogger exports the access logs over OTLP. It is shut down with the router.
+		OTLPLogger *logging.OTLPLogger
+		// File is the access log file. It is reopened on live reloads and closed with the router.
+		File *logging.RotatingFile
+		// BufferedLogger buffers the access logs. It is flushed when the router is shut down.
+		BufferedLogger *logging.BufferedLogger
 	}
 
 	// Option defines the method to customize server.
@@ -207,6 +227,46 @@ func (r *SubgraphCircuitBreakerOptions) IsEnabled() bool {
 	return r.CircuitBreaker.Enabled || len(r.SubgraphMap) > 0
 }
 
+type SubgraphHedgingOptions struct {
+	Hedging     hedgetransport.Options
+	SubgraphMap map[string]hedgetransport.Options
+}
+
+func (r *SubgraphHedgingOptions) IsEnabled() bool {
+	if r == nil {
+		return false
+	}
+	if r.Hedging.Enabled {
+		return true
+	}
+	for _, opts := range r.SubgraphMap {
+		if opts.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+type SubgraphCompressionOptions struct {
+	Compression compression.Options
+	SubgraphMap map[string]compression.Options
+}
+
+func (r *SubgraphCompressionOptions) IsEnabled() bool {
+	if r == nil {
+		return false
+	}
+	if r.Compression.Enabled {
+		return true
+	}
+	for _, opts := range r.SubgraphMap {
+		if opts.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
 // NewRouter creates a new Router instance. Router.Start() must be called to start the server.
 // Alternatively, use Router.NewServer() to create a new server instance without starting it.
 func NewRouter(ctx context.Context, opts ...Option) (*Router, error) {
@@ -288,10 +348,6 @@ func NewRouter(ctx context.Context, opts ...Option) (*Router, error) {
 		r.subscriptionHooks.beforeEventsDispatch.timeout = 5 * time.Second
 	}
 
-	if r.corsOptions == nil {
-		r.corsOptions = CorsDefaultOptions()
-	}
-
 	if r.subgraphTransportOptions == nil {
 		r.subgraphTransportOptions = DefaultSubgraphTransportOptions()
 	}
@@ -308,6 +364,12 @@ func NewRouter(ctx context.Context, opts ...Option) (*Router, error) {
 		r.fileUploadConfig = DefaultFileUploadConfig()
 	}
 
+	if r.apiKeyStore != nil {
+		if err := r.addAPIKeyAuthenticator(); err != nil {
+			return nil, err
+		}
+	}
+
 	if r.accessController != nil {
 		if len(r.accessController.authenticators) == 0 && r.accessController.authenticationRequired {
 			r.logger.Warn("authentication is required but no authenticators are configured")
@@ -333,51 +395,11 @@ func NewRouter(ctx context.Context, opts ...Option) (*Router, error) {
 		r.livenessCheckPath = "/health/live"
 	}
 
-	postRules := CreateCacheControlPolicyHeaderRules(r.cacheControlPolicy)
 	var err error
-	r.headerPropagation, err = NewHeaderPropagation(ctx, r.logger, r.headerRules, postRules)
-	if err != nil {
+	if err = r.prepareLiveConfig(ctx); err != nil {
 		return nil, err
 	}
 
-	defaultCorsHeaders := []string{
-		// Common headers
-		"authorization",
-		"origin",
-		"content-length",
-		"content-type",
-		// Semi standard client info headers
-		"graphql-client-name",
-		"graphql-client-version",
-		// Apollo client info headers
-		"apollographql-client-name",
-		"apollographql-client-version",
-		// Required for WunderGraph ART
-		"x-wg-trace",
-		"x-wg-disable-tracing",
-		"x-wg-token",
-		"x-wg-skip-loader",
-		"x-wg-include-query-plan",
-		// Required for Trace Context propagation
-		"traceparent",
-		"tracestate",
-		// Required for feature flags
-		"x-feature-flag",
-	}
-
-	if r.clientHeader.Name != "" {
-		defaultCorsHeaders = append(defaultCorsHeaders, r.clientHeader.Name)
-	}
-	if r.clientHeader.Version != "" {
-		defaultCorsHeaders = append(defaultCorsHeaders, r.clientHeader.Version)
-	}
-
-	defaultMethods := []string{
-		"HEAD", "GET", "POST",
-	}
-	r.corsOptions.AllowHeaders = stringsx.RemoveDuplicates(append(r.corsOptions.AllowHeaders, defaultCorsHeaders...))
-	r.corsOptions.AllowMethods = stringsx.RemoveDuplicates(append(r.corsOptions.AllowMethods, defaultMethods...))
-
 	if r.tls.settings.Server.Enabled {
 		r.baseURL = fmt.Sprintf("https://%s", r.listenAddr)
 		r.tls.compiledServerConfig, err = r.serverTLSConfig()
@@ -470,29 +492,6 @@ func NewRouter(ctx context.Context, opts ...Option) (*Router, error) {
 		return nil, errors.New("automatic persisted queries and safelist cannot be enabled at the same time (as APQ would permit queries that are not in the safelist)")
 	}
 
-	if r.securityConfiguration.BlockPersistedOperations.Enabled &&
-		r.securityConfiguration.BlockNonPersistedOperations.Enabled {
-
-		// Both have no condition, unusable state
-		if r.securityConfiguration.BlockPersistedOperations.Condition == "" &&
-			r.securityConfiguration.BlockNonPersistedOperations.Condition == "" {
-			return nil, errors.New("persisted and non-persisted operations are both unconditionally blocked")
-		}
-
-		// One or both have a condition, could be intentional for edge cases
-		r.logger.Warn("The security configuration fields 'block_persisted_operations' and 'block_non_persisted_operations' are both enabled. Take care to ensure this is intentional.")
-	}
-
-	if r.persistedOperationsConfig.Safelist.Enabled && r.securityConfiguration.BlockPersistedOperations.Enabled {
-		// Both have no condition, unusable state
-		if r.securityConfiguration.BlockPersistedOperations.Condition == "" {
-			return nil, errors.New("safelist cannot be enabled while persisted operations are unconditionally blocked")
-		}
-
-		// Has a condition, could be intentional for edge cases
-		r.logger.Warn("The security configuration field 'block_persisted_operations' is enabled alongside the persisted operations safelist. Take care to ensure this is intentional. Misconfiguration will result in safelisted queries being blocked.")
-	}
-
 	if r.engineExecutionConfiguration.EnableExecutionPlanCacheResponseHeader {
 		r.logger.Warn("The engine execution configuration field 'enable_execution_plan_cache_response_header' is deprecated, and will be removed. Use 'enable_cache_response_headers' instead.")
 		r.engineExecutionConfiguration.Debug.EnableCacheResponseHeaders = true
@@ -506,32 +505,6 @@ func NewRouter(ctx context.Context, opts ...Option) (*Router, error) {
 		r.engineExecutionConfiguration.Debug.EnableCacheResponseHeaders = true
 	}
 
-	if r.securityConfiguration.DepthLimit != nil {
-		r.logger.Warn("The security configuration field 'depth_limit' is deprecated, and will be removed. Use 'security.complexity_limits.depth' instead.")
-
-		if r.securityConfiguration.ComplexityCalculationCache == nil {
-			r.securityConfiguration.ComplexityCalculationCache = &config.ComplexityCalculationCache{
-				Enabled:   true,
-				CacheSize: r.securityConfiguration.DepthLimit.CacheSize,
-			}
-		}
-
-		if r.securityConfiguration.ComplexityLimits == nil {
-			r.securityConfiguration.ComplexityLimits = &config.ComplexityLimits{
-				Mode: config.ComplexityLimitsModeEnforce,
-			}
-		}
-		if r.securityConfiguration.ComplexityLimits.Depth == nil {
-			r.securityConfiguration.ComplexityLimits.Depth = &config.ComplexityLimit{
-				Enabled:                   r.securityConfiguration.DepthLimit.Enabled,
-				Limit:                     r.securityConfiguration.DepthLimit.Limit,
-				IgnorePersistedOperations: r.securityConfiguration.DepthLimit.IgnorePersistedOperations,
-			}
-		} else {
-			r.logger.Warn("Ignoring deprecated security configuration field 'depth_limit', in favor of the `security.complexity_limits.depth` configuration")
-		}
-	}
-
 	if r.developmentMode {
 		r.logger.Warn("Development mode enabled. This should only be used for testing purposes")
 	}
@@ -590,54 +563,20 @@ func (r *Router) serverTLSConfig() (*tls.Config, error) {
 		return nil, nil
 	}
 
-	if serverTLS.CertFile == "" {
-		return nil, errors.New("tls cert file not provided")
-	}
-
-	if serverTLS.KeyFile == "" {
-		return nil, errors.New("tls key file not provided")
-	}
-
-	var caCertPool *x509.CertPool
-	clientAuthMode := tls.NoClientCert
-
-	if serverTLS.ClientAuth.CertFile != "" {
-		caCert, err := os.ReadFile(serverTLS.ClientAuth.CertFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read cert file: %w", err)
-		}
-
-		// Create a CA an empty cert pool and add the CA cert to it to serve as authority to validate client certs
-		caPool := x509.NewCertPool()
-		if ok := caPool.AppendCertsFromPEM(caCert); !ok {
-			return nil, errors.New("failed to append cert to pool")
-		}
-		caCertPool = caPool
-
-		if serverTLS.ClientAuth.Required {
-			clientAuthMode = tls.RequireAndVerifyClientCert
-		} else {
-			clientAuthMode = tls.VerifyClientCertIfGiven
-		}
-
-		r.logger.Debug("Client auth enabled", zap.String("mode", clientAuthMode.String()))
-	}
-
-	// Load the server cert and private key
-	cer, err := tls.LoadX509KeyPair(serverTLS.CertFile, serverTLS.KeyFile)
+	certificates, err := newServerTLSCertificates(r.logger, serverTLS)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load tls cert and key: %w", err)
+		return nil, err
 	}
+	r.tls.serverCertificates = certificates
 
-	return &tls.Config{
-		ClientCAs:    caCertPool,
-		Certificates: []tls.Certificate{cer},
-		ClientAuth:   clientAuthMode,
-	}, nil
+	return certificates.TLSConfig(), nil
 }
 
 // newGraphServer creates a new server.
 func (r *Router) newServer(ctx context.Context, response *routerconfig.Response) error {
+	r.graphServerLock.Lock()
+	defer r.graphServerLock.Unlock()
+
 	server, err := newGraphServer(ctx, r, response, r.proxy)
 	if err != nil {
 		r.logger.Error("Failed to create graph server. Keeping the old server", zap.Error(err))
@@ -652,12 +591,15 @@ func (r *Router) newServer(ctx context.Context, response *routerconfig.Response)
 	return nil
 }
 
-// startPQLPoller starts the PQL manifest poller in a background goroutine if configured.
+// startPQLPoller starts the PQL manifest poller and recorder in background goroutines if configured.
 // Must be called after newServer so that SetOnUpdate has been registered on the store.
 func (r *Router) startPQLPoller(ctx context.Context) {
 	if r.pqlPoller != nil {
 		go r.pqlPoller.Poll(ctx)
 	}
+	if r.pqlRecorder != nil {
+		go r.pqlRecorder.Run(ctx)
+	}
 }
 
 func (r *Router) listenAndServe() error {
@@ -688,6 +630,7 @@ func (r *Router) initModules(ctx context.Context) error {
 	}
 
 	moduleList = sortModules(moduleList)
+	r.loadedModules = moduleList
 
 	for _, moduleInfo := range moduleList {
 		now := time.Now()
@@ -695,9 +638,10 @@ func (r *Router) initModules(ctx context.Context) error {
 		moduleInstance := moduleInfo.New()
 
 		mc := &ModuleContext{
-			Context: ctx,
-			Module:  moduleInstance,
-			Logger:  r.logger.With(zap.String("module", string(moduleInfo.ID))),
+			Context:       ctx,
+			Module:        moduleInstance,
+			Logger:        r.logger.With(zap.String("module", string(moduleInfo.ID))),
+			ResponseCache: r.responseCacheStore,
 		}
 
 		moduleConfig, ok := r.modulesConfig[string(moduleInfo.ID)]
@@ -909,6 +853,13 @@ func (r *Router) bootstrap(ctx context.Context) error {
 					)
 					r.traceConfig.Sampler = float64(r.registrationInfo.AccountLimits.TraceSamplingRate)
 				}
+				if r.traceConfig.Sampling != nil {
+					for i, rule := range r.traceConfig.Sampling.Rules {
+						if rule.Ratio > float64(r.registrationInfo.AccountLimits.TraceSamplingRate) {
+							r.traceConfig.Sampling.Rules[i].Ratio = float64(r.registrationInfo.AccountLimits.TraceSamplingRate)
+						}
+					}
+				}
 			}
 		}
 	}
@@ -917,7 +868,31 @@ func (r *Router) bootstrap(ctx context.Context) error {
 		return err
 	}
 
-	if r.rateLimit != nil && r.rateLimit.Enabled {
+	if r.tls.serverCertificates != nil {
+		if err := rmetric.RegisterTLSMetrics(r.tls.serverCertificates.expirations, r.otlpMeterProvider, r.promMeterProvider); err != nil {
+			return fmt.Errorf("failed to register tls metrics: %w", err)
+		}
+
+		if r.tls.settings.Server.Watch {
+			if err := r.tls.serverCertificates.watch(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := validateCostRateLimit(r.rateLimit, r.securityConfiguration); err != nil {
+		return err
+	}
+
+	r.configReloadMetrics, err = rmetric.NewConfigReloadMetrics(r.otlpMeterProvider, r.promMeterProvider)
+	if err != nil {
+		return fmt.Errorf("failed to create config reload metrics: %w", err)
+	}
+
+	if r.rateLimit != nil && r.rateLimit.Enabled && r.rateLimit.Backend == "memory" {
+		// The buckets outlive config reloads, so that a reload doesn't reset the limits
+		r.rateLimitStore = NewMemoryRateLimitStore()
+	} else if r.rateLimit != nil && r.rateLimit.Enabled {
 		var err error
 		r.redisClient, err = rd.NewRedisCloser(&rd.RedisCloserOptions{
 			URLs:           r.rateLimit.Storage.URLs,
@@ -929,10 +904,40 @@ func (r *Router) bootstrap(ctx context.Context) error {
 		}
 	}
 
+	if r.responseCache != nil && r.responseCache.Enabled {
+		if err := r.buildResponseCache(ctx); err != nil {
+			return err
+		}
+	}
+
+	if r.subgraphLoadBalancing != nil && len(r.subgraphLoadBalancing.Subgraphs) > 0 {
+		if err := r.buildSubgraphLoadBalancers(ctx); err != nil {
+			return err
+		}
+	}
+
+	if r.subgraphHedgingOptions.IsEnabled() {
+		r.hedgeMetrics, err = rmetric.NewHedgeMetrics(r.otlpMeterProvider, r.promMeterProvider)
+		if err != nil {
+			return fmt.Errorf("failed to create hedge metrics: %w", err)
+		}
+	}
+
 	if err := r.startMCPServer(ctx); err != nil {
 		return err
 	}
 
+	if r.adminAPI.Enabled {
+		if r.adminAPI.Token == "" {
+			return errors.New("admin API requires a token")
+		}
+
+		r.adminAPIServer = newAdminAPIServer(r)
+		if err := r.adminAPIServer.listen(); err != nil {
+			return fmt.Errorf("failed to start admin API: %w", err)
+		}
+	}
+
 	if r.connectRPC.Enabled {
 		r.logger.Debug("ConnectRPC configuration",
 			zap.Bool("enabled", r.connectRPC.Enabled),
@@ -1061,6 +1066,9 @@ func (r *Router) bootstrap(ctx context.Context) error {
 
 	if r.traceConfig.Enabled && len(r.tracePropagators) > 0 {
 		r.compositePropagator = propagation.NewCompositeTextMapPropagator(r.tracePropagators...)
+		if r.deferredSampler != nil {
+			r.compositePropagator = r.deferredSampler.Propagator(r.compositePropagator)
+		}
 
 		// Don't set it globally when we use the router in tests.
 		// In practice, setting it globally only makes sense for module development.
@@ -1074,6 +1082,10 @@ func (r *Router) bootstrap(ctx context.Context) error {
 
 func (r *Router) setupTelemetry(ctx context.Context) error {
 	if r.traceConfig.Enabled {
+		if r.traceConfig.Sampling.IsEnabled() {
+			r.deferredSampler = rtrace.NewDeferredSampler(*r.traceConfig.Sampling, r.traceConfig.Sampler)
+		}
+
 		tp, err := rtrace.NewTracerProvider(ctx, &rtrace.ProviderConfig{
 			Logger:            r.logger,
 			Config:            r.traceConfig,
@@ -1082,8 +1094,9 @@ func (r *Router) setupTelemetry(ctx context.Context) error {
 				Enabled: r.ipAnonymization.Enabled,
 				Method:  attributeprocessor.IPAnonymizationMethod(r.ipAnonymization.Method),
 			},
-			SanitizeUTF8:   r.traceConfig.SanitizeUTF8,
-			MemoryExporter: r.traceConfig.TestMemoryExporter,
+			SanitizeUTF8:    r.traceConfig.SanitizeUTF8,
+			MemoryExporter:  r.traceConfig.TestMemoryExporter,
+			DeferredSampler: r.deferredSampler,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to start trace agent: %w", err)
@@ -1213,6 +1226,7 @@ func (r *Router) startMCPServer(ctx context.Context) error {
 		mcpserver.WithExposeSchema(r.mcp.ExposeSchema),
 		mcpserver.WithOmitToolNamePrefix(r.mcp.OmitToolNamePrefix),
 		mcpserver.WithOutputSchemaEnabled(r.mcp.OutputSchema.Enabled),
+		mcpserver.WithSubscriptions(r.mcp.Subscriptions),
 		mcpserver.WithStateless(r.mcp.Session.Stateless),
 		mcpserver.WithInstructions(r.mcp.Server.Discover.Instructions),
 		mcpserver.WithServerVersion(cmp.Or(r.mcp.Server.Version, Version)),
@@ -1287,6 +1301,10 @@ func (r *Router) buildClients(ctx context.Context) error {
 		pClient = nil
 	}
 
+	if err := r.buildManifestRecorder(ctx, registry); err != nil {
+		return err
+	}
+
 	if pClient != nil || apqClient != nil || pqlStore != nil {
 		// For backwards compatibility with cdn config field
 		cacheSize := r.persistedOperationsConfig.Cache.Size.Uint64()
@@ -1514,6 +1532,79 @@ func (r *Router) buildManifestStore(ctx context.Context, registry *ProviderRegis
 	return pqlStore, nil
 }
 
+// buildManifestRecorder sets up the recording of the operations to a PQL manifest in learning mode.
+// The manifest is written to a filesystem or S3 storage provider.
+func (r *Router) buildManifestRecorder(ctx context.Context, registry *ProviderRegistry) error {
+	learning := r.persistedOperationsConfig.Learning
+	if !learning.Enabled || r.persistedOperationsConfig.Disabled {
+		return nil
+	}
+
+	switch strings.ToLower(path.Ext(learning.FileName)) {
+	case ".gz", ".zst":
+		return fmt.Errorf("compressed manifest file name %q is not supported for persisted operations learning", learning.FileName)
+	}
+
+	var (
+		readManifest  pqlmanifest.VersionedManifestReaderFunc
+		writeManifest pqlmanifest.ManifestWriterFunc
+	)
+
+	if provider, ok := registry.S3(learning.ProviderID); ok {
+		c, err := s3.NewClient(provider.Endpoint, &s3.Options{
+			AccessKeyID:      provider.AccessKey,
+			SecretAccessKey:  provider.SecretKey,
+			Region:           provider.Region,
+			UseSSL:           provider.Secure,
+			BucketName:       provider.Bucket,
+			ObjectPathPrefix: learning.ObjectPrefix,
+			TraceProvider:    r.tracerProvider,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create S3 client: %w", err)
+		}
+		readManifest, writeManifest = c.ReadManifestVersion, c.WriteManifest
+	} else if provider, ok := registry.FileSystem(learning.ProviderID); ok {
+		c, err := fs.NewClient(provider.Path, &fs.Options{
+			ObjectPathPrefix: learning.ObjectPrefix,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create filesystem client: %w", err)
+		}
+		readManifest, writeManifest = c.ReadManifestVersion, c.WriteManifest
+	} else {
+		return fmt.Errorf("unknown storage provider id '%s' for persisted operations learning, only filesystem and S3 providers are supported", learning.ProviderID)
+	}
+
+	objectPath := learning.FileName
+	if learning.ObjectPrefix != "" {
+		objectPath = path.Join(learning.ObjectPrefix, learning.FileName)
+	}
+
+	recorder := pqlmanifest.NewRecorder(pqlmanifest.RecorderOptions{
+		ReadManifest:  readManifest,
+		WriteManifest: writeManifest,
+		ObjectPath:    objectPath,
+		FlushInterval: learning.FlushInterval,
+		MaxOperations: learning.MaxOperations,
+		Logger:        r.logger,
+	})
+
+	// Load the previously recorded operations, so that they are not counted twice towards the limit
+	if err := recorder.Load(ctx); err != nil {
+		return fmt.Errorf("failed to load recorded PQL manifest from storage provider %q: %w", learning.ProviderID, err)
+	}
+
+	r.logger.Info("Recording operations to PQL manifest",
+		zap.String("provider_id", learning.ProviderID),
+		zap.String("object_path", objectPath),
+		zap.Duration("flush_interval", learning.FlushInterval),
+	)
+
+	r.pqlRecorder = recorder
+	return nil
+}
+
 // buildConfigPoller initializes the execution config poller.
 func (r *Router) buildConfigPoller(registry *ProviderRegistry) error {
 	configPoller, err := InitializeConfigPoller(r, registry)
@@ -1618,8 +1709,10 @@ func (r *Router) Start(ctx context.Context) error {
 		r.logger.Info("localhost fallback enabled, connections that fail to connect to localhost will be retried using host.docker.internal")
 	}
 
-	if r.redisClient != nil {
+	if r.rateLimit != nil && r.rateLimit.Enabled {
 		r.logger.Info("Rate limiting enabled",
+			zap.String("strategy", r.rateLimit.Strategy),
+			zap.String("backend", r.rateLimit.Backend),
 			zap.Int("rate", r.rateLimit.SimpleStrategy.Rate),
 			zap.Int("burst", r.rateLimit.SimpleStrategy.Burst),
 			zap.Duration("duration", r.rateLimit.SimpleStrategy.Period),
@@ -1909,6 +2002,14 @@ func (r *Router) Shutdown(ctx context.Context) error {
 		})
 	}
 
+	if r.adminAPIServer != nil {
+		wg.Go(func() {
+			if subErr := r.adminAPIServer.Shutdown(ctx); subErr != nil {
+				err.Append(fmt.Errorf("failed to shutdown admin API: %w", subErr))
+			}
+		})
+	}
+
 	if r.connectRPCServer != nil {
 		wg.Go(func() {
 			if subErr := r.connectRPCServer.Stop(ctx); subErr != nil {
@@ -1941,6 +2042,39 @@ func (r *Router) Shutdown(ctx context.Context) error {
 		})
 	}
 
+	if r.pqlRecorder != nil {
+		wg.Go(func() {
+			// Write the operations recorded since the last flush
+			if subErr := r.pqlRecorder.Flush(ctx); subErr != nil {
+				err.Append(fmt.Errorf("failed to write recorded PQL manifest: %w", subErr))
+			}
+		})
+	}
+
+	if r.accessLogsConfig != nil && (r.accessLogsConfig.BufferedLogger != nil || r.accessLogsConfig.File != nil) {
+		wg.Go(func() {
+			// Flush the buffered lines before the file is closed
+			if r.accessLogsConfig.BufferedLogger != nil {
+				if subErr := r.accessLogsConfig.BufferedLogger.Close(); subErr != nil {
+					err.Append(fmt.Errorf("failed to flush access logs: %w", subErr))
+				}
+			}
+			if r.accessLogsConfig.File != nil {
+				if subErr := r.accessLogsConfig.File.Close(); subErr != nil {
+					err.Append(fmt.Errorf("failed to close access log file: %w", subErr))
+				}
+			}
+		})
+	}
+
+	if r.accessLogsConfig != nil && r.accessLogsConfig.OTLPLogger != nil {
+		wg.Go(func() {
+			if subErr := r.accessLogsConfig.OTLPLogger.Shutdown(ctx); subErr != nil {
+				err.Append(fmt.Errorf("failed to shutdown OTLP access logger: %w", subErr))
+			}
+		})
+	}
+
 	if r.otlpMeterProvider != nil {
 		wg.Go(func() {
 			if subErr := r.otlpMeterProvider.Shutdown(ctx); subErr != nil {
@@ -1965,6 +2099,18 @@ func (r *Router) Shutdown(ctx context.Context) error {
 		})
 	}
 
+	if r.responseCacheRedisClient != nil {
+		wg.Go(func() {
+			if closeErr := r.responseCacheRedisClient.Close(); closeErr != nil {
+				err.Append(fmt.Errorf("failed to close response cache redis client: %w", closeErr))
+			}
+		})
+	}
+
+	for _, balancer := range r.subgraphLoadBalancers {
+		wg.Go(balancer.Stop)
+	}
+
 	wg.Go(func() {
 		for _, module := range r.modules {
 			if cleaner, ok := module.(Cleaner); ok {
@@ -2262,6 +2408,21 @@ func WithSubgraphCircuitBreakerOptions(opts *SubgraphCircuitBreakerOptions) Opti
 	}
 }
 
+// WithSubgraphHedgingOptions hedges slow query requests to subgraphs.
+func WithSubgraphHedgingOptions(opts *SubgraphHedgingOptions) Option {
+	return func(r *Router) {
+		r.subgraphHedgingOptions = opts
+	}
+}
+
+// WithSubgraphCompressionOptions compresses the request bodies sent to subgraphs and accepts
+// compressed responses from them.
+func WithSubgraphCompressionOptions(opts *SubgraphCompressionOptions) Option {
+	return func(r *Router) {
+		r.subgraphCompressionOptions = opts
+	}
+}
+
 func WithSubgraphRetryOptions(
 	enabled bool,
 	algorithm string,
@@ -2303,6 +2464,37 @@ func WithAccessController(controller *AccessController) Option {
 	}
 }
 
+// WithAPIKeyStore authenticates requests with the API keys of the store, in addition to the
+// authenticators of the access controller. The key is read from the X-API-Key header. Use it to
+// load the keys from a source other than a file or Redis.
+func WithAPIKeyStore(store authentication.APIKeyStore) Option {
+	return func(r *Router) {
+		r.apiKeyStore = store
+	}
+}
+
+// addAPIKeyAuthenticator adds an authenticator for the keys of the API key store to the access
+// controller. The access controller is copied, as it may be shared with other routers.
+func (r *Router) addAPIKeyAuthenticator() error {
+	var accessController AccessController
+	if r.accessController != nil {
+		accessController = *r.accessController
+	}
+
+	authenticator, err := authentication.NewAPIKeyAuthenticator(authentication.APIKeyAuthenticatorOptions{
+		ScopeClaim: accessController.scopeClaim,
+		Store:      r.apiKeyStore,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create API key authenticator: %w", err)
+	}
+
+	accessController.authenticators = append(slices.Clip(accessController.authenticators), authenticator)
+	r.accessController = &accessController
+
+	return nil
+}
+
 func WithAuthorizationConfig(cfg *config.AuthorizationConfiguration) Option {
 	return func(r *Router) {
 		r.authorization = cfg
@@ -2329,8 +2521,9 @@ func WithDisableUsageTracking() Option {
 
 func DefaultRouterTrafficConfig() *config.RouterTrafficConfiguration {
 	return &config.RouterTrafficConfiguration{
-		MaxRequestBodyBytes:        1000 * 1000 * 5, // 5 MB
-		ResponseCompressionMinSize: 1024 * 4,        // 4 KiB
+		MaxRequestBodyBytes:          1000 * 1000 * 5, // 5 MB
+		ResponseCompressionMinSize:   1024 * 4,        // 4 KiB
+		ResponseCompressionEncodings: DefaultResponseCompressionEncodings,
 	}
 }
 
@@ -2426,6 +2619,69 @@ func newCircuitBreakerConfig(cb config.CircuitBreaker) circuit.CircuitBreakerCon
 	}
 }
 
+func NewSubgraphHedgingOptions(cfg config.TrafficShapingRules) *SubgraphHedgingOptions {
+	entry := &SubgraphHedgingOptions{
+		Hedging:     newHedgingOptions(cfg.All.Hedging),
+		SubgraphMap: map[string]hedgetransport.Options{},
+	}
+	// Subgraph specific hedging replaces the global default
+	for k, v := range cfg.Subgraphs {
+		entry.SubgraphMap[k] = newHedgingOptions(v.Hedging)
+	}
+
+	return entry
+}
+
+func newHedgingOptions(h config.SubgraphHedging) hedgetransport.Options {
+	opts := hedgetransport.Options{
+		Enabled:     h.Enabled,
+		Delay:       h.Delay,
+		Percentile:  h.Percentile,
+		MaxInFlight: h.MaxInFlight,
+	}
+	// The env defaults are not applied to the subgraph specific rules
+	if opts.Delay <= 0 {
+		opts.Delay = 100 * time.Millisecond
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 10
+	}
+	return opts
+}
+
+func NewSubgraphCompressionOptions(cfg config.TrafficShapingRules) *SubgraphCompressionOptions {
+	entry := &SubgraphCompressionOptions{
+		Compression: newCompressionOptions(cfg.All.Compression),
+		SubgraphMap: map[string]compression.Options{},
+	}
+	// Subgraph specific compression replaces the global default
+	for k, v := range cfg.Subgraphs {
+		entry.SubgraphMap[k] = newCompressionOptions(v.Compression)
+	}
+
+	return entry
+}
+
+func newCompressionOptions(c config.SubgraphCompression) compression.Options {
+	opts := compression.Options{
+		Enabled:             c.Enabled,
+		Encoding:            c.Encoding,
+		MinSize:             int64(c.MinSize),
+		MaxDecompressedSize: int64(c.MaxDecompressedSize),
+	}
+	// The env defaults are not applied to the subgraph specific rules
+	if opts.Encoding == "" {
+		opts.Encoding = compression.Gzip
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1024
+	}
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = 64 << 20
+	}
+	return opts
+}
+
 func DefaultSubgraphTransportOptions() *SubgraphTransportOptions {
 	return &SubgraphTransportOptions{
 		TransportRequestOptions: DefaultTransportRequestOptions(),
@@ -2595,6 +2851,29 @@ func WithCacheWarmupConfig(cfg *config.CacheWarmupConfiguration) Option {
 	}
 }
 
+// WithFeatureFlagRouting configures the rules used to route requests to feature flags
+// when the client does not select one.
+func WithFeatureFlagRouting(cfg *config.FeatureFlagRoutingConfiguration) Option {
+	return func(r *Router) {
+		r.featureFlagRouting = cfg
+	}
+}
+
+// WithResponseCache configures the cache of complete query responses.
+func WithResponseCache(cfg *config.ResponseCacheConfiguration) Option {
+	return func(r *Router) {
+		r.responseCache = cfg
+	}
+}
+
+// WithSubgraphLoadBalancing spreads the requests to a subgraph over the configured endpoints
+// instead of its routing URL.
+func WithSubgraphLoadBalancing(cfg *config.SubgraphLoadBalancingConfiguration) Option {
+	return func(r *Router) {
+		r.subgraphLoadBalancing = cfg
+	}
+}
+
 // WithPlanningDurationOverride sets a function that overrides the measured planning duration.
 // Used in tests to simulate slow queries that exceed the expensive query threshold.
 func WithPlanningDurationOverride(fn func(content string) time.Duration) Option {
@@ -2629,6 +2908,20 @@ func WithConnectRPC(cfg config.ConnectRPCConfiguration) Option {
 	}
 }
 
+func WithAdminAPI(cfg config.AdminAPIConfiguration) Option {
+	return func(r *Router) {
+		r.adminAPI = cfg
+	}
+}
+
+// WithReloadFunc sets the function the admin API calls to reload the router.
+// The RouterSupervisor sets it to its Reload method.
+func WithReloadFunc(fn func()) Option {
+	return func(r *Router) {
+		r.reloadFunc = fn
+	}
+}
+
 func WithDemoMode(demoMode bool) Option {
 	return func(r *Router) {
 		r.demoMode = demoMode
@@ -2816,9 +3109,28 @@ func TraceConfigFromTelemetry(cfg *config.Telemetry) *rtrace.Config {
 			Enabled:          cfg.Tracing.SanitizeUTF8.Enabled,
 			LogSanitizations: cfg.Tracing.SanitizeUTF8.LogSanitizations,
 		},
+		Sampling: deferredSamplingFromTelemetry(cfg.Tracing.Sampling),
 	}
 }
 
+// deferredSamplingFromTelemetry returns nil when neither sampling rules nor tail sampling are configured
+func deferredSamplingFromTelemetry(cfg config.TracingSampling) *rtrace.DeferredSamplingConfig {
+	sampling := &rtrace.DeferredSamplingConfig{
+		Rules:             cfg.Rules,
+		MaxBufferedTraces: cfg.MaxBufferedTraces,
+		MaxSpansPerTrace:  cfg.MaxSpansPerTrace,
+	}
+	if cfg.Tail.Enabled {
+		sampling.KeepOnError = cfg.Tail.KeepOnError
+		sampling.LatencyThreshold = cfg.Tail.LatencyThreshold
+		sampling.PropagateSampled = cfg.Tail.PropagateSampled
+	}
+	if !sampling.IsEnabled() {
+		return nil
+	}
+	return sampling
+}
+
 // buildAttributesMap returns a map of custom attributes to quickly check if a field is used in the custom attributes.
 func buildAttributesMap(attributes []config.CustomAttribute) map[string]string {
 	result := make(map[string]string)
@@ -2869,6 +3181,33 @@ func buildResourceAttributes(attributes []config.CustomStaticAttribute) []attrib
 	return r.ToSlice()
 }
 
+// LogsConfigFromTelemetry returns the OTLP options of the logs. It returns nil when no exporter is
+// enabled.
+func LogsConfigFromTelemetry(cfg *config.Telemetry) *logging.OTLPOptions {
+	var exporters []*logging.OTLPExporter
+	for _, exp := range cfg.Logs.Exporters {
+		if exp.Disabled {
+			continue
+		}
+		exporters = append(exporters, &logging.OTLPExporter{
+			Exporter: exp.Exporter,
+			Endpoint: exp.Endpoint,
+			HTTPPath: exp.HTTPPath,
+			Headers:  exp.Headers,
+		})
+	}
+	if len(exporters) == 0 {
+		return nil
+	}
+
+	return &logging.OTLPOptions{
+		Exporters:          exporters,
+		ServiceName:        cfg.ServiceName,
+		ServiceVersion:     Version,
+		ResourceAttributes: buildResourceAttributes(cfg.ResourceAttributes),
+	}
+}
+
 func MetricConfigFromTelemetry(cfg *config.Telemetry) *rmetric.Config {
 	var openTelemetryExporters []*rmetric.OpenTelemetryExporter
 	for _, exp := range cfg.Metrics.OTLP.Exporters {
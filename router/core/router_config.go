@@ -6,10 +6,12 @@ import (
 
 	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
 	"github.com/wundergraph/cosmo/router/internal/graphqlmetrics"
+	"github.com/wundergraph/cosmo/router/internal/loadbalancer"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation/pqlmanifest"
 	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
 	"github.com/wundergraph/cosmo/router/internal/retrytransport"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/cosmo/router/pkg/connectrpc"
 	"github.com/wundergraph/cosmo/router/pkg/controlplane/configpoller"
@@ -101,6 +103,7 @@ type Config struct {
 	persistedOperationClient        *persistedoperation.Client
 	pqlStore                        *pqlmanifest.Store
 	pqlPoller                       *pqlmanifest.Poller
+	pqlRecorder                     *pqlmanifest.Recorder
 	persistedOperationsConfig       config.PersistedOperationsConfig
 	automaticPersistedQueriesConfig config.AutomaticPersistedQueriesConfig
 	apolloCompatibilityFlags        config.ApolloCompatibilityFlags
@@ -120,15 +123,24 @@ type Config struct {
 	headerRules                     *config.HeaderRules
 	subgraphTransportOptions        *SubgraphTransportOptions
 	subgraphCircuitBreakerOptions   *SubgraphCircuitBreakerOptions
+	subgraphHedgingOptions          *SubgraphHedgingOptions
+	subgraphCompressionOptions      *SubgraphCompressionOptions
 	graphqlMetricsConfig            *GraphQLMetricsConfig
 	routerTrafficConfig             *config.RouterTrafficConfiguration
 	batchingConfig                  *BatchingConfig
 	fileUploadConfig                *config.FileUpload
 	accessController                *AccessController
+	apiKeyStore                     authentication.APIKeyStore
 	retryOptions                    retrytransport.RetryOptions
 	redisClient                     rd.RDCloser
+	rateLimitStore                  RateLimitStore
+	responseCacheRedisClient        rd.RDCloser
+	responseCacheStore              *ResponseCache
+	subgraphLoadBalancers           map[string]*loadbalancer.Balancer
+	hedgeMetrics                    *rmetric.HedgeMetrics
 	mcpServer                       *mcpserver.GraphQLSchemaServer
 	connectRPCServer                *connectrpc.Server
+	adminAPIServer                  *adminAPIServer
 	processStartTime                time.Time
 	developmentMode                 bool
 	healthcheck                     health.Checker
@@ -140,6 +152,8 @@ type Config struct {
 	tracePropagators              []propagation.TextMapPropagator
 	compositePropagator           propagation.TextMapPropagator
 	spanNameFormatter             SpanNameFormatterFunc
+	// deferredSampler decides at the end of the request whether its trace is exported
+	deferredSampler *rtrace.DeferredSampler
 	// Poller
 	configPoller                 configpoller.ConfigPoller
 	selfRegister                 selfregister.SelfRegister
@@ -158,15 +172,23 @@ type Config struct {
 	subgraphExtensionPropagation  config.SubgraphExtensionPropagationConfiguration
 	clientHeader                  config.ClientHeader
 	cacheWarmup                   *config.CacheWarmupConfiguration
+	responseCache                 *config.ResponseCacheConfiguration
+	subgraphLoadBalancing         *config.SubgraphLoadBalancingConfiguration
+	featureFlagRouting            *config.FeatureFlagRoutingConfiguration
 	planningDurationOverride      func(content string) time.Duration
 	subscriptionHeartbeatInterval time.Duration
 	hostName                      string
 	mcp                           config.MCPConfiguration
 	connectRPC                    config.ConnectRPCConfiguration
+	adminAPI                      config.AdminAPIConfiguration
 	plugins                       config.PluginsConfiguration
 	grpcPluginDialOptions         []grpc.DialOption
 	tracingAttributes             []config.CustomAttribute
 	subscriptionHooks             subscriptionHooks
+	// reloadFunc reloads the router when it runs under a RouterSupervisor
+	reloadFunc func()
+	// loadedModules lists the modules in the order they were provisioned
+	loadedModules []ModuleInfo
 }
 
 // Usage returns an anonymized version of the config for usage tracking
@@ -190,6 +212,7 @@ func (c *Config) Usage() map[string]any {
 	usage["query_plans_enabled"] = c.queryPlansEnabled
 	usage["graph_api_token"] = c.graphApiToken != ""
 	usage["automatic_persisted_queries"] = c.automaticPersistedQueriesConfig.Enabled
+	usage["persisted_operations_learning"] = c.pqlRecorder != nil
 
 	usage["apollo_compatibility_flags_enable_all"] = c.apolloCompatibilityFlags.EnableAll
 	usage["apollo_compatibility_flags_replace_invalid_var_errors_enabled"] = c.apolloCompatibilityFlags.ReplaceInvalidVarErrors.Enabled
@@ -215,6 +238,7 @@ func (c *Config) Usage() map[string]any {
 			exporters[i].Endpoint = exporter.Endpoint
 		}
 		usage["tracing_exporters"] = exporters
+		usage["tracing_deferred_sampling"] = c.traceConfig.Sampling.IsEnabled()
 	}
 
 	metricsEnabled := c.metricConfig != nil && c.metricConfig.IsEnabled()
@@ -253,6 +277,11 @@ func (c *Config) Usage() map[string]any {
 		}
 	}
 
+	usage["response_cache"] = c.responseCache != nil && c.responseCache.Enabled
+	usage["response_cache_redis"] = c.responseCacheRedisClient != nil
+	usage["subgraph_load_balancing"] = len(c.subgraphLoadBalancers) > 0
+	usage["feature_flag_routing"] = c.featureFlagRouting != nil && len(c.featureFlagRouting.Rules) > 0
+
 	usage["edfs_nats"] = len(c.eventsConfig.Providers.Nats) > 0
 	usage["edfs_kafka"] = len(c.eventsConfig.Providers.Kafka) > 0
 
@@ -261,6 +290,8 @@ func (c *Config) Usage() map[string]any {
 	usage["header_rules"] = c.headerRules != nil && (c.headerRules.All != nil || len(c.headerRules.Subgraphs) > 0)
 	usage["subgraph_transport_options"] = c.subgraphTransportOptions != nil
 	usage["subgraph_circuit_breaker_options"] = c.subgraphCircuitBreakerOptions.IsEnabled()
+	usage["subgraph_hedging"] = c.subgraphHedgingOptions.IsEnabled()
+	usage["subgraph_compression"] = c.subgraphCompressionOptions.IsEnabled()
 	usage["graphql_metrics"] = c.graphqlMetricsConfig != nil && c.graphqlMetricsConfig.Enabled
 	usage["batching"] = c.batchingConfig != nil && c.batchingConfig.Enabled
 	if c.batchingConfig != nil && c.batchingConfig.Enabled {
@@ -274,9 +305,12 @@ func (c *Config) Usage() map[string]any {
 		usage["file_upload_max_files"] = c.fileUploadConfig.MaxFiles
 	}
 	usage["access_controller"] = c.accessController != nil
+	usage["api_key_store"] = c.apiKeyStore != nil
 	usage["retry_options"] = c.retryOptions.Enabled
 	usage["development_mode"] = c.developmentMode
 	usage["access_logs"] = c.accessLogsConfig != nil
+	usage["access_logs_otlp"] = c.accessLogsConfig != nil && c.accessLogsConfig.OTLPLogger != nil
+	usage["access_logs_file_rotation"] = c.accessLogsConfig != nil && c.accessLogsConfig.File != nil && c.accessLogsConfig.File.Rotates()
 	usage["localhost_fallback_inside_docker"] = c.localhostFallbackInsideDocker
 	usage["tls_server"] = c.tls.settings.Server.Enabled
 	usage["tls_client"] = c.tls.settings.Client.Enabled()
@@ -310,6 +344,7 @@ func (c *Config) Usage() map[string]any {
 
 	usage["overrides_subgraphs"] = len(c.overrides.Subgraphs) > 0
 	usage["authorization"] = c.authorization != nil
+	usage["authorization_policies"] = c.authorization != nil && len(c.authorization.Policies) > 0
 	usage["rate_limiting"] = c.rateLimit != nil
 
 	if c.webSocketConfiguration != nil {
@@ -361,6 +396,8 @@ func (c *Config) Usage() map[string]any {
 
 	usage["connect_rpc"] = c.connectRPC.Enabled
 
+	usage["admin_api"] = c.adminAPI.Enabled
+
 	usage["cosmo_cdn"] = c.cdnConfig.URL == "https://cosmo-cdn.wundergraph.com"
 
 	usage["static_execution_config"] = c.staticExecutionConfig != nil
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
@@ -8,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/common"
 	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 )
 
@@ -88,6 +92,30 @@ func TestApqAndSafelistErrors(t *testing.T) {
 	assert.Contains(t, err.Error(), "automatic persisted queries and safelist cannot be enabled at the same time (as APQ would permit queries that are not in the safelist)")
 }
 
+type staticAPIKeyStore map[string]*authentication.APIKey
+
+func (s staticAPIKeyStore) Lookup(_ context.Context, hash string) (*authentication.APIKey, error) {
+	return s[hash], nil
+}
+
+func TestWithAPIKeyStore(t *testing.T) {
+	store := staticAPIKeyStore{authentication.HashAPIKey("secret"): {Name: "mobile"}}
+	accessController, err := NewAccessController(AccessControllerOptions{ScopeClaim: "scope"})
+	assert.NoError(t, err)
+
+	router, err := NewRouter(t.Context(), WithAccessController(accessController), WithAPIKeyStore(store))
+	assert.NoError(t, err)
+
+	assert.Len(t, router.accessController.authenticators, 1)
+	assert.Empty(t, accessController.authenticators, "the shared access controller must not be changed")
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	req.Header.Set("X-API-Key", "secret")
+	req, err = router.accessController.Access(httptest.NewRecorder(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "mobile", authentication.FromContext(req.Context()).Claims()[authentication.APIKeyNameClaim])
+}
+
 func TestOverridesConfig(t *testing.T) {
 	options := []Option{
 		WithOverrides(config.OverridesConfiguration{
@@ -0,0 +1,18 @@
+package core
+
+import (
+	"github.com/wundergraph/cosmo/router/internal/compression"
+)
+
+// buildCompressionConfig returns the compression configuration of the subgraph transports of a
+// graph mux. It is nil when the traffic to no subgraph is compressed.
+func (s *graphServer) buildCompressionConfig() *compression.Config {
+	if !s.subgraphCompressionOptions.IsEnabled() {
+		return nil
+	}
+
+	return &compression.Config{
+		Default:     s.subgraphCompressionOptions.Compression,
+		SubgraphMap: s.subgraphCompressionOptions.SubgraphMap,
+	}
+}
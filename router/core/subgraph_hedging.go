@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+	"github.com/wundergraph/cosmo/router/internal/hedgetransport"
+	"github.com/wundergraph/cosmo/router/internal/loadbalancer"
+	"github.com/wundergraph/cosmo/router/internal/traceclient"
+)
+
+// buildHedgingConfig returns the hedging configuration of the subgraph transports of a graph mux.
+// It is nil when no subgraph is hedged.
+func (s *graphServer) buildHedgingConfig() *hedgetransport.Config {
+	if !s.subgraphHedgingOptions.IsEnabled() {
+		return nil
+	}
+
+	return &hedgetransport.Config{
+		Default:      s.subgraphHedgingOptions.Hedging,
+		SubgraphMap:  s.subgraphHedgingOptions.SubgraphMap,
+		ShouldHedge:  isHedgeableRequest,
+		HedgeContext: hedgeContext,
+		OnHedge: func(req *http.Request, subgraph string, won bool) {
+			s.hedgeMetrics.MeasureHedge(req.Context(), subgraph, won)
+		},
+	}
+}
+
+// isHedgeableRequest reports whether the subgraph request belongs to a query. Mutations are never
+// hedged, as sending them twice is not safe.
+func isHedgeableRequest(req *http.Request) bool {
+	reqContext := getRequestContext(req.Context())
+	if reqContext == nil || reqContext.operation == nil {
+		return false
+	}
+	return reqContext.operation.opType == OperationTypeQuery
+}
+
+// hedgeContext gives the hedged request its own per-fetch state, so it doesn't race with the
+// request that is still in flight
+func hedgeContext(ctx context.Context) context.Context {
+	duration := atomic.Int64{}
+	ctx = context.WithValue(ctx, rcontext.FetchTimingKey, &duration)
+	ctx = traceclient.WithClientTraceResults(ctx)
+	return loadbalancer.WithSelection(ctx)
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router/internal/hedgetransport"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestNewSubgraphHedgingOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		opts := NewSubgraphHedgingOptions(config.TrafficShapingRules{
+			Subgraphs: map[string]config.GlobalSubgraphRequestRule{
+				"products": {},
+			},
+		})
+		require.False(t, opts.IsEnabled())
+	})
+
+	t.Run("applies the defaults to the subgraph rules", func(t *testing.T) {
+		t.Parallel()
+
+		opts := NewSubgraphHedgingOptions(config.TrafficShapingRules{
+			Subgraphs: map[string]config.GlobalSubgraphRequestRule{
+				"products": {Hedging: config.SubgraphHedging{Enabled: true, Percentile: 95}},
+			},
+		})
+		require.True(t, opts.IsEnabled())
+		require.Equal(t, hedgetransport.Options{
+			Enabled:     true,
+			Delay:       100 * time.Millisecond,
+			Percentile:  95,
+			MaxInFlight: 10,
+		}, opts.SubgraphMap["products"])
+	})
+}
+
+func TestBuildHedgingConfig(t *testing.T) {
+	t.Parallel()
+
+	s := &graphServer{Config: &Config{}}
+	require.Nil(t, s.buildHedgingConfig())
+
+	s.subgraphHedgingOptions = &SubgraphHedgingOptions{Hedging: hedgetransport.Options{Enabled: true}}
+	cfg := s.buildHedgingConfig()
+	require.True(t, cfg.IsEnabled())
+	require.NotNil(t, cfg.ShouldHedge)
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/expr-lang/expr/vm"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/circuit"
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/internal/loadbalancer"
+	rmetric "github.com/wundergraph/cosmo/router/pkg/metric"
+)
+
+// buildSubgraphLoadBalancers creates the load balancers of the subgraphs with several endpoints and
+// starts their health checks. The load balancers are shared by all graph servers, so the health of
+// the endpoints is kept across config reloads.
+func (r *Router) buildSubgraphLoadBalancers(ctx context.Context) error {
+	defaultClientTLS, perSubgraphTLS, err := buildSubgraphHTTPTLSConfigs(r.logger, &r.tls.settings.Client)
+	if err != nil {
+		return fmt.Errorf("could not build subgraph client TLS config: %w", err)
+	}
+
+	exprManager := expr.CreateNewExprManager()
+	logger := r.logger.With(zap.String("component", "subgraph_load_balancing"))
+
+	r.subgraphLoadBalancers = make(map[string]*loadbalancer.Balancer, len(r.subgraphLoadBalancing.Subgraphs))
+
+	for name, settings := range r.subgraphLoadBalancing.Subgraphs {
+		strategy := loadbalancer.Strategy(settings.Strategy)
+
+		var hashProgram *vm.Program
+		if strategy == loadbalancer.StrategyConsistentHash {
+			if settings.HashExpression == "" {
+				return fmt.Errorf("load balancing of subgraph '%s' requires a hash_expression for the consistent_hash strategy", name)
+			}
+			hashProgram, err = exprManager.CompileExpression(settings.HashExpression, reflect.String)
+			if err != nil {
+				return fmt.Errorf("failed to compile load balancing hash expression of subgraph '%s': %w", name, err)
+			}
+		}
+
+		// The probes use the transport settings of the subgraph, so they pass the same TLS handshake
+		transportOpts := r.subgraphTransportOptions.TransportRequestOptions
+		if sgOpts, ok := r.subgraphTransportOptions.SubgraphMap[name]; ok {
+			transportOpts = sgOpts
+		}
+		clientTLS := defaultClientTLS
+		if sgTLS, ok := perSubgraphTLS[name]; ok {
+			clientTLS = sgTLS
+		}
+
+		endpoints := make([]string, 0, len(settings.Endpoints))
+		for _, endpoint := range settings.Endpoints {
+			endpoints = append(endpoints, endpoint.URL)
+		}
+
+		balancer, err := loadbalancer.New(loadbalancer.Options{
+			Subgraph:  name,
+			Strategy:  strategy,
+			Endpoints: endpoints,
+			HashKey:   loadBalancingHashKey(hashProgram),
+			HealthCheck: loadbalancer.HealthCheckOptions{
+				Enabled:            settings.HealthCheck.Enabled,
+				Path:               settings.HealthCheck.Path,
+				Interval:           settings.HealthCheck.Interval,
+				Timeout:            settings.HealthCheck.Timeout,
+				UnhealthyThreshold: settings.HealthCheck.UnhealthyThreshold,
+				HealthyThreshold:   settings.HealthCheck.HealthyThreshold,
+				Client: &http.Client{
+					Transport: newHTTPTransport(transportOpts, r.proxy, nil, name, clientTLS),
+				},
+			},
+			CircuitBreaker: r.subgraphCircuitBreakerOptions.subgraphConfig(name),
+			Logger:         logger,
+		})
+		if err != nil {
+			return err
+		}
+
+		balancer.StartHealthChecks(ctx)
+		r.subgraphLoadBalancers[name] = balancer
+
+		logger.Info("Load balancing subgraph",
+			zap.String("subgraph_name", name),
+			zap.String("strategy", string(strategy)),
+			zap.Strings("endpoints", endpoints),
+		)
+	}
+
+	if err := rmetric.RegisterLoadBalancerMetrics(r.endpointEjections, r.otlpMeterProvider, r.promMeterProvider); err != nil {
+		return fmt.Errorf("failed to register load balancer metrics: %w", err)
+	}
+
+	return nil
+}
+
+// endpointEjections returns the subgraph endpoints that are currently ejected from load balancing
+func (r *Router) endpointEjections() []rmetric.EndpointEjection {
+	var ejections []rmetric.EndpointEjection
+	for _, balancer := range r.subgraphLoadBalancers {
+		for _, endpoint := range balancer.Endpoints() {
+			if reason := endpoint.EjectionReason(); reason != "" {
+				ejections = append(ejections, rmetric.EndpointEjection{
+					Subgraph: balancer.Subgraph(),
+					Endpoint: endpoint.URL(),
+					Reason:   string(reason),
+				})
+			}
+		}
+	}
+	return ejections
+}
+
+// loadBalancingHashKey resolves the hash expression of the consistent hash strategy on the request
+func loadBalancingHashKey(program *vm.Program) func(req *http.Request) string {
+	if program == nil {
+		return nil
+	}
+
+	return func(req *http.Request) string {
+		reqCtx := getRequestContext(req.Context())
+		if reqCtx == nil {
+			return ""
+		}
+
+		key, err := expr.ResolveStringExpression(program, reqCtx.expressionContext)
+		if err != nil {
+			reqCtx.logger.Warn("Failed to resolve load balancing hash expression", zap.Error(err))
+			return ""
+		}
+
+		return key
+	}
+}
+
+// subgraphConfig returns the circuit breaker configuration that applies to the subgraph
+func (r *SubgraphCircuitBreakerOptions) subgraphConfig(name string) circuit.CircuitBreakerConfig {
+	if r == nil {
+		return circuit.CircuitBreakerConfig{}
+	}
+	if cfg, ok := r.SubgraphMap[name]; ok {
+		return cfg
+	}
+	return r.CircuitBreaker
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/circuit"
+	"github.com/wundergraph/cosmo/router/internal/loadbalancer"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func newTestLoadBalancingRouter(cfg *config.SubgraphLoadBalancingConfiguration) *Router {
+	return &Router{
+		Config: Config{
+			logger:                   zap.NewNop(),
+			subgraphTransportOptions: DefaultSubgraphTransportOptions(),
+			subgraphLoadBalancing:    cfg,
+			subgraphCircuitBreakerOptions: &SubgraphCircuitBreakerOptions{
+				CircuitBreaker: circuit.CircuitBreakerConfig{
+					Enabled:         true,
+					RollingDuration: 10 * time.Second,
+					NumBuckets:      10,
+				},
+				SubgraphMap: map[string]circuit.CircuitBreakerConfig{
+					"employees": {Enabled: false},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildSubgraphLoadBalancers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("creates a load balancer per subgraph", func(t *testing.T) {
+		t.Parallel()
+
+		r := newTestLoadBalancingRouter(&config.SubgraphLoadBalancingConfiguration{
+			Subgraphs: map[string]config.SubgraphLoadBalancingSettings{
+				"products": {
+					Strategy:       "consistent_hash",
+					HashExpression: "request.auth.claims.sub",
+					Endpoints: []config.SubgraphLoadBalancingEndpoint{
+						{URL: "http://products-1:4001/graphql"},
+						{URL: "http://products-2:4001/graphql"},
+					},
+				},
+				"employees": {
+					Endpoints: []config.SubgraphLoadBalancingEndpoint{{URL: "http://employees-1:4001/graphql"}},
+				},
+			},
+		})
+
+		require.NoError(t, r.buildSubgraphLoadBalancers(t.Context()))
+		require.Len(t, r.subgraphLoadBalancers, 2)
+		require.Len(t, r.subgraphLoadBalancers["products"].Endpoints(), 2)
+		require.Empty(t, r.endpointEjections())
+	})
+
+	t.Run("requires a hash expression for consistent hashing", func(t *testing.T) {
+		t.Parallel()
+
+		r := newTestLoadBalancingRouter(&config.SubgraphLoadBalancingConfiguration{
+			Subgraphs: map[string]config.SubgraphLoadBalancingSettings{
+				"products": {
+					Strategy:  string(loadbalancer.StrategyConsistentHash),
+					Endpoints: []config.SubgraphLoadBalancingEndpoint{{URL: "http://products-1:4001/graphql"}},
+				},
+			},
+		})
+
+		require.ErrorContains(t, r.buildSubgraphLoadBalancers(t.Context()), "requires a hash_expression")
+	})
+}
+
+func TestSubgraphCircuitBreakerConfig(t *testing.T) {
+	t.Parallel()
+
+	opts := newTestLoadBalancingRouter(nil).subgraphCircuitBreakerOptions
+
+	require.True(t, opts.subgraphConfig("products").Enabled)
+	require.False(t, opts.subgraphConfig("employees").Enabled)
+
+	var disabled *SubgraphCircuitBreakerOptions
+	require.False(t, disabled.subgraphConfig("products").Enabled)
+}
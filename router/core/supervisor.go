@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/wundergraph/cosmo/router/pkg/config"
+	rmetric "github.com/wundergraph/cosmo/router/pkg/metric"
 	"go.uber.org/zap"
 )
 
@@ -17,10 +18,8 @@ type RouterSupervisor struct {
 	routerCtx    context.Context
 	routerCancel context.CancelFunc
 
-	// Sending to this channel will trigger a graceful shutdown of the router
-	// Sending true will result in a Reload, false will result in a Shutdown
-	// Value means "to kill or not to kill"
-	shutdownChan chan bool
+	// Sending to this channel will trigger a graceful shutdown, a restart or a reload of the router
+	shutdownChan chan supervisorSignal
 
 	configFactory func() (*config.Config, error)
 	routerFactory func(ctx context.Context, res *RouterResources) (*Router, error)
@@ -28,11 +27,27 @@ type RouterSupervisor struct {
 	resources *RouterResources
 }
 
+// supervisorSignal tells the supervisor what to do with the running router
+type supervisorSignal int
+
+const (
+	// signalShutdown stops the router
+	signalShutdown supervisorSignal = iota
+	// signalReload loads the config and restarts the router, whether the config changed or not,
+	// so that resources like the execution config file are loaded again
+	signalReload
+	// signalConfigChanged loads the config after the config file changed. Changes of fields that
+	// can be applied live don't restart the router.
+	signalConfigChanged
+)
+
 // RouterResources is a struct for holding resources used by the router.
 type RouterResources struct {
 	Config                *config.Config
 	Logger                *zap.Logger
 	ReloadPersistentState *ReloadPersistentState
+	// Reload triggers a reload of the router through the supervisor
+	Reload func()
 }
 
 // RouterSupervisorOpts is a struct for configuring the router supervisor.
@@ -46,7 +61,7 @@ type RouterSupervisorOpts struct {
 // NewRouterSupervisor creates a new RouterSupervisor instance.
 func NewRouterSupervisor(opts *RouterSupervisorOpts) (*RouterSupervisor, error) {
 	rs := &RouterSupervisor{
-		shutdownChan:  make(chan bool),
+		shutdownChan:  make(chan supervisorSignal),
 		logger:        opts.BaseLogger.With(zap.String("component", "supervisor")),
 		configFactory: opts.ConfigFactory,
 		resources: &RouterResources{
@@ -55,6 +70,8 @@ func NewRouterSupervisor(opts *RouterSupervisorOpts) (*RouterSupervisor, error)
 		},
 	}
 
+	rs.resources.Reload = rs.Reload
+
 	if rs.configFactory == nil {
 		return nil, errors.New("a config factory is required")
 	}
@@ -154,9 +171,7 @@ func (rs *RouterSupervisor) Start() error {
 
 		rs.logger.Info("Router started")
 
-		shutdown := <-rs.shutdownChan
-
-		rs.logger.Debug("Got shutdown signal", zap.Bool("shutdown", shutdown))
+		shutdown := rs.waitForRestart()
 
 		if !shutdown {
 			rs.router.reloadPersistentState.OnRouterConfigReload()
@@ -176,29 +191,106 @@ func (rs *RouterSupervisor) Start() error {
 			rs.logger.Debug("Router exiting")
 			break
 		}
+	}
 
-		// Reload resources for new router, if failed, continue to restart with the old resources
-		if err := rs.loadConfig(); err != nil {
-			rs.logger.Warn("reloading resources failed, keeping old ones", zap.Error(err))
-			continue
+	return nil
+}
+
+// waitForRestart blocks until the router has to be stopped or restarted and returns true if it has
+// to be stopped. Config file changes that only touch fields which can be applied live are applied
+// to the running router without restarting it.
+func (rs *RouterSupervisor) waitForRestart() bool {
+	for {
+		signal := <-rs.shutdownChan
+
+		rs.logger.Debug("Got shutdown signal", zap.Bool("shutdown", signal == signalShutdown))
+
+		if signal == signalShutdown {
+			return true
 		}
+		if rs.reloadConfig(signal == signalConfigChanged) {
+			return false
+		}
+	}
+}
+
+// reloadConfig loads the config and returns true if the router has to be restarted. Explicit
+// reloads always restart the router. When the reload is triggered by a change of the config file,
+// changes of fields that can be applied live are applied to the running router instead.
+func (rs *RouterSupervisor) reloadConfig(configChanged bool) bool {
+	// External rotators like logrotate send SIGHUP after moving the access log file, whether the
+	// config changed or not. A restarted router opens the file again anyway.
+	if err := rs.router.reopenAccessLogFile(); err != nil {
+		rs.logger.Error("Failed to reopen the access log file", zap.Error(err))
 	}
 
-	return nil
+	cfg, err := rs.configFactory()
+	if err != nil {
+		// Restart with the old resources, as before
+		rs.logger.Warn("reloading resources failed, keeping old ones", zap.Error(err))
+		rs.router.recordConfigReload(rs.routerCtx, rmetric.ConfigReloadModeRestart, false)
+		return true
+	}
+
+	diff := config.Diff(rs.resources.Config, cfg)
+	rs.resources.Config = cfg
+
+	if !configChanged {
+		rs.logger.Info("Restarting router to reload it",
+			zap.Strings("restart_fields", diff.Restart),
+			zap.Strings("live_fields", diff.Live),
+		)
+		rs.router.recordConfigReload(rs.routerCtx, rmetric.ConfigReloadModeRestart, true)
+		return true
+	}
+
+	if diff.Empty() {
+		rs.logger.Info("Router config unchanged, nothing to reload")
+		return false
+	}
+
+	if diff.RequiresRestart() {
+		rs.logger.Info("Restarting router to apply the config",
+			zap.Strings("restart_fields", diff.Restart),
+			zap.Strings("live_fields", diff.Live),
+		)
+		rs.router.recordConfigReload(rs.routerCtx, rmetric.ConfigReloadModeRestart, true)
+		return true
+	}
+
+	if err := rs.router.ApplyConfig(rs.routerCtx, cfg); err != nil {
+		rs.logger.Error("Failed to apply the config to the running router, restarting it instead",
+			zap.Strings("live_fields", diff.Live),
+			zap.Error(err),
+		)
+		rs.router.recordConfigReload(rs.routerCtx, rmetric.ConfigReloadModeLive, false)
+		return true
+	}
+
+	rs.logger.Info("Router config reloaded without restart", zap.Strings("live_fields", diff.Live))
+	rs.router.recordConfigReload(rs.routerCtx, rmetric.ConfigReloadModeLive, true)
+
+	return false
 }
 
 // Stop stops the router supervisor.
 func (rs *RouterSupervisor) Stop() {
 	rs.logger.Info("Stopping Router")
 
-	// true == kill
-	rs.shutdownChan <- true
+	rs.shutdownChan <- signalShutdown
 }
 
-// Reload restarts the router supervisor.
+// Reload loads the config and restarts the router, which also loads the execution config again.
 func (rs *RouterSupervisor) Reload() {
 	rs.logger.Info("Reloading Router")
 
-	// false == don't kill
-	rs.shutdownChan <- false
+	rs.shutdownChan <- signalReload
+}
+
+// ReloadOnConfigChange loads the config after the config file changed. The router is only
+// restarted if the changes can't be applied to the running router.
+func (rs *RouterSupervisor) ReloadOnConfigChange() {
+	rs.logger.Info("Reloading Router config")
+
+	rs.shutdownChan <- signalConfigChanged
 }
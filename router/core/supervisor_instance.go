@@ -2,23 +2,29 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/KimMachineGun/automemlimit/memlimit"
 	"github.com/dustin/go-humanize"
+	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
 	"github.com/wundergraph/cosmo/router/pkg/authentication"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/cosmo/router/pkg/controlplane/selfregister"
-	"github.com/wundergraph/cosmo/router/pkg/cors"
 	"github.com/wundergraph/cosmo/router/pkg/logging"
 	"go.uber.org/automaxprocs/maxprocs"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// accessLogsScopeName is the instrumentation scope of the access logs exported over OTLP
+const accessLogsScopeName = "wundergraph/cosmo/router/access_logs"
+
 // newRouter creates a new router instance.
 //
 // additionalOptions can be used to override default options or options provided in the config.
@@ -51,6 +57,9 @@ func newRouter(ctx context.Context, params RouterResources, additionalOptions ..
 	}
 
 	options := optionsFromResources(logger, cfg, params.ReloadPersistentState)
+	if params.Reload != nil {
+		options = append(options, WithReloadFunc(params.Reload))
+	}
 	options = append(options, additionalOptions...)
 
 	authenticators, err := setupAuthenticators(ctx, logger, cfg)
@@ -105,10 +114,23 @@ func newRouter(ctx context.Context, params RouterResources, additionalOptions ..
 		}
 
 		if cfg.AccessLogs.Output.File.Enabled {
-			f, err := logging.NewLogFile(cfg.AccessLogs.Output.File.Path, os.FileMode(cfg.AccessLogs.Output.File.Mode))
+			var rotation logging.RotationOptions
+			if rc := cfg.AccessLogs.Output.File.Rotation; rc.Enabled {
+				rotation = logging.RotationOptions{
+					MaxSize:    int64(rc.MaxSize.Uint64()),
+					Interval:   rc.Interval,
+					MaxBackups: rc.MaxBackups,
+					MaxAge:     rc.MaxAge,
+					Compress:   rc.Compress,
+				}
+			}
+
+			// Without rotation options, the file is never rotated but can still be reopened on reload
+			f, err := logging.NewRotatingFile(cfg.AccessLogs.Output.File.Path, os.FileMode(cfg.AccessLogs.Output.File.Mode), rotation)
 			if err != nil {
 				return nil, fmt.Errorf("could not create log file: %w", err)
 			}
+			c.File = f
 			if cfg.AccessLogs.Buffer.Enabled {
 				bl, err := logging.NewJSONZapBufferedLogger(logging.BufferedLoggerOptions{
 					WS:            f,
@@ -123,6 +145,7 @@ func newRouter(ctx context.Context, params RouterResources, additionalOptions ..
 					return nil, fmt.Errorf("could not create buffered logger: %w", err)
 				}
 				c.Logger = bl.Logger
+				c.BufferedLogger = bl
 			} else {
 				c.Logger = logging.NewZapAccessLogger(f, level, cfg.DevelopmentMode, !cfg.JSONLog, cfg.AccessLogs.AddStacktrace)
 			}
@@ -141,11 +164,36 @@ func newRouter(ctx context.Context, params RouterResources, additionalOptions ..
 					return nil, fmt.Errorf("could not create buffered logger: %w", err)
 				}
 				c.Logger = bl.Logger
+				c.BufferedLogger = bl
 			} else {
 				c.Logger = logging.NewZapAccessLogger(os.Stdout, level, cfg.DevelopmentMode, !cfg.JSONLog, cfg.AccessLogs.AddStacktrace)
 			}
 		}
 
+		if cfg.AccessLogs.Output.OTLP.Enabled {
+			otlpOptions := LogsConfigFromTelemetry(&cfg.Telemetry)
+			if otlpOptions == nil {
+				return nil, errors.New("access_logs.output.otlp requires at least one exporter in telemetry.logs.exporters")
+			}
+			otlpOptions.ServiceInstanceID = cfg.InstanceID
+			if cfg.AccessLogs.Buffer.Enabled {
+				otlpOptions.FlushInterval = cfg.AccessLogs.Buffer.FlushInterval
+				otlpOptions.BufferSize = int(cfg.AccessLogs.Buffer.Size.Uint64())
+			}
+
+			otlpLogger, err := logging.NewOTLPLogger(ctx, *otlpOptions)
+			if err != nil {
+				return nil, fmt.Errorf("could not create OTLP access logger: %w", err)
+			}
+			c.OTLPLogger = otlpLogger
+
+			if c.Logger != nil {
+				c.Logger = otlpLogger.Tee(c.Logger, accessLogsScopeName, level)
+			} else {
+				c.Logger = logging.NewZapLoggerWithCore(otlpLogger.Core(accessLogsScopeName, level), cfg.DevelopmentMode, cfg.AccessLogs.AddStacktrace)
+			}
+		}
+
 		options = append(options, WithAccessLogs(c))
 	}
 
@@ -243,11 +291,12 @@ func optionsFromResources(logger *zap.Logger, config *config.Config, reloadPersi
 		WithClusterName(config.Cluster.Name),
 		WithInstanceID(config.InstanceID),
 		WithReadinessCheckPath(config.ReadinessCheckPath),
-		WithHeaderRules(config.Headers),
 		WithRouterTrafficConfig(&config.TrafficShaping.Router),
 		WithFileUploadConfig(&config.FileUpload),
 		WithSubgraphTransportOptions(NewSubgraphTransportOptions(config.TrafficShaping)),
 		WithSubgraphCircuitBreakerOptions(NewSubgraphCircuitBreakerOptions(config.TrafficShaping)),
+		WithSubgraphHedgingOptions(NewSubgraphHedgingOptions(config.TrafficShaping)),
+		WithSubgraphCompressionOptions(NewSubgraphCompressionOptions(config.TrafficShaping)),
 		WithSubgraphRetryOptions(
 			config.TrafficShaping.All.BackoffJitterRetry.Enabled,
 			config.TrafficShaping.All.BackoffJitterRetry.Algorithm,
@@ -257,14 +306,6 @@ func optionsFromResources(logger *zap.Logger, config *config.Config, reloadPersi
 			config.TrafficShaping.All.BackoffJitterRetry.Expression,
 			nil,
 		),
-		WithCors(&cors.Config{
-			Enabled:          config.CORS.Enabled,
-			AllowOrigins:     config.CORS.AllowOrigins,
-			AllowMethods:     config.CORS.AllowMethods,
-			AllowCredentials: config.CORS.AllowCredentials,
-			AllowHeaders:     config.CORS.AllowHeaders,
-			MaxAge:           config.CORS.MaxAge,
-		}),
 		WithTLSConfig(config.TLS),
 		WithDevelopmentMode(config.DevelopmentMode),
 		WithTracing(TraceConfigFromTelemetry(&config.Telemetry)),
@@ -273,8 +314,6 @@ func optionsFromResources(logger *zap.Logger, config *config.Config, reloadPersi
 		WithTelemetryAttributes(config.Telemetry.Attributes),
 		WithTracingAttributes(config.Telemetry.Tracing.Attributes),
 		WithEngineExecutionConfig(config.EngineExecutionConfiguration),
-		WithCacheControlPolicy(config.CacheControl),
-		WithSecurityConfig(config.SecurityConfiguration),
 		WithAuthorizationConfig(&config.Authorization),
 		WithWebSocketConfiguration(&config.WebSocket),
 		WithSubgraphErrorPropagation(config.SubgraphErrorPropagation),
@@ -282,54 +321,85 @@ func optionsFromResources(logger *zap.Logger, config *config.Config, reloadPersi
 		WithLocalhostFallbackInsideDocker(config.LocalhostFallbackInsideDocker),
 		WithCDN(config.CDN),
 		WithEvents(config.Events),
-		WithRateLimitConfig(&config.RateLimit),
 		WithClientHeader(config.ClientHeader),
 		WithCacheWarmupConfig(&config.CacheWarmup),
+		WithResponseCache(&config.ResponseCache),
+		WithSubgraphLoadBalancing(&config.SubgraphLoadBalancing),
+		WithFeatureFlagRouting(&config.FeatureFlagRouting),
 		WithMCP(config.MCP),
 		WithConnectRPC(config.ConnectRPC),
+		WithAdminAPI(config.AdminAPI),
 		WithPlugins(config.Plugins),
 		WithDemoMode(config.DemoMode),
 		WithStreamsHandlerConfiguration(config.Events.Handlers),
 		WithReloadPersistentState(reloadPersistentState),
 	}
 
-	return options
+	return append(options, liveConfigOptions(config)...)
 }
 
 func setupAuthenticators(ctx context.Context, logger *zap.Logger, cfg *config.Config) ([]authentication.Authenticator, error) {
 	jwtConf := cfg.Authentication.JWT
-	if len(jwtConf.JWKS) == 0 {
-		// No JWT authenticators configured
+	introspectionConf := cfg.Authentication.TokenIntrospection
+	if len(jwtConf.JWKS) == 0 && !introspectionConf.Enabled && !cfg.Authentication.APIKey.Enabled && !cfg.Authentication.ClientCertificate.Enabled {
+		// No authenticators configured
 		return nil, nil
 	}
 
-	var authenticators []authentication.Authenticator
-	configs := make([]authentication.JWKSConfig, 0, len(jwtConf.JWKS))
-
-	for _, jwks := range cfg.Authentication.JWT.JWKS {
-		configs = append(configs, authentication.JWKSConfig{
-			URL:               jwks.URL,
-			RefreshInterval:   jwks.RefreshInterval,
-			AllowedAlgorithms: jwks.Algorithms,
-			AllowedUse:        jwks.AllowedUse,
-
-			Secret:    jwks.Secret,
-			Algorithm: jwks.Algorithm,
-			KeyId:     jwks.KeyId,
-
-			Audiences: jwks.Audiences,
-			RefreshUnknownKID: authentication.RefreshUnknownKIDConfig{
-				Enabled:  jwks.RefreshUnknownKID.Enabled,
-				MaxWait:  jwks.RefreshUnknownKID.MaxWait,
-				Interval: jwks.RefreshUnknownKID.Interval,
-				Burst:    jwks.RefreshUnknownKID.Burst,
-			},
-		})
+	// The token decoders by the name of their authenticator
+	type namedTokenDecoder struct {
+		name    string
+		decoder authentication.TokenDecoder
 	}
+	var tokenDecoders []namedTokenDecoder
+
+	if len(jwtConf.JWKS) > 0 {
+		configs := make([]authentication.JWKSConfig, 0, len(jwtConf.JWKS))
+
+		for _, jwks := range cfg.Authentication.JWT.JWKS {
+			configs = append(configs, authentication.JWKSConfig{
+				URL:               jwks.URL,
+				RefreshInterval:   jwks.RefreshInterval,
+				AllowedAlgorithms: jwks.Algorithms,
+				AllowedUse:        jwks.AllowedUse,
+
+				Secret:    jwks.Secret,
+				Algorithm: jwks.Algorithm,
+				KeyId:     jwks.KeyId,
+
+				Audiences: jwks.Audiences,
+				RefreshUnknownKID: authentication.RefreshUnknownKIDConfig{
+					Enabled:  jwks.RefreshUnknownKID.Enabled,
+					MaxWait:  jwks.RefreshUnknownKID.MaxWait,
+					Interval: jwks.RefreshUnknownKID.Interval,
+					Burst:    jwks.RefreshUnknownKID.Burst,
+				},
+			})
+		}
 
-	tokenDecoder, err := authentication.NewJwksTokenDecoder(ctx, logger, configs)
-	if err != nil {
-		return nil, err
+		tokenDecoder, err := authentication.NewJwksTokenDecoder(ctx, logger, configs)
+		if err != nil {
+			return nil, err
+		}
+		tokenDecoders = append(tokenDecoders, namedTokenDecoder{name: "jwks", decoder: tokenDecoder})
+	}
+
+	// Opaque tokens are validated with the introspection endpoint after the JWKS failed to validate them
+	if introspectionConf.Enabled {
+		tokenDecoder, err := authentication.NewIntrospectionTokenDecoder(ctx, logger, authentication.IntrospectionConfig{
+			URL:              introspectionConf.URL,
+			ClientID:         introspectionConf.ClientID,
+			ClientSecret:     introspectionConf.ClientSecret,
+			TokenTypeHint:    introspectionConf.TokenTypeHint,
+			ScopeClaim:       jwtConf.ScopeClaim,
+			CacheTTL:         introspectionConf.CacheTTL,
+			InactiveCacheTTL: introspectionConf.InactiveCacheTTL,
+			Timeout:          introspectionConf.Timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not create token introspection decoder: %w", err)
+		}
+		tokenDecoders = append(tokenDecoders, namedTokenDecoder{name: "introspection", decoder: tokenDecoder})
 	}
 
 	// create a map for the `httpHeaderAuthenticator`
@@ -352,19 +422,23 @@ func setupAuthenticators(ctx context.Context, logger *zap.Logger, cfg *config.Co
 
 	}
 
-	opts := authentication.HttpHeaderAuthenticatorOptions{
-		Name:                 "jwks",
-		HeaderSourcePrefixes: headerSourceMap,
-		TokenDecoder:         tokenDecoder,
-	}
+	var authenticators []authentication.Authenticator
 
-	authenticator, err := authentication.NewHttpHeaderAuthenticator(opts)
-	if err != nil {
-		logger.Error("Could not create HttpHeader authenticator", zap.Error(err))
-		return nil, err
-	}
+	for _, tokenDecoder := range tokenDecoders {
+		opts := authentication.HttpHeaderAuthenticatorOptions{
+			Name:                 tokenDecoder.name,
+			HeaderSourcePrefixes: headerSourceMap,
+			TokenDecoder:         tokenDecoder.decoder,
+		}
+
+		authenticator, err := authentication.NewHttpHeaderAuthenticator(opts)
+		if err != nil {
+			logger.Error("Could not create HttpHeader authenticator", zap.Error(err))
+			return nil, err
+		}
 
-	authenticators = append(authenticators, authenticator)
+		authenticators = append(authenticators, authenticator)
+	}
 
 	if cfg.WebSocket.Authentication.FromInitialPayload.Enabled {
 		headerPrefixes := make([]string, 0, len(prefixSet))
@@ -372,15 +446,49 @@ func setupAuthenticators(ctx context.Context, logger *zap.Logger, cfg *config.Co
 			headerPrefixes = append(headerPrefixes, prefix)
 		}
 
-		opts := authentication.WebsocketInitialPayloadAuthenticatorOptions{
-			TokenDecoder:        tokenDecoder,
-			Key:                 cfg.WebSocket.Authentication.FromInitialPayload.Key,
-			HeaderValuePrefixes: headerPrefixes,
+		for _, tokenDecoder := range tokenDecoders {
+			opts := authentication.WebsocketInitialPayloadAuthenticatorOptions{
+				TokenDecoder:        tokenDecoder.decoder,
+				Key:                 cfg.WebSocket.Authentication.FromInitialPayload.Key,
+				HeaderValuePrefixes: headerPrefixes,
+			}
+			authenticator, err := authentication.NewWebsocketInitialPayloadAuthenticator(opts)
+			if err != nil {
+				logger.Error("Could not create WebsocketInitialPayload authenticator", zap.Error(err))
+				return nil, err
+			}
+			authenticators = append(authenticators, authenticator)
+		}
+	}
+
+	if cfg.Authentication.APIKey.Enabled {
+		authenticator, err := setupAPIKeyAuthenticator(ctx, logger, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not create API key authenticator: %w", err)
 		}
-		authenticator, err = authentication.NewWebsocketInitialPayloadAuthenticator(opts)
+		authenticators = append(authenticators, authenticator)
+	}
+
+	if certConf := cfg.Authentication.ClientCertificate; certConf.Enabled {
+		if !cfg.TLS.Server.Enabled || cfg.TLS.Server.ClientAuth.CertFile == "" {
+			return nil, errors.New("client certificate authentication requires TLS with client authentication")
+		}
+
+		rules := make([]authentication.CertificateScopeRule, 0, len(certConf.ScopeRules))
+		for _, rule := range certConf.ScopeRules {
+			rules = append(rules, authentication.CertificateScopeRule{
+				Attribute: rule.Attribute,
+				Matching:  rule.Matching,
+				Scopes:    rule.Scopes,
+			})
+		}
+
+		authenticator, err := authentication.NewClientCertificateAuthenticator(authentication.ClientCertificateAuthenticatorOptions{
+			ScopeClaim: jwtConf.ScopeClaim,
+			ScopeRules: rules,
+		})
 		if err != nil {
-			logger.Error("Could not create WebsocketInitialPayload authenticator", zap.Error(err))
-			return nil, err
+			return nil, fmt.Errorf("could not create client certificate authenticator: %w", err)
 		}
 		authenticators = append(authenticators, authenticator)
 	}
@@ -388,6 +496,75 @@ func setupAuthenticators(ctx context.Context, logger *zap.Logger, cfg *config.Co
 	return authenticators, nil
 }
 
+// setupAPIKeyAuthenticator creates the API key authenticator with the keys of the configured file
+// or Redis storage provider
+func setupAPIKeyAuthenticator(ctx context.Context, logger *zap.Logger, cfg *config.Config) (authentication.Authenticator, error) {
+	apiKeyConf := cfg.Authentication.APIKey
+
+	var store authentication.APIKeyStore
+
+	switch {
+	case apiKeyConf.File.Path != "" && apiKeyConf.Storage.ProviderID != "":
+		return nil, errors.New("api keys can either be read from a file or a storage provider, not both")
+	case apiKeyConf.File.Path != "":
+		var watchInterval time.Duration
+		if apiKeyConf.File.Watch {
+			watchInterval = apiKeyConf.File.WatchInterval
+		}
+
+		fileStore, err := authentication.NewFileAPIKeyStore(ctx, logger, apiKeyConf.File.Path, watchInterval)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	case apiKeyConf.Storage.ProviderID != "":
+		idx := slices.IndexFunc(cfg.StorageProviders.Redis, func(p config.RedisStorageProvider) bool {
+			return p.ID == apiKeyConf.Storage.ProviderID
+		})
+		if idx == -1 {
+			return nil, fmt.Errorf("redis storage provider with id '%s' for api keys not found", apiKeyConf.Storage.ProviderID)
+		}
+		provider := cfg.StorageProviders.Redis[idx]
+
+		client, err := rd.NewRedisCloser(&rd.RedisCloserOptions{
+			URLs:           provider.URLs,
+			ClusterEnabled: provider.ClusterEnabled,
+			Logger:         logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redis client for api keys: %w", err)
+		}
+
+		prefix := apiKeyConf.Storage.KeyPrefix
+		if prefix != "" {
+			prefix += ":"
+		}
+
+		redisStore, err := authentication.NewRedisAPIKeyStore(client, prefix, apiKeyConf.Storage.CacheTTL)
+		if err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+
+		context.AfterFunc(ctx, func() {
+			redisStore.Close()
+			if err := client.Close(); err != nil {
+				logger.Error("Failed to close redis client for api keys", zap.Error(err))
+			}
+		})
+		store = redisStore
+	default:
+		return nil, errors.New("api keys require a file path or a storage provider")
+	}
+
+	return authentication.NewAPIKeyAuthenticator(authentication.APIKeyAuthenticatorOptions{
+		HeaderName:     apiKeyConf.HeaderName,
+		QueryParameter: apiKeyConf.QueryParameter,
+		ScopeClaim:     cfg.Authentication.JWT.ScopeClaim,
+		Store:          store,
+	})
+}
+
 func hasProxyConfigured() bool {
 	_, httpProxy := os.LookupEnv("HTTP_PROXY")
 	_, httpsProxy := os.LookupEnv("HTTPS_PROXY")
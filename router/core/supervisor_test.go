@@ -0,0 +1,86 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/cosmo/router/pkg/logging"
+)
+
+func TestRouterSupervisorReloadConfig(t *testing.T) {
+	t.Parallel()
+
+	newSupervisor := func(t *testing.T, current, next *config.Config, opts ...Option) *RouterSupervisor {
+		t.Helper()
+		rs, err := NewRouterSupervisor(&RouterSupervisorOpts{
+			BaseLogger: zap.NewNop(),
+			ConfigFactory: func() (*config.Config, error) {
+				return next, nil
+			},
+		})
+		require.NoError(t, err)
+		rs.resources.Config = current
+		rs.router = &Router{}
+		for _, opt := range opts {
+			opt(rs.router)
+		}
+		rs.routerCtx = t.Context()
+		return rs
+	}
+
+	t.Run("an unchanged config file keeps the router running", func(t *testing.T) {
+		t.Parallel()
+		rs := newSupervisor(t, &config.Config{ListenAddr: "localhost:3002"}, &config.Config{ListenAddr: "localhost:3002"})
+		require.False(t, rs.reloadConfig(true))
+	})
+
+	t.Run("an explicit reload restarts the router with an unchanged config", func(t *testing.T) {
+		t.Parallel()
+		rs := newSupervisor(t, &config.Config{ListenAddr: "localhost:3002"}, &config.Config{ListenAddr: "localhost:3002"})
+		require.True(t, rs.reloadConfig(false))
+	})
+
+	t.Run("an explicit reload restarts the router for live changes", func(t *testing.T) {
+		t.Parallel()
+		rs := newSupervisor(t,
+			&config.Config{ListenAddr: "localhost:3002"},
+			&config.Config{ListenAddr: "localhost:3002", CacheControl: config.CacheControlPolicy{Enabled: true, Value: "max-age=60"}},
+		)
+		require.True(t, rs.reloadConfig(false))
+	})
+
+	t.Run("an unchanged config reopens the access log file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "access.log")
+		file, err := logging.NewRotatingFile(path, 0o640, logging.RotationOptions{})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = file.Close()
+		})
+
+		rs := newSupervisor(t, &config.Config{ListenAddr: "localhost:3002"}, &config.Config{ListenAddr: "localhost:3002"},
+			WithAccessLogs(&AccessLogsConfig{File: file}))
+
+		// An external rotator moves the file and sends SIGHUP
+		require.NoError(t, os.Rename(path, path+".1"))
+		require.True(t, rs.reloadConfig(false))
+
+		_, err = file.Write([]byte("after rotation\n"))
+		require.NoError(t, err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "after rotation\n", string(content))
+	})
+
+	t.Run("a change that can't be applied live restarts the router", func(t *testing.T) {
+		t.Parallel()
+		rs := newSupervisor(t, &config.Config{ListenAddr: "localhost:3002"}, &config.Config{ListenAddr: "localhost:3003"})
+		require.True(t, rs.reloadConfig(true))
+	})
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	rmetric "github.com/wundergraph/cosmo/router/pkg/metric"
+	"github.com/wundergraph/cosmo/router/pkg/watcher"
+)
+
+// serverTLSCertificates holds the certificates and the client auth CA of the server TLS config.
+// They are loaded from their files and can be reloaded while the server is running, without
+// affecting established connections.
+type serverTLSCertificates struct {
+	settings config.TLSServerConfiguration
+	logger   *zap.Logger
+
+	clientAuth tls.ClientAuthType
+	state      atomic.Pointer[serverTLSState]
+}
+
+type serverTLSState struct {
+	defaultCertificate *tls.Certificate
+	// certificates maps lower-cased server names to their certificates. Wildcard names are stored
+	// as "*.example.com".
+	certificates map[string]*tls.Certificate
+	clientCAs    *x509.CertPool
+	expirations  []rmetric.CertificateExpiration
+}
+
+func newServerTLSCertificates(logger *zap.Logger, settings config.TLSServerConfiguration) (*serverTLSCertificates, error) {
+	if settings.CertFile == "" {
+		return nil, errors.New("tls cert file not provided")
+	}
+
+	if settings.KeyFile == "" {
+		return nil, errors.New("tls key file not provided")
+	}
+
+	c := &serverTLSCertificates{
+		settings:   settings,
+		logger:     logger,
+		clientAuth: tls.NoClientCert,
+	}
+
+	if settings.ClientAuth.CertFile != "" {
+		if settings.ClientAuth.Required {
+			c.clientAuth = tls.RequireAndVerifyClientCert
+		} else {
+			c.clientAuth = tls.VerifyClientCertIfGiven
+		}
+
+		logger.Debug("Client auth enabled", zap.String("mode", c.clientAuth.String()))
+	}
+
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reload loads the certificates and the client auth CA from their files. If loading fails, the
+// previously loaded ones are kept.
+func (c *serverTLSCertificates) Reload() error {
+	state := &serverTLSState{
+		certificates: make(map[string]*tls.Certificate, len(c.settings.Certificates)),
+	}
+
+	defaultCertificate, err := loadServerCertificate(c.settings.CertFile, c.settings.KeyFile)
+	if err != nil {
+		return err
+	}
+	state.defaultCertificate = defaultCertificate
+	state.expirations = append(state.expirations, rmetric.CertificateExpiration{
+		File:     c.settings.CertFile,
+		NotAfter: defaultCertificate.Leaf.NotAfter,
+	})
+
+	for _, certCfg := range c.settings.Certificates {
+		cert, err := loadServerCertificate(certCfg.CertFile, certCfg.KeyFile)
+		if err != nil {
+			return err
+		}
+
+		serverNames := certCfg.ServerNames
+		if len(serverNames) == 0 {
+			serverNames = cert.Leaf.DNSNames
+		}
+		if len(serverNames) == 0 {
+			return fmt.Errorf("tls cert %s has no server names", certCfg.CertFile)
+		}
+
+		for _, name := range serverNames {
+			state.certificates[strings.ToLower(name)] = cert
+		}
+
+		state.expirations = append(state.expirations, rmetric.CertificateExpiration{
+			File:     certCfg.CertFile,
+			NotAfter: cert.Leaf.NotAfter,
+		})
+	}
+
+	if c.settings.ClientAuth.CertFile != "" {
+		caCert, err := os.ReadFile(c.settings.ClientAuth.CertFile)
+		if err != nil {
+			return fmt.Errorf("failed to read cert file: %w", err)
+		}
+
+		// Create a CA an empty cert pool and add the CA cert to it to serve as authority to validate client certs
+		caPool := x509.NewCertPool()
+		if ok := caPool.AppendCertsFromPEM(caCert); !ok {
+			return errors.New("failed to append cert to pool")
+		}
+		state.clientCAs = caPool
+	}
+
+	c.state.Store(state)
+
+	return nil
+}
+
+func loadServerCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls cert and key: %w", err)
+	}
+	return &cert, nil
+}
+
+// TLSConfig returns the config for the server. Every handshake uses the certificates that are
+// loaded at that time.
+func (c *serverTLSCertificates) TLSConfig() *tls.Config {
+	return &tls.Config{
+		// The config returned by GetConfigForClient replaces the server config, so the protocols the
+		// http server would add to it must be set here
+		NextProtos:         []string{"h2", "http/1.1"},
+		ClientAuth:         c.clientAuth,
+		GetCertificate:     c.getCertificate,
+		GetConfigForClient: c.getConfigForClient,
+	}
+}
+
+func (c *serverTLSCertificates) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return &tls.Config{
+		NextProtos:     []string{"h2", "http/1.1"},
+		ClientAuth:     c.clientAuth,
+		ClientCAs:      c.state.Load().clientCAs,
+		GetCertificate: c.getCertificate,
+	}, nil
+}
+
+// getCertificate returns the certificate for the requested server name. An exact name takes
+// precedence over a wildcard name. Without a matching certificate, the default one is returned.
+func (c *serverTLSCertificates) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	state := c.state.Load()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name == "" || len(state.certificates) == 0 {
+		return state.defaultCertificate, nil
+	}
+
+	if cert, ok := state.certificates[name]; ok {
+		return cert, nil
+	}
+
+	if _, parent, ok := strings.Cut(name, "."); ok {
+		if cert, ok := state.certificates["*."+parent]; ok {
+			return cert, nil
+		}
+	}
+
+	return state.defaultCertificate, nil
+}
+
+// expirations returns the expiration times of the loaded certificates
+func (c *serverTLSCertificates) expirations() []rmetric.CertificateExpiration {
+	return c.state.Load().expirations
+}
+
+// files returns all files the certificates are loaded from
+func (c *serverTLSCertificates) files() []string {
+	files := []string{c.settings.CertFile, c.settings.KeyFile}
+	for _, certCfg := range c.settings.Certificates {
+		files = append(files, certCfg.CertFile, certCfg.KeyFile)
+	}
+	if c.settings.ClientAuth.CertFile != "" {
+		files = append(files, c.settings.ClientAuth.CertFile)
+	}
+	return files
+}
+
+// watch reloads the certificates when one of their files changes, until the context is done
+func (c *serverTLSCertificates) watch(ctx context.Context) error {
+	ll := c.logger.With(zap.String("watcher_label", "tls_server_certificates"))
+
+	w, err := watcher.New(watcher.Options{
+		Logger:   ll,
+		Paths:    c.files(),
+		Interval: c.settings.WatchInterval,
+		Callback: func() {
+			if err := c.Reload(); err != nil {
+				ll.Error("Failed to reload TLS certificates. Keeping the old ones", zap.Error(err))
+				return
+			}
+
+			ll.Info("TLS certificates reloaded")
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	go func() {
+		if err := w(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			ll.Error("Error watching TLS certificates", zap.Error(err))
+		}
+	}()
+
+	ll.Info("Watching TLS certificates for changes", zap.Strings("files", c.files()))
+
+	return nil
+}
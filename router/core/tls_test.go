@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -283,3 +284,102 @@ func generateTestCert(t *testing.T, prefix string) (certPath, keyPath string) {
 
 	return certPath, keyPath
 }
+
+func TestServerTLSCertificates(t *testing.T) {
+	t.Parallel()
+
+	leafCommonName := func(t *testing.T, cert *tls.Certificate) string {
+		t.Helper()
+		require.NotNil(t, cert)
+		return cert.Leaf.Subject.CommonName
+	}
+
+	t.Run("selects the certificate by server name", func(t *testing.T) {
+		t.Parallel()
+
+		defaultCert, defaultKey := generateTestCert(t, "default")
+		apiCert, apiKey := generateTestCert(t, "api")
+		wildcardCert, wildcardKey := generateTestCert(t, "wildcard")
+
+		certificates, err := newServerTLSCertificates(zap.NewNop(), config.TLSServerConfiguration{
+			CertFile: defaultCert,
+			KeyFile:  defaultKey,
+			Certificates: []config.TLSServerCertificate{
+				{CertFile: apiCert, KeyFile: apiKey, ServerNames: []string{"api.example.com"}},
+				{CertFile: wildcardCert, KeyFile: wildcardKey, ServerNames: []string{"*.example.com"}},
+			},
+		})
+		require.NoError(t, err)
+
+		for serverName, expected := range map[string]string{
+			"":                  "default-test",
+			"API.example.com":   "api-test",
+			"www.example.com":   "wildcard-test",
+			"a.b.example.com":   "default-test",
+			"example.com":       "default-test",
+			"other.example.org": "default-test",
+		} {
+			cert, err := certificates.getCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+			require.NoError(t, err)
+			require.Equal(t, expected, leafCommonName(t, cert), "server name %q", serverName)
+		}
+	})
+
+	t.Run("reloads the certificates and keeps the old ones on failure", func(t *testing.T) {
+		t.Parallel()
+
+		certPath, keyPath := generateTestCert(t, "old")
+		caPath, _ := generateTestCert(t, "ca")
+
+		certificates, err := newServerTLSCertificates(zap.NewNop(), config.TLSServerConfiguration{
+			CertFile:   certPath,
+			KeyFile:    keyPath,
+			ClientAuth: config.TLSClientAuthConfiguration{CertFile: caPath, Required: true},
+		})
+		require.NoError(t, err)
+
+		tlsCfg, err := certificates.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		require.Equal(t, tls.RequireAndVerifyClientCert, tlsCfg.ClientAuth)
+		require.NotNil(t, tlsCfg.ClientCAs)
+
+		newCertPath, newKeyPath := generateTestCert(t, "new")
+		copyFile(t, newCertPath, certPath)
+		copyFile(t, newKeyPath, keyPath)
+		require.NoError(t, certificates.Reload())
+
+		cert, err := certificates.getCertificate(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		require.Equal(t, "new-test", leafCommonName(t, cert))
+
+		require.NoError(t, os.WriteFile(certPath, []byte("invalid"), 0o600))
+		require.Error(t, certificates.Reload())
+
+		cert, err = certificates.getCertificate(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		require.Equal(t, "new-test", leafCommonName(t, cert))
+		require.Len(t, certificates.expirations(), 1)
+	})
+
+	t.Run("requires server names for additional certificates", func(t *testing.T) {
+		t.Parallel()
+
+		defaultCert, defaultKey := generateTestCert(t, "default")
+		apiCert, apiKey := generateTestCert(t, "api")
+
+		_, err := newServerTLSCertificates(zap.NewNop(), config.TLSServerConfiguration{
+			CertFile:     defaultCert,
+			KeyFile:      defaultKey,
+			Certificates: []config.TLSServerCertificate{{CertFile: apiCert, KeyFile: apiKey}},
+		})
+		require.ErrorContains(t, err, "has no server names")
+	})
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0o600))
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+type traceSamplingRule struct {
+	expression *vm.Program
+	ratio      float64
+}
+
+// newTraceSamplingRules compiles the trace sampling rules. The rules are evaluated at the end of
+// the request, so they can access the operation, the client, the feature flag and the subgraphs.
+func newTraceSamplingRules(exprManager *expr.Manager, rules []config.TracingSamplingRule) ([]traceSamplingRule, error) {
+	compiled := make([]traceSamplingRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Ratio < 0 || rule.Ratio > 1 {
+			return nil, fmt.Errorf("trace sampling rule %d: ratio must be between 0 and 1", i)
+		}
+		program, err := exprManager.CompileExpression(rule.Expression, reflect.Bool)
+		if err != nil {
+			return nil, fmt.Errorf("trace sampling rule %d: failed to compile expression: %w", i, err)
+		}
+		compiled = append(compiled, traceSamplingRule{
+			expression: program,
+			ratio:      rule.Ratio,
+		})
+	}
+	return compiled, nil
+}
+
+// matchTraceSamplingRule returns the ratio of the first rule matching the request
+func matchTraceSamplingRule(rules []traceSamplingRule, ctx *expr.Context) (float64, bool, error) {
+	for _, rule := range rules {
+		matched, err := expr.ResolveBoolExpression(rule.expression, *ctx)
+		if err != nil {
+			return 0, false, err
+		}
+		if matched {
+			return rule.ratio, true, nil
+		}
+	}
+	return 0, false, nil
+}
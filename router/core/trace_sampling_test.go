@@ -0,0 +1,65 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestTraceSamplingRules(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the ratio of the first matching rule", func(t *testing.T) {
+		t.Parallel()
+
+		rules, err := newTraceSamplingRules(expr.CreateNewExprManager(), []config.TracingSamplingRule{
+			{Expression: `request.operation.name == "Checkout"`, Ratio: 1},
+			{Expression: `"payments" in request.operation.subgraphs`, Ratio: 0.5},
+			{Expression: `request.client.name == "web" || request.featureFlag == "canary"`, Ratio: 0.1},
+		})
+		require.NoError(t, err)
+
+		ctx := &expr.Context{}
+		ctx.Request.Operation.Name = "Checkout"
+		ctx.Request.Operation.Subgraphs = []string{"payments"}
+		ratio, matched, err := matchTraceSamplingRule(rules, ctx)
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.Equal(t, 1.0, ratio)
+
+		ctx.Request.Operation.Name = "Employees"
+		ratio, matched, err = matchTraceSamplingRule(rules, ctx)
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.Equal(t, 0.5, ratio)
+
+		ctx.Request.Operation.Subgraphs = nil
+		ctx.Request.FeatureFlag = "canary"
+		ratio, matched, err = matchTraceSamplingRule(rules, ctx)
+		require.NoError(t, err)
+		require.True(t, matched)
+		require.Equal(t, 0.1, ratio)
+
+		ctx.Request.FeatureFlag = ""
+		_, matched, err = matchTraceSamplingRule(rules, ctx)
+		require.NoError(t, err)
+		require.False(t, matched)
+	})
+
+	t.Run("rejects invalid rules", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newTraceSamplingRules(expr.CreateNewExprManager(), []config.TracingSamplingRule{
+			{Expression: `request.operation.name`, Ratio: 1},
+		})
+		require.ErrorContains(t, err, "trace sampling rule 0: failed to compile expression")
+
+		_, err = newTraceSamplingRules(expr.CreateNewExprManager(), []config.TracingSamplingRule{
+			{Expression: `true`, Ratio: 2},
+		})
+		require.ErrorContains(t, err, "trace sampling rule 0: ratio must be between 0 and 1")
+	})
+}
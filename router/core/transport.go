@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/wundergraph/cosmo/router/internal/circuit"
+	"github.com/wundergraph/cosmo/router/internal/compression"
 	"github.com/wundergraph/cosmo/router/internal/expr"
+	"github.com/wundergraph/cosmo/router/internal/hedgetransport"
+	"github.com/wundergraph/cosmo/router/internal/loadbalancer"
 	"github.com/wundergraph/cosmo/router/internal/traceclient"
 	"go.opentelemetry.io/otel/propagation"
 
@@ -50,6 +53,7 @@ func NewCustomTransport(
 	metricStore metric.Store,
 	connectionMetricStore metric.ConnectionMetricStore,
 	breaker *circuit.Manager,
+	hedging *hedgetransport.Config,
 	enableTraceClient bool,
 ) *CustomTransport {
 	ct := &CustomTransport{
@@ -82,6 +86,11 @@ func NewCustomTransport(
 		baseRoundTripper = traceclient.NewTraceInjectingRoundTripper(baseRoundTripper, connectionMetricStore, getValuesFromRequest)
 	}
 
+	// Hedging runs inside the circuit breaker and the retries, so a hedged fetch counts as a single request
+	if hedging.IsEnabled() {
+		baseRoundTripper = hedgetransport.NewHedgeTransport(baseRoundTripper, hedging)
+	}
+
 	if breaker.HasCircuits() {
 		baseRoundTripper = circuit.NewCircuitTripper(baseRoundTripper, breaker, getRequestContextLogger)
 	}
@@ -191,6 +200,9 @@ type TransportFactory struct {
 	metricStore                   metric.Store
 	connectionMetricStore         metric.ConnectionMetricStore
 	circuitBreaker                *circuit.Manager
+	loadBalancers                 map[string]*loadbalancer.Balancer
+	hedging                       *hedgetransport.Config
+	compression                   *compression.Config
 	logger                        *zap.Logger
 	tracerProvider                *sdktrace.TracerProvider
 	tracePropagators              propagation.TextMapPropagator
@@ -209,11 +221,16 @@ type TransportOptions struct {
 	MetricStore                   metric.Store
 	ConnectionMetricStore         metric.ConnectionMetricStore
 	CircuitBreaker                *circuit.Manager
-	Logger                        *zap.Logger
-	TracerProvider                *sdktrace.TracerProvider
-	TracePropagators              propagation.TextMapPropagator
-	SpanNameFormatter             SpanNameFormatterFunc
-	EnableTraceClient             bool
+	// LoadBalancers send the requests of the load balanced subgraphs to their endpoints
+	LoadBalancers map[string]*loadbalancer.Balancer
+	// Hedging sends a second request for slow queries to the subgraphs with hedging enabled
+	Hedging           *hedgetransport.Config
+	Compression       *compression.Config
+	Logger            *zap.Logger
+	TracerProvider    *sdktrace.TracerProvider
+	TracePropagators  propagation.TextMapPropagator
+	SpanNameFormatter SpanNameFormatterFunc
+	EnableTraceClient bool
 }
 
 type SubscriptionClientOptions struct {
@@ -242,6 +259,9 @@ func NewTransport(opts *TransportOptions) *TransportFactory {
 		tracePropagators:              opts.TracePropagators,
 		spanNameFormatter:             spanNameFormatter,
 		circuitBreaker:                opts.CircuitBreaker,
+		loadBalancers:                 opts.LoadBalancers,
+		hedging:                       opts.Hedging,
+		compression:                   opts.Compression,
 		enableTraceClient:             opts.EnableTraceClient,
 	}
 }
@@ -251,6 +271,14 @@ func (t TransportFactory) RoundTripper(baseTransport http.RoundTripper) http.Rou
 		baseTransport = docker.NewLocalhostFallbackRoundTripper(baseTransport)
 	}
 
+	if len(t.loadBalancers) > 0 {
+		baseTransport = loadbalancer.NewTransport(baseTransport, t.loadBalancers)
+	}
+
+	if t.compression.IsEnabled() {
+		baseTransport = compression.NewTransport(baseTransport, t.compression)
+	}
+
 	otelHttpOptions := []otelhttp.Option{
 		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
 			return t.spanNameFormatter(r)
@@ -289,6 +317,7 @@ func (t TransportFactory) RoundTripper(baseTransport http.RoundTripper) http.Rou
 		t.metricStore,
 		t.connectionMetricStore,
 		t.circuitBreaker,
+		t.hedging,
 		t.enableTraceClient,
 	)
 
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"slices"
 	"sync"
+	stdatomic "sync/atomic"
 	"syscall"
 	"time"
 
@@ -70,6 +71,9 @@ type WebsocketMiddlewareOptions struct {
 	DisableVariablesRemapping bool
 
 	ApolloCompatibilityFlags config.ApolloCompatibilityFlags
+
+	// ActiveConnections is incremented for every open connection, if set
+	ActiveConnections *stdatomic.Int64
 }
 
 func NewWebsocketMiddleware(ctx context.Context, opts WebsocketMiddlewareOptions) func(http.Handler) http.Handler {
@@ -84,6 +88,7 @@ func NewWebsocketMiddleware(ctx context.Context, opts WebsocketMiddlewareOptions
 		accessController:          opts.AccessController,
 		logger:                    opts.Logger,
 		stats:                     opts.Stats,
+		activeConnections:         opts.ActiveConnections,
 		readTimeout:               opts.ReadTimeout,
 		writeTimeout:              opts.WriteTimeout,
 		config:                    opts.WebSocketConfiguration,
@@ -254,7 +259,8 @@ type WebsocketHandler struct {
 	connections   map[int]*WebSocketConnectionHandler
 	connectionsMu sync.RWMutex
 
-	stats statistics.EngineStatistics
+	stats             statistics.EngineStatistics
+	activeConnections *stdatomic.Int64
 
 	readTimeout  time.Duration
 	writeTimeout time.Duration
@@ -391,7 +397,8 @@ func (h *WebsocketHandler) handleUpgradeRequest(w http.ResponseWriter, r *http.R
 	fromInitialPayloadConfig := h.config.Authentication.FromInitialPayload
 	if fromInitialPayloadConfig.Enabled {
 		// Setting the initialPayload in the context to be used by the websocketInitialPayloadAuthenticator
-		r = r.WithContext(authentication.WithWebsocketInitialPayloadContextKey(r.Context(), handler.initialPayload))
+		// The upgrade request may have been authenticated for feature flag routing without the payload
+		r = r.WithContext(authentication.WithWebsocketInitialPayloadContextKey(withoutAuthenticationResult(r.Context()), handler.initialPayload))
 
 		// Later check access control after initial payload is read and set into the context
 		if h.accessController != nil {
@@ -450,8 +457,8 @@ func (h *WebsocketHandler) handleUpgradeRequest(w http.ResponseWriter, r *http.R
 }
 
 func (h *WebsocketHandler) handleConnectionSync(handler *WebSocketConnectionHandler) {
-	h.stats.ConnectionsInc()
-	defer h.stats.ConnectionsDec()
+	h.connectionOpened()
+	defer h.connectionClosed()
 	serverDone := h.ctx.Done()
 
 	for {
@@ -483,7 +490,7 @@ func (h *WebsocketHandler) handleConnectionSync(handler *WebSocketConnectionHand
 }
 
 func (h *WebsocketHandler) addConnection(conn net.Conn, handler *WebSocketConnectionHandler) error {
-	h.stats.ConnectionsInc()
+	h.connectionOpened()
 	h.connectionsMu.Lock()
 	defer h.connectionsMu.Unlock()
 	fd := socketFd(conn)
@@ -495,7 +502,7 @@ func (h *WebsocketHandler) addConnection(conn net.Conn, handler *WebSocketConnec
 }
 
 func (h *WebsocketHandler) removeConnection(conn net.Conn, handler *WebSocketConnectionHandler, fd int, closeKind wsproto.CloseKind) {
-	h.stats.ConnectionsDec()
+	h.connectionClosed()
 	h.connectionsMu.Lock()
 	delete(h.connections, fd)
 	h.connectionsMu.Unlock()
@@ -621,11 +628,25 @@ func (h *WebsocketHandler) closeAllConnections() {
 	h.connectionsMu.Unlock()
 
 	for _, handler := range handlers {
-		h.stats.ConnectionsDec()
+		h.connectionClosed()
 		handler.Close(true, wsproto.CloseKindGoingAway)
 	}
 }
 
+func (h *WebsocketHandler) connectionOpened() {
+	h.stats.ConnectionsInc()
+	if h.activeConnections != nil {
+		h.activeConnections.Add(1)
+	}
+}
+
+func (h *WebsocketHandler) connectionClosed() {
+	h.stats.ConnectionsDec()
+	if h.activeConnections != nil {
+		h.activeConnections.Add(-1)
+	}
+}
+
 type websocketResponseWriter struct {
 	id              string
 	protocol        wsproto.Proto
@@ -877,6 +898,7 @@ func (h *WebSocketConnectionHandler) writeErrorMessage(operationID string, err e
 	var gqlErr graphqlError
 
 	var poNotFoundErr *persistedoperation.PersistentOperationNotFoundError
+	var httpErr HttpError
 	switch {
 	case errors.As(err, &poNotFoundErr):
 		// We follow the same pattern of not mentioning the sha256hash
@@ -887,6 +909,13 @@ func (h *WebSocketConnectionHandler) writeErrorMessage(operationID string, err e
 				Code: ExtCodeErrPersistedQueryNotFound,
 			},
 		}
+	case errors.As(err, &httpErr) && httpErr.ExtensionCode() != "":
+		gqlErr = graphqlError{
+			Message: httpErr.Message(),
+			Extensions: &Extensions{
+				Code: httpErr.ExtensionCode(),
+			},
+		}
 	default:
 		gqlErr = graphqlError{Message: err.Error()}
 	}
@@ -1160,6 +1189,14 @@ func (h *WebSocketConnectionHandler) executeSubscription(registration *Subscript
 		}
 	}
 	resolveCtx = h.graphqlHandler.configureRateLimiting(resolveCtx)
+	if h.graphqlHandler.costRateLimiter != nil {
+		if err = h.graphqlHandler.costRateLimiter.Reserve(h.ctx, reqContext, nil); err != nil {
+			if wErr := h.writeErrorMessage(registration.msg.ID, err); wErr != nil {
+				h.logger.Warn("writing error message", zap.Error(wErr))
+			}
+			return
+		}
+	}
 
 	// Put in a closure to evaluate err after defer
 	defer func() {
@@ -2,6 +2,8 @@ package circuit
 
 import (
 	"errors"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -90,6 +92,90 @@ func (c *Manager) HasCircuits() bool {
 	return len(c.circuits) > 0
 }
 
+// ErrCircuitNotFound is returned when no circuit breaker exists for a subgraph
+var ErrCircuitNotFound = errors.New("circuit breaker not found")
+
+// ForceMode overrides the state of a circuit breaker
+type ForceMode string
+
+const (
+	// ForceNone lets the circuit breaker open and close based on the subgraph errors
+	ForceNone ForceMode = "none"
+	// ForceOpen keeps the circuit breaker open, so no request reaches the subgraph
+	ForceOpen ForceMode = "open"
+	// ForceClosed keeps the circuit breaker closed, so every request reaches the subgraph
+	ForceClosed ForceMode = "closed"
+)
+
+// CircuitState is a snapshot of the circuit breaker of a subgraph
+type CircuitState struct {
+	Subgraph string `json:"subgraph"`
+	// Circuit is the name of the circuit breaker. Subgraphs sharing a routing URL share a circuit breaker
+	// unless they have a custom configuration.
+	Circuit            string    `json:"circuit"`
+	Open               bool      `json:"open"`
+	Forced             ForceMode `json:"forced"`
+	ConcurrentCommands int64     `json:"concurrent_commands"`
+}
+
+// States returns the state of all circuit breakers sorted by subgraph name
+func (c *Manager) States() []CircuitState {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	states := make([]CircuitState, 0, len(c.circuits))
+	for sgName, cb := range c.circuits {
+		general := cb.Config().General
+
+		forced := ForceNone
+		if general.ForceOpen {
+			forced = ForceOpen
+		} else if general.ForcedClosed {
+			forced = ForceClosed
+		}
+
+		states = append(states, CircuitState{
+			Subgraph:           sgName,
+			Circuit:            cb.Name(),
+			Open:               cb.IsOpen(),
+			Forced:             forced,
+			ConcurrentCommands: cb.ConcurrentCommands(),
+		})
+	}
+
+	slices.SortFunc(states, func(a, b CircuitState) int {
+		return strings.Compare(a.Subgraph, b.Subgraph)
+	})
+
+	return states
+}
+
+// Force overrides the state of the circuit breaker of a subgraph until it is forced again with ForceNone
+// or the router is reloaded. Subgraphs sharing the circuit breaker are affected as well.
+func (c *Manager) Force(subgraph string, mode ForceMode) error {
+	cb := c.GetCircuitBreaker(subgraph)
+	if cb == nil {
+		return ErrCircuitNotFound
+	}
+
+	switch mode {
+	case ForceNone, ForceOpen, ForceClosed:
+	default:
+		return errors.New("invalid force mode, must be one of none, open or closed")
+	}
+
+	cfg := cb.Config()
+	cfg.General.ForceOpen = mode == ForceOpen
+	cfg.General.ForcedClosed = mode == ForceClosed
+	cb.SetConfigThreadSafe(cfg)
+
+	return nil
+}
+
 type ManagerOpts struct {
 	SubgraphCircuitBreakers map[string]CircuitBreakerConfig
 	MetricStore             metric.CircuitMetricStore
@@ -164,6 +250,16 @@ func (c *Manager) Initialize(opts ManagerOpts) error {
 	return joinErr
 }
 
+// NewCircuit creates a circuit breaker that is not registered with a Manager, e.g. for a single
+// endpoint of a load balanced subgraph
+func NewCircuit(name string, opts CircuitBreakerConfig) (*circuit.Circuit, error) {
+	configFunc, err := createConfiguration(opts)
+	if err != nil {
+		return nil, err
+	}
+	return circuit.NewCircuitFromConfig(name, configFunc(name)), nil
+}
+
 func createConfiguration(opts CircuitBreakerConfig) (circuit.CommandPropertiesConstructor, error) {
 	// This is only applicable for tests and is blocked by the config schema
 	if opts.NumBuckets > 0 {
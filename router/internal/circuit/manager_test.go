@@ -1,6 +1,7 @@
 package circuit
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -396,3 +397,78 @@ func TestManager_Initialize(t *testing.T) {
 	})
 
 }
+
+func TestManager_Force(t *testing.T) {
+	t.Parallel()
+
+	newManager := func(t *testing.T) *Manager {
+		t.Helper()
+
+		manager, err := NewManager(CircuitBreakerConfig{
+			Enabled:         true,
+			RollingDuration: 10 * time.Second,
+			NumBuckets:      10,
+			SleepWindow:     5 * time.Second,
+		})
+		require.NoError(t, err)
+
+		err = manager.Initialize(ManagerOpts{
+			AllGroupings: map[string]map[string]bool{
+				"http://test-url": {
+					"subgraph1": true,
+					"subgraph2": true,
+				},
+				"http://test-url2": {
+					"subgraph3": true,
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		return manager
+	}
+
+	t.Run("states are sorted by subgraph", func(t *testing.T) {
+		t.Parallel()
+
+		states := newManager(t).States()
+		require.Len(t, states, 3)
+		require.Equal(t, CircuitState{Subgraph: "subgraph1", Circuit: "http://test-url", Forced: ForceNone}, states[0])
+		require.Equal(t, "subgraph2", states[1].Subgraph)
+		require.Equal(t, "http://test-url2", states[2].Circuit)
+	})
+
+	t.Run("force open and closed", func(t *testing.T) {
+		t.Parallel()
+
+		manager := newManager(t)
+
+		require.NoError(t, manager.Force("subgraph1", ForceOpen))
+		require.True(t, manager.GetCircuitBreaker("subgraph1").IsOpen())
+
+		// Subgraphs sharing the circuit are affected as well
+		states := manager.States()
+		require.True(t, states[1].Open)
+		require.Equal(t, ForceOpen, states[1].Forced)
+		require.False(t, states[2].Open)
+
+		require.NoError(t, manager.Force("subgraph1", ForceClosed))
+		manager.GetCircuitBreaker("subgraph1").OpenCircuit(context.Background())
+		require.False(t, manager.GetCircuitBreaker("subgraph1").IsOpen())
+
+		require.NoError(t, manager.Force("subgraph1", ForceNone))
+		require.Equal(t, ForceNone, manager.States()[0].Forced)
+	})
+
+	t.Run("unknown subgraph", func(t *testing.T) {
+		t.Parallel()
+
+		require.ErrorIs(t, newManager(t).Force("unknown", ForceOpen), ErrCircuitNotFound)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		t.Parallel()
+
+		require.Error(t, newManager(t).Force("subgraph1", "half-open"))
+	})
+}
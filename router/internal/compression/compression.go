@@ -0,0 +1,266 @@
+// Package compression encodes and decodes the bodies of compressed requests and responses with
+// gzip, Zstandard and Brotli.
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings of the supported compression algorithms
+const (
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Brotli = "br"
+)
+
+// brotliLevel trades some compression ratio for speed, as the bodies are compressed on the fly
+const brotliLevel = 4
+
+const (
+	// zstdMaxWindow is the largest window a client or subgraph may use, as recommended for
+	// Zstandard over HTTP by RFC 9659. Larger windows are rejected instead of allocated.
+	zstdMaxWindow = 8 << 20
+	// zstdMaxMemory bounds the memory of a decoder, including the size of single segment frames
+	zstdMaxMemory = 64 << 20
+)
+
+// ErrSizeLimitExceeded is returned by the readers of NewReader when the decompressed body
+// exceeds the size limit
+var ErrSizeLimitExceeded = errors.New("decompressed body exceeds the size limit")
+
+// Writer compresses the data written to it. Flush compresses the pending data, so that the
+// receiver can decompress everything written so far, which is required for streamed responses.
+// Close must be called to complete the compressed stream. The writer must not be used afterward.
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// encoder is a compressor of one of the supported algorithms that can be reused
+type encoder interface {
+	Writer
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	Gzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	Zstd: {New: func() any {
+		// The small window keeps the memory per response low and is supported by all decoders
+		w, _ := zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.SpeedFastest),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+			zstd.WithWindowSize(128<<10),
+		)
+		return w
+	}},
+	Brotli: {New: func() any {
+		return brotli.NewWriterLevel(nil, brotliLevel)
+	}},
+}
+
+// IsSupported reports whether the content coding is one of the supported algorithms
+func IsSupported(encoding string) bool {
+	_, ok := encoderPools[encoding]
+	return ok
+}
+
+// pooledWriter returns the encoder to its pool when it is closed
+type pooledWriter struct {
+	encoder
+	pool *sync.Pool
+}
+
+func (w *pooledWriter) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	err := w.encoder.Close()
+	w.encoder.Reset(nil)
+	w.pool.Put(w.encoder)
+	w.encoder = nil
+	return err
+}
+
+// NewWriter returns a writer that compresses the data with the algorithm of the content coding
+// and writes it to w
+func NewWriter(w io.Writer, encoding string) (Writer, error) {
+	pool, ok := encoderPools[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+	enc := pool.Get().(encoder)
+	enc.Reset(w)
+	return &pooledWriter{encoder: enc, pool: pool}, nil
+}
+
+// decoder is a decompressor of one of the supported algorithms that can be reused
+type decoder interface {
+	io.Reader
+	// reset starts decompressing r. A nil reader releases the references to the previous body.
+	reset(r io.Reader) error
+}
+
+type gzipDecoder struct{ *gzip.Reader }
+
+func (d gzipDecoder) reset(r io.Reader) error {
+	if r == nil {
+		// gzip reads the header on reset, so it can't be reset to nil
+		return d.Reader.Reset(bytes.NewReader(nil))
+	}
+	return d.Reader.Reset(r)
+}
+
+type zstdDecoder struct{ *zstd.Decoder }
+
+func (d zstdDecoder) reset(r io.Reader) error {
+	return d.Decoder.Reset(r)
+}
+
+type brotliDecoder struct{ *brotli.Reader }
+
+func (d brotliDecoder) reset(r io.Reader) error {
+	return d.Reader.Reset(r)
+}
+
+var decoderPools = map[string]*sync.Pool{
+	Gzip: {New: func() any {
+		return gzipDecoder{Reader: new(gzip.Reader)}
+	}},
+	Zstd: {New: func() any {
+		d, err := zstd.NewReader(nil,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderLowmem(true),
+			zstd.WithDecoderMaxWindow(zstdMaxWindow),
+			zstd.WithDecoderMaxMemory(zstdMaxMemory),
+		)
+		if err != nil {
+			// The options are static, so creating the decoder can't fail
+			panic(err)
+		}
+		return zstdDecoder{Decoder: d}
+	}},
+	Brotli: {New: func() any {
+		return brotliDecoder{Reader: brotli.NewReader(nil)}
+	}},
+}
+
+// readCloser limits the size of the decompressed body. Closing it returns the decoder to its pool
+// and closes the compressed body.
+type readCloser struct {
+	decoder   decoder
+	pool      *sync.Pool
+	body      io.Closer
+	remaining int64
+}
+
+func (r *readCloser) Read(p []byte) (int, error) {
+	if r.decoder == nil {
+		return 0, io.ErrClosedPipe
+	}
+	if r.remaining <= 0 {
+		// Read a single byte to tell a body of exactly the limit from a larger one
+		var b [1]byte
+		if n, err := r.decoder.Read(b[:]); n == 0 {
+			return 0, err
+		}
+		return 0, ErrSizeLimitExceeded
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.decoder.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
+
+func (r *readCloser) Close() error {
+	if r.decoder != nil {
+		_ = r.decoder.reset(nil)
+		r.pool.Put(r.decoder)
+		r.decoder = nil
+	}
+	return r.body.Close()
+}
+
+// NewReader returns a reader that decompresses the body with the algorithm of the content coding.
+// Reading more than maxSize decompressed bytes fails with ErrSizeLimitExceeded, a maxSize of zero
+// doesn't limit the size. Closing the reader closes the body.
+func NewReader(body io.ReadCloser, encoding string, maxSize int64) (io.ReadCloser, error) {
+	pool, ok := decoderPools[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+
+	dec := pool.Get().(decoder)
+	if err := dec.reset(body); err != nil {
+		_ = dec.reset(nil)
+		pool.Put(dec)
+		return nil, err
+	}
+
+	if maxSize <= 0 {
+		maxSize = math.MaxInt64
+	}
+
+	return &readCloser{decoder: dec, pool: pool, body: body, remaining: maxSize}, nil
+}
+
+// Negotiate returns the encoding of the response to a request with the Accept-Encoding header.
+// The encoding with the highest quality value is selected. Encodings with the same quality are
+// selected in the order of the supported encodings. It returns an empty string when the client
+// accepts none of the supported encodings.
+func Negotiate(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64, 4)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			value, ok := strings.CutPrefix(strings.TrimSpace(param), "q=")
+			if !ok {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = min(max(parsed, 0), 1)
+			}
+		}
+		accepted[coding] = q
+	}
+
+	selected, selectedQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := accepted[encoding]
+		if !ok {
+			// The wildcard matches the encodings that are not listed explicitly
+			q = accepted["*"]
+		}
+		if q > selectedQ {
+			selected, selectedQ = encoding, q
+		}
+	}
+
+	return selected
+}
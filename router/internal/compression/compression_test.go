@@ -0,0 +1,121 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+
+	supported := []string{Zstd, Brotli, Gzip}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       string
+	}{
+		{name: "no header", acceptEncoding: "", expected: ""},
+		{name: "single encoding", acceptEncoding: "gzip", expected: Gzip},
+		{name: "server preference on equal quality", acceptEncoding: "gzip, deflate, br, zstd", expected: Zstd},
+		{name: "highest quality", acceptEncoding: "zstd;q=0.5, br;q=0.8, gzip", expected: Gzip},
+		{name: "rejected encoding", acceptEncoding: "zstd;q=0, br", expected: Brotli},
+		{name: "wildcard", acceptEncoding: "*", expected: Zstd},
+		{name: "wildcard with rejected encoding", acceptEncoding: "zstd;q=0, *;q=0.5", expected: Brotli},
+		{name: "unsupported encodings", acceptEncoding: "deflate, identity", expected: ""},
+		{name: "case insensitive", acceptEncoding: "BR", expected: Brotli},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, Negotiate(tc.acceptEncoding, supported))
+		})
+	}
+}
+
+func TestWriterAndReader(t *testing.T) {
+	t.Parallel()
+
+	payload := strings.Repeat(`{"data":{"employees":[{"id":1}]}}`, 100)
+
+	for _, encoding := range []string{Gzip, Zstd, Brotli} {
+		t.Run(encoding, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, encoding)
+			require.NoError(t, err)
+
+			_, err = w.Write([]byte(payload[:100]))
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			// The flushed data can be decompressed before the stream is complete
+			require.NotZero(t, buf.Len())
+
+			_, err = w.Write([]byte(payload[100:]))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			require.Less(t, buf.Len(), len(payload))
+
+			r, err := NewReader(io.NopCloser(&buf), encoding, int64(len(payload)))
+			require.NoError(t, err)
+
+			decompressed, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			require.Equal(t, payload, string(decompressed))
+
+			// The pooled decoder is reset for every body
+			r, err = NewReader(io.NopCloser(bytes.NewReader(compress(t, encoding, payload))), encoding, int64(len(payload))-1)
+			require.NoError(t, err)
+
+			_, err = io.ReadAll(r)
+			require.ErrorIs(t, err, ErrSizeLimitExceeded)
+			require.NoError(t, r.Close())
+		})
+	}
+
+	t.Run("rejects Zstandard windows above the limit", func(t *testing.T) {
+		t.Parallel()
+
+		// A frame with a 16 MiB window, made of the magic number, the frame header descriptor,
+		// the window descriptor and an empty last block
+		frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 14 << 3, 0x01, 0x00, 0x00}
+
+		r, err := NewReader(io.NopCloser(bytes.NewReader(frame)), Zstd, 0)
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(r)
+		require.ErrorIs(t, err, zstd.ErrWindowSizeExceeded)
+		require.NoError(t, r.Close())
+	})
+
+	t.Run("rejects unsupported encodings", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewWriter(io.Discard, "deflate")
+		require.ErrorContains(t, err, "unsupported content encoding 'deflate'")
+
+		_, err = NewReader(io.NopCloser(strings.NewReader("")), "deflate", 0)
+		require.ErrorContains(t, err, "unsupported content encoding 'deflate'")
+	})
+}
+
+func compress(t *testing.T, encoding, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, encoding)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
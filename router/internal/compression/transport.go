@@ -0,0 +1,155 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+)
+
+// subgraphAcceptEncoding is advertised to the subgraphs with compression enabled. The engine
+// decompresses gzip and deflate responses itself, the transport decompresses the others.
+const subgraphAcceptEncoding = "zstd, br, gzip, deflate"
+
+// Options configures the compression of the traffic to a subgraph
+type Options struct {
+	Enabled bool
+	// Encoding is the content coding of the compressed request bodies
+	Encoding string
+	// MinSize is the minimum size of a request body in bytes to be compressed
+	MinSize int64
+	// MaxDecompressedSize is the maximum size of a decompressed Zstandard or Brotli response body
+	// in bytes. Zero doesn't limit the size.
+	MaxDecompressedSize int64
+}
+
+// Config holds the compression options of all subgraphs
+type Config struct {
+	// Default applies to the subgraphs without options in SubgraphMap
+	Default     Options
+	SubgraphMap map[string]Options
+}
+
+// IsEnabled reports whether the traffic to any subgraph is compressed
+func (c *Config) IsEnabled() bool {
+	if c == nil {
+		return false
+	}
+	if c.Default.Enabled {
+		return true
+	}
+	for _, opts := range c.SubgraphMap {
+		if opts.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) options(subgraph string) Options {
+	if opts, ok := c.SubgraphMap[subgraph]; ok {
+		return opts
+	}
+	return c.Default
+}
+
+// Transport compresses the request bodies sent to the subgraphs with compression enabled and
+// advertises the supported encodings to them. Zstandard and Brotli responses are decompressed.
+type Transport struct {
+	next   http.RoundTripper
+	config *Config
+}
+
+func NewTransport(next http.RoundTripper, config *Config) *Transport {
+	return &Transport{
+		next:   next,
+		config: config,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	subgraph, _ := req.Context().Value(rcontext.CurrentSubgraphContextKey{}).(string)
+
+	opts := t.config.options(subgraph)
+	if !opts.Enabled {
+		return t.next.RoundTrip(req)
+	}
+
+	req, err := compressRequest(req, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressResponse(resp, opts.MaxDecompressedSize)
+}
+
+// compressRequest returns a copy of the request that advertises the supported encodings. Its body
+// is compressed when it is at least as large as the minimum size.
+func compressRequest(req *http.Request, opts Options) (*http.Request, error) {
+	out := req.Clone(req.Context())
+	out.Header.Set("Accept-Encoding", subgraphAcceptEncoding)
+
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return out, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) >= opts.MinSize {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, opts.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(body); err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+		out.Header.Set("Content-Encoding", opts.Encoding)
+	}
+
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	out.ContentLength = int64(len(body))
+
+	return out, nil
+}
+
+// decompressResponse decompresses Zstandard and Brotli response bodies up to the maximum size.
+// Other bodies are left to the engine.
+func decompressResponse(resp *http.Response, maxSize int64) (*http.Response, error) {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding != Zstd && encoding != Brotli {
+		return resp, nil
+	}
+
+	body, err := NewReader(resp.Body, encoding, maxSize)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
@@ -0,0 +1,113 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+)
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	query := `{"query":"{ employees { id details { forename surname } } }"}`
+	response := strings.Repeat(`{"id":1}`, 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("X-Content-Encoding", r.Header.Get("Content-Encoding"))
+
+		body := r.Body
+		if encoding := r.Header.Get("Content-Encoding"); encoding != "" {
+			var err error
+			body, err = NewReader(r.Body, encoding, 0)
+			require.NoError(t, err)
+		}
+		received, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, query, string(received))
+
+		encoding := Negotiate(r.Header.Get("Accept-Encoding"), []string{Brotli})
+		if encoding == "" {
+			_, _ = io.WriteString(w, response)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", encoding)
+		cw, err := NewWriter(w, encoding)
+		require.NoError(t, err)
+		_, _ = io.WriteString(cw, response)
+		require.NoError(t, cw.Close())
+	}))
+	t.Cleanup(server.Close)
+
+	transport := NewTransport(http.DefaultTransport, &Config{
+		SubgraphMap: map[string]Options{
+			"employees": {Enabled: true, Encoding: Zstd, MinSize: 10},
+			"products":  {Enabled: true, Encoding: Gzip, MinSize: 1024},
+			"accounts":  {Enabled: true, Encoding: Zstd, MinSize: 10, MaxDecompressedSize: 100},
+		},
+	})
+
+	send := func(t *testing.T, subgraph string) *http.Response {
+		t.Helper()
+
+		ctx := context.WithValue(t.Context(), rcontext.CurrentSubgraphContextKey{}, subgraph)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, bytes.NewReader([]byte(query)))
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = resp.Body.Close()
+		})
+		return resp
+	}
+
+	t.Run("compresses the request and decompresses the response", func(t *testing.T) {
+		t.Parallel()
+
+		resp := send(t, "employees")
+		require.Equal(t, Zstd, resp.Header.Get("X-Content-Encoding"))
+		require.Equal(t, subgraphAcceptEncoding, resp.Header.Get("X-Accept-Encoding"))
+		require.Empty(t, resp.Header.Get("Content-Encoding"))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, response, string(body))
+	})
+
+	t.Run("limits the size of the decompressed response", func(t *testing.T) {
+		t.Parallel()
+
+		resp := send(t, "accounts")
+		require.Empty(t, resp.Header.Get("Content-Encoding"))
+
+		_, err := io.ReadAll(resp.Body)
+		require.ErrorIs(t, err, ErrSizeLimitExceeded)
+	})
+
+	t.Run("does not compress requests below the minimum size", func(t *testing.T) {
+		t.Parallel()
+
+		resp := send(t, "products")
+		require.Empty(t, resp.Header.Get("X-Content-Encoding"))
+		require.Equal(t, subgraphAcceptEncoding, resp.Header.Get("X-Accept-Encoding"))
+	})
+
+	t.Run("leaves the traffic to other subgraphs untouched", func(t *testing.T) {
+		t.Parallel()
+
+		resp := send(t, "inventory")
+		require.Empty(t, resp.Header.Get("X-Content-Encoding"))
+		require.Equal(t, "gzip", resp.Header.Get("X-Accept-Encoding"))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"time"
 
 	"github.com/expr-lang/expr/file"
@@ -58,6 +59,8 @@ func (copyCtx Context) Clone() *Context {
 	}
 	copyCtx.Request.URL.Query = query
 
+	copyCtx.Request.Operation.Subgraphs = slices.Clone(copyCtx.Request.Operation.Subgraphs)
+
 	return &copyCtx
 }
 
@@ -72,6 +75,8 @@ type Request struct {
 	Operation Operation   `expr:"operation"`
 	Client    Client      `expr:"client"`
 	Error     error       `expr:"error"`
+	// FeatureFlag is the name of the feature flag the request is served with. It is empty for the base graph.
+	FeatureFlag string `expr:"featureFlag"`
 }
 
 type Response struct {
@@ -102,6 +107,10 @@ type Operation struct {
 	// The value can contain sensitive data and can be large, so it should be logged with care.
 	// Not populated for WebSocket subscriptions (see request.operation note in the docs).
 	Variables string `expr:"variables"`
+
+	// Subgraphs are the names of the subgraphs the operation was resolved with. They are known once
+	// the operation was executed.
+	Subgraphs []string `expr:"subgraphs"`
 }
 
 type Client struct {
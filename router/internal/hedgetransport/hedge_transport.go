@@ -0,0 +1,349 @@
+// Package hedgetransport sends a second identical request to a subgraph when the first one is slow
+// and uses whichever response arrives first.
+package hedgetransport
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+)
+
+const (
+	// latencySamples is the number of recent latencies the percentile is computed from
+	latencySamples = 1000
+	// minLatencySamples is the number of latencies that have to be observed before the percentile is used
+	minLatencySamples = 100
+	// percentileRefreshInterval is the number of observed latencies after which the percentile is recomputed
+	percentileRefreshInterval = 50
+)
+
+type Options struct {
+	Enabled bool
+	// Delay is the time after which the hedged request is sent. It is used until enough latencies
+	// were observed when a percentile is configured.
+	Delay time.Duration
+	// Percentile of the observed latencies after which the hedged request is sent, e.g. 95.
+	// Zero always uses the Delay.
+	Percentile float64
+	// MaxInFlight caps the number of hedged requests in flight to a subgraph
+	MaxInFlight int64
+}
+
+type (
+	ShouldHedgeFunc  func(req *http.Request) bool
+	HedgeContextFunc func(ctx context.Context) context.Context
+	OnHedgeFunc      func(req *http.Request, subgraph string, won bool)
+)
+
+// Config holds the hedging options of all subgraphs. It is shared by the transports of all
+// subgraphs, so the observed latencies and the hedges in flight are tracked per subgraph.
+type Config struct {
+	// Default applies to the subgraphs without options in SubgraphMap
+	Default     Options
+	SubgraphMap map[string]Options
+	// ShouldHedge decides whether a request can be hedged. Only idempotent requests must be hedged.
+	ShouldHedge ShouldHedgeFunc
+	// HedgeContext returns the context of the hedged request. It should give the hedged request its own
+	// per-fetch state, as both requests are in flight at the same time.
+	HedgeContext HedgeContextFunc
+	// OnHedge is called with the outcome of every hedged request
+	OnHedge OnHedgeFunc
+
+	hedgers sync.Map
+}
+
+// IsEnabled reports whether any subgraph is hedged
+func (c *Config) IsEnabled() bool {
+	if c == nil {
+		return false
+	}
+	if c.Default.Enabled {
+		return true
+	}
+	for _, opts := range c.SubgraphMap {
+		if opts.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// hedger returns the hedging state of the subgraph. It is nil when the subgraph is not hedged.
+func (c *Config) hedger(subgraph string) *hedger {
+	if h, ok := c.hedgers.Load(subgraph); ok {
+		return h.(*hedger)
+	}
+
+	opts, ok := c.SubgraphMap[subgraph]
+	if !ok {
+		opts = c.Default
+	}
+
+	var h *hedger
+	if opts.Enabled {
+		h = &hedger{opts: opts, samples: make([]time.Duration, 0, latencySamples)}
+	}
+
+	actual, _ := c.hedgers.LoadOrStore(subgraph, h)
+	return actual.(*hedger)
+}
+
+// hedger tracks the latencies and the hedged requests in flight of a subgraph
+type hedger struct {
+	opts     Options
+	inFlight atomic.Int64
+
+	mu         sync.Mutex
+	samples    []time.Duration
+	next       int
+	observed   int
+	percentile atomic.Int64
+}
+
+// delay returns the time to wait for the first response before the request is hedged
+func (h *hedger) delay() time.Duration {
+	if h.opts.Percentile > 0 {
+		if p := h.percentile.Load(); p > 0 {
+			return time.Duration(p)
+		}
+	}
+	return h.opts.Delay
+}
+
+// acquire reserves a hedged request. It returns false when the cap of hedged requests is reached.
+func (h *hedger) acquire() bool {
+	if h.opts.MaxInFlight <= 0 {
+		return false
+	}
+	if h.inFlight.Add(1) > h.opts.MaxInFlight {
+		h.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (h *hedger) release() {
+	h.inFlight.Add(-1)
+}
+
+// observe records the latency of a response and refreshes the percentile from time to time
+func (h *hedger) observe(latency time.Duration) {
+	if h.opts.Percentile <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < latencySamples {
+		h.samples = append(h.samples, latency)
+	} else {
+		h.samples[h.next] = latency
+		h.next = (h.next + 1) % latencySamples
+	}
+	h.observed++
+
+	if len(h.samples) < minLatencySamples || h.observed%percentileRefreshInterval != 0 {
+		return
+	}
+
+	sorted := slices.Clone(h.samples)
+	slices.Sort(sorted)
+	idx := int(math.Ceil(h.opts.Percentile/100*float64(len(sorted)))) - 1
+	idx = max(0, min(idx, len(sorted)-1))
+	h.percentile.Store(int64(sorted[idx]))
+}
+
+type HedgeTransport struct {
+	roundTripper http.RoundTripper
+	config       *Config
+}
+
+func NewHedgeTransport(roundTripper http.RoundTripper, config *Config) *HedgeTransport {
+	return &HedgeTransport{
+		roundTripper: roundTripper,
+		config:       config,
+	}
+}
+
+type result struct {
+	resp   *http.Response
+	err    error
+	hedged bool
+	cancel context.CancelFunc
+}
+
+// ok reports whether the result is a response that can be returned right away
+func (r result) ok() bool {
+	return r.err == nil && r.resp != nil && r.resp.StatusCode < http.StatusInternalServerError
+}
+
+// finish returns the response of the result. The context of the request is canceled when the
+// response body is closed.
+func (r result) finish() (*http.Response, error) {
+	if r.resp == nil || r.resp.Body == nil {
+		r.cancel()
+		return r.resp, r.err
+	}
+	r.resp.Body = &cancelBody{ReadCloser: r.resp.Body, cancel: r.cancel}
+	return r.resp, r.err
+}
+
+// discard cancels the request of the result and releases its response
+func (r result) discard() {
+	r.cancel()
+	if r.resp != nil && r.resp.Body != nil {
+		_ = r.resp.Body.Close()
+	}
+}
+
+func (t *HedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	subgraph, _ := req.Context().Value(rcontext.CurrentSubgraphContextKey{}).(string)
+
+	h := t.config.hedger(subgraph)
+	if h == nil || !replayable(req) || (t.config.ShouldHedge != nil && !t.config.ShouldHedge(req)) {
+		return t.roundTripper.RoundTrip(req)
+	}
+
+	results := make(chan result, 2)
+	start := time.Now()
+
+	cancelPrimary := t.send(req, req.Context(), false, results)
+
+	timer := time.NewTimer(h.delay())
+	defer timer.Stop()
+
+	select {
+	case r := <-results:
+		if r.err == nil {
+			h.observe(time.Since(start))
+		}
+		return r.finish()
+	case <-timer.C:
+	}
+
+	if !h.acquire() {
+		r := <-results
+		if r.err == nil {
+			h.observe(time.Since(start))
+		}
+		return r.finish()
+	}
+
+	hedgeReq, err := cloneRequest(req)
+	if err != nil {
+		h.release()
+		return (<-results).finish()
+	}
+
+	hedgeCtx := req.Context()
+	if t.config.HedgeContext != nil {
+		hedgeCtx = t.config.HedgeContext(hedgeCtx)
+	}
+
+	hedgeStart := time.Now()
+	cancelHedge := t.send(hedgeReq, hedgeCtx, true, results)
+
+	winner := <-results
+	if winner.hedged {
+		h.release()
+	}
+
+	if winner.ok() {
+		// The slower request is canceled right away and its response is discarded once it returns
+		if winner.hedged {
+			cancelPrimary()
+		} else {
+			cancelHedge()
+		}
+		go func() {
+			loser := <-results
+			if loser.hedged {
+				h.release()
+			}
+			loser.discard()
+		}()
+	} else {
+		// The other request can still succeed, so it is awaited before giving up
+		second := <-results
+		if second.hedged {
+			h.release()
+		}
+
+		if second.ok() {
+			winner.discard()
+			winner = second
+		} else {
+			second.discard()
+		}
+	}
+
+	if winner.err == nil {
+		if winner.hedged {
+			h.observe(time.Since(hedgeStart))
+		} else {
+			h.observe(time.Since(start))
+		}
+	}
+
+	if t.config.OnHedge != nil {
+		t.config.OnHedge(req, subgraph, winner.hedged)
+	}
+
+	return winner.finish()
+}
+
+// send starts the request in the background and delivers its result on the channel. The returned
+// function cancels the request.
+func (t *HedgeTransport) send(req *http.Request, ctx context.Context, hedged bool, results chan<- result) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	req = req.WithContext(ctx)
+
+	go func() {
+		resp, err := t.roundTripper.RoundTrip(req)
+		results <- result{resp: resp, err: err, hedged: hedged, cancel: cancel}
+	}()
+
+	return cancel
+}
+
+// replayable reports whether the request body can be sent a second time
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// cloneRequest clones the request with a fresh body, so it can be sent concurrently to the original
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return r, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = body
+	return r, nil
+}
+
+// cancelBody cancels the context of the request when the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
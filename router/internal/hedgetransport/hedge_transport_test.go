@@ -0,0 +1,189 @@
+package hedgetransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+)
+
+type hedgeCtxKey struct{}
+
+// slowFirstTransport answers the first request after the given delay and every other request right away
+type slowFirstTransport struct {
+	delay    time.Duration
+	requests atomic.Int64
+	canceled atomic.Int64
+}
+
+func (s *slowFirstTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := s.requests.Add(1)
+
+	if n == 1 {
+		select {
+		case <-time.After(s.delay):
+		case <-req.Context().Done():
+			s.canceled.Add(1)
+			return nil, req.Context().Err()
+		}
+	}
+
+	body := "primary"
+	if req.Context().Value(hedgeCtxKey{}) != nil {
+		body = "hedge"
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func newRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	ctx := context.WithValue(t.Context(), rcontext.CurrentSubgraphContextKey{}, "products")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://products/graphql", strings.NewReader(`{"query":"{a}"}`))
+	require.NoError(t, err)
+	return req
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	return string(body)
+}
+
+type hedgeRecorder struct {
+	mu   sync.Mutex
+	wins []bool
+}
+
+func (r *hedgeRecorder) onHedge(_ *http.Request, subgraph string, won bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wins = append(r.wins, won)
+}
+
+func (r *hedgeRecorder) outcomes() []bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.wins
+}
+
+func newConfig(opts Options, recorder *hedgeRecorder) *Config {
+	return &Config{
+		SubgraphMap: map[string]Options{"products": opts},
+		HedgeContext: func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, hedgeCtxKey{}, true)
+		},
+		OnHedge: recorder.onHedge,
+	}
+}
+
+func TestHedgeTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("does not hedge fast responses", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &hedgeRecorder{}
+		next := &slowFirstTransport{}
+		rt := NewHedgeTransport(next, newConfig(Options{Enabled: true, Delay: time.Second, MaxInFlight: 10}, recorder))
+
+		resp, err := rt.RoundTrip(newRequest(t))
+		require.NoError(t, err)
+		require.Equal(t, "primary", readBody(t, resp))
+		require.Equal(t, int64(1), next.requests.Load())
+		require.Empty(t, recorder.outcomes())
+	})
+
+	t.Run("uses the hedged response when the first request is slow", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &hedgeRecorder{}
+		next := &slowFirstTransport{delay: 10 * time.Second}
+		rt := NewHedgeTransport(next, newConfig(Options{Enabled: true, Delay: 10 * time.Millisecond, MaxInFlight: 10}, recorder))
+
+		resp, err := rt.RoundTrip(newRequest(t))
+		require.NoError(t, err)
+		require.Equal(t, "hedge", readBody(t, resp))
+		require.Equal(t, []bool{true}, recorder.outcomes())
+
+		// The slow request is canceled
+		require.Eventually(t, func() bool {
+			return next.canceled.Load() == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("does not hedge requests that are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &hedgeRecorder{}
+		next := &slowFirstTransport{delay: 50 * time.Millisecond}
+		cfg := newConfig(Options{Enabled: true, Delay: time.Millisecond, MaxInFlight: 10}, recorder)
+		cfg.ShouldHedge = func(_ *http.Request) bool { return false }
+		rt := NewHedgeTransport(next, cfg)
+
+		resp, err := rt.RoundTrip(newRequest(t))
+		require.NoError(t, err)
+		require.Equal(t, "primary", readBody(t, resp))
+		require.Equal(t, int64(1), next.requests.Load())
+	})
+
+	t.Run("does not hedge subgraphs without hedging", func(t *testing.T) {
+		t.Parallel()
+
+		next := &slowFirstTransport{delay: 50 * time.Millisecond}
+		rt := NewHedgeTransport(next, &Config{Default: Options{Enabled: true, Delay: time.Millisecond, MaxInFlight: 10}, SubgraphMap: map[string]Options{
+			"products": {Enabled: false},
+		}})
+
+		resp, err := rt.RoundTrip(newRequest(t))
+		require.NoError(t, err)
+		require.Equal(t, "primary", readBody(t, resp))
+		require.Equal(t, int64(1), next.requests.Load())
+	})
+
+	t.Run("caps the hedged requests in flight", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := newConfig(Options{Enabled: true, Delay: time.Millisecond, MaxInFlight: 1}, &hedgeRecorder{})
+		h := cfg.hedger("products")
+
+		require.True(t, h.acquire())
+		require.False(t, h.acquire())
+		h.release()
+		require.True(t, h.acquire())
+
+		next := &slowFirstTransport{delay: 50 * time.Millisecond}
+		rt := NewHedgeTransport(next, cfg)
+
+		resp, err := rt.RoundTrip(newRequest(t))
+		require.NoError(t, err)
+		require.Equal(t, "primary", readBody(t, resp))
+		require.Equal(t, int64(1), next.requests.Load())
+	})
+}
+
+func TestHedgerPercentile(t *testing.T) {
+	t.Parallel()
+
+	h := &hedger{opts: Options{Enabled: true, Delay: time.Second, Percentile: 90}}
+
+	for i := range minLatencySamples - 1 {
+		h.observe(time.Duration(i+1) * time.Millisecond)
+	}
+	require.Equal(t, time.Second, h.delay(), "the delay is used until enough latencies were observed")
+
+	h.observe(100 * time.Millisecond)
+	require.Equal(t, 90*time.Millisecond, h.delay())
+}
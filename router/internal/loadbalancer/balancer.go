@@ -0,0 +1,310 @@
+// Package loadbalancer spreads the requests to a subgraph over several endpoints. Endpoints are
+// ejected when they fail the active health checks or when their circuit breaker opens.
+package loadbalancer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	cepcircuit "github.com/cep21/circuit/v4"
+	"github.com/cespare/xxhash/v2"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/circuit"
+)
+
+type Strategy string
+
+const (
+	// StrategyRoundRobin rotates over the endpoints
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyLeastOutstandingRequests selects the endpoint with the fewest requests in flight
+	StrategyLeastOutstandingRequests Strategy = "least_outstanding_requests"
+	// StrategyConsistentHash sends the requests with the same hash key to the same endpoint
+	StrategyConsistentHash Strategy = "consistent_hash"
+)
+
+// EjectionReason is the reason an endpoint doesn't receive requests
+type EjectionReason string
+
+const (
+	// EjectionReasonHealthCheck is used when the endpoint fails the active health checks
+	EjectionReasonHealthCheck EjectionReason = "health_check"
+	// EjectionReasonOutlier is used when the circuit breaker of the endpoint is open
+	EjectionReasonOutlier EjectionReason = "outlier"
+)
+
+type Options struct {
+	Subgraph  string
+	Strategy  Strategy
+	Endpoints []string
+	// HashKey returns the value hashed by the consistent hash strategy
+	HashKey     func(req *http.Request) string
+	HealthCheck HealthCheckOptions
+	// CircuitBreaker ejects an endpoint while its circuit breaker is open. Every endpoint gets
+	// its own circuit breaker. No endpoint is ejected by it when it is disabled.
+	CircuitBreaker circuit.CircuitBreakerConfig
+	Logger         *zap.Logger
+}
+
+// Endpoint is a single instance of a load balanced subgraph
+type Endpoint struct {
+	url         *url.URL
+	outstanding atomic.Int64
+	// unhealthy is set while the endpoint fails the active health checks
+	unhealthy atomic.Bool
+	// circuit is nil when the circuit breaker is disabled for the subgraph
+	circuit *cepcircuit.Circuit
+
+	// successes and failures count the consecutive probe results. They are only accessed by the
+	// health check of the endpoint.
+	successes int
+	failures  int
+}
+
+// URL returns the URL of the endpoint
+func (e *Endpoint) URL() string {
+	return e.url.String()
+}
+
+// EjectionReason returns why the endpoint is ejected. It is empty when the endpoint receives requests.
+func (e *Endpoint) EjectionReason() EjectionReason {
+	if e.unhealthy.Load() {
+		return EjectionReasonHealthCheck
+	}
+	if e.circuit.IsOpen() {
+		return EjectionReasonOutlier
+	}
+	return ""
+}
+
+// Balancer selects the endpoint of every request to a subgraph
+type Balancer struct {
+	subgraph    string
+	strategy    Strategy
+	endpoints   []*Endpoint
+	hashKey     func(req *http.Request) string
+	healthCheck HealthCheckOptions
+	logger      *zap.Logger
+
+	next atomic.Uint64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(opts Options) (*Balancer, error) {
+	if len(opts.Endpoints) == 0 {
+		return nil, fmt.Errorf("subgraph '%s' has no load balancing endpoints", opts.Subgraph)
+	}
+
+	b := &Balancer{
+		subgraph:    opts.Subgraph,
+		strategy:    opts.Strategy,
+		hashKey:     opts.HashKey,
+		healthCheck: opts.HealthCheck.withDefaults(),
+		logger:      opts.Logger,
+		endpoints:   make([]*Endpoint, 0, len(opts.Endpoints)),
+	}
+
+	if b.logger == nil {
+		b.logger = zap.NewNop()
+	}
+
+	switch b.strategy {
+	case "":
+		b.strategy = StrategyRoundRobin
+	case StrategyRoundRobin, StrategyLeastOutstandingRequests, StrategyConsistentHash:
+	default:
+		return nil, fmt.Errorf("unknown load balancing strategy '%s' for subgraph '%s'", opts.Strategy, opts.Subgraph)
+	}
+
+	for _, rawURL := range opts.Endpoints {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid load balancing endpoint of subgraph '%s': %w", opts.Subgraph, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("load balancing endpoint '%s' of subgraph '%s' must be an absolute http or https URL", rawURL, opts.Subgraph)
+		}
+
+		endpoint := &Endpoint{url: u}
+
+		if opts.CircuitBreaker.Enabled {
+			endpoint.circuit, err = circuit.NewCircuit(opts.Subgraph+"@"+u.Host, opts.CircuitBreaker)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		b.endpoints = append(b.endpoints, endpoint)
+	}
+
+	return b, nil
+}
+
+// Subgraph returns the name of the load balanced subgraph
+func (b *Balancer) Subgraph() string {
+	return b.subgraph
+}
+
+// Endpoints returns all endpoints of the subgraph
+func (b *Balancer) Endpoints() []*Endpoint {
+	return b.endpoints
+}
+
+// candidates returns the endpoints in the order they are tried for a request. Endpoints failing the
+// health checks are left out unless every endpoint fails them. Endpoints with an open circuit breaker
+// stay in, so the circuit breaker can let requests through once it is half-open.
+func (b *Balancer) candidates(req *http.Request) []*Endpoint {
+	ordered := b.order(req)
+
+	healthy := make([]*Endpoint, 0, len(ordered))
+	for _, e := range ordered {
+		if !e.unhealthy.Load() {
+			healthy = append(healthy, e)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return ordered
+	}
+	return healthy
+}
+
+func (b *Balancer) order(req *http.Request) []*Endpoint {
+	n := len(b.endpoints)
+	ordered := make([]*Endpoint, 0, n)
+
+	switch b.strategy {
+	case StrategyConsistentHash:
+		var key string
+		if b.hashKey != nil {
+			key = b.hashKey(req)
+		}
+
+		// Rendezvous hashing only moves the keys of an ejected endpoint to the other endpoints
+		ordered = append(ordered, b.endpoints...)
+		scores := make(map[*Endpoint]uint64, n)
+		for _, e := range ordered {
+			scores[e] = xxhash.Sum64String(e.url.String() + "\x00" + key)
+		}
+		slices.SortFunc(ordered, func(x, y *Endpoint) int {
+			return cmp.Compare(scores[y], scores[x])
+		})
+	default:
+		start := int((b.next.Add(1) - 1) % uint64(n))
+		ordered = append(ordered, b.endpoints[start:]...)
+		ordered = append(ordered, b.endpoints[:start]...)
+
+		if b.strategy == StrategyLeastOutstandingRequests {
+			outstanding := make(map[*Endpoint]int64, n)
+			for _, e := range ordered {
+				outstanding[e] = e.outstanding.Load()
+			}
+			// The stable sort keeps the round robin order between endpoints with the same load
+			slices.SortStableFunc(ordered, func(x, y *Endpoint) int {
+				return cmp.Compare(outstanding[x], outstanding[y])
+			})
+		}
+	}
+
+	return ordered
+}
+
+// RoundTrip sends the request to the first candidate endpoint whose circuit breaker accepts it
+func (b *Balancer) RoundTrip(next http.RoundTripper, req *http.Request) (resp *http.Response, err error) {
+	candidates := b.candidates(req)
+
+	selection := SelectionFromContext(req.Context())
+	if selection != nil {
+		selection.Ejected = selection.Ejected[:0]
+		for _, e := range b.endpoints {
+			if e.EjectionReason() != "" {
+				selection.Ejected = append(selection.Ejected, e.URL())
+			}
+		}
+	}
+
+	for _, e := range candidates {
+		resp, err = e.roundTrip(next, req)
+
+		var circuitErr cepcircuit.Error
+		if errors.As(err, &circuitErr) && circuitErr.CircuitOpen() {
+			b.logger.Debug("Circuit breaker of endpoint open, trying the next one",
+				zap.String("subgraph_name", b.subgraph),
+				zap.String("endpoint", e.URL()),
+			)
+			continue
+		}
+
+		if selection != nil {
+			selection.Endpoint = e.URL()
+		}
+
+		return resp, err
+	}
+
+	return resp, err
+}
+
+func (e *Endpoint) roundTrip(next http.RoundTripper, req *http.Request) (resp *http.Response, err error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = e.url.Scheme
+	out.URL.Host = e.url.Host
+	out.Host = e.url.Host
+	if e.url.Path != "" {
+		out.URL.Path = e.url.Path
+		out.URL.RawPath = e.url.RawPath
+	}
+
+	e.outstanding.Add(1)
+	defer func() {
+		if resp == nil || resp.Body == nil {
+			e.outstanding.Add(-1)
+			return
+		}
+		// The request is outstanding until its response body is consumed
+		resp.Body = &trackedBody{ReadCloser: resp.Body, done: func() { e.outstanding.Add(-1) }}
+	}()
+
+	if e.circuit == nil {
+		return next.RoundTrip(out)
+	}
+
+	err = e.circuit.Run(out.Context(), func(_ context.Context) error {
+		resp, err = next.RoundTrip(out)
+		return err
+	})
+
+	return resp, err
+}
+
+// trackedBody calls done once when the response body is closed
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// Stop stops the health checks of the endpoints
+func (b *Balancer) Stop() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.wg.Wait()
+}
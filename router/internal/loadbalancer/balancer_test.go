@@ -0,0 +1,287 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router/internal/circuit"
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+)
+
+// roundTripperFunc answers every request with the host it was sent to
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func echoHost(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req, Header: http.Header{"X-Host": {req.URL.Host + req.URL.Path}}}, nil
+}
+
+func newSubgraphRequest(t *testing.T, subgraph string) *http.Request {
+	t.Helper()
+
+	ctx := context.WithValue(t.Context(), rcontext.CurrentSubgraphContextKey{}, subgraph)
+	ctx = WithSelection(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://products:4001/graphql?x=1", nil)
+	require.NoError(t, err)
+	return req
+}
+
+func send(t *testing.T, rt http.RoundTripper, req *http.Request) string {
+	t.Helper()
+
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "x=1", resp.Request.URL.RawQuery)
+	return resp.Header.Get("X-Host")
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(Options{Subgraph: "products"})
+	require.ErrorContains(t, err, "no load balancing endpoints")
+
+	_, err = New(Options{Subgraph: "products", Strategy: "random", Endpoints: []string{"http://a"}})
+	require.ErrorContains(t, err, "unknown load balancing strategy")
+
+	_, err = New(Options{Subgraph: "products", Endpoints: []string{"products-1:4001"}})
+	require.ErrorContains(t, err, "must be an absolute http or https URL")
+
+	b, err := New(Options{Subgraph: "products", Endpoints: []string{"http://a"}})
+	require.NoError(t, err)
+	require.Equal(t, StrategyRoundRobin, b.strategy)
+	require.Equal(t, "/health", b.healthCheck.Path)
+}
+
+func TestBalancer(t *testing.T) {
+	t.Parallel()
+
+	endpoints := []string{"http://a:4001/graphql", "http://b:4001/graphql", "http://c:4001"}
+
+	t.Run("rotates over the endpoints with round robin", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(Options{Subgraph: "products", Endpoints: endpoints})
+		require.NoError(t, err)
+		rt := NewTransport(roundTripperFunc(echoHost), map[string]*Balancer{"products": b})
+
+		var hosts []string
+		for range 4 {
+			hosts = append(hosts, send(t, rt, newSubgraphRequest(t, "products")))
+		}
+		require.Equal(t, []string{"a:4001/graphql", "b:4001/graphql", "c:4001/graphql", "a:4001/graphql"}, hosts)
+	})
+
+	t.Run("passes on the requests of other subgraphs", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(Options{Subgraph: "products", Endpoints: endpoints})
+		require.NoError(t, err)
+		rt := NewTransport(roundTripperFunc(echoHost), map[string]*Balancer{"products": b})
+
+		req := newSubgraphRequest(t, "employees")
+		require.Equal(t, "products:4001/graphql", send(t, rt, req))
+		require.Empty(t, SelectionFromContext(req.Context()).Endpoint)
+	})
+
+	t.Run("selects the endpoint with the least outstanding requests", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(Options{Subgraph: "products", Strategy: StrategyLeastOutstandingRequests, Endpoints: endpoints})
+		require.NoError(t, err)
+		rt := NewTransport(roundTripperFunc(echoHost), map[string]*Balancer{"products": b})
+
+		// The responses of a and b are not consumed yet
+		for range 2 {
+			_, err := rt.RoundTrip(newSubgraphRequest(t, "products"))
+			require.NoError(t, err)
+		}
+		require.Equal(t, int64(1), b.endpoints[0].outstanding.Load())
+
+		require.Equal(t, "c:4001/graphql", send(t, rt, newSubgraphRequest(t, "products")))
+		require.Equal(t, int64(0), b.endpoints[2].outstanding.Load())
+	})
+
+	t.Run("sends the same hash key to the same endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		var key atomic.Value
+		b, err := New(Options{
+			Subgraph:  "products",
+			Strategy:  StrategyConsistentHash,
+			Endpoints: endpoints,
+			HashKey: func(_ *http.Request) string {
+				return key.Load().(string)
+			},
+		})
+		require.NoError(t, err)
+		rt := NewTransport(roundTripperFunc(echoHost), map[string]*Balancer{"products": b})
+
+		selected := map[string]string{}
+		for _, k := range []string{"alice", "bob", "carol", "dave", "eve"} {
+			key.Store(k)
+			selected[k] = send(t, rt, newSubgraphRequest(t, "products"))
+			for range 3 {
+				require.Equal(t, selected[k], send(t, rt, newSubgraphRequest(t, "products")), k)
+			}
+		}
+
+		// Ejecting an endpoint only moves the keys it owned
+		b.endpoints[0].unhealthy.Store(true)
+		for k, host := range selected {
+			key.Store(k)
+			if host != "a:4001/graphql" {
+				require.Equal(t, host, send(t, rt, newSubgraphRequest(t, "products")), k)
+			}
+		}
+	})
+
+	t.Run("skips endpoints failing the health checks", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(Options{Subgraph: "products", Endpoints: endpoints})
+		require.NoError(t, err)
+		rt := NewTransport(roundTripperFunc(echoHost), map[string]*Balancer{"products": b})
+
+		b.endpoints[1].unhealthy.Store(true)
+
+		req := newSubgraphRequest(t, "products")
+		for range 6 {
+			require.NotEqual(t, "b:4001/graphql", send(t, rt, req))
+		}
+		require.Equal(t, []string{"http://b:4001/graphql"}, SelectionFromContext(req.Context()).Ejected)
+
+		// Every endpoint is used again when all of them fail the health checks
+		b.endpoints[0].unhealthy.Store(true)
+		b.endpoints[2].unhealthy.Store(true)
+		seen := map[string]bool{}
+		for range 3 {
+			seen[send(t, rt, newSubgraphRequest(t, "products"))] = true
+		}
+		require.Len(t, seen, 3)
+	})
+
+	t.Run("ejects endpoints with an open circuit breaker", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := New(Options{
+			Subgraph:  "products",
+			Endpoints: endpoints[:2],
+			CircuitBreaker: circuit.CircuitBreakerConfig{
+				Enabled:                  true,
+				ErrorThresholdPercentage: 50,
+				RequestThreshold:         1,
+				SleepWindow:              time.Minute,
+				RollingDuration:          10 * time.Second,
+				NumBuckets:               10,
+				ExecutionTimeout:         time.Minute,
+				MaxConcurrentRequests:    -1,
+			},
+		})
+		require.NoError(t, err)
+
+		rt := NewTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "a:4001" {
+				return nil, errors.New("connection refused")
+			}
+			return echoHost(req)
+		}), map[string]*Balancer{"products": b})
+
+		_, err = rt.RoundTrip(newSubgraphRequest(t, "products"))
+		require.ErrorContains(t, err, "connection refused")
+		require.Equal(t, EjectionReasonOutlier, b.endpoints[0].EjectionReason())
+
+		req := newSubgraphRequest(t, "products")
+		for range 4 {
+			require.Equal(t, "b:4001/graphql", send(t, rt, req))
+		}
+
+		selection := SelectionFromContext(req.Context())
+		require.Equal(t, "http://b:4001/graphql", selection.Endpoint)
+		require.Equal(t, []string{"http://a:4001/graphql"}, selection.Ejected)
+	})
+}
+
+func TestHealthChecks(t *testing.T) {
+	t.Parallel()
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" || !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	b, err := New(Options{
+		Subgraph:  "products",
+		Endpoints: []string{server.URL + "/graphql"},
+		HealthCheck: HealthCheckOptions{
+			Enabled:            true,
+			Path:               "/ready",
+			Interval:           10 * time.Millisecond,
+			UnhealthyThreshold: 2,
+			HealthyThreshold:   2,
+			Client:             server.Client(),
+		},
+	})
+	require.NoError(t, err)
+
+	b.StartHealthChecks(t.Context())
+	t.Cleanup(b.Stop)
+
+	endpoint := b.Endpoints()[0]
+
+	healthy.Store(false)
+	require.Eventually(t, func() bool {
+		return endpoint.EjectionReason() == EjectionReasonHealthCheck
+	}, 5*time.Second, 10*time.Millisecond)
+
+	healthy.Store(true)
+	require.Eventually(t, func() bool {
+		return endpoint.EjectionReason() == ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRecordProbe(t *testing.T) {
+	t.Parallel()
+
+	b, err := New(Options{
+		Subgraph:    "products",
+		Endpoints:   []string{"http://a"},
+		HealthCheck: HealthCheckOptions{UnhealthyThreshold: 3, HealthyThreshold: 2},
+	})
+	require.NoError(t, err)
+	e := b.endpoints[0]
+
+	b.recordProbe(e, false)
+	b.recordProbe(e, false)
+	b.recordProbe(e, true)
+	b.recordProbe(e, false)
+	b.recordProbe(e, false)
+	require.False(t, e.unhealthy.Load(), "failures must be consecutive")
+
+	b.recordProbe(e, false)
+	require.True(t, e.unhealthy.Load())
+
+	b.recordProbe(e, true)
+	require.True(t, e.unhealthy.Load())
+	b.recordProbe(e, true)
+	require.False(t, e.unhealthy.Load())
+}
@@ -0,0 +1,124 @@
+package loadbalancer
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HealthCheckOptions configures the active health checks of the endpoints
+type HealthCheckOptions struct {
+	Enabled            bool
+	Path               string
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold int
+	HealthyThreshold   int
+	// Client sends the probes. It should use the TLS configuration of the subgraph.
+	Client *http.Client
+}
+
+func (o HealthCheckOptions) withDefaults() HealthCheckOptions {
+	if o.Path == "" {
+		o.Path = "/health"
+	}
+	if o.Interval <= 0 {
+		o.Interval = 10 * time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 2 * time.Second
+	}
+	if o.UnhealthyThreshold <= 0 {
+		o.UnhealthyThreshold = 3
+	}
+	if o.HealthyThreshold <= 0 {
+		o.HealthyThreshold = 2
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	return o
+}
+
+// StartHealthChecks probes every endpoint until Stop is called or the context is done. Endpoints
+// are healthy until they fail the first probes.
+func (b *Balancer) StartHealthChecks(ctx context.Context) {
+	if !b.healthCheck.Enabled {
+		return
+	}
+
+	ctx, b.cancel = context.WithCancel(ctx)
+
+	for _, e := range b.endpoints {
+		b.wg.Go(func() {
+			b.runHealthCheck(ctx, e)
+		})
+	}
+}
+
+func (b *Balancer) runHealthCheck(ctx context.Context, e *Endpoint) {
+	ticker := time.NewTicker(b.healthCheck.Interval)
+	defer ticker.Stop()
+
+	for {
+		b.recordProbe(e, b.probe(ctx, e))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe reports whether the health check path of the endpoint answers with a 2xx status code
+func (b *Balancer) probe(ctx context.Context, e *Endpoint) bool {
+	ctx, cancel := context.WithTimeout(ctx, b.healthCheck.Timeout)
+	defer cancel()
+
+	probeURL := url.URL{Scheme: e.url.Scheme, Host: e.url.Host, Path: b.healthCheck.Path}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL.String(), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := b.healthCheck.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// recordProbe ejects the endpoint after UnhealthyThreshold failed probes in a row and restores it
+// after HealthyThreshold successful probes in a row
+func (b *Balancer) recordProbe(e *Endpoint, healthy bool) {
+	if healthy {
+		e.failures = 0
+		e.successes++
+		if e.unhealthy.Load() && e.successes >= b.healthCheck.HealthyThreshold {
+			e.unhealthy.Store(false)
+			b.logger.Info("Subgraph endpoint passed the health checks and is restored",
+				zap.String("subgraph_name", b.subgraph),
+				zap.String("endpoint", e.URL()),
+			)
+		}
+		return
+	}
+
+	e.successes = 0
+	e.failures++
+	if !e.unhealthy.Load() && e.failures >= b.healthCheck.UnhealthyThreshold {
+		e.unhealthy.Store(true)
+		b.logger.Warn("Subgraph endpoint failed the health checks and is ejected",
+			zap.String("subgraph_name", b.subgraph),
+			zap.String("endpoint", e.URL()),
+			zap.Int("failed_probes", e.failures),
+		)
+	}
+}
@@ -0,0 +1,55 @@
+package loadbalancer
+
+import (
+	"context"
+	"net/http"
+
+	rcontext "github.com/wundergraph/cosmo/router/internal/context"
+)
+
+// Transport sends the requests of load balanced subgraphs to one of their endpoints. The requests
+// of other subgraphs are passed on unchanged.
+type Transport struct {
+	next      http.RoundTripper
+	balancers map[string]*Balancer
+}
+
+func NewTransport(next http.RoundTripper, balancers map[string]*Balancer) *Transport {
+	return &Transport{
+		next:      next,
+		balancers: balancers,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	subgraph, _ := req.Context().Value(rcontext.CurrentSubgraphContextKey{}).(string)
+
+	balancer, ok := t.balancers[subgraph]
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	return balancer.RoundTrip(t.next, req)
+}
+
+type selectionContextKey struct{}
+
+// Selection records the endpoint a subgraph request was sent to
+type Selection struct {
+	// Endpoint is the URL of the endpoint that handled the request. It is empty when every
+	// endpoint rejected the request.
+	Endpoint string
+	// Ejected holds the URLs of the endpoints that were ejected when the request was sent
+	Ejected []string
+}
+
+// WithSelection returns a context carrying a fresh per-fetch selection
+func WithSelection(ctx context.Context) context.Context {
+	return context.WithValue(ctx, selectionContextKey{}, &Selection{})
+}
+
+// SelectionFromContext returns the selection of the current fetch
+func SelectionFromContext(ctx context.Context) *Selection {
+	value, _ := ctx.Value(selectionContextKey{}).(*Selection)
+	return value
+}
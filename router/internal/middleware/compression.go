@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/compression"
 )
 
 func HandleCompression(logger *zap.Logger) func(http.Handler) http.Handler {
@@ -24,7 +26,7 @@ func HandleCompression(logger *zap.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
-			switch strings.TrimSpace(encodings[0]) {
+			switch encoding := strings.TrimSpace(encodings[0]); encoding {
 			case "gzip":
 				gzr, err := gzip.NewReader(r.Body)
 				if err != nil {
@@ -47,6 +49,26 @@ func HandleCompression(logger *zap.Logger) func(http.Handler) http.Handler {
 
 				r.Body = gzr
 
+				// Content-Length is no longer valid after decompression
+				r.Header.Del("Content-Length")
+				r.ContentLength = -1
+			case compression.Zstd, compression.Brotli:
+				// The decompressed body is limited by the RequestSize middleware
+				body, err := compression.NewReader(r.Body, encoding, 0)
+				if err != nil {
+					logger.Error("failed to create decompression reader", zap.String("encoding", encoding), zap.Error(err))
+					http.Error(w, "invalid "+encoding+" payload", http.StatusUnprocessableEntity)
+					return
+				}
+
+				defer func() {
+					if err := body.Close(); err != nil {
+						logger.Error("failed to close original body", zap.Error(err))
+					}
+				}()
+
+				r.Body = body
+
 				// Content-Length is no longer valid after decompression
 				r.Header.Del("Content-Length")
 				r.ContentLength = -1
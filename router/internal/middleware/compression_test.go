@@ -12,6 +12,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/compression"
 )
 
 func TestHandleCompression(t *testing.T) {
@@ -107,6 +109,38 @@ func TestHandleCompression(t *testing.T) {
 
 	})
 
+	t.Run("Should successfully process zstd and brotli compressed payloads", func(t *testing.T) {
+		t.Parallel()
+
+		for _, encoding := range []string{compression.Zstd, compression.Brotli} {
+			recorder := httptest.NewRecorder()
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Emptyf(t, r.Header.Get("Content-Encoding"), "Content-Encoding header should be removed")
+
+				b, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.Equal(t, "test", string(b))
+
+				w.WriteHeader(http.StatusOK)
+			})
+
+			var buf bytes.Buffer
+			w, err := compression.NewWriter(&buf, encoding)
+			require.NoError(t, err)
+
+			_, err = w.Write([]byte("test"))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/", &buf)
+			req.Header.Set("Content-Encoding", encoding)
+
+			HandleCompression(zap.NewNop())(next).ServeHTTP(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Code, encoding)
+		}
+	})
+
 	t.Run("Should return status 422 for invalid gzip payload", func(t *testing.T) {
 		t.Parallel()
 
@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/wundergraph/cosmo/router/internal/compression"
+)
+
+type ResponseCompressionOptions struct {
+	// Encodings are the supported content codings in order of preference. The preference decides
+	// between encodings that the client accepts with the same quality.
+	Encodings []string
+	// MinSize is the minimum size of a response body in bytes to be compressed. Flushed responses
+	// are compressed regardless of their size.
+	MinSize int
+	// ContentTypes are the media types of the responses to compress
+	ContentTypes []string
+}
+
+// ResponseCompression compresses the responses with the encoding negotiated from the
+// Accept-Encoding header of the request. Flushing the response flushes the compressor, so that
+// streamed responses like SSE and multipart/mixed can be decompressed by the client as they arrive.
+func ResponseCompression(opts ResponseCompressionOptions) (func(http.Handler) http.Handler, error) {
+	if len(opts.Encodings) == 0 {
+		return nil, errors.New("at least one response compression encoding is required")
+	}
+	for _, encoding := range opts.Encodings {
+		if !compression.IsSupported(encoding) {
+			return nil, fmt.Errorf("unsupported response compression encoding '%s'", encoding)
+		}
+	}
+
+	contentTypes := make([]string, 0, len(opts.ContentTypes))
+	for _, contentType := range opts.ContentTypes {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compressible content type '%s': %w", contentType, err)
+		}
+		contentTypes = append(contentTypes, mediaType)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			// HEAD responses are not compressed, as some proxies mishandle their headers
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			encoding := compression.Negotiate(r.Header.Get("Accept-Encoding"), opts.Encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        opts.MinSize,
+				contentTypes:   contentTypes,
+			}
+			defer func() {
+				_ = cw.Close()
+			}()
+
+			next.ServeHTTP(cw, r)
+		})
+	}, nil
+}
+
+// compressResponseWriter buffers the beginning of the response until it knows whether the response
+// is large enough to be compressed
+type compressResponseWriter struct {
+	http.ResponseWriter
+
+	encoding     string
+	minSize      int
+	contentTypes []string
+
+	// code is the status code until the headers are written
+	code int
+	buf  []byte
+	// cw is set once the response is compressed
+	cw compression.Writer
+	// plain is set once the response is written uncompressed
+	plain bool
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	// Informational responses are sent right away
+	if code >= 100 && code <= 199 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if w.cw != nil {
+		return w.cw.Write(p)
+	}
+	if w.plain {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	if !w.compressible() {
+		return len(p), w.startPlain()
+	}
+
+	if len(w.buf) < w.minSize && w.contentLength() < w.minSize {
+		return len(p), nil
+	}
+
+	return len(p), w.startCompression()
+}
+
+// Flush writes the buffered response. A response that is flushed before the minimum size is
+// reached is compressed when its content type is compressible, as streamed responses are expected
+// to grow.
+func (w *compressResponseWriter) Flush() {
+	if w.cw == nil && !w.plain {
+		var err error
+		if w.compressible() {
+			err = w.startCompression()
+		} else {
+			err = w.startPlain()
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if w.cw != nil {
+		if err := w.cw.Flush(); err != nil {
+			return
+		}
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes the remaining response and completes the compressed stream
+func (w *compressResponseWriter) Close() error {
+	if w.cw == nil && !w.plain {
+		if len(w.buf) > 0 && len(w.buf) >= w.minSize && w.compressible() {
+			if err := w.startCompression(); err != nil {
+				return err
+			}
+		} else {
+			return w.startPlain()
+		}
+	}
+
+	if w.cw != nil {
+		err := w.cw.Close()
+		w.cw = nil
+		return err
+	}
+
+	return nil
+}
+
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker interface is not supported")
+}
+
+// compressible reports whether the response can be compressed. The content type is detected from
+// the buffered response when it is not set.
+func (w *compressResponseWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if !bodyAllowedForStatus(w.code) {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		if len(w.buf) == 0 {
+			return false
+		}
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(w.contentTypes, mediaType)
+}
+
+// contentLength returns the Content-Length set by the handler or zero if it is unknown
+func (w *compressResponseWriter) contentLength() int {
+	length, _ := strconv.Atoi(w.Header().Get("Content-Length"))
+	return length
+}
+
+func (w *compressResponseWriter) writeHeader() {
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+func (w *compressResponseWriter) startCompression() error {
+	cw, err := compression.NewWriter(w.ResponseWriter, w.encoding)
+	if err != nil {
+		return err
+	}
+
+	header := w.Header()
+	header.Set("Content-Encoding", w.encoding)
+	// The length and the ranges of the compressed body differ from the original body
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	w.writeHeader()
+
+	w.cw = cw
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err = w.cw.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *compressResponseWriter) startPlain() error {
+	w.plain = true
+	w.writeHeader()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// bodyAllowedForStatus reports whether a response with the status code can have a body
+func bodyAllowedForStatus(code int) bool {
+	switch {
+	case code >= 100 && code <= 199:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
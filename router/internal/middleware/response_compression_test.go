@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router/internal/compression"
+)
+
+func TestResponseCompression(t *testing.T) {
+	t.Parallel()
+
+	wrapper, err := ResponseCompression(ResponseCompressionOptions{
+		Encodings:    []string{compression.Zstd, compression.Brotli, compression.Gzip},
+		MinSize:      1024,
+		ContentTypes: []string{"application/json", "text/event-stream"},
+	})
+	require.NoError(t, err)
+
+	large := strings.Repeat(`{"id":1}`, 200)
+
+	serve := func(handler http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		wrapper(handler).ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	decompress := func(t *testing.T, recorder *httptest.ResponseRecorder) string {
+		t.Helper()
+
+		r, err := compression.NewReader(io.NopCloser(recorder.Body), recorder.Header().Get("Content-Encoding"), 0)
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	writeJSON := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, body)
+		}
+	}
+
+	t.Run("compresses with the negotiated encoding", func(t *testing.T) {
+		t.Parallel()
+
+		for acceptEncoding, expected := range map[string]string{
+			"gzip, deflate, br, zstd": compression.Zstd,
+			"gzip, br":                compression.Brotli,
+			"gzip":                    compression.Gzip,
+		} {
+			recorder := serve(writeJSON(large), acceptEncoding)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, expected, recorder.Header().Get("Content-Encoding"))
+			require.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+			require.Equal(t, large, decompress(t, recorder))
+		}
+	})
+
+	t.Run("does not compress small responses", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(writeJSON(`{"data":{}}`), "zstd")
+		require.Empty(t, recorder.Header().Get("Content-Encoding"))
+		require.Equal(t, `{"data":{}}`, recorder.Body.String())
+	})
+
+	t.Run("does not compress other content types", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = io.WriteString(w, large)
+		}, "br")
+		require.Empty(t, recorder.Header().Get("Content-Encoding"))
+		require.Equal(t, large, recorder.Body.String())
+	})
+
+	t.Run("does not compress when the client accepts no supported encoding", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(writeJSON(large), "deflate")
+		require.Empty(t, recorder.Header().Get("Content-Encoding"))
+		require.Equal(t, large, recorder.Body.String())
+	})
+
+	t.Run("flushes streamed responses through the compressor", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		req.Header.Set("Accept-Encoding", "br")
+
+		wrapper(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "event: next\ndata: {\"data\":{}}\n\n")
+			w.(http.Flusher).Flush()
+			// The first event is sent before the response is complete, although it is below the minimum size
+			require.NotZero(t, recorder.Body.Len())
+			_, _ = io.WriteString(w, "event: complete\ndata: \n\n")
+		})).ServeHTTP(recorder, req)
+
+		require.True(t, recorder.Flushed)
+		require.Equal(t, compression.Brotli, recorder.Header().Get("Content-Encoding"))
+		require.Equal(t, "event: next\ndata: {\"data\":{}}\n\nevent: complete\ndata: \n\n", decompress(t, recorder))
+	})
+
+	t.Run("rejects unsupported encodings", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ResponseCompression(ResponseCompressionOptions{Encodings: []string{"deflate"}})
+		require.ErrorContains(t, err, "unsupported response compression encoding 'deflate'")
+	})
+}
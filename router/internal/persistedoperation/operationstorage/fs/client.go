@@ -2,12 +2,16 @@ package fs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation/pqlmanifest"
 )
 
 type client struct {
@@ -69,4 +73,90 @@ func (c client) persistedOperation(clientName string, sha256Hash string) ([]byte
 	return []byte(po.Body), nil
 }
 
+// ReadManifest reads and parses a PQL manifest at the given path relative to the storage path.
+// The file is read unconditionally, modifiedSince is ignored.
+func (c client) ReadManifest(_ context.Context, objectPath string, _ time.Time) (*pqlmanifest.Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(c.path, objectPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read manifest file: %w: %w", pqlmanifest.ErrManifestNotFound, err)
+		}
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	return pqlmanifest.ParseManifest(data)
+}
+
+// ReadManifestVersion reads and parses a PQL manifest at the given path relative to the storage
+// path and returns the SHA-256 hash of the file as its version.
+func (c client) ReadManifestVersion(_ context.Context, objectPath string) (*pqlmanifest.Manifest, string, error) {
+	data, err := os.ReadFile(filepath.Join(c.path, objectPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("failed to read manifest file: %w: %w", pqlmanifest.ErrManifestNotFound, err)
+		}
+		return nil, "", fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	manifest, err := pqlmanifest.ParseManifest(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return manifest, fileVersion(data), nil
+}
+
+// WriteManifest writes the manifest JSON data at the given path relative to the storage path, if
+// the file still has the given version or does not exist when the version is empty.
+// The data is written to a temporary file first, so that readers never see a partial manifest.
+// The version check and the rename are not atomic, so processes writing the same file at the same
+// moment can still overwrite each other. Use S3 to share the manifest between router instances.
+func (c client) WriteManifest(_ context.Context, objectPath string, data []byte, version string) error {
+	manifestPath := filepath.Join(c.path, objectPath)
+
+	current, err := os.ReadFile(manifestPath)
+	switch {
+	case os.IsNotExist(err):
+		if version != "" {
+			return fmt.Errorf("failed to write manifest file: %w: the file was removed", pqlmanifest.ErrManifestModified)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to read manifest file: %w", err)
+	case fileVersion(current) != version:
+		return fmt.Errorf("failed to write manifest file: %w", pqlmanifest.ErrManifestModified)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(manifestPath), filepath.Base(manifestPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), manifestPath); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	return nil
+}
+
+func fileVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (c client) Close() {}
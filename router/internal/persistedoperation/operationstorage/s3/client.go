@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -142,12 +143,71 @@ func (c Client) ReadManifest(ctx context.Context, objectPath string, modifiedSin
 		if errors.As(err, &minioErr) && minioErr.StatusCode == http.StatusNotModified {
 			return nil, nil
 		}
+		if errors.As(err, &minioErr) && minioErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("failed to read manifest from S3: %w: %w", pqlmanifest.ErrManifestNotFound, err)
+		}
 		return nil, fmt.Errorf("failed to read manifest from S3: %w", err)
 	}
 
 	return pqlmanifest.ParseManifest(data)
 }
 
+// ReadManifestVersion fetches and parses a PQL manifest from S3 at the given object path and
+// returns the ETag of the object as its version.
+func (c Client) ReadManifestVersion(ctx context.Context, objectPath string) (*pqlmanifest.Manifest, string, error) {
+	minioReader, err := c.client.GetObject(ctx, c.options.BucketName, objectPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get manifest from S3: %w", err)
+	}
+	defer func() {
+		_ = minioReader.Close()
+	}()
+
+	info, err := minioReader.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, "", fmt.Errorf("failed to read manifest from S3: %w: %w", pqlmanifest.ErrManifestNotFound, err)
+		}
+		return nil, "", fmt.Errorf("failed to read manifest from S3: %w", err)
+	}
+
+	data, err := decompressAndRead(minioReader, objectPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest from S3: %w", err)
+	}
+
+	manifest, err := pqlmanifest.ParseManifest(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return manifest, info.ETag, nil
+}
+
+// WriteManifest uploads the manifest JSON data to S3 at the given object path. The upload is
+// conditional: it only succeeds if the ETag of the object is the given version, or if the object
+// does not exist when the version is empty. The bucket must support conditional writes.
+func (c Client) WriteManifest(ctx context.Context, objectPath string, data []byte, version string) error {
+	opts := minio.PutObjectOptions{
+		ContentType: "application/json",
+	}
+	if version == "" {
+		opts.SetMatchETagExcept("*")
+	} else {
+		opts.SetMatchETag(version)
+	}
+
+	_, err := c.client.PutObject(ctx, c.options.BucketName, objectPath, bytes.NewReader(data), int64(len(data)), opts)
+	if err != nil {
+		switch minio.ToErrorResponse(err).StatusCode {
+		// S3 returns 409 Conflict when a concurrent conditional write to the object is in progress
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			return fmt.Errorf("failed to write manifest to S3: %w: %w", pqlmanifest.ErrManifestModified, err)
+		}
+		return fmt.Errorf("failed to write manifest to S3: %w", err)
+	}
+	return nil
+}
+
 // decompressAndRead reads the full content from a reader, decompressing
 // based on the file extension (.gz, .zst). Plain content is read as-is.
 func decompressAndRead(r io.Reader, objectPath string) ([]byte, error) {
@@ -3,11 +3,17 @@ package s3
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation/pqlmanifest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestDecompressAndRead(t *testing.T) {
@@ -88,3 +94,51 @@ func TestDecompressAndRead(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestWriteManifest(t *testing.T) {
+	t.Parallel()
+
+	const etag = "5d41402abc4b2a76b9719d911017c592"
+
+	var object []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+
+		matches := object == nil && r.Header.Get("If-None-Match") == "*" ||
+			object != nil && r.Header.Get("If-Match") == `"`+etag+`"`
+		if !matches {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = io.WriteString(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+
+		// The body is sent with signed chunks
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		object = body
+		w.Header().Set("ETag", `"`+etag+`"`)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(strings.TrimPrefix(server.URL, "http://"), &Options{
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+		Region:          "us-east-1",
+		BucketName:      "manifests",
+		TraceProvider:   sdktrace.NewTracerProvider(),
+	})
+	require.NoError(t, err)
+
+	// The manifest must not exist yet
+	require.NoError(t, client.WriteManifest(t.Context(), "manifest.json", []byte(`{"version":1}`), ""))
+	require.Contains(t, string(object), `{"version":1}`)
+
+	err = client.WriteManifest(t.Context(), "manifest.json", []byte(`{"version":2}`), "")
+	require.ErrorIs(t, err, pqlmanifest.ErrManifestModified)
+
+	err = client.WriteManifest(t.Context(), "manifest.json", []byte(`{"version":2}`), "outdated")
+	require.ErrorIs(t, err, pqlmanifest.ErrManifestModified)
+
+	require.NoError(t, client.WriteManifest(t.Context(), "manifest.json", []byte(`{"version":2}`), etag))
+	require.Contains(t, string(object), `{"version":2}`)
+}
@@ -0,0 +1,393 @@
+package pqlmanifest
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrManifestNotFound is returned by a ManifestReaderFunc when no manifest exists at the object path.
+	ErrManifestNotFound = errors.New("manifest not found")
+	// ErrManifestModified is returned by a ManifestWriterFunc when the manifest in the storage is not
+	// the version the written manifest is based on.
+	ErrManifestModified = errors.New("manifest modified concurrently")
+)
+
+// maxWriteAttempts limits how often a flush merges the recorded operations into the manifest again
+// after another router instance modified it
+const maxWriteAttempts = 5
+
+// VersionedManifestReaderFunc reads and parses a PQL manifest from storage at the given path. It
+// returns the version of the manifest in the storage, e.g. the ETag of the object.
+type VersionedManifestReaderFunc func(ctx context.Context, objectPath string) (*Manifest, string, error)
+
+// ManifestWriterFunc writes the manifest JSON data to storage at the given path, if the manifest in
+// the storage still has the given version. An empty version requires that there is no manifest
+// yet. It returns ErrManifestModified when the manifest has another version.
+type ManifestWriterFunc func(ctx context.Context, objectPath string, data []byte, version string) error
+
+// OperationUsage describes how an operation of a recorded manifest has been used.
+type OperationUsage struct {
+	FirstSeen time.Time     `json:"firstSeen"`
+	LastSeen  time.Time     `json:"lastSeen"`
+	Hits      int64         `json:"hits"`
+	Clients   []ClientUsage `json:"clients"`
+}
+
+// ClientUsage describes how often a client version sent an operation.
+type ClientUsage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Hits    int64  `json:"hits"`
+}
+
+type clientKey struct {
+	name    string
+	version string
+}
+
+// recordedOperation holds the usage of an operation since the last flush
+type recordedOperation struct {
+	body      string
+	firstSeen time.Time
+	lastSeen  time.Time
+	hits      int64
+	clients   map[clientKey]int64
+}
+
+type RecorderOptions struct {
+	// ReadManifest reads the previously recorded manifest. It must return ErrManifestNotFound if there is none.
+	ReadManifest VersionedManifestReaderFunc
+	// WriteManifest writes the recorded manifest if the manifest in the storage wasn't modified
+	// since it was read
+	WriteManifest ManifestWriterFunc
+	// ObjectPath is the path of the manifest in the storage
+	ObjectPath string
+	// FlushInterval is the interval at which the recorded operations are written
+	FlushInterval time.Duration
+	// MaxOperations limits the number of distinct operations in the manifest. Zero means no limit.
+	MaxOperations int
+	Logger        *zap.Logger
+}
+
+// Recorder records the operations sent to the router and writes them to a manifest that can be
+// loaded by the Store. The usage of each operation is recorded next to the operations.
+// On every flush, the recorded usage is merged into the manifest in the storage, so that the
+// operations are deduplicated across restarts and router instances sharing the manifest. The
+// manifest is written conditionally on the version that was read. When another instance modified
+// it in between, the usage is merged into the new version, so no instance overwrites the usage
+// recorded by another one.
+type Recorder struct {
+	opts   RecorderOptions
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+	// known are the operations of the manifest at the last flush
+	known   map[string]struct{}
+	pending map[string]*recordedOperation
+	// added is the number of pending operations that are not known yet
+	added int
+	// limitLogged avoids logging the operation limit on every request
+	limitLogged bool
+
+	// flushMu serializes the flushes, so that recorded usage is never merged twice
+	flushMu sync.Mutex
+}
+
+func NewRecorder(opts RecorderOptions) *Recorder {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Minute
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Recorder{
+		opts:    opts,
+		logger:  logger.With(zap.String("component", "pql_manifest_recorder")),
+		now:     time.Now,
+		known:   map[string]struct{}{},
+		pending: map[string]*recordedOperation{},
+	}
+}
+
+// Record records a use of the operation with the given sha256 hash and body by a client.
+func (r *Recorder) Record(sha256Hash, body, clientName, clientVersion string) {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	op, ok := r.pending[sha256Hash]
+	if !ok {
+		if _, known := r.known[sha256Hash]; !known {
+			if r.opts.MaxOperations > 0 && len(r.known)+r.added >= r.opts.MaxOperations {
+				if !r.limitLogged {
+					r.limitLogged = true
+					r.logger.Warn("Maximum number of recorded operations reached, new operations are not recorded",
+						zap.Int("max_operations", r.opts.MaxOperations),
+					)
+				}
+				return
+			}
+			r.added++
+		}
+
+		op = &recordedOperation{
+			body:      body,
+			firstSeen: now,
+			clients:   map[clientKey]int64{},
+		}
+		r.pending[sha256Hash] = op
+	}
+
+	op.lastSeen = now
+	op.hits++
+	op.clients[clientKey{name: clientName, version: clientVersion}]++
+}
+
+// Load reads the previously recorded manifest, so that the operation limit accounts for its operations.
+func (r *Recorder) Load(ctx context.Context) error {
+	manifest, _, err := r.readManifest(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setKnown(manifest)
+
+	return nil
+}
+
+// readManifest returns the recorded manifest and its version. Both are empty if there is none.
+func (r *Recorder) readManifest(ctx context.Context) (*Manifest, string, error) {
+	manifest, version, err := r.opts.ReadManifest(ctx, r.opts.ObjectPath)
+	if err != nil {
+		if errors.Is(err, ErrManifestNotFound) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read recorded manifest: %w", err)
+	}
+	return manifest, version, nil
+}
+
+// setKnown sets the operations of the manifest as known. The caller must hold the lock.
+func (r *Recorder) setKnown(manifest *Manifest) {
+	if manifest != nil {
+		r.known = make(map[string]struct{}, len(manifest.Operations))
+		for hash := range manifest.Operations {
+			r.known[hash] = struct{}{}
+		}
+	}
+
+	r.added = 0
+	for hash := range r.pending {
+		if _, ok := r.known[hash]; !ok {
+			r.added++
+		}
+	}
+}
+
+// Run flushes the recorded operations periodically until ctx is cancelled.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.logger.Warn("Failed to write recorded PQL manifest", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Flush merges the operations recorded since the last flush into the manifest in the storage.
+// When it fails, the recorded operations are kept for the next flush.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = map[string]*recordedOperation{}
+	// The taken operations still count towards the limit until they are known
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	manifest, err := r.write(ctx, pending)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		// Merge the operations back, so that their usage is written with the next flush
+		for hash, op := range pending {
+			if recorded, ok := r.pending[hash]; ok {
+				recorded.merge(op)
+			} else {
+				r.pending[hash] = op
+			}
+		}
+		r.setKnown(nil)
+		return err
+	}
+
+	r.setKnown(manifest)
+	r.limitLogged = false
+
+	r.logger.Debug("Wrote recorded PQL manifest",
+		zap.String("object_path", r.opts.ObjectPath),
+		zap.Int("operation_count", len(manifest.Operations)),
+	)
+
+	return nil
+}
+
+// write merges the recorded operations into the manifest in the storage and writes it. The merge
+// is repeated when another instance modified the manifest in the meantime.
+func (r *Recorder) write(ctx context.Context, pending map[string]*recordedOperation) (*Manifest, error) {
+	for attempt := 1; ; attempt++ {
+		manifest, version, err := r.merge(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.opts.WriteManifest(ctx, r.opts.ObjectPath, data, version)
+		if err == nil {
+			return manifest, nil
+		}
+		if !errors.Is(err, ErrManifestModified) || attempt == maxWriteAttempts {
+			return nil, err
+		}
+
+		r.logger.Debug("Recorded PQL manifest was modified concurrently, merging again",
+			zap.String("object_path", r.opts.ObjectPath),
+			zap.Int("attempt", attempt),
+		)
+	}
+}
+
+// merge reads the manifest from the storage and merges the recorded operations into it. It returns
+// the version of the manifest that was read.
+func (r *Recorder) merge(ctx context.Context, pending map[string]*recordedOperation) (*Manifest, string, error) {
+	manifest, version, err := r.readManifest(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if manifest == nil {
+		manifest = &Manifest{Version: 1}
+	}
+	if manifest.Operations == nil {
+		manifest.Operations = map[string]string{}
+	}
+	if manifest.Usage == nil {
+		manifest.Usage = map[string]*OperationUsage{}
+	}
+
+	for hash, op := range pending {
+		if _, ok := manifest.Operations[hash]; !ok {
+			if r.opts.MaxOperations > 0 && len(manifest.Operations) >= r.opts.MaxOperations {
+				continue
+			}
+			manifest.Operations[hash] = op.body
+		}
+
+		usage, ok := manifest.Usage[hash]
+		if !ok {
+			usage = &OperationUsage{FirstSeen: op.firstSeen}
+			manifest.Usage[hash] = usage
+		}
+		usage.merge(op)
+	}
+
+	manifest.Revision = revision(manifest.Operations)
+	manifest.GeneratedAt = r.now().UTC().Format(time.RFC3339)
+
+	return manifest, version, nil
+}
+
+func (o *recordedOperation) merge(other *recordedOperation) {
+	o.firstSeen = minTime(o.firstSeen, other.firstSeen)
+	o.lastSeen = maxTime(o.lastSeen, other.lastSeen)
+	o.hits += other.hits
+	for client, hits := range other.clients {
+		o.clients[client] += hits
+	}
+}
+
+func (u *OperationUsage) merge(op *recordedOperation) {
+	u.FirstSeen = minTime(u.FirstSeen, op.firstSeen)
+	u.LastSeen = maxTime(u.LastSeen, op.lastSeen)
+	u.Hits += op.hits
+
+	for client, hits := range op.clients {
+		i := slices.IndexFunc(u.Clients, func(c ClientUsage) bool {
+			return c.Name == client.name && c.Version == client.version
+		})
+		if i == -1 {
+			u.Clients = append(u.Clients, ClientUsage{Name: client.name, Version: client.version, Hits: hits})
+			continue
+		}
+		u.Clients[i].Hits += hits
+	}
+
+	slices.SortFunc(u.Clients, func(a, b ClientUsage) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Version, b.Version))
+	})
+}
+
+// revision derives the revision from the operation hashes, so that it only changes when operations are added
+func revision(operations map[string]string) string {
+	hashes := make([]string, 0, len(operations))
+	for hash := range operations {
+		hashes = append(hashes, hash)
+	}
+	slices.Sort(hashes)
+
+	h := sha256.New()
+	for _, hash := range hashes {
+		h.Write([]byte(hash))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,277 @@
+package pqlmanifest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage stands in for a storage provider holding the recorded manifest
+type memoryStorage struct {
+	mu       sync.Mutex
+	data     map[string][]byte
+	versions map[string]int
+	writeErr error
+	// beforeWrite is called before every write, e.g. to write the manifest from another recorder
+	beforeWrite func()
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{data: map[string][]byte{}, versions: map[string]int{}}
+}
+
+func (s *memoryStorage) ReadManifest(_ context.Context, objectPath string, _ time.Time) (*Manifest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.data[objectPath]
+	if !ok {
+		return nil, ErrManifestNotFound
+	}
+	return ParseManifest(data)
+}
+
+func (s *memoryStorage) ReadManifestVersion(_ context.Context, objectPath string) (*Manifest, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.data[objectPath]
+	if !ok {
+		return nil, "", ErrManifestNotFound
+	}
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return manifest, strconv.Itoa(s.versions[objectPath]), nil
+}
+
+func (s *memoryStorage) WriteManifest(_ context.Context, objectPath string, data []byte, version string) error {
+	if s.beforeWrite != nil {
+		s.beforeWrite()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+
+	current := ""
+	if _, ok := s.data[objectPath]; ok {
+		current = strconv.Itoa(s.versions[objectPath])
+	}
+	if current != version {
+		return ErrManifestModified
+	}
+
+	s.data[objectPath] = data
+	s.versions[objectPath]++
+	return nil
+}
+
+func newTestRecorder(storage *memoryStorage, maxOperations int) *Recorder {
+	return NewRecorder(RecorderOptions{
+		ReadManifest:  storage.ReadManifestVersion,
+		WriteManifest: storage.WriteManifest,
+		ObjectPath:    "learned/manifest.json",
+		MaxOperations: maxOperations,
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes a manifest that can be loaded by the store", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+		recorder := newTestRecorder(storage, 0)
+
+		first := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+		now := first
+		recorder.now = func() time.Time { return now }
+
+		require.NoError(t, recorder.Load(t.Context()))
+
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		now = first.Add(time.Minute)
+		recorder.Record("hash-a", "query A { a }", "ios", "2.1.0")
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		recorder.Record("hash-b", "query B { b }", "web", "1.0.0")
+
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		manifest, err := storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"hash-a": "query A { a }",
+			"hash-b": "query B { b }",
+		}, manifest.Operations)
+		require.Equal(t, &OperationUsage{
+			FirstSeen: first,
+			LastSeen:  first.Add(time.Minute),
+			Hits:      3,
+			Clients: []ClientUsage{
+				{Name: "ios", Version: "2.1.0", Hits: 1},
+				{Name: "web", Version: "1.0.0", Hits: 2},
+			},
+		}, manifest.Usage["hash-a"])
+
+		store := NewStore(nil)
+		require.NoError(t, store.LoadFromData(storage.data["learned/manifest.json"]))
+		body, found := store.LookupByHash("hash-b")
+		require.True(t, found)
+		require.Equal(t, "query B { b }", string(body))
+	})
+
+	t.Run("merges the usage into the existing manifest", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+
+		previous := newTestRecorder(storage, 0)
+		previous.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.NoError(t, previous.Flush(t.Context()))
+		manifest, err := storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		revision := manifest.Revision
+
+		// A restarted router records the same operation again
+		recorder := newTestRecorder(storage, 0)
+		require.NoError(t, recorder.Load(t.Context()))
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		manifest, err = storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.Len(t, manifest.Operations, 1)
+		require.Equal(t, int64(2), manifest.Usage["hash-a"].Hits)
+		require.Equal(t, []ClientUsage{{Name: "web", Version: "1.0.0", Hits: 2}}, manifest.Usage["hash-a"].Clients)
+		// The revision only changes when operations are added
+		require.Equal(t, revision, manifest.Revision)
+
+		recorder.Record("hash-b", "query B { b }", "web", "1.0.0")
+		require.NoError(t, recorder.Flush(t.Context()))
+		manifest, err = storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.NotEqual(t, revision, manifest.Revision)
+	})
+
+	t.Run("keeps the recorded operations when the write fails", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+		storage.writeErr = errors.New("access denied")
+		recorder := newTestRecorder(storage, 0)
+
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.ErrorContains(t, recorder.Flush(t.Context()), "access denied")
+
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		storage.writeErr = nil
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		manifest, err := storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), manifest.Usage["hash-a"].Hits)
+	})
+
+	t.Run("merges the usage again when the manifest was modified concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+		recorder := newTestRecorder(storage, 0)
+		other := newTestRecorder(storage, 0)
+
+		// Another instance writes the manifest after the recorder read it
+		storage.beforeWrite = func() {
+			storage.beforeWrite = nil
+			other.Record("hash-b", "query B { b }", "ios", "2.1.0")
+			require.NoError(t, other.Flush(t.Context()))
+		}
+
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		manifest, err := storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"hash-a": "query A { a }",
+			"hash-b": "query B { b }",
+		}, manifest.Operations)
+		require.Equal(t, int64(1), manifest.Usage["hash-b"].Hits)
+	})
+
+	t.Run("keeps the recorded operations when the manifest keeps being modified", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+		previous := newTestRecorder(storage, 0)
+		previous.Record("hash-b", "query B { b }", "web", "1.0.0")
+		require.NoError(t, previous.Flush(t.Context()))
+
+		storage.beforeWrite = func() {
+			storage.mu.Lock()
+			defer storage.mu.Unlock()
+			storage.versions["learned/manifest.json"]++
+		}
+
+		recorder := newTestRecorder(storage, 0)
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.ErrorIs(t, recorder.Flush(t.Context()), ErrManifestModified)
+
+		storage.beforeWrite = nil
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		manifest, err := storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), manifest.Usage["hash-a"].Hits)
+	})
+
+	t.Run("stops recording new operations at the limit", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+		previous := newTestRecorder(storage, 0)
+		previous.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.NoError(t, previous.Flush(t.Context()))
+
+		recorder := newTestRecorder(storage, 2)
+		require.NoError(t, recorder.Load(t.Context()))
+
+		recorder.Record("hash-b", "query B { b }", "web", "1.0.0")
+		recorder.Record("hash-c", "query C { c }", "web", "1.0.0")
+		// Known operations are still recorded
+		recorder.Record("hash-a", "query A { a }", "web", "1.0.0")
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		recorder.Record("hash-d", "query D { d }", "web", "1.0.0")
+		require.NoError(t, recorder.Flush(t.Context()))
+
+		manifest, err := storage.ReadManifest(t.Context(), "learned/manifest.json", time.Time{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"hash-a": "query A { a }",
+			"hash-b": "query B { b }",
+		}, manifest.Operations)
+		require.Equal(t, int64(2), manifest.Usage["hash-a"].Hits)
+		require.NotContains(t, manifest.Usage, "hash-c")
+	})
+
+	t.Run("fails to load an unreadable manifest", func(t *testing.T) {
+		t.Parallel()
+
+		storage := newMemoryStorage()
+		storage.data["learned/manifest.json"] = []byte("{")
+
+		recorder := newTestRecorder(storage, 0)
+		require.ErrorContains(t, recorder.Load(t.Context()), "failed to read recorded manifest")
+	})
+}
@@ -15,6 +15,8 @@ type Manifest struct {
 	Revision    string            `json:"revision"`
 	GeneratedAt string            `json:"generatedAt"`
 	Operations  map[string]string `json:"operations"` // sha256 hash -> operation body
+	// Usage is written by the Recorder and ignored when the manifest is loaded
+	Usage map[string]*OperationUsage `json:"usage,omitempty"` // sha256 hash -> usage
 }
 
 type Store struct {
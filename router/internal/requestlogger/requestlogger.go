@@ -275,5 +275,8 @@ func (al *accessLogger) getRequestFields(r *http.Request, logger *zap.Logger) []
 		}
 	}
 
+	// Correlates the access log exported over OTLP with the span of the request
+	fields = append(fields, logging.WithContext(r.Context()))
+
 	return fields
 }
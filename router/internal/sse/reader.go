@@ -0,0 +1,57 @@
+// Package sse reads Server-Sent Events streams as the router writes them for subscriptions.
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Event is a dispatched event of a stream. Data joins multiple data lines with a newline.
+type Event struct {
+	Name string
+	Data string
+}
+
+// Read calls onEvent for every event of the stream until onEvent returns false or an error,
+// or the stream ends. Comments, e.g. heartbeats, and unknown fields are skipped. A line longer
+// than maxLineSize fails the read with bufio.ErrTooLong.
+func Read(r io.Reader, maxLineSize int, onEvent func(event Event) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(64*1024, maxLineSize)), maxLineSize)
+
+	var name string
+	var data strings.Builder
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, ":"):
+			continue
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		case line != "":
+			continue
+		}
+
+		// An empty line dispatches the event
+		event := Event{Name: name, Data: data.String()}
+		name = ""
+		data.Reset()
+
+		next, err := onEvent(event)
+		if err != nil || !next {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
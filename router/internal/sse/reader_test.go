@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, stream string, maxLineSize int) ([]Event, error) {
+	t.Helper()
+	var events []Event
+	err := Read(strings.NewReader(stream), maxLineSize, func(event Event) (bool, error) {
+		events = append(events, event)
+		return true, nil
+	})
+	return events, err
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("events", func(t *testing.T) {
+		t.Parallel()
+		events, err := readAll(t, "event: next\ndata: {\"a\":1}\n\n:heartbeat\n\nevent: complete\n\n", 1024)
+		require.NoError(t, err)
+		require.Equal(t, []Event{
+			{Name: "next", Data: `{"a":1}`},
+			{},
+			{Name: "complete"},
+		}, events)
+	})
+
+	t.Run("multiple data lines are joined", func(t *testing.T) {
+		t.Parallel()
+		events, err := readAll(t, "data: first\ndata:second\nid: 1\n\n", 1024)
+		require.NoError(t, err)
+		require.Equal(t, []Event{{Data: "first\nsecond"}}, events)
+	})
+
+	t.Run("an event without a trailing empty line is not dispatched", func(t *testing.T) {
+		t.Parallel()
+		events, err := readAll(t, "event: next\ndata: {}\n", 1024)
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("onEvent stops the read", func(t *testing.T) {
+		t.Parallel()
+		calls := 0
+		err := Read(strings.NewReader("data: 1\n\ndata: 2\n\n"), 1024, func(Event) (bool, error) {
+			calls++
+			return false, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+
+		stop := errors.New("stop")
+		err = Read(strings.NewReader("data: 1\n\ndata: 2\n\n"), 1024, func(Event) (bool, error) {
+			return true, stop
+		})
+		require.ErrorIs(t, err, stop)
+	})
+
+	t.Run("lines above the limit fail the read", func(t *testing.T) {
+		t.Parallel()
+		_, err := readAll(t, "data: "+strings.Repeat("a", 100)+"\n\n", 32)
+		require.ErrorIs(t, err, bufio.ErrTooLong)
+	})
+}
@@ -0,0 +1,162 @@
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAPIKeyHeaderName = "X-API-Key"
+
+	// APIKeyNameClaim is the claim that holds the name of the client the API key belongs to
+	APIKeyNameClaim = "sub"
+	// APIKeyRateLimitClaim is the claim that holds the *APIKeyRateLimit of the API key
+	APIKeyRateLimitClaim = "api_key_rate_limit"
+)
+
+var errUnknownAPIKey = errors.New("unknown API key")
+
+// APIKey is a static API key of a client. Only the hash of the key is stored.
+type APIKey struct {
+	// Hash is the hex encoded SHA-256 hash of the key, see HashAPIKey
+	Hash string `yaml:"hash" json:"hash"`
+	// Name identifies the client of the key. It is the sub claim of the request.
+	Name string `yaml:"name" json:"name"`
+	// Scopes are the scopes of the key
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	// Claims are added to the claims of the request
+	Claims map[string]any `yaml:"claims,omitempty" json:"claims,omitempty"`
+	// RateLimit overrides the rate limit of the requests with the key
+	RateLimit *APIKeyRateLimit `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+}
+
+// APIKeyRateLimit is the rate limit of the requests with an API key
+type APIKeyRateLimit struct {
+	Rate   int           `yaml:"rate" json:"rate"`
+	Burst  int           `yaml:"burst" json:"burst"`
+	Period time.Duration `yaml:"period" json:"period"`
+}
+
+// APIKeyStore looks up API keys by their hash. A custom store can be used to load the keys from
+// any source.
+type APIKeyStore interface {
+	// Lookup returns the API key with the given hash. It returns nil without an error when no key
+	// has the hash.
+	Lookup(ctx context.Context, hash string) (*APIKey, error)
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of the key, as stored in an APIKeyStore
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// QueryProvider is implemented by the Providers that provide authentication information
+// in the query parameters
+type QueryProvider interface {
+	AuthenticationQuery() url.Values
+}
+
+type apiKeyAuthenticator struct {
+	name           string
+	headerName     string
+	queryParameter string
+	scopeClaim     string
+	store          APIKeyStore
+}
+
+func (a *apiKeyAuthenticator) Name() string {
+	return a.name
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, p Provider) (Claims, error) {
+	key := a.key(p)
+	if key == "" {
+		return nil, nil
+	}
+
+	apiKey, err := a.store.Lookup(ctx, HashAPIKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("could not look up API key: %w", err)
+	}
+	if apiKey == nil {
+		return nil, errUnknownAPIKey
+	}
+
+	claims := make(Claims, len(apiKey.Claims)+3)
+	maps.Copy(claims, apiKey.Claims)
+
+	claims[APIKeyNameClaim] = apiKey.Name
+	if len(apiKey.Scopes) > 0 {
+		claims[a.scopeClaim] = strings.Join(apiKey.Scopes, " ")
+	}
+	if apiKey.RateLimit != nil {
+		claims[APIKeyRateLimitClaim] = apiKey.RateLimit
+	}
+
+	return claims, nil
+}
+
+// key returns the API key of the provider. The header takes precedence over the query parameter.
+func (a *apiKeyAuthenticator) key(p Provider) string {
+	if key := strings.TrimSpace(p.AuthenticationHeaders().Get(a.headerName)); key != "" {
+		return key
+	}
+
+	if a.queryParameter == "" {
+		return ""
+	}
+	if qp, ok := p.(QueryProvider); ok {
+		return qp.AuthenticationQuery().Get(a.queryParameter)
+	}
+	return ""
+}
+
+// APIKeyAuthenticatorOptions contains the available options for the API key authenticator
+type APIKeyAuthenticatorOptions struct {
+	// Name is the authenticator name. It defaults to api_key.
+	Name string
+	// HeaderName is the header the key is read from. It defaults to X-API-Key.
+	HeaderName string
+	// QueryParameter is the query parameter the key is read from when it is not in the header.
+	// The query parameter is not used when empty.
+	QueryParameter string
+	// ScopeClaim is the claim the scopes of the key are written to. It defaults to scope.
+	ScopeClaim string
+	// Store looks up the keys. It cannot be nil.
+	Store APIKeyStore
+}
+
+// NewAPIKeyAuthenticator returns an authenticator that authenticates requests with static API keys.
+// See APIKeyAuthenticatorOptions for the available options.
+func NewAPIKeyAuthenticator(opts APIKeyAuthenticatorOptions) (Authenticator, error) {
+	if opts.Store == nil {
+		return nil, fmt.Errorf("API key store must be provided")
+	}
+
+	if opts.Name == "" {
+		opts.Name = "api_key"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = defaultAPIKeyHeaderName
+	}
+	if opts.ScopeClaim == "" {
+		opts.ScopeClaim = DefaultScopeClaim
+	}
+
+	return &apiKeyAuthenticator{
+		name:           opts.Name,
+		headerName:     http.CanonicalHeaderKey(opts.HeaderName),
+		queryParameter: opts.QueryParameter,
+		scopeClaim:     opts.ScopeClaim,
+		store:          opts.Store,
+	}, nil
+}
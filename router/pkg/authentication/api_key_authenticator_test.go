@@ -0,0 +1,183 @@
+package authentication
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writeAPIKeyFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "api_keys.yaml")
+	writeAPIKeyFile(t, path, `
+keys:
+  - name: partner-a
+    hash: `+HashAPIKey("secret-a")+`
+    scopes: [read:products, write:products]
+    claims:
+      tier: gold
+    rate_limit:
+      rate: 100
+      burst: 200
+      period: 1m
+  - name: partner-b
+    hash: `+HashAPIKey("secret-b")+`
+`)
+
+	store, err := NewFileAPIKeyStore(t.Context(), zap.NewNop(), path, 0)
+	require.NoError(t, err)
+
+	authenticator, err := NewAPIKeyAuthenticator(APIKeyAuthenticatorOptions{
+		QueryParameter: "api_key",
+		ScopeClaim:     "scp",
+		Store:          store,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "api_key", authenticator.Name())
+
+	t.Run("maps the key to claims", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set("X-Api-Key", "secret-a")
+
+		auth, err := AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, r, "scp")
+		require.NoError(t, err)
+		require.Equal(t, "api_key", auth.Authenticator())
+		require.Equal(t, "partner-a", auth.Claims()["sub"])
+		require.Equal(t, "gold", auth.Claims()["tier"])
+		require.Equal(t, []string{"read:products", "write:products"}, auth.Scopes())
+		require.Equal(t, &APIKeyRateLimit{Rate: 100, Burst: 200, Period: time.Minute}, auth.Claims()[APIKeyRateLimitClaim])
+	})
+
+	t.Run("reads the key from the query parameter", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodGet, "/graphql?api_key=secret-b", nil)
+
+		auth, err := AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, r, "scp")
+		require.NoError(t, err)
+		require.Equal(t, "partner-b", auth.Claims()["sub"])
+		require.Nil(t, auth.Scopes())
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.Header.Set("X-API-Key", "unknown")
+
+		_, err := AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, r, "scp")
+		require.ErrorIs(t, err, errUnknownAPIKey)
+	})
+
+	t.Run("ignores requests without a key", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+
+		auth, err := AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, r, "scp")
+		require.NoError(t, err)
+		require.Nil(t, auth)
+	})
+}
+
+func TestFileAPIKeyStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps the keys when the reloaded file is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "api_keys.json")
+		writeAPIKeyFile(t, path, `{"keys": [{"name": "partner-a", "hash": "`+HashAPIKey("secret-a")+`"}]}`)
+
+		store, err := NewFileAPIKeyStore(t.Context(), zap.NewNop(), path, 0)
+		require.NoError(t, err)
+
+		writeAPIKeyFile(t, path, `{"keys": [{"name": "partner-a", "hash": "not-a-hash"}]}`)
+		require.ErrorContains(t, store.Reload(), "must be a hex encoded SHA-256 hash")
+
+		key, err := store.Lookup(t.Context(), HashAPIKey("secret-a"))
+		require.NoError(t, err)
+		require.Equal(t, "partner-a", key.Name)
+	})
+
+	t.Run("reloads the keys when the file changes", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "api_keys.yaml")
+		writeAPIKeyFile(t, path, "keys: []\n")
+
+		store, err := NewFileAPIKeyStore(t.Context(), zap.NewNop(), path, 10*time.Millisecond)
+		require.NoError(t, err)
+
+		// Make sure the modification time differs from the initial file
+		time.Sleep(20 * time.Millisecond)
+		writeAPIKeyFile(t, path, "keys:\n  - name: partner-a\n    hash: "+HashAPIKey("secret-a")+"\n")
+
+		require.Eventually(t, func() bool {
+			key, _ := store.Lookup(t.Context(), HashAPIKey("secret-a"))
+			return key != nil
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("rejects duplicate keys", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "api_keys.yaml")
+		hash := HashAPIKey("secret-a")
+		writeAPIKeyFile(t, path, "keys:\n  - name: a\n    hash: "+hash+"\n  - name: b\n    hash: "+hash+"\n")
+
+		_, err := NewFileAPIKeyStore(t.Context(), zap.NewNop(), path, 0)
+		require.ErrorContains(t, err, "duplicate API key hash for 'b'")
+	})
+}
+
+func TestRedisAPIKeyStore(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	hash := HashAPIKey("secret-a")
+	require.NoError(t, mr.Set("api_keys:"+hash, `{"name": "partner-a", "scopes": ["read:products"], "rate_limit": {"rate": 10, "burst": 10, "period": "1s"}}`))
+
+	store, err := NewRedisAPIKeyStore(client, "api_keys:", time.Minute)
+	require.NoError(t, err)
+	t.Cleanup(store.Close)
+
+	key, err := store.Lookup(t.Context(), hash)
+	require.NoError(t, err)
+	require.Equal(t, &APIKey{
+		Hash:      hash,
+		Name:      "partner-a",
+		Scopes:    []string{"read:products"},
+		RateLimit: &APIKeyRateLimit{Rate: 10, Burst: 10, Period: time.Second},
+	}, key)
+
+	key, err = store.Lookup(t.Context(), HashAPIKey("unknown"))
+	require.NoError(t, err)
+	require.Nil(t, key)
+
+	// The lookups are served from the cache until the TTL expires
+	store.cache.Wait()
+	mr.Del("api_keys:" + hash)
+	key, err = store.Lookup(t.Context(), hash)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+}
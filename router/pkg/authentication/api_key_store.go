@@ -0,0 +1,208 @@
+package authentication
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/goccy/go-yaml"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	rd "github.com/wundergraph/cosmo/router/internal/rediscloser"
+	"github.com/wundergraph/cosmo/router/pkg/watcher"
+)
+
+const defaultAPIKeyCacheSize = 10_000
+
+// apiKeyFile is the format of the API key file
+type apiKeyFile struct {
+	Keys []APIKey `yaml:"keys"`
+}
+
+// FileAPIKeyStore serves the API keys of a YAML or JSON file. The keys are reloaded when the
+// file changes.
+type FileAPIKeyStore struct {
+	path   string
+	logger *zap.Logger
+	keys   atomic.Pointer[map[string]*APIKey]
+}
+
+// NewFileAPIKeyStore loads the API keys of the file. When watchInterval is greater than zero, the
+// file is watched for changes until the context is done.
+func NewFileAPIKeyStore(ctx context.Context, logger *zap.Logger, path string, watchInterval time.Duration) (*FileAPIKeyStore, error) {
+	s := &FileAPIKeyStore{
+		path:   path,
+		logger: logger.With(zap.String("api_key_file", path)),
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	if watchInterval <= 0 {
+		return s, nil
+	}
+
+	w, err := watcher.New(watcher.Options{
+		Interval: watchInterval,
+		Logger:   s.logger,
+		Paths:    []string{path},
+		Callback: func() {
+			if err := s.Reload(); err != nil {
+				s.logger.Error("Failed to reload API keys. Keeping the old ones", zap.Error(err))
+				return
+			}
+			s.logger.Info("API keys reloaded")
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key file watcher: %w", err)
+	}
+
+	go func() {
+		if err := w(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Error("Error watching API key file", zap.Error(err))
+		}
+	}()
+
+	return s, nil
+}
+
+// Reload reads the API keys of the file. The keys are only replaced when the file is valid.
+func (s *FileAPIKeyStore) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("could not read API key file: %w", err)
+	}
+
+	var file apiKeyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("could not parse API key file: %w", err)
+	}
+
+	keys := make(map[string]*APIKey, len(file.Keys))
+	for i := range file.Keys {
+		key := &file.Keys[i]
+		if err := normalizeAPIKey(key); err != nil {
+			return fmt.Errorf("invalid API key %d in %s: %w", i, s.path, err)
+		}
+		if _, ok := keys[key.Hash]; ok {
+			return fmt.Errorf("duplicate API key hash for '%s' in %s", key.Name, s.path)
+		}
+		keys[key.Hash] = key
+	}
+
+	s.keys.Store(&keys)
+	return nil
+}
+
+// Lookup implements APIKeyStore.
+func (s *FileAPIKeyStore) Lookup(_ context.Context, hash string) (*APIKey, error) {
+	return (*s.keys.Load())[hash], nil
+}
+
+// RedisAPIKeyStore looks up the API keys in Redis. Every key is stored as a YAML or JSON encoded
+// APIKey under the key prefix followed by its hash. The lookups are cached for a short time.
+type RedisAPIKeyStore struct {
+	client    rd.RDCloser
+	keyPrefix string
+	cacheTTL  time.Duration
+	cache     *ristretto.Cache[string, *APIKey]
+}
+
+// NewRedisAPIKeyStore returns a store that looks up the API keys in Redis. The lookups are cached
+// for cacheTTL, so that revoked keys stop working after at most cacheTTL. A zero cacheTTL
+// disables the cache.
+func NewRedisAPIKeyStore(client rd.RDCloser, keyPrefix string, cacheTTL time.Duration) (*RedisAPIKeyStore, error) {
+	s := &RedisAPIKeyStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+		cacheTTL:  cacheTTL,
+	}
+
+	if cacheTTL > 0 {
+		cache, err := ristretto.NewCache(&ristretto.Config[string, *APIKey]{
+			MaxCost:            defaultAPIKeyCacheSize,
+			NumCounters:        defaultAPIKeyCacheSize * 10,
+			IgnoreInternalCost: true,
+			BufferItems:        64,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create API key cache: %w", err)
+		}
+		s.cache = cache
+	}
+
+	return s, nil
+}
+
+// Lookup implements APIKeyStore.
+func (s *RedisAPIKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	if s.cache != nil {
+		if key, ok := s.cache.Get(hash); ok {
+			return key, nil
+		}
+	}
+
+	key, err := s.get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys are cached as well, so that invalid keys don't reach Redis on every request
+	if s.cache != nil {
+		s.cache.SetWithTTL(hash, key, 1, s.cacheTTL)
+	}
+
+	return key, nil
+}
+
+func (s *RedisAPIKeyStore) get(ctx context.Context, hash string) (*APIKey, error) {
+	data, err := s.client.Get(ctx, s.keyPrefix+hash).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var key APIKey
+	if err := yaml.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("could not parse API key: %w", err)
+	}
+
+	// The hash is implied by the Redis key
+	key.Hash = hash
+	if err := normalizeAPIKey(&key); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// Close releases the cache of the store
+func (s *RedisAPIKeyStore) Close() {
+	if s.cache != nil {
+		s.cache.Close()
+	}
+}
+
+func normalizeAPIKey(key *APIKey) error {
+	if key.Name == "" {
+		return errors.New("name must be provided")
+	}
+
+	key.Hash = strings.ToLower(key.Hash)
+	if b, err := hex.DecodeString(key.Hash); err != nil || len(b) != 32 {
+		return fmt.Errorf("hash of '%s' must be a hex encoded SHA-256 hash", key.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The attributes of a client certificate that scope rules can match
+const (
+	CertificateAttributeSubject            = "subject"
+	CertificateAttributeSubjectCommonName  = "subject_cn"
+	CertificateAttributeOrganization       = "organization"
+	CertificateAttributeOrganizationalUnit = "organizational_unit"
+	CertificateAttributeIssuer             = "issuer"
+	CertificateAttributeDNSName            = "dns_name"
+	CertificateAttributeURI                = "uri"
+	CertificateAttributeSPIFFEID           = "spiffe_id"
+	CertificateAttributeEmail              = "email"
+	CertificateAttributeFingerprint        = "fingerprint"
+)
+
+var certificateAttributes = []string{
+	CertificateAttributeSubject,
+	CertificateAttributeSubjectCommonName,
+	CertificateAttributeOrganization,
+	CertificateAttributeOrganizationalUnit,
+	CertificateAttributeIssuer,
+	CertificateAttributeDNSName,
+	CertificateAttributeURI,
+	CertificateAttributeSPIFFEID,
+	CertificateAttributeEmail,
+	CertificateAttributeFingerprint,
+}
+
+// TLSProvider is implemented by the Providers of requests received over TLS
+type TLSProvider interface {
+	AuthenticationTLS() *tls.ConnectionState
+}
+
+// CertificateScopeRule grants the scopes to the client certificates with an attribute matching
+// the pattern
+type CertificateScopeRule struct {
+	// Attribute is one of the certificate attributes, e.g. spiffe_id
+	Attribute string
+	// Matching is a regular expression the attribute must match
+	Matching string
+	Scopes   []string
+}
+
+type compiledCertificateScopeRule struct {
+	attribute string
+	pattern   *regexp.Regexp
+	scopes    []string
+}
+
+type clientCertificateAuthenticator struct {
+	name       string
+	scopeClaim string
+	rules      []compiledCertificateScopeRule
+}
+
+func (a *clientCertificateAuthenticator) Name() string {
+	return a.name
+}
+
+func (a *clientCertificateAuthenticator) Authenticate(_ context.Context, p Provider) (Claims, error) {
+	tp, ok := p.(TLSProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	// Only certificates verified during the handshake are trusted
+	state := tp.AuthenticationTLS()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	attributes := certificateAttributesOf(state.VerifiedChains[0][0])
+
+	claims := Claims{
+		"subject":              attributes[CertificateAttributeSubject][0],
+		"issuer":               attributes[CertificateAttributeIssuer][0],
+		"fingerprint":          attributes[CertificateAttributeFingerprint][0],
+		"organizations":        attributes[CertificateAttributeOrganization],
+		"organizational_units": attributes[CertificateAttributeOrganizationalUnit],
+		"dns_names":            attributes[CertificateAttributeDNSName],
+		"uris":                 attributes[CertificateAttributeURI],
+		"emails":               attributes[CertificateAttributeEmail],
+	}
+	if cn := attributes[CertificateAttributeSubjectCommonName]; len(cn) > 0 {
+		claims["subject_cn"] = cn[0]
+		claims["sub"] = cn[0]
+	}
+	// The SPIFFE ID identifies the workload better than the common name
+	if spiffeID := attributes[CertificateAttributeSPIFFEID]; len(spiffeID) > 0 {
+		claims["spiffe_id"] = spiffeID[0]
+		claims["sub"] = spiffeID[0]
+	}
+
+	var scopes []string
+	for _, rule := range a.rules {
+		if slices.ContainsFunc(attributes[rule.attribute], rule.pattern.MatchString) {
+			for _, scope := range rule.scopes {
+				if !slices.Contains(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+	}
+	if len(scopes) > 0 {
+		claims[a.scopeClaim] = strings.Join(scopes, " ")
+	}
+
+	return claims, nil
+}
+
+// certificateAttributesOf returns the values of every attribute of the certificate
+func certificateAttributesOf(cert *x509.Certificate) map[string][]string {
+	sum := sha256.Sum256(cert.Raw)
+
+	attributes := map[string][]string{
+		CertificateAttributeSubject:            {cert.Subject.String()},
+		CertificateAttributeOrganization:       cert.Subject.Organization,
+		CertificateAttributeOrganizationalUnit: cert.Subject.OrganizationalUnit,
+		CertificateAttributeIssuer:             {cert.Issuer.String()},
+		CertificateAttributeDNSName:            cert.DNSNames,
+		CertificateAttributeEmail:              cert.EmailAddresses,
+		CertificateAttributeFingerprint:        {hex.EncodeToString(sum[:])},
+	}
+
+	if cert.Subject.CommonName != "" {
+		attributes[CertificateAttributeSubjectCommonName] = []string{cert.Subject.CommonName}
+	}
+
+	for _, uri := range cert.URIs {
+		attributes[CertificateAttributeURI] = append(attributes[CertificateAttributeURI], uri.String())
+		if uri.Scheme == "spiffe" {
+			attributes[CertificateAttributeSPIFFEID] = append(attributes[CertificateAttributeSPIFFEID], uri.String())
+		}
+	}
+
+	return attributes
+}
+
+// ClientCertificateAuthenticatorOptions contains the available options for the client certificate authenticator
+type ClientCertificateAuthenticatorOptions struct {
+	// Name is the authenticator name. It defaults to client_certificate.
+	Name string
+	// ScopeClaim is the claim the scopes of the scope rules are written to. It defaults to scope.
+	ScopeClaim string
+	// ScopeRules map the attributes of the certificates to scopes
+	ScopeRules []CertificateScopeRule
+}
+
+// NewClientCertificateAuthenticator returns an authenticator that authenticates requests with the
+// client certificate verified during the TLS handshake. The attributes of the certificate are the
+// claims of the request.
+func NewClientCertificateAuthenticator(opts ClientCertificateAuthenticatorOptions) (Authenticator, error) {
+	if opts.Name == "" {
+		opts.Name = "client_certificate"
+	}
+	if opts.ScopeClaim == "" {
+		opts.ScopeClaim = DefaultScopeClaim
+	}
+
+	rules := make([]compiledCertificateScopeRule, 0, len(opts.ScopeRules))
+	for i, rule := range opts.ScopeRules {
+		if !slices.Contains(certificateAttributes, rule.Attribute) {
+			return nil, fmt.Errorf("unknown certificate attribute '%s' in scope rule %d", rule.Attribute, i)
+		}
+
+		pattern, err := regexp.Compile(rule.Matching)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s' for scope rule %d: %w", rule.Matching, i, err)
+		}
+
+		rules = append(rules, compiledCertificateScopeRule{
+			attribute: rule.Attribute,
+			pattern:   pattern,
+			scopes:    rule.Scopes,
+		})
+	}
+
+	return &clientCertificateAuthenticator{
+		name:       opts.Name,
+		scopeClaim: opts.ScopeClaim,
+		rules:      rules,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newClientCertificate returns a self-signed client certificate with the given attributes
+func newClientCertificate(t *testing.T, template *x509.Certificate) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(1)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestClientCertificateAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	spiffeID, err := url.Parse("spiffe://example.org/ns/payments/sa/billing")
+	require.NoError(t, err)
+
+	cert := newClientCertificate(t, &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         "billing",
+			Organization:       []string{"Example"},
+			OrganizationalUnit: []string{"Payments"},
+		},
+		DNSNames: []string{"billing.payments.svc"},
+		URIs:     []*url.URL{spiffeID},
+	})
+
+	authenticator, err := NewClientCertificateAuthenticator(ClientCertificateAuthenticatorOptions{
+		ScopeRules: []CertificateScopeRule{
+			{Attribute: CertificateAttributeSPIFFEID, Matching: "^spiffe://example.org/ns/payments/", Scopes: []string{"read:payments"}},
+			{Attribute: CertificateAttributeOrganizationalUnit, Matching: "^Payments$", Scopes: []string{"read:payments", "write:payments"}},
+			{Attribute: CertificateAttributeDNSName, Matching: "^orders\\.", Scopes: []string{"write:orders"}},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("maps the verified certificate to claims and scopes", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+
+		auth, err := AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, r, "")
+		require.NoError(t, err)
+		require.Equal(t, "client_certificate", auth.Authenticator())
+
+		claims := auth.Claims()
+		require.Equal(t, "spiffe://example.org/ns/payments/sa/billing", claims["sub"])
+		require.Equal(t, "spiffe://example.org/ns/payments/sa/billing", claims["spiffe_id"])
+		require.Equal(t, "billing", claims["subject_cn"])
+		require.Equal(t, "CN=billing,OU=Payments,O=Example", claims["subject"])
+		require.Equal(t, []string{"billing.payments.svc"}, claims["dns_names"])
+		require.Len(t, claims["fingerprint"], 64)
+		require.Equal(t, []string{"read:payments", "write:payments"}, auth.Scopes())
+	})
+
+	t.Run("ignores certificates that were not verified", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+		auth, err := AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, r, "")
+		require.NoError(t, err)
+		require.Nil(t, auth)
+
+		auth, err = AuthenticateHTTPRequest(t.Context(), []Authenticator{authenticator}, httptest.NewRequest(http.MethodPost, "/graphql", nil), "")
+		require.NoError(t, err)
+		require.Nil(t, auth)
+	})
+
+	t.Run("rejects unknown attributes", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewClientCertificateAuthenticator(ClientCertificateAuthenticatorOptions{
+			ScopeRules: []CertificateScopeRule{{Attribute: "serial", Matching: ".*"}},
+		})
+		require.ErrorContains(t, err, "unknown certificate attribute 'serial'")
+	})
+}
@@ -2,7 +2,9 @@ package authentication
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
+	"net/url"
 )
 
 type httpRequestProvider http.Request
@@ -11,6 +13,17 @@ func (a httpRequestProvider) AuthenticationHeaders() http.Header {
 	return a.Header
 }
 
+func (a httpRequestProvider) AuthenticationQuery() url.Values {
+	if a.URL == nil {
+		return nil
+	}
+	return a.URL.Query()
+}
+
+func (a httpRequestProvider) AuthenticationTLS() *tls.ConnectionState {
+	return a.TLS
+}
+
 // AuthenticateHTTPRequest is a convenience function that calls Authenticate
 // when the authentication information is provided by an *http.Request
 func AuthenticateHTTPRequest(ctx context.Context, authenticators []Authenticator, r *http.Request, scopeClaim string) (Authentication, error) {
@@ -0,0 +1,241 @@
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultIntrospectionCacheTTL         = time.Minute
+	defaultIntrospectionInactiveCacheTTL = 10 * time.Second
+	defaultIntrospectionTimeout          = 5 * time.Second
+	defaultIntrospectionCacheSize        = 10_000
+	// maxIntrospectionResponseSize limits the size of the introspection responses that are read
+	maxIntrospectionResponseSize = 1 << 20
+)
+
+var errInactiveToken = errors.New("token is not active")
+
+// IntrospectionConfig configures the validation of opaque access tokens with an OAuth2 token
+// introspection endpoint, as specified by RFC 7662.
+type IntrospectionConfig struct {
+	// URL is the introspection endpoint. It cannot be empty.
+	URL string
+	// ClientID and ClientSecret authenticate the router at the introspection endpoint with HTTP Basic
+	// authentication (client_secret_basic)
+	ClientID     string
+	ClientSecret string
+	// TokenTypeHint is sent as the token_type_hint parameter when set, e.g. access_token
+	TokenTypeHint string
+	// ScopeClaim is the claim the scopes of the introspection response are mapped to. It defaults
+	// to scope.
+	ScopeClaim string
+	// CacheTTL is the maximum time an active token is cached. An active token is never cached
+	// beyond its expiry. It defaults to 1m.
+	CacheTTL time.Duration
+	// InactiveCacheTTL is the time an inactive token is cached. It defaults to 10s.
+	InactiveCacheTTL time.Duration
+	// Timeout of the requests to the introspection endpoint. It defaults to 5s.
+	Timeout time.Duration
+	// Client is used for the requests to the introspection endpoint. It is created when nil.
+	Client *http.Client
+}
+
+type introspectionResult struct {
+	claims Claims
+	active bool
+}
+
+type introspectionTokenDecoder struct {
+	ctx    context.Context
+	logger *zap.Logger
+	config IntrospectionConfig
+	client *http.Client
+	cache  *ristretto.Cache[string, introspectionResult]
+	group  singleflight.Group
+}
+
+// NewIntrospectionTokenDecoder returns a TokenDecoder that validates opaque tokens with the
+// introspection endpoint and returns the introspection response as the claims of the token.
+// The results are cached, so that not every request reaches the introspection endpoint.
+func NewIntrospectionTokenDecoder(ctx context.Context, logger *zap.Logger, config IntrospectionConfig) (TokenDecoder, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("introspection URL must be an absolute http or https URL: %q", config.URL)
+	}
+
+	if config.ScopeClaim == "" {
+		config.ScopeClaim = DefaultScopeClaim
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultIntrospectionCacheTTL
+	}
+	if config.InactiveCacheTTL <= 0 {
+		config.InactiveCacheTTL = defaultIntrospectionInactiveCacheTTL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultIntrospectionTimeout
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	cache, err := ristretto.NewCache(&ristretto.Config[string, introspectionResult]{
+		MaxCost:            defaultIntrospectionCacheSize,
+		NumCounters:        defaultIntrospectionCacheSize * 10,
+		IgnoreInternalCost: true,
+		BufferItems:        64,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection cache: %w", err)
+	}
+
+	context.AfterFunc(ctx, cache.Close)
+
+	return &introspectionTokenDecoder{
+		ctx:    ctx,
+		logger: logger.With(zap.String("url", config.URL)),
+		config: config,
+		client: client,
+		cache:  cache,
+	}, nil
+}
+
+// Decode implements TokenDecoder.
+func (d *introspectionTokenDecoder) Decode(token string) (Claims, error) {
+	if token == "" {
+		return nil, errInactiveToken
+	}
+
+	// The tokens are only kept as a hash
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	result, ok := d.cache.Get(key)
+	if !ok {
+		// Concurrent requests with the same token share a single introspection request
+		v, err, _ := d.group.Do(key, func() (any, error) {
+			result, ttl, err := d.introspect(token)
+			if err != nil {
+				return introspectionResult{}, err
+			}
+			if ttl > 0 {
+				d.cache.SetWithTTL(key, result, 1, ttl)
+			}
+			return result, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = v.(introspectionResult)
+	}
+
+	if !result.active {
+		return nil, errInactiveToken
+	}
+
+	// Every request gets its own claims, as the scopes of the claims can be replaced
+	return maps.Clone(result.claims), nil
+}
+
+// introspect sends the token to the introspection endpoint. It returns the result and the time it
+// can be cached for.
+func (d *introspectionTokenDecoder) introspect(token string) (introspectionResult, time.Duration, error) {
+	form := url.Values{"token": {token}}
+	if d.config.TokenTypeHint != "" {
+		form.Set("token_type_hint", d.config.TokenTypeHint)
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return introspectionResult{}, 0, fmt.Errorf("could not create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if d.config.ClientID != "" {
+		// The client credentials are form encoded before they are used for basic authentication,
+		// see RFC 6749 section 2.3.1
+		req.SetBasicAuth(url.QueryEscape(d.config.ClientID), url.QueryEscape(d.config.ClientSecret))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.logger.Error("Failed to introspect token", zap.Error(err))
+		return introspectionResult{}, 0, fmt.Errorf("could not introspect token: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		d.logger.Error("Unexpected introspection response status", zap.Int("status_code", resp.StatusCode))
+		return introspectionResult{}, 0, fmt.Errorf("could not introspect token: unexpected status code %d", resp.StatusCode)
+	}
+
+	var response map[string]any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxIntrospectionResponseSize)).Decode(&response); err != nil {
+		return introspectionResult{}, 0, fmt.Errorf("could not decode introspection response: %w", err)
+	}
+
+	if active, _ := response["active"].(bool); !active {
+		return introspectionResult{}, d.config.InactiveCacheTTL, nil
+	}
+
+	ttl := d.config.CacheTTL
+	if exp, ok := numericDate(response["exp"]); ok {
+		remaining := time.Until(exp)
+		if remaining <= 0 {
+			return introspectionResult{}, d.config.InactiveCacheTTL, nil
+		}
+		ttl = min(ttl, remaining)
+	}
+
+	return introspectionResult{claims: d.claims(response), active: true}, ttl, nil
+}
+
+// claims maps the introspection response to the claims of the token
+func (d *introspectionTokenDecoder) claims(response map[string]any) Claims {
+	claims := make(Claims, len(response))
+	for k, v := range response {
+		if k == "active" {
+			continue
+		}
+		claims[k] = v
+	}
+
+	if _, ok := claims[d.config.ScopeClaim]; !ok {
+		if scope, ok := response["scope"].(string); ok {
+			claims[d.config.ScopeClaim] = scope
+		}
+	}
+
+	return claims
+}
+
+// numericDate parses a JSON numeric date, the number of seconds since the epoch
+func numericDate(v any) (time.Time, bool) {
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
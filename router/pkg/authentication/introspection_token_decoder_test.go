@@ -0,0 +1,150 @@
+package authentication
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newIntrospectionServer answers the introspection requests with the response of the token and
+// counts the requests
+func newIntrospectionServer(t *testing.T, responses map[string]map[string]any) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "router" || clientSecret != "s%3Acret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		response, ok := responses[r.PostFormValue("token")]
+		if !ok {
+			response = map[string]any{"active": false}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestIntrospectionTokenDecoder(t *testing.T) {
+	t.Parallel()
+
+	exp := time.Now().Add(time.Hour).Unix()
+	responses := map[string]map[string]any{
+		"opaque":  {"active": true, "sub": "user-1", "scope": "read:products write:products", "exp": exp},
+		"expired": {"active": true, "sub": "user-2", "exp": time.Now().Add(-time.Minute).Unix()},
+	}
+
+	t.Run("maps an active token to claims", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newIntrospectionServer(t, responses)
+		decoder, err := NewIntrospectionTokenDecoder(t.Context(), zap.NewNop(), IntrospectionConfig{
+			URL:          server.URL,
+			ClientID:     "router",
+			ClientSecret: "s:cret",
+			ScopeClaim:   "scp",
+		})
+		require.NoError(t, err)
+
+		claims, err := decoder.Decode("opaque")
+		require.NoError(t, err)
+		require.Equal(t, "user-1", claims["sub"])
+		require.Equal(t, "read:products write:products", claims["scp"])
+		require.NotContains(t, claims, "active")
+
+		auth, err := Authenticate(t.Context(), []Authenticator{mustHeaderAuthenticator(t, decoder)}, bearer("opaque"), "scp")
+		require.NoError(t, err)
+		require.Equal(t, []string{"read:products", "write:products"}, auth.Scopes())
+
+		// The result is served from the cache
+		decoder.(*introspectionTokenDecoder).cache.Wait()
+		_, err = decoder.Decode("opaque")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), requests.Load())
+	})
+
+	t.Run("rejects inactive and expired tokens", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newIntrospectionServer(t, responses)
+		decoder, err := NewIntrospectionTokenDecoder(t.Context(), zap.NewNop(), IntrospectionConfig{
+			URL:          server.URL,
+			ClientID:     "router",
+			ClientSecret: "s:cret",
+		})
+		require.NoError(t, err)
+
+		_, err = decoder.Decode("unknown")
+		require.ErrorIs(t, err, errInactiveToken)
+
+		_, err = decoder.Decode("expired")
+		require.ErrorIs(t, err, errInactiveToken)
+
+		// Inactive tokens are cached as well
+		decoder.(*introspectionTokenDecoder).cache.Wait()
+		_, err = decoder.Decode("unknown")
+		require.ErrorIs(t, err, errInactiveToken)
+		require.Equal(t, int64(2), requests.Load())
+	})
+
+	t.Run("does not cache failed introspections", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newIntrospectionServer(t, responses)
+		decoder, err := NewIntrospectionTokenDecoder(t.Context(), zap.NewNop(), IntrospectionConfig{
+			URL:          server.URL,
+			ClientID:     "router",
+			ClientSecret: "wrong",
+		})
+		require.NoError(t, err)
+
+		for range 2 {
+			_, err = decoder.Decode("opaque")
+			require.ErrorContains(t, err, "unexpected status code 401")
+		}
+		require.Equal(t, int64(2), requests.Load())
+	})
+
+	t.Run("requires an introspection URL", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewIntrospectionTokenDecoder(t.Context(), zap.NewNop(), IntrospectionConfig{})
+		require.ErrorContains(t, err, "introspection URL must be an absolute http or https URL")
+	})
+}
+
+type headerProvider http.Header
+
+func (p headerProvider) AuthenticationHeaders() http.Header {
+	return http.Header(p)
+}
+
+func bearer(token string) Provider {
+	return headerProvider{"Authorization": {"Bearer " + token}}
+}
+
+func mustHeaderAuthenticator(t *testing.T, decoder TokenDecoder) Authenticator {
+	t.Helper()
+
+	authenticator, err := NewHttpHeaderAuthenticator(HttpHeaderAuthenticatorOptions{
+		Name:         "introspection",
+		TokenDecoder: decoder,
+	})
+	require.NoError(t, err)
+	return authenticator
+}
@@ -85,12 +85,48 @@ type Tracing struct {
 
 	OperationContentAttributes bool `yaml:"operation_content_attributes" envDefault:"false" env:"TRACING_OPERATION_CONTENT_ATTRIBUTES"`
 
+	// Sampling defers the sampling decision to the end of the request
+	Sampling TracingSampling `yaml:"sampling" envPrefix:"TRACING_SAMPLING_"`
+
 	TracingGlobalFeatures `yaml:",inline"`
 
 	// SanitizeUTF8 configures sanitization of invalid UTF-8 sequences in span attribute values
 	SanitizeUTF8 SanitizeUTF8Config `yaml:"sanitize_utf8" envPrefix:"TRACING_SANITIZE_UTF8_"`
 }
 
+// TracingSampling decides at the end of a request whether its spans are exported. The spans are
+// buffered in the router until then.
+type TracingSampling struct {
+	// Rules set the sampling ratio of the requests matching their expression. The first matching
+	// rule applies. The other requests are sampled with the sampling rate.
+	Rules []TracingSamplingRule `yaml:"rules,omitempty"`
+	// Tail keeps the traces of failed or slow requests regardless of the sampling ratio
+	Tail TracingTailSampling `yaml:"tail" envPrefix:"TAIL_"`
+	// MaxBufferedTraces caps the number of traces buffered until their request ends. At most
+	// MaxBufferedTraces * MaxSpansPerTrace spans are held in memory.
+	MaxBufferedTraces int `yaml:"max_buffered_traces" envDefault:"10000" env:"MAX_BUFFERED_TRACES"`
+	// MaxSpansPerTrace caps the number of buffered spans of a trace
+	MaxSpansPerTrace int `yaml:"max_spans_per_trace" envDefault:"512" env:"MAX_SPANS_PER_TRACE"`
+}
+
+type TracingSamplingRule struct {
+	// Expression is evaluated with the request context at the end of the request
+	Expression string `yaml:"expression"`
+	// Ratio is the sampling ratio of the matching requests between 0 and 1
+	Ratio float64 `yaml:"ratio"`
+}
+
+type TracingTailSampling struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"ENABLED"`
+	// KeepOnError keeps the traces with a span that ended with an error
+	KeepOnError bool `yaml:"keep_on_error" envDefault:"true" env:"KEEP_ON_ERROR"`
+	// LatencyThreshold keeps the traces of the requests that took at least the threshold. Zero disables it.
+	LatencyThreshold time.Duration `yaml:"latency_threshold" envDefault:"0s" env:"LATENCY_THRESHOLD"`
+	// PropagateSampled sends a sampled trace context to the subgraphs for the requests whose decision
+	// is deferred, so that kept traces include the spans of the subgraphs
+	PropagateSampled bool `yaml:"propagate_sampled" envDefault:"true" env:"PROPAGATE_SAMPLED"`
+}
+
 type PropagationConfig struct {
 	TraceContext bool `yaml:"trace_context" envDefault:"true"`
 	Jaeger       bool `yaml:"jaeger"`
@@ -198,6 +234,23 @@ type Telemetry struct {
 	ResourceAttributes []CustomStaticAttribute `yaml:"resource_attributes"`
 	Tracing            Tracing                 `yaml:"tracing"`
 	Metrics            Metrics                 `yaml:"metrics"`
+	Logs               Logs                    `yaml:"logs"`
+}
+
+// Logs configures the OTLP exporters of the logs
+type Logs struct {
+	// Enabled exports the application log with the exporters. The access log is exported with
+	// access_logs.output.otlp.
+	Enabled   bool               `yaml:"enabled" envDefault:"false" env:"TELEMETRY_LOGS_ENABLED"`
+	Exporters []LogsOTLPExporter `yaml:"exporters"`
+}
+
+type LogsOTLPExporter struct {
+	Disabled bool                `yaml:"disabled"`
+	Exporter otelconfig.Exporter `yaml:"exporter" envDefault:"http"`
+	Endpoint string              `yaml:"endpoint"`
+	HTTPPath string              `yaml:"path" envDefault:"/v1/logs"`
+	Headers  map[string]string   `yaml:"headers"`
 }
 
 type CORS struct {
@@ -233,11 +286,16 @@ type RouterTrafficConfiguration struct {
 	DecompressionEnabled bool `yaml:"decompression_enabled" envDefault:"true"`
 	// ResponseCompressionMinSize is the minimum size of the response body in bytes to enable response compression
 	ResponseCompressionMinSize BytesString `yaml:"response_compression_min_size" envDefault:"4KiB" env:"RESPONSE_COMPRESSION_MIN_SIZE"`
+	// ResponseCompressionEncodings are the encodings of compressed responses in order of preference
+	ResponseCompressionEncodings []string `yaml:"response_compression_encodings" envDefault:"zstd,br,gzip" env:"RESPONSE_COMPRESSION_ENCODINGS"`
 }
 
 type GlobalSubgraphRequestRule struct {
 	BackoffJitterRetry BackoffJitterRetry `yaml:"retry"`
 	CircuitBreaker     CircuitBreaker     `yaml:"circuit_breaker"`
+	Hedging            SubgraphHedging    `yaml:"hedging"`
+	// Compression of the request and response bodies exchanged with the subgraph
+	Compression SubgraphCompression `yaml:"compression"`
 	// See https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
 
 	RequestTimeout         *time.Duration `yaml:"request_timeout,omitempty" envDefault:"60s"`
@@ -271,6 +329,31 @@ type CircuitBreaker struct {
 	MaxConcurrentRequests      int64         `yaml:"max_concurrent_requests" envDefault:"-1"`
 }
 
+// SubgraphHedging sends a second identical request for a query when the first one is slow and
+// uses whichever response arrives first. Mutations are never hedged.
+type SubgraphHedging struct {
+	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// Delay is the time to wait for a response before the request is hedged.
+	Delay time.Duration `yaml:"delay" envDefault:"100ms"`
+	// Percentile of the observed subgraph latencies to wait before the request is hedged, e.g. 95.
+	// The delay is used until enough latencies were observed.
+	Percentile float64 `yaml:"percentile,omitempty"`
+	// MaxInFlight caps the hedged requests in flight per subgraph.
+	MaxInFlight int64 `yaml:"max_in_flight" envDefault:"10"`
+}
+
+// SubgraphCompression compresses the request bodies sent to a subgraph and advertises the
+// supported encodings to it with the Accept-Encoding header.
+type SubgraphCompression struct {
+	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// Encoding is the encoding of the compressed request bodies, one of gzip, zstd or br.
+	Encoding string `yaml:"encoding" envDefault:"gzip"`
+	// MinSize is the minimum size of a request body to be compressed.
+	MinSize BytesString `yaml:"min_size" envDefault:"1KiB"`
+	// MaxDecompressedSize is the maximum size of a decompressed zstd or br response body.
+	MaxDecompressedSize BytesString `yaml:"max_decompressed_size" envDefault:"64MiB"`
+}
+
 type GraphqlMetrics struct {
 	Enabled           bool   `yaml:"enabled" envDefault:"true" env:"GRAPHQL_METRICS_ENABLED"`
 	CollectorEndpoint string `yaml:"collector_endpoint" envDefault:"https://cosmo-metrics.wundergraph.com" env:"GRAPHQL_METRICS_COLLECTOR_ENDPOINT"`
@@ -716,9 +799,66 @@ type JWTAuthenticationConfiguration struct {
 	HeaderSources     []HeaderSource      `yaml:"header_sources"`
 }
 
+// TokenIntrospectionConfiguration validates opaque access tokens with an OAuth2 token
+// introspection endpoint (RFC 7662). The token is read from the same headers as the JWT.
+type TokenIntrospectionConfiguration struct {
+	Enabled      bool   `yaml:"enabled" envDefault:"false"`
+	URL          string `yaml:"url,omitempty"`
+	ClientID     string `yaml:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// TokenTypeHint is sent to the introspection endpoint when set, e.g. access_token
+	TokenTypeHint string `yaml:"token_type_hint,omitempty"`
+	// CacheTTL is the maximum time an active token is cached. It is never cached beyond its expiry.
+	CacheTTL         time.Duration `yaml:"cache_ttl" envDefault:"1m"`
+	InactiveCacheTTL time.Duration `yaml:"inactive_cache_ttl" envDefault:"10s"`
+	Timeout          time.Duration `yaml:"timeout" envDefault:"5s"`
+}
+
+// APIKeyAuthenticationConfiguration authenticates requests with static API keys. The keys are
+// looked up by their SHA-256 hash in a file or in a Redis storage provider.
+type APIKeyAuthenticationConfiguration struct {
+	Enabled    bool   `yaml:"enabled" envDefault:"false"`
+	HeaderName string `yaml:"header_name" envDefault:"X-API-Key"`
+	// QueryParameter is the query parameter the key is read from when it is not in the header
+	QueryParameter string              `yaml:"query_parameter,omitempty"`
+	File           APIKeyFileSource    `yaml:"file"`
+	Storage        APIKeyStorageSource `yaml:"storage"`
+}
+
+type APIKeyFileSource struct {
+	Path          string        `yaml:"path,omitempty"`
+	Watch         bool          `yaml:"watch" envDefault:"true"`
+	WatchInterval time.Duration `yaml:"watch_interval" envDefault:"10s"`
+}
+
+type APIKeyStorageSource struct {
+	ProviderID string `yaml:"provider_id,omitempty"`
+	KeyPrefix  string `yaml:"key_prefix" envDefault:"cosmo_api_keys"`
+	// CacheTTL is the time the keys looked up in Redis are cached
+	CacheTTL time.Duration `yaml:"cache_ttl" envDefault:"30s"`
+}
+
+// ClientCertificateAuthenticationConfiguration authenticates requests with the client certificate
+// verified by the TLS client auth. The attributes of the certificate are the claims of the request.
+type ClientCertificateAuthenticationConfiguration struct {
+	Enabled    bool                         `yaml:"enabled" envDefault:"false"`
+	ScopeRules []ClientCertificateScopeRule `yaml:"scope_rules,omitempty"`
+}
+
+// ClientCertificateScopeRule grants the scopes to the certificates with an attribute matching
+// the regular expression
+type ClientCertificateScopeRule struct {
+	Attribute string   `yaml:"attribute"`
+	Matching  string   `yaml:"matching"`
+	Scopes    []string `yaml:"scopes"`
+}
+
 type AuthenticationConfiguration struct {
-	JWT                 JWTAuthenticationConfiguration `yaml:"jwt"`
-	IgnoreIntrospection bool                           `yaml:"ignore_introspection" envDefault:"false"`
+	JWT                 JWTAuthenticationConfiguration               `yaml:"jwt"`
+	TokenIntrospection  TokenIntrospectionConfiguration              `yaml:"token_introspection"`
+	APIKey              APIKeyAuthenticationConfiguration            `yaml:"api_key"`
+	ClientCertificate   ClientCertificateAuthenticationConfiguration `yaml:"client_certificate"`
+	IgnoreIntrospection bool                                         `yaml:"ignore_introspection" envDefault:"false"`
 }
 
 type AuthorizationConfiguration struct {
@@ -730,13 +870,36 @@ type AuthorizationConfiguration struct {
 	// instead of filtering them out of the response after the fetch. This avoids fetching data that the
 	// client is not authorized to see.
 	EnablePreFetchFieldAuthorization bool `yaml:"enable_pre_fetch_field_authorization" envDefault:"false" env:"ENABLE_PRE_FETCH_FIELD_AUTHORIZATION"`
+	// Policies protect types and fields with expressions in addition to the scopes of the schema
+	Policies []AuthorizationPolicy `yaml:"policies,omitempty"`
+}
+
+// AuthorizationPolicy denies access to the coordinates when its condition is not met
+type AuthorizationPolicy struct {
+	// Name identifies the policy in the authorization extension of the response
+	Name string `yaml:"name"`
+	// Coordinates are the types (e.g. Employee) or fields (e.g. Query.employees) the policy applies to.
+	// A type applies the policy to all of its fields.
+	Coordinates []string `yaml:"coordinates"`
+	// Condition is an expression that must evaluate to true to access the coordinates
+	Condition string `yaml:"condition"`
 }
 
 type RateLimitConfiguration struct {
 	Enabled        bool                    `yaml:"enabled" envDefault:"false" env:"RATE_LIMIT_ENABLED"`
 	Strategy       string                  `yaml:"strategy" envDefault:"simple" env:"RATE_LIMIT_STRATEGY"`
 	SimpleStrategy RateLimitSimpleStrategy `yaml:"simple_strategy"`
-	Storage        RedisConfiguration      `yaml:"storage"`
+	CostStrategy   RateLimitCostStrategy   `yaml:"cost_strategy"`
+	// ScopedLimits are enforced in addition to the simple strategy for the fetches of a subgraph,
+	// a root field or an operation
+	ScopedLimits []RateLimitScopedLimit `yaml:"scoped_limits,omitempty"`
+	// Backend is either "redis" to share the limits between router instances or "memory" to keep
+	// them in a local token bucket per instance
+	Backend string             `yaml:"backend" envDefault:"redis" env:"RATE_LIMIT_BACKEND"`
+	Storage RedisConfiguration `yaml:"storage"`
+	// ResponseHeaders adds the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+	// Retry-After headers to the response
+	ResponseHeaders bool `yaml:"response_headers" envDefault:"false" env:"RATE_LIMIT_RESPONSE_HEADERS"`
 	// Debug ensures that retryAfter and resetAfter are set to stable values for testing
 	// Debug also exposes the rate limit key in the response extension for debugging purposes
 	Debug               bool                        `yaml:"debug" envDefault:"false" env:"RATE_LIMIT_DEBUG"`
@@ -765,6 +928,18 @@ type RateLimitSimpleStrategy struct {
 	Overrides                      []RateLimitOverride `yaml:"overrides,omitempty"`
 }
 
+// RateLimitCostStrategy subtracts the cost of every operation, as calculated by the cost control,
+// from a budget per rate limit key that is refilled over the period
+type RateLimitCostStrategy struct {
+	Budget int           `yaml:"budget" envDefault:"1000" env:"RATE_LIMIT_COST_BUDGET"`
+	Period time.Duration `yaml:"period" envDefault:"1m" env:"RATE_LIMIT_COST_PERIOD"`
+	// UseActualCost charges the actual cost after the operation was executed instead of the
+	// estimated cost before. Operations are then only rejected once the budget is exhausted.
+	UseActualCost      bool   `yaml:"use_actual_cost" envDefault:"false" env:"RATE_LIMIT_COST_USE_ACTUAL_COST"`
+	RejectStatusCode   int    `yaml:"reject_status_code" envDefault:"429" env:"RATE_LIMIT_COST_REJECT_STATUS_CODE"`
+	ErrorExtensionCode string `yaml:"error_extension_code" envDefault:"COST_BUDGET_EXCEEDED" env:"RATE_LIMIT_COST_ERROR_EXTENSION_CODE"`
+}
+
 type RateLimitOverride struct {
 	Matching string        `yaml:"matching"`
 	Rate     int           `yaml:"rate"`
@@ -772,6 +947,17 @@ type RateLimitOverride struct {
 	Period   time.Duration `yaml:"period"`
 }
 
+// RateLimitScopedLimit limits the fetches matching exactly one of Subgraph, Field or Operation
+type RateLimitScopedLimit struct {
+	Subgraph string `yaml:"subgraph,omitempty"`
+	// Field is a root field coordinate, e.g. "Query.employees"
+	Field     string        `yaml:"field,omitempty"`
+	Operation string        `yaml:"operation,omitempty"`
+	Rate      int           `yaml:"rate"`
+	Burst     int           `yaml:"burst"`
+	Period    time.Duration `yaml:"period"`
+}
+
 type CDNConfiguration struct {
 	URL       string      `yaml:"url" env:"CDN_URL" envDefault:"https://cosmo-cdn.wundergraph.com"`
 	CacheSize BytesString `yaml:"cache_size,omitempty" env:"CDN_CACHE_SIZE" envDefault:"100MB"`
@@ -845,22 +1031,71 @@ type KafkaTLSConfiguration struct {
 	Enabled bool `yaml:"enabled" envDefault:"false"`
 }
 
+type KafkaStartOffset string
+
+const (
+	KafkaStartOffsetEarliest  KafkaStartOffset = "earliest"
+	KafkaStartOffsetLatest    KafkaStartOffset = "latest"
+	KafkaStartOffsetTimestamp KafkaStartOffset = "timestamp"
+)
+
+// KafkaConsumerGroupConfiguration switches subscriptions of a Kafka provider from the stateless
+// publish-subscribe model to consumer groups. Offsets are committed once events were handed to
+// the subscriptions, so events are not lost when the router restarts, and partitions are balanced
+// across all router instances sharing the same group ID.
+type KafkaConsumerGroupConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// GroupID is the template of the consumer group ID. The placeholders {provider_id}, {topics},
+	// {field_name} and {hostname} are replaced for every subscription.
+	// Defaults to "cosmo.router.{provider_id}.{topics}.{field_name}".
+	GroupID string `yaml:"group_id,omitempty"`
+	// StartOffset is used when the group has no committed offset for a partition yet.
+	// Defaults to "latest".
+	StartOffset KafkaStartOffset `yaml:"start_offset,omitempty"`
+	// StartTimestamp is required when StartOffset is "timestamp".
+	StartTimestamp time.Time `yaml:"start_timestamp,omitempty"`
+	// CommitInterval is the interval in which processed offsets are committed. Defaults to 5s.
+	CommitInterval time.Duration `yaml:"commit_interval,omitempty"`
+}
+
 type KafkaEventSource struct {
-	ID             string                 `yaml:"id,omitempty"`
-	Brokers        []string               `yaml:"brokers,omitempty"`
-	Authentication *KafkaAuthentication   `yaml:"authentication,omitempty"`
-	TLS            *KafkaTLSConfiguration `yaml:"tls,omitempty"`
-	FetchMaxWait   time.Duration          `yaml:"fetch_max_wait,omitempty"`
+	ID             string                           `yaml:"id,omitempty"`
+	Brokers        []string                         `yaml:"brokers,omitempty"`
+	Authentication *KafkaAuthentication             `yaml:"authentication,omitempty"`
+	TLS            *KafkaTLSConfiguration           `yaml:"tls,omitempty"`
+	FetchMaxWait   time.Duration                    `yaml:"fetch_max_wait,omitempty"`
+	ConsumerGroup  *KafkaConsumerGroupConfiguration `yaml:"consumer_group,omitempty"`
 }
 
 func (k KafkaEventSource) GetID() string {
 	return k.ID
 }
 
+// RedisStreamsConfiguration switches a Redis provider from Pub/Sub to Redis Streams. Channels of
+// subscriptions and publish operations are used as stream keys. Unlike Pub/Sub, events published
+// while a subscription reconnects or the router reloads are not lost.
+type RedisStreamsConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// ConsumerGroup is the template of the consumer group name. When set, subscriptions read with
+	// XREADGROUP and acknowledge every entry after it was dispatched. The placeholders {provider_id},
+	// {channels}, {field_name} and {hostname} are replaced for every subscription.
+	ConsumerGroup string `yaml:"consumer_group,omitempty"`
+	// StartID is the stream ID to start reading from: "$" for new entries only, "0" for the whole
+	// stream or the last seen ID to resume from. Defaults to "$".
+	StartID string `yaml:"start_id,omitempty"`
+	// MaxLen trims the streams to approximately this many entries on publish. Zero disables trimming.
+	MaxLen int64 `yaml:"max_len,omitempty"`
+	// BatchSize is the maximum number of entries read per call. Defaults to 100.
+	BatchSize int64 `yaml:"batch_size,omitempty"`
+	// BlockTimeout is how long a read waits for new entries. Defaults to 5s.
+	BlockTimeout time.Duration `yaml:"block_timeout,omitempty"`
+}
+
 type RedisEventSource struct {
-	ID             string   `yaml:"id,omitempty"`
-	URLs           []string `yaml:"urls,omitempty"`
-	ClusterEnabled bool     `yaml:"cluster_enabled"`
+	ID             string                     `yaml:"id,omitempty"`
+	URLs           []string                   `yaml:"urls,omitempty"`
+	ClusterEnabled bool                       `yaml:"cluster_enabled"`
+	Streams        *RedisStreamsConfiguration `yaml:"streams,omitempty"`
 }
 
 func (r RedisEventSource) GetID() string {
@@ -1003,8 +1238,24 @@ type TLSServerConfiguration struct {
 	CertFile string `yaml:"cert_file,omitempty" env:"TLS_SERVER_CERT_FILE"`
 	KeyFile  string `yaml:"key_file,omitempty" env:"TLS_SERVER_KEY_FILE"`
 
+	// Certificates are served instead of the default certificate to clients that request one of
+	// their server names (SNI).
+	Certificates []TLSServerCertificate `yaml:"certificates,omitempty"`
+
 	// ClientAuth configures the router to accept or require mTLS from clients.
 	ClientAuth TLSClientAuthConfiguration `yaml:"client_auth,omitempty"`
+
+	// Watch reloads the certificates and the client auth CA when their files change.
+	Watch         bool          `yaml:"watch" envDefault:"false" env:"TLS_SERVER_WATCH"`
+	WatchInterval time.Duration `yaml:"watch_interval" envDefault:"10s" env:"TLS_SERVER_WATCH_INTERVAL"`
+}
+
+type TLSServerCertificate struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerNames are matched against the server name requested by the client. A name can start
+	// with a wildcard label e.g. "*.example.com". If empty, the DNS names of the certificate are used.
+	ServerNames []string `yaml:"server_names,omitempty"`
 }
 
 type TLSClientAuthConfiguration struct {
@@ -1231,6 +1482,18 @@ type PQLManifestConfig struct {
 	Warmup       PQLManifestWarmupConfig `yaml:"warmup" envPrefix:"WARMUP_"`
 }
 
+type PQLManifestLearningConfig struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"ENABLED"`
+	// ProviderID is the file system or S3 storage provider the manifest is written to
+	ProviderID   string `yaml:"provider_id,omitempty" env:"PROVIDER_ID"`
+	ObjectPrefix string `yaml:"object_prefix,omitempty" env:"OBJECT_PREFIX"`
+	FileName     string `yaml:"file_name" envDefault:"manifest.json" env:"FILE_NAME"`
+	// FlushInterval is the interval at which the recorded operations are merged into the manifest
+	FlushInterval time.Duration `yaml:"flush_interval" envDefault:"1m" env:"FLUSH_INTERVAL"`
+	// MaxOperations limits the number of distinct operations in the manifest
+	MaxOperations int `yaml:"max_operations" envDefault:"10000" env:"MAX_OPERATIONS"`
+}
+
 type PersistedOperationsConfig struct {
 	Disabled   bool                             `yaml:"disabled" env:"DISABLED" envDefault:"false"`
 	LogUnknown bool                             `yaml:"log_unknown" env:"LOG_UNKNOWN" envDefault:"false"`
@@ -1238,6 +1501,8 @@ type PersistedOperationsConfig struct {
 	Cache      PersistedOperationsCacheConfig   `yaml:"cache"`
 	Storage    PersistedOperationsStorageConfig `yaml:"storage"`
 	Manifest   PQLManifestConfig                `yaml:"manifest" envPrefix:"MANIFEST_"`
+	// Learning records the operations sent by the clients to a manifest
+	Learning PQLManifestLearningConfig `yaml:"learning" envPrefix:"LEARNING_"`
 }
 
 type SafelistConfiguration struct {
@@ -1278,6 +1543,12 @@ type AccessLogsBufferConfig struct {
 type AccessLogsOutputConfig struct {
 	Stdout AccessLogsStdOutOutputConfig `yaml:"stdout" env:"ACCESS_LOGS_OUTPUT_STDOUT"`
 	File   AccessLogsFileOutputConfig   `yaml:"file,omitempty" env:"ACCESS_LOGS_FILE_OUTPUT"`
+	OTLP   AccessLogsOTLPOutputConfig   `yaml:"otlp,omitempty" env:"ACCESS_LOGS_OUTPUT_OTLP"`
+}
+
+type AccessLogsOTLPOutputConfig struct {
+	// Enabled exports the access logs with the exporters of telemetry.logs in addition to stdout or the file
+	Enabled bool `yaml:"enabled" env:"ACCESS_LOGS_OUTPUT_OTLP_ENABLED" envDefault:"false"`
 }
 
 type AccessLogsStdOutOutputConfig struct {
@@ -1288,6 +1559,22 @@ type AccessLogsFileOutputConfig struct {
 	Enabled bool     `yaml:"enabled" env:"ACCESS_LOGS_OUTPUT_FILE_ENABLED" envDefault:"false"`
 	Path    string   `yaml:"path" env:"ACCESS_LOGS_FILE_OUTPUT_PATH" envDefault:"access.log"`
 	Mode    FileMode `yaml:"mode" env:"ACCESS_LOGS_FILE_OUTPUT_MODE" envDefault:"0640"`
+	// Rotation rotates the file by size and/or interval. Without it, the file is only reopened on SIGHUP.
+	Rotation AccessLogsFileRotationConfig `yaml:"rotation,omitempty" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION"`
+}
+
+type AccessLogsFileRotationConfig struct {
+	Enabled bool `yaml:"enabled" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION_ENABLED" envDefault:"false"`
+	// MaxSize rotates the file before it exceeds the size. Zero disables the size based rotation.
+	MaxSize BytesString `yaml:"max_size" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION_MAX_SIZE" envDefault:"100MB"`
+	// Interval rotates the file at boundaries aligned to UTC, e.g. 24h at midnight. Zero disables the interval based rotation.
+	Interval time.Duration `yaml:"interval" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION_INTERVAL" envDefault:"0s"`
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackups int `yaml:"max_backups" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION_MAX_BACKUPS" envDefault:"10"`
+	// MaxAge removes rotated files older than the age. Zero keeps them regardless of their age.
+	MaxAge time.Duration `yaml:"max_age" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION_MAX_AGE" envDefault:"0s"`
+	// Compress compresses the rotated files with gzip
+	Compress bool `yaml:"compress" env:"ACCESS_LOGS_FILE_OUTPUT_ROTATION_COMPRESS" envDefault:"false"`
 }
 
 type AccessLogsRouterConfig struct {
@@ -1350,6 +1637,88 @@ type CacheWarmupConfiguration struct {
 	InMemoryFallback bool              `yaml:"in_memory_fallback" envDefault:"true" env:"CACHE_WARMUP_IN_MEMORY_FALLBACK"`
 }
 
+// ResponseCacheConfiguration configures the cache of complete query responses. A response is
+// only cached when its merged Cache-Control header allows it. Its max-age is used as the TTL.
+type ResponseCacheConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"RESPONSE_CACHE_ENABLED"`
+	// KeyExpression is added to the cache key to scope the cached responses, e.g. per user.
+	// It must return a string. Whether the request is authenticated and its scopes are always
+	// part of the key, because field authorization depends on them. It is required with
+	// authorization policies, which can decide by any claim of the request.
+	KeyExpression string                             `yaml:"key_expression,omitempty" env:"RESPONSE_CACHE_KEY_EXPRESSION"`
+	InMemory      ResponseCacheInMemoryConfiguration `yaml:"in_memory,omitempty"`
+	Storage       ResponseCacheStorageConfiguration  `yaml:"storage,omitempty"`
+}
+
+type ResponseCacheInMemoryConfiguration struct {
+	// MaxEntries is the number of responses the in-memory cache holds before it evicts the least recently used one.
+	MaxEntries int `yaml:"max_entries" envDefault:"10000" env:"RESPONSE_CACHE_IN_MEMORY_MAX_ENTRIES"`
+}
+
+type ResponseCacheStorageConfiguration struct {
+	// ProviderID references a storage_providers.redis entry. When empty, the in-memory cache is used.
+	ProviderID string `yaml:"provider_id,omitempty" env:"RESPONSE_CACHE_STORAGE_PROVIDER_ID"`
+	KeyPrefix  string `yaml:"key_prefix,omitempty" envDefault:"cosmo_response_cache" env:"RESPONSE_CACHE_STORAGE_KEY_PREFIX"`
+}
+
+// SubgraphLoadBalancingConfiguration spreads the requests to a subgraph over several endpoints
+// instead of the single routing URL of the subgraph.
+type SubgraphLoadBalancingConfiguration struct {
+	Subgraphs map[string]SubgraphLoadBalancingSettings `yaml:"subgraphs,omitempty"`
+}
+
+type SubgraphLoadBalancingSettings struct {
+	// Strategy is one of round_robin, least_outstanding_requests or consistent_hash.
+	Strategy string `yaml:"strategy,omitempty"`
+	// HashExpression selects the value the consistent_hash strategy hashes, e.g. a user claim.
+	// It must return a string.
+	HashExpression string                           `yaml:"hash_expression,omitempty"`
+	Endpoints      []SubgraphLoadBalancingEndpoint  `yaml:"endpoints"`
+	HealthCheck    SubgraphLoadBalancingHealthCheck `yaml:"health_check,omitempty"`
+}
+
+type SubgraphLoadBalancingEndpoint struct {
+	URL string `yaml:"url"`
+}
+
+// SubgraphLoadBalancingHealthCheck configures the active health probes of the endpoints. Zero
+// values fall back to the defaults of the schema.
+type SubgraphLoadBalancingHealthCheck struct {
+	Enabled            bool          `yaml:"enabled"`
+	Path               string        `yaml:"path,omitempty"`
+	Interval           time.Duration `yaml:"interval,omitempty"`
+	Timeout            time.Duration `yaml:"timeout,omitempty"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold,omitempty"`
+	HealthyThreshold   int           `yaml:"healthy_threshold,omitempty"`
+}
+
+// FeatureFlagRoutingConfiguration lets the router send traffic to a feature flag on its own.
+// It only applies to requests that do not select a feature flag through the X-Feature-Flag
+// header or the feature_flag cookie.
+type FeatureFlagRoutingConfiguration struct {
+	// Rules are evaluated in order. The first matching rule selects the feature flag.
+	Rules []FeatureFlagRoutingRule `yaml:"rules,omitempty"`
+}
+
+type FeatureFlagRoutingRule struct {
+	FeatureFlag string `yaml:"feature_flag"`
+	// Expression must evaluate to a boolean. The rule only matches when it returns true.
+	Expression string `yaml:"expression,omitempty"`
+	// Percentage is the share of the traffic (0-100) sent to the feature flag. When only an
+	// expression is set, every request matching the expression is sent to the feature flag.
+	Percentage float64 `yaml:"percentage,omitempty"`
+	// Sticky makes the percentage split deterministic for the same claim, header or client.
+	Sticky FeatureFlagRoutingStickiness `yaml:"sticky,omitempty"`
+}
+
+// FeatureFlagRoutingStickiness defines the request value that is hashed to place a request in
+// the percentage split. Requests without the value are placed randomly.
+type FeatureFlagRoutingStickiness struct {
+	Claim      string `yaml:"claim,omitempty"`
+	Header     string `yaml:"header,omitempty"`
+	ClientName bool   `yaml:"client_name,omitempty"`
+}
+
 type MCPConfiguration struct {
 	Enabled                   bool             `yaml:"enabled" envDefault:"false" env:"MCP_ENABLED"`
 	Server                    MCPServer        `yaml:"server,omitempty"`
@@ -1369,6 +1738,18 @@ type MCPConfiguration struct {
 	ResourceDocumentation string `yaml:"resource_documentation,omitempty" env:"MCP_RESOURCE_DOCUMENTATION"`
 	// OutputSchema configures MCP structured tool output (outputSchema + structuredContent).
 	OutputSchema MCPOutputSchemaConfiguration `yaml:"output_schema,omitempty"`
+	// Subscriptions exposes subscription operations as tools that stream events.
+	Subscriptions MCPSubscriptionsConfiguration `yaml:"subscriptions,omitempty"`
+}
+
+// MCPSubscriptionsConfiguration exposes subscription operations of the operations directory as tools.
+// A tool call subscribes through the router, reports every event as a progress notification and returns
+// the collected events once the requested number of events arrived or the requested duration elapsed.
+// MaxEvents and MaxDuration bound what a client can request.
+type MCPSubscriptionsConfiguration struct {
+	Enabled     bool          `yaml:"enabled" envDefault:"false" env:"MCP_SUBSCRIPTIONS_ENABLED"`
+	MaxEvents   int           `yaml:"max_events" envDefault:"100" env:"MCP_SUBSCRIPTIONS_MAX_EVENTS"`
+	MaxDuration time.Duration `yaml:"max_duration" envDefault:"25s" env:"MCP_SUBSCRIPTIONS_MAX_DURATION"`
 }
 
 // MCPOutputSchemaConfiguration configures MCP structured tool output (spec revision 2025-06-18):
@@ -1491,6 +1872,14 @@ type ConnectRPCServer struct {
 	BaseURL    string `yaml:"base_url,omitempty" env:"BASE_URL"`
 }
 
+// AdminAPIConfiguration configures the admin API, which serves runtime introspection and control
+// endpoints on a separate listener. Every request must carry the token as a bearer token.
+type AdminAPIConfiguration struct {
+	Enabled    bool   `yaml:"enabled" envDefault:"false" env:"ADMIN_API_ENABLED"`
+	ListenAddr string `yaml:"listen_addr" envDefault:"localhost:3009" env:"ADMIN_API_LISTEN_ADDR"`
+	Token      string `yaml:"token,omitempty" env:"ADMIN_API_TOKEN"`
+}
+
 type PluginsConfiguration struct {
 	Enabled  bool                        `yaml:"enabled" envDefault:"false" env:"ENABLED"`
 	Path     string                      `yaml:"path" envDefault:"plugins" env:"PATH"`
@@ -1522,6 +1911,7 @@ type Config struct {
 	CacheControl   CacheControlPolicy      `yaml:"cache_control_policy"`
 	MCP            MCPConfiguration        `yaml:"mcp,omitempty"`
 	ConnectRPC     ConnectRPCConfiguration `yaml:"connect_rpc,omitempty"`
+	AdminAPI       AdminAPIConfiguration   `yaml:"admin_api,omitempty"`
 	DemoMode       bool                    `yaml:"demo_mode,omitempty" envDefault:"false" env:"DEMO_MODE"`
 
 	Modules        map[string]interface{} `yaml:"modules,omitempty"`
@@ -1558,6 +1948,11 @@ type Config struct {
 	DevelopmentMode               bool                        `yaml:"dev_mode" envDefault:"false" env:"DEV_MODE"`
 	Events                        EventsConfiguration         `yaml:"events,omitempty"`
 	CacheWarmup                   CacheWarmupConfiguration    `yaml:"cache_warmup,omitempty"`
+	ResponseCache                 ResponseCacheConfiguration  `yaml:"response_cache,omitempty"`
+
+	FeatureFlagRouting FeatureFlagRoutingConfiguration `yaml:"feature_flag_routing,omitempty"`
+
+	SubgraphLoadBalancing SubgraphLoadBalancingConfiguration `yaml:"subgraph_load_balancing,omitempty"`
 
 	RouterConfigPath   string `yaml:"router_config_path,omitempty" env:"ROUTER_CONFIG_PATH"`
 	RouterRegistration bool   `yaml:"router_registration" env:"ROUTER_REGISTRATION" envDefault:"true"`
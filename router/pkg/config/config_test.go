@@ -1262,6 +1262,11 @@ func TestConfigMerging(t *testing.T) {
 						Timeout: 30 * time.Second,
 					},
 				},
+				Learning: PQLManifestLearningConfig{
+					FileName:      "manifest.json",
+					FlushInterval: time.Minute,
+					MaxOperations: 10000,
+				},
 			},
 			AutomaticPersistedQueries: AutomaticPersistedQueriesConfig{
 				Storage: AutomaticPersistedQueriesStorageConfig{
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// liveReloadableFields are the config fields that the router can apply to a running instance.
+// Changes to any other field require a restart of the router.
+var liveReloadableFields = map[string]bool{
+	"headers":                          true,
+	"cache_control_policy":             true,
+	"cors":                             true,
+	"security":                         true,
+	"rate_limit.strategy":              true,
+	"rate_limit.simple_strategy":       true,
+	"rate_limit.cost_strategy":         true,
+	"rate_limit.scoped_limits":         true,
+	"rate_limit.response_headers":      true,
+	"rate_limit.debug":                 true,
+	"rate_limit.key_suffix_expression": true,
+	"rate_limit.error_extension_code":  true,
+	"access_logs.router":               true,
+	"access_logs.subgraphs":            true,
+}
+
+// diffedSections are compared field by field, as only some of their fields can be applied live
+var diffedSections = map[string]bool{
+	"rate_limit":  true,
+	"access_logs": true,
+}
+
+// ConfigDiff lists the changed fields between two configs by their YAML path, e.g. "rate_limit.simple_strategy"
+type ConfigDiff struct {
+	// Live are the changed fields that can be applied to a running router
+	Live []string
+	// Restart are the changed fields that require a restart of the router
+	Restart []string
+}
+
+// Empty returns true if no field has changed
+func (d ConfigDiff) Empty() bool {
+	return len(d.Live) == 0 && len(d.Restart) == 0
+}
+
+// RequiresRestart returns true if at least one changed field can't be applied live
+func (d ConfigDiff) RequiresRestart() bool {
+	return len(d.Restart) > 0
+}
+
+// Diff compares two configs and classifies every changed field
+func Diff(oldCfg, newCfg *Config) ConfigDiff {
+	var diff ConfigDiff
+
+	diffFields(&diff, "", reflect.ValueOf(*oldCfg), reflect.ValueOf(*newCfg))
+
+	// The MCP and ConnectRPC servers are created with the CORS config at startup
+	if slices.Contains(diff.Live, "cors") && (newCfg.MCP.Enabled || newCfg.ConnectRPC.Enabled) {
+		diff.Live = slices.DeleteFunc(diff.Live, func(path string) bool { return path == "cors" })
+		diff.Restart = append(diff.Restart, "cors")
+	}
+
+	return diff
+}
+
+func diffFields(diff *ConfigDiff, prefix string, oldValue, newValue reflect.Value) {
+	t := oldValue.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := prefix + name
+
+		oldField, newField := oldValue.Field(i), newValue.Field(i)
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+
+		if diffedSections[path] && oldField.Kind() == reflect.Struct {
+			diffFields(diff, path+".", oldField, newField)
+			continue
+		}
+
+		if liveReloadableFields[path] {
+			diff.Live = append(diff.Live, path)
+		} else {
+			diff.Restart = append(diff.Restart, path)
+		}
+	}
+}
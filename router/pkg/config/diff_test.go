@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadDiffTestConfig(t *testing.T, content string) *Config {
+	t.Helper()
+
+	f := createTempFileFromFixture(t, content)
+	res, err := LoadConfig([]string{f})
+	require.NoError(t, err)
+
+	return &res.Config
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	base := `
+version: "1"
+
+router_config_path: "config.json"
+`
+
+	t.Run("reports no changes for equal configs", func(t *testing.T) {
+		t.Parallel()
+
+		diff := Diff(loadDiffTestConfig(t, base), loadDiffTestConfig(t, base))
+		require.True(t, diff.Empty())
+		require.False(t, diff.RequiresRestart())
+	})
+
+	t.Run("classifies live sections", func(t *testing.T) {
+		t.Parallel()
+
+		diff := Diff(loadDiffTestConfig(t, base), loadDiffTestConfig(t, base+`
+cors:
+  allow_origins: ["https://example.com"]
+headers:
+  all:
+    request:
+      - op: propagate
+        named: X-Test
+security:
+  block_mutations:
+    enabled: true
+rate_limit:
+  simple_strategy:
+    rate: 100
+    burst: 100
+    period: 1s
+access_logs:
+  router:
+    fields:
+      - key: "service"
+        value_from:
+          request_header: "x-service"
+`))
+		require.ElementsMatch(t, []string{"cors", "headers", "security", "rate_limit.simple_strategy", "access_logs.router"}, diff.Live)
+		require.Empty(t, diff.Restart)
+	})
+
+	t.Run("classifies sections requiring a restart", func(t *testing.T) {
+		t.Parallel()
+
+		diff := Diff(loadDiffTestConfig(t, base), loadDiffTestConfig(t, base+`
+listen_addr: "localhost:4000"
+rate_limit:
+  enabled: true
+  backend: memory
+access_logs:
+  level: debug
+`))
+		require.Empty(t, diff.Live)
+		require.ElementsMatch(t, []string{"listen_addr", "rate_limit.enabled", "rate_limit.backend", "access_logs.level"}, diff.Restart)
+		require.True(t, diff.RequiresRestart())
+	})
+
+	t.Run("requires a restart for cors when the MCP server is enabled", func(t *testing.T) {
+		t.Parallel()
+
+		mcp := base + `
+mcp:
+  enabled: true
+`
+		diff := Diff(loadDiffTestConfig(t, mcp), loadDiffTestConfig(t, mcp+`
+cors:
+  allow_origins: ["https://example.com"]
+`))
+		require.Empty(t, diff.Live)
+		require.Equal(t, []string{"cors"}, diff.Restart)
+	})
+}
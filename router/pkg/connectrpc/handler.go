@@ -15,6 +15,7 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/wundergraph/cosmo/router/internal/headers"
+	"github.com/wundergraph/cosmo/router/pkg/schemaloader"
 )
 
 var (
@@ -85,20 +86,25 @@ type GraphQLResponse struct {
 
 // RPCHandler handles RPC requests and orchestrates GraphQL execution
 type RPCHandler struct {
-	graphqlEndpoint   string
-	httpClient        *http.Client
-	logger            *zap.Logger
-	operationRegistry *OperationRegistry
-	protoLoader       *ProtoLoader
+	graphqlEndpoint     string
+	httpClient          *http.Client
+	streamingHTTPClient *http.Client
+	logger              *zap.Logger
+	operationRegistry   *OperationRegistry
+	protoLoader         *ProtoLoader
 }
 
 // HandlerConfig contains configuration for the RPC handler
 type HandlerConfig struct {
-	GraphQLEndpoint   string
-	HTTPClient        *http.Client
-	Logger            *zap.Logger
-	OperationRegistry *OperationRegistry
-	ProtoLoader       *ProtoLoader
+	GraphQLEndpoint string
+	HTTPClient      *http.Client
+	// StreamingHTTPClient executes the subscriptions of server-streaming methods. It must not
+	// have a timeout, streams last until the client or the router ends them. Defaults to a
+	// client without timeout.
+	StreamingHTTPClient *http.Client
+	Logger              *zap.Logger
+	OperationRegistry   *OperationRegistry
+	ProtoLoader         *ProtoLoader
 }
 
 // NewRPCHandler creates a new RPC handler
@@ -128,12 +134,17 @@ func NewRPCHandler(config HandlerConfig) (*RPCHandler, error) {
 		config.GraphQLEndpoint = "http://" + config.GraphQLEndpoint
 	}
 
+	if config.StreamingHTTPClient == nil {
+		config.StreamingHTTPClient = &http.Client{}
+	}
+
 	return &RPCHandler{
-		graphqlEndpoint:   config.GraphQLEndpoint,
-		httpClient:        config.HTTPClient,
-		logger:            config.Logger,
-		operationRegistry: config.OperationRegistry,
-		protoLoader:       config.ProtoLoader,
+		graphqlEndpoint:     config.GraphQLEndpoint,
+		httpClient:          config.HTTPClient,
+		streamingHTTPClient: config.StreamingHTTPClient,
+		logger:              config.Logger,
+		operationRegistry:   config.OperationRegistry,
+		protoLoader:         config.ProtoLoader,
 	}, nil
 }
 
@@ -148,30 +159,11 @@ func (h *RPCHandler) HandleRPC(ctx context.Context, serviceName, methodName stri
 		zap.String("method", methodName),
 		zap.String("request_json", string(requestJSON)))
 
-	// Look up operation from registry scoped to this service
-	// This ensures operations can only be called from their owning service
-	// The method name must exactly match the operation name
-	operation := h.operationRegistry.GetOperationForService(serviceName, methodName)
-	if operation == nil {
-		// Log all available operations for this service to help diagnose the issue
-		allOps := h.operationRegistry.GetAllOperationsForService(serviceName)
-		var availableOps []string
-		for _, op := range allOps {
-			availableOps = append(availableOps, op.Name)
-		}
-		h.logger.Error("operation not found",
-			zap.String("service", serviceName),
-			zap.String("requested_method", methodName),
-			zap.Strings("available_operations", availableOps))
-		return nil, fmt.Errorf("operation not found for service %s: %s", serviceName, methodName)
+	operation, err := h.lookupOperation(serviceName, methodName)
+	if err != nil {
+		return nil, err
 	}
 
-	h.logger.Debug("resolved operation",
-		zap.String("service", serviceName),
-		zap.String("rpc_method", methodName),
-		zap.String("operation", operation.Name),
-		zap.String("type", operation.OperationType))
-
 	// Convert proto JSON to GraphQL variables
 	// This handles:
 	// - Field name mapping via graphql_variable_name options (e.g., hasPets → HAS_PETS)
@@ -201,6 +193,34 @@ func (h *RPCHandler) HandleRPC(ctx context.Context, serviceName, methodName stri
 	return protoResponseJSON, nil
 }
 
+// lookupOperation looks up the operation from the registry scoped to this service.
+// This ensures operations can only be called from their owning service.
+// The method name must exactly match the operation name.
+func (h *RPCHandler) lookupOperation(serviceName, methodName string) (*schemaloader.Operation, error) {
+	operation := h.operationRegistry.GetOperationForService(serviceName, methodName)
+	if operation == nil {
+		// Log all available operations for this service to help diagnose the issue
+		allOps := h.operationRegistry.GetAllOperationsForService(serviceName)
+		var availableOps []string
+		for _, op := range allOps {
+			availableOps = append(availableOps, op.Name)
+		}
+		h.logger.Error("operation not found",
+			zap.String("service", serviceName),
+			zap.String("requested_method", methodName),
+			zap.Strings("available_operations", availableOps))
+		return nil, fmt.Errorf("operation not found for service %s: %s", serviceName, methodName)
+	}
+
+	h.logger.Debug("resolved operation",
+		zap.String("service", serviceName),
+		zap.String("rpc_method", methodName),
+		zap.String("operation", operation.Name),
+		zap.String("type", operation.OperationType))
+
+	return operation, nil
+}
+
 // convertProtoJSONToGraphQLVariables processes proto JSON for GraphQL compatibility.
 //
 // IMPORTANT: Field names ARE converted here when graphql_variable_name field options are present.
@@ -666,22 +686,7 @@ func (h *RPCHandler) executeGraphQL(ctx context.Context, query string, variables
 	}
 
 	// Forward headers from the original RPC request
-	reqHeaders, err := headersFromContext(ctx)
-	if err != nil {
-		h.logger.Debug("no headers in context", zap.Error(err))
-	} else {
-		// Copy headers, skipping those that shouldn't be forwarded
-		for key, values := range reqHeaders {
-			// Normalize header key to canonical form for case-insensitive comparison
-			canonicalKey := http.CanonicalHeaderKey(key)
-			if _, skip := headers.SkippedHeaders[canonicalKey]; skip {
-				continue
-			}
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
-	}
+	h.forwardHeaders(ctx, req)
 
 	// Set required headers for GraphQL
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
@@ -702,27 +707,73 @@ func (h *RPCHandler) executeGraphQL(ctx context.Context, query string, variables
 
 	// Check for HTTP errors (non-2xx status codes)
 	if resp.StatusCode != http.StatusOK {
-		// Map HTTP status to Connect error code
-		code := HTTPStatusToConnectCode(resp.StatusCode)
+		return nil, h.makeHTTPError(resp.StatusCode, responseBody)
+	}
 
-		// Log full response body server-side only
-		h.logger.Error("HTTP error from GraphQL endpoint",
-			zap.Int("status_code", resp.StatusCode),
-			zap.String("connect_code", code.String()),
-			zap.Int("response_body_length", len(responseBody)),
-			zap.String("response_body", string(responseBody)))
+	// Parse the GraphQL response to check for errors
+	graphqlResponse, err := h.parseGraphQLResponse(responseBody, resp.StatusCode)
+	if err != nil {
+		return nil, err
+	}
 
-		// Create Connect error with metadata
-		// Note: We do NOT include the response body in client-facing metadata to prevent
-		// leaking sensitive information (internal URLs, stack traces, auth tokens, etc.)
-		connectErr := connect.NewError(code, fmt.Errorf("GraphQL request failed with HTTP %d", resp.StatusCode))
-		connectErr.Meta().Set(MetaKeyErrorClassification, ErrorClassificationCritical)
-		connectErr.Meta().Set(MetaKeyHTTPStatus, fmt.Sprintf("%d", resp.StatusCode))
+	// Success case: Return only the data field
+	// The proto response message expects just the data payload: {...}
+	// Not the GraphQL wrapper: {"data": {...}, "errors": [...]}
+	if len(graphqlResponse.Data) > 0 && string(graphqlResponse.Data) != "null" {
+		return graphqlResponse.Data, nil
+	}
+
+	// Edge case: No errors but also no data (empty response)
+	// Return empty object to ensure valid JSON for proto unmarshaling
+	// The caller (vanguard_service.go) expects non-nil JSON bytes
+	return []byte("{}"), nil
+}
+
+// forwardHeaders copies the headers of the original RPC request to the GraphQL request,
+// skipping those that shouldn't be forwarded
+func (h *RPCHandler) forwardHeaders(ctx context.Context, req *http.Request) {
+	reqHeaders, err := headersFromContext(ctx)
+	if err != nil {
+		h.logger.Debug("no headers in context", zap.Error(err))
+		return
+	}
 
-		return nil, connectErr
+	for key, values := range reqHeaders {
+		// Normalize header key to canonical form for case-insensitive comparison
+		canonicalKey := http.CanonicalHeaderKey(key)
+		if _, skip := headers.SkippedHeaders[canonicalKey]; skip {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
 	}
+}
 
-	// Parse the GraphQL response to check for errors
+// makeHTTPError creates a Connect error for a non-2xx response of the GraphQL endpoint
+func (h *RPCHandler) makeHTTPError(statusCode int, responseBody []byte) error {
+	// Map HTTP status to Connect error code
+	code := HTTPStatusToConnectCode(statusCode)
+
+	// Log full response body server-side only
+	h.logger.Error("HTTP error from GraphQL endpoint",
+		zap.Int("status_code", statusCode),
+		zap.String("connect_code", code.String()),
+		zap.Int("response_body_length", len(responseBody)),
+		zap.String("response_body", string(responseBody)))
+
+	// Create Connect error with metadata
+	// Note: We do NOT include the response body in client-facing metadata to prevent
+	// leaking sensitive information (internal URLs, stack traces, auth tokens, etc.)
+	connectErr := connect.NewError(code, fmt.Errorf("GraphQL request failed with HTTP %d", statusCode))
+	connectErr.Meta().Set(MetaKeyErrorClassification, ErrorClassificationCritical)
+	connectErr.Meta().Set(MetaKeyHTTPStatus, fmt.Sprintf("%d", statusCode))
+
+	return connectErr
+}
+
+// parseGraphQLResponse parses a GraphQL response and turns GraphQL errors into Connect errors
+func (h *RPCHandler) parseGraphQLResponse(responseBody []byte, statusCode int) (*GraphQLResponse, error) {
 	var graphqlResponse GraphQLResponse
 	if err := json.Unmarshal(responseBody, &graphqlResponse); err != nil {
 		// If we can't parse it, return the raw response (backward compatibility)
@@ -740,24 +791,14 @@ func (h *RPCHandler) executeGraphQL(ctx context.Context, query string, variables
 
 		if !hasData {
 			// CRITICAL: Errors with no data - complete failure
-			return nil, h.makeCriticalGraphQLError(graphqlResponse.Errors, resp.StatusCode)
+			return nil, h.makeCriticalGraphQLError(graphqlResponse.Errors, statusCode)
 		}
 
 		// PARTIAL: Errors with partial data - partial success
-		return nil, h.makePartialGraphQLError(graphqlResponse.Errors, graphqlResponse.Data, resp.StatusCode)
+		return nil, h.makePartialGraphQLError(graphqlResponse.Errors, graphqlResponse.Data, statusCode)
 	}
 
-	// Success case: Return only the data field
-	// The proto response message expects just the data payload: {...}
-	// Not the GraphQL wrapper: {"data": {...}, "errors": [...]}
-	if len(graphqlResponse.Data) > 0 && string(graphqlResponse.Data) != "null" {
-		return graphqlResponse.Data, nil
-	}
-
-	// Edge case: No errors but also no data (empty response)
-	// Return empty object to ensure valid JSON for proto unmarshaling
-	// The caller (vanguard_service.go) expects non-nil JSON bytes
-	return []byte("{}"), nil
+	return &graphqlResponse, nil
 }
 
 // GetOperationCount returns the number of operations available
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"connectrpc.com/vanguard"
@@ -345,9 +346,17 @@ func (s *Server) createHandler() http.Handler {
 	mux := http.NewServeMux()
 
 	transcoder := s.transcoder
+	protoLoader := s.protoLoader
 
 	// Wrap transcoder with response writer that implements required interfaces
 	wrappedTranscoder := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Server streams last as long as the subscription behind them, not the write timeout
+		if isServerStreamingRequest(protoLoader, r) {
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+				s.logger.Debug("failed to clear write deadline of server stream", zap.Error(err))
+			}
+		}
+
 		// Create a response writer that implements required interfaces for gRPC streaming
 		rw := &responseWriter{ResponseWriter: w}
 
@@ -368,6 +377,17 @@ func (s *Server) createHandler() http.Handler {
 	return mux
 }
 
+// isServerStreamingRequest reports whether the request calls a server-streaming method.
+// Streaming calls always use the /package.Service/Method path, whatever the protocol.
+func isServerStreamingRequest(protoLoader *ProtoLoader, r *http.Request) bool {
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !ok || protoLoader == nil {
+		return false
+	}
+	method, err := protoLoader.GetMethod(serviceName, methodName)
+	return err == nil && method.IsServerStreaming
+}
+
 // GetServiceCount returns the number of registered services
 func (s *Server) GetServiceCount() int {
 	if s.vanguardService == nil {
@@ -0,0 +1,291 @@
+package connectrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/wundergraph/cosmo/router/internal/sse"
+	"go.uber.org/zap"
+)
+
+const (
+	// connectStreamingJSONContentType is the content type of Connect streaming requests and responses
+	// with the JSON codec, which Vanguard transcodes every protocol to
+	connectStreamingJSONContentType = "application/connect+json"
+
+	// Flags of a Connect streaming envelope
+	connectFlagCompressed = 0b00000001
+	connectFlagEndStream  = 0b00000010
+
+	// maxStreamMessageSize limits the size of the request message and of a single subscription event
+	maxStreamMessageSize = 10 * 1024 * 1024
+)
+
+// HandleServerStreamingRPC processes a server-streaming RPC request. The method must be backed by a
+// subscription operation. Every subscription event is converted to proto JSON and passed to send.
+// It returns when the subscription completes, the context is cancelled or an error occurs.
+func (h *RPCHandler) HandleServerStreamingRPC(ctx context.Context, serviceName, methodName string, requestJSON []byte, send func(message []byte) error) error {
+	h.logger.Debug("handling server-streaming RPC request",
+		zap.String("service", serviceName),
+		zap.String("method", methodName),
+		zap.String("request_json", string(requestJSON)))
+
+	operation, err := h.lookupOperation(serviceName, methodName)
+	if err != nil {
+		return err
+	}
+
+	if operation.OperationType != "subscription" {
+		return connect.NewError(connect.CodeUnimplemented, fmt.Errorf("server-streaming method %s must be backed by a subscription operation", methodName))
+	}
+
+	variables, err := h.convertProtoJSONToGraphQLVariables(serviceName, methodName, requestJSON)
+	if err != nil {
+		return fmt.Errorf("failed to convert proto JSON to GraphQL variables: %w", err)
+	}
+
+	return h.executeGraphQLSubscription(ctx, operation.OperationString, variables, func(data json.RawMessage) error {
+		// Every event is converted like the response of a unary method
+		protoJSON, err := h.convertGraphQLResponseToProtoJSON(serviceName, methodName, data)
+		if err != nil {
+			return fmt.Errorf("failed to convert GraphQL response to proto JSON: %w", err)
+		}
+		return send(protoJSON)
+	})
+}
+
+// executeGraphQLSubscription subscribes to the router endpoint over Server-Sent Events and calls
+// onEvent with the data of every event
+func (h *RPCHandler) executeGraphQLSubscription(ctx context.Context, query string, variables json.RawMessage, onEvent func(data json.RawMessage) error) error {
+	requestBody, err := json.Marshal(GraphQLRequest{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal GraphQL request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.graphqlEndpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	h.forwardHeaders(ctx, req)
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := h.streamingHTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return connect.NewError(connect.CodeCanceled, ctx.Err())
+		}
+		return fmt.Errorf("failed to execute HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxStreamMessageSize))
+		return h.makeHTTPError(resp.StatusCode, responseBody)
+	}
+
+	// The router answers with a single JSON response if the subscription could not be started,
+	// e.g. because the operation failed validation
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxStreamMessageSize))
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		graphqlResponse, err := h.parseGraphQLResponse(responseBody, resp.StatusCode)
+		if err != nil {
+			return err
+		}
+		return onEvent(graphqlResponse.Data)
+	}
+
+	err = sse.Read(resp.Body, maxStreamMessageSize, func(event sse.Event) (bool, error) {
+		if event.Name == "complete" {
+			return false, nil
+		}
+		if event.Data == "" {
+			return true, nil
+		}
+
+		graphqlResponse, err := h.parseGraphQLResponse([]byte(event.Data), resp.StatusCode)
+		if err != nil {
+			return false, err
+		}
+		return true, onEvent(graphqlResponse.Data)
+	})
+	if err != nil && ctx.Err() != nil {
+		return connect.NewError(connect.CodeCanceled, ctx.Err())
+	}
+
+	return err
+}
+
+// handleServerStream serves a server-streaming method over the Connect streaming protocol. Vanguard
+// transcodes it from and to the protocol of the client, so the stream works over Connect, gRPC and
+// gRPC-Web alike.
+func (vs *VanguardService) handleServerStream(w http.ResponseWriter, r *http.Request, serviceName, methodName string) {
+	requestBody, err := readConnectEnvelope(r.Body, r.Header.Get("Connect-Content-Encoding"))
+	if err != nil {
+		vs.logger.Error("failed to read streaming request", zap.Error(err))
+		connectErr := connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to read request"))
+		vs.writeStreamError(w, connectErr, serviceName, methodName)
+		return
+	}
+
+	// Connect streams always respond with 200, errors are part of the end of the stream
+	w.Header().Set("Content-Type", connectStreamingJSONContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	// Add headers to context for forwarding to GraphQL
+	ctx := withRequestHeaders(r.Context(), r.Header)
+
+	err = vs.handler.HandleServerStreamingRPC(ctx, serviceName, methodName, requestBody, func(message []byte) error {
+		if err := writeConnectEnvelope(w, 0, message); err != nil {
+			return fmt.Errorf("failed to write stream message: %w", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	// Nobody is left to receive the end of the stream
+	if r.Context().Err() != nil {
+		vs.logger.Debug("server stream cancelled by client",
+			zap.String("service", serviceName),
+			zap.String("method", methodName))
+		return
+	}
+
+	if err := writeConnectEnvelope(w, connectFlagEndStream, vs.endStreamMessage(err, serviceName, methodName)); err != nil {
+		vs.logger.Error("failed to write end of stream", zap.Error(err))
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// writeStreamError responds to a streaming call with a stream that only carries the error
+func (vs *VanguardService) writeStreamError(w http.ResponseWriter, connectErr *connect.Error, serviceName, methodName string) {
+	w.Header().Set("Content-Type", connectStreamingJSONContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeConnectEnvelope(w, connectFlagEndStream, vs.endStreamMessage(connectErr, serviceName, methodName)); err != nil {
+		vs.logger.Error("failed to write end of stream", zap.Error(err))
+	}
+}
+
+// endStreamMessage builds the Connect end-of-stream message, which carries the error of a failed stream
+func (vs *VanguardService) endStreamMessage(err error, serviceName, methodName string) []byte {
+	if err == nil {
+		return []byte("{}")
+	}
+
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		// Log the original error with full details for diagnostics
+		vs.logger.Error("internal error during server stream",
+			zap.String("service", serviceName),
+			zap.String("method", methodName),
+			zap.Error(err))
+
+		// Return a sanitized error to the client to avoid leaking internal details
+		connectErr = connect.NewError(connect.CodeInternal, fmt.Errorf("internal server error"))
+	} else {
+		vs.logger.Error("server stream error",
+			zap.String("service", serviceName),
+			zap.String("method", methodName),
+			zap.String("connect_code", connectErr.Code().String()),
+			zap.String("error", connectErr.Message()))
+	}
+
+	endStream := map[string]any{
+		"error": map[string]any{
+			"code":    connectErr.Code().String(),
+			"message": connectErr.Message(),
+		},
+	}
+
+	if len(connectErr.Meta()) > 0 {
+		metadata := make(map[string][]string, len(connectErr.Meta()))
+		for key, values := range connectErr.Meta() {
+			metadata[strings.ToLower(key)] = values
+		}
+		endStream["metadata"] = metadata
+	}
+
+	message, marshalErr := json.Marshal(endStream)
+	if marshalErr != nil {
+		vs.logger.Error("failed to marshal end of stream", zap.Error(marshalErr))
+		return []byte(`{"error":{"code":"internal","message":"internal server error"}}`)
+	}
+
+	return message
+}
+
+// readConnectEnvelope reads the single request message of a server-streaming call.
+// An envelope consists of one flags byte, the message length as a 4-byte big-endian integer
+// and the message.
+func readConnectEnvelope(r io.Reader, encoding string) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, fmt.Errorf("failed to read envelope prefix: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxStreamMessageSize {
+		return nil, fmt.Errorf("message size %d exceeds the limit of %d bytes", size, maxStreamMessageSize)
+	}
+
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, fmt.Errorf("failed to read envelope message: %w", err)
+	}
+
+	if prefix[0]&connectFlagCompressed == 0 {
+		return message, nil
+	}
+
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("unsupported message encoding %q", encoding)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress message: %w", err)
+	}
+	defer gr.Close()
+
+	return io.ReadAll(io.LimitReader(gr, maxStreamMessageSize))
+}
+
+// writeConnectEnvelope writes a single message of a Connect stream
+func writeConnectEnvelope(w io.Writer, flags byte, message []byte) error {
+	var prefix [5]byte
+	prefix[0] = flags
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(message)
+	return err
+}
@@ -0,0 +1,196 @@
+package connectrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/wundergraph/cosmo/router/pkg/schemaloader"
+)
+
+const streamingServiceName = "employeeupdates.v1.EmployeeUpdatesService"
+
+// newSSEGraphQLServer answers every request with the given Server-Sent Events
+func newSSEGraphQLServer(t *testing.T, events ...string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		var req GraphQLRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.JSONEq(t, `{"employeeId":1}`, string(req.Variables))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ":heartbeat\n\n")
+		for _, event := range events {
+			_, _ = fmt.Fprintf(w, "event: next\ndata: %s\n\n", event)
+		}
+		_, _ = fmt.Fprint(w, "event: complete\ndata: \n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newStreamingTestServer serves the streaming test service over HTTP/2, so every protocol can be used
+func newStreamingTestServer(t *testing.T, graphqlEndpoint string) (*httptest.Server, protoreflect.MethodDescriptor) {
+	t.Helper()
+
+	server, err := NewServer(ServerConfig{
+		ServicesDir:     "testdata/streaming",
+		GraphQLEndpoint: graphqlEndpoint,
+		Logger:          zap.NewNop(),
+	})
+	require.NoError(t, err)
+
+	httpServer := httptest.NewUnstartedServer(server.createHandler())
+	httpServer.EnableHTTP2 = true
+	httpServer.StartTLS()
+	t.Cleanup(httpServer.Close)
+
+	service, ok := server.protoLoader.GetService(streamingServiceName)
+	require.True(t, ok)
+
+	return httpServer, service.ServiceDescriptor.Methods().ByName("SubscribeEmployeeUpdated")
+}
+
+// subscribeEmployeeUpdated calls the streaming method with dynamic messages and collects the
+// received messages as "id:mood"
+func subscribeEmployeeUpdated(t *testing.T, httpServer *httptest.Server, methodDesc protoreflect.MethodDescriptor, opts ...connect.ClientOption) ([]string, error) {
+	t.Helper()
+
+	opts = append(opts,
+		connect.WithSchema(methodDesc),
+		connect.WithResponseInitializer(func(spec connect.Spec, msg any) error {
+			*msg.(*dynamicpb.Message) = *dynamicpb.NewMessage(methodDesc.Output())
+			return nil
+		}),
+	)
+	client := connect.NewClient[dynamicpb.Message, dynamicpb.Message](
+		httpServer.Client(),
+		httpServer.URL+"/"+streamingServiceName+"/SubscribeEmployeeUpdated",
+		opts...,
+	)
+
+	req := dynamicpb.NewMessage(methodDesc.Input())
+	req.Set(methodDesc.Input().Fields().ByName("employee_id"), protoreflect.ValueOfInt32(1))
+
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(req))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var messages []string
+	for stream.Receive() {
+		updated := stream.Msg().Get(methodDesc.Output().Fields().ByName("employee_updated")).Message()
+		fields := updated.Descriptor().Fields()
+		id := updated.Get(fields.ByName("id")).Int()
+		mood := fields.ByName("current_mood").Enum().Values().ByNumber(updated.Get(fields.ByName("current_mood")).Enum())
+		messages = append(messages, fmt.Sprintf("%d:%s", id, mood.Name()))
+	}
+
+	return messages, stream.Err()
+}
+
+func TestServerStreaming(t *testing.T) {
+	protocols := []struct {
+		name string
+		opts []connect.ClientOption
+	}{
+		{name: "connect"},
+		{name: "grpc", opts: []connect.ClientOption{connect.WithGRPC()}},
+		{name: "grpc-web", opts: []connect.ClientOption{connect.WithGRPCWeb()}},
+	}
+
+	for _, protocol := range protocols {
+		t.Run(protocol.name, func(t *testing.T) {
+			t.Run("streams every subscription event", func(t *testing.T) {
+				graphqlServer := newSSEGraphQLServer(t,
+					`{"data":{"employeeUpdated":{"id":1,"currentMood":"HAPPY"}}}`,
+					`{"data":{"employeeUpdated":{"id":1,"currentMood":"SAD"}}}`,
+				)
+				httpServer, methodDesc := newStreamingTestServer(t, graphqlServer.URL)
+
+				messages, err := subscribeEmployeeUpdated(t, httpServer, methodDesc, protocol.opts...)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"1:MOOD_HAPPY", "1:MOOD_SAD"}, messages)
+			})
+
+			t.Run("ends the stream with GraphQL errors", func(t *testing.T) {
+				graphqlServer := newSSEGraphQLServer(t,
+					`{"data":{"employeeUpdated":{"id":1,"currentMood":"HAPPY"}}}`,
+					`{"errors":[{"message":"boom"}]}`,
+				)
+				httpServer, methodDesc := newStreamingTestServer(t, graphqlServer.URL)
+
+				messages, err := subscribeEmployeeUpdated(t, httpServer, methodDesc, protocol.opts...)
+				assert.Equal(t, []string{"1:MOOD_HAPPY"}, messages)
+				require.Error(t, err)
+				assert.Equal(t, connect.CodeUnknown, connect.CodeOf(err))
+				assert.Contains(t, err.Error(), "GraphQL operation failed: boom")
+			})
+		})
+	}
+}
+
+func TestHandleServerStreamingRPC(t *testing.T) {
+	t.Run("rejects operations that are not subscriptions", func(t *testing.T) {
+		handler, err := NewRPCHandler(HandlerConfig{
+			GraphQLEndpoint: "http://localhost:4000/graphql",
+			HTTPClient:      &http.Client{},
+			Logger:          zap.NewNop(),
+			OperationRegistry: NewOperationRegistry(buildTestOperations(streamingServiceName, "SubscribeEmployeeUpdated", &schemaloader.Operation{
+				Name:            "SubscribeEmployeeUpdated",
+				OperationType:   "query",
+				OperationString: "query SubscribeEmployeeUpdated { employees { id } }",
+			})),
+			ProtoLoader: NewProtoLoader(zap.NewNop()),
+		})
+		require.NoError(t, err)
+
+		err = handler.HandleServerStreamingRPC(context.Background(), streamingServiceName, "SubscribeEmployeeUpdated", []byte(`{}`), func([]byte) error {
+			return nil
+		})
+		require.Error(t, err)
+		assert.Equal(t, connect.CodeUnimplemented, connect.CodeOf(err))
+	})
+
+	t.Run("stops reading events when sending fails", func(t *testing.T) {
+		graphqlServer := newSSEGraphQLServer(t,
+			`{"data":{"employeeUpdated":{"id":1}}}`,
+			`{"data":{"employeeUpdated":{"id":2}}}`,
+		)
+
+		handler, err := NewRPCHandler(HandlerConfig{
+			GraphQLEndpoint: graphqlServer.URL,
+			HTTPClient:      &http.Client{},
+			Logger:          zap.NewNop(),
+			OperationRegistry: NewOperationRegistry(buildTestOperations(streamingServiceName, "SubscribeEmployeeUpdated", &schemaloader.Operation{
+				Name:            "SubscribeEmployeeUpdated",
+				OperationType:   "subscription",
+				OperationString: "subscription SubscribeEmployeeUpdated($employeeId: Int!) { employeeUpdated(employeeID: $employeeId) { id } }",
+			})),
+			ProtoLoader: NewProtoLoader(zap.NewNop()),
+		})
+		require.NoError(t, err)
+
+		sent := 0
+		err = handler.HandleServerStreamingRPC(context.Background(), streamingServiceName, "SubscribeEmployeeUpdated", []byte(`{"employeeId":1}`), func([]byte) error {
+			sent++
+			return io.ErrClosedPipe
+		})
+		require.ErrorIs(t, err, io.ErrClosedPipe)
+		assert.Equal(t, 1, sent)
+	})
+}
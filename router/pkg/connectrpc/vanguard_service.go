@@ -162,21 +162,34 @@ func (vs *VanguardService) createServiceHandler(serviceName string, serviceDef *
 		}
 
 		// Validate method exists in service
-		methodExists := false
-		for _, method := range serviceDef.Methods {
-			if method.Name == methodName {
-				methodExists = true
+		var methodDef *MethodDefinition
+		for i := range serviceDef.Methods {
+			if serviceDef.Methods[i].Name == methodName {
+				methodDef = &serviceDef.Methods[i]
 				break
 			}
 		}
 
-		if !methodExists {
+		if methodDef == nil {
 			// Return Connect error for method not found
 			connectErr := connect.NewError(connect.CodeNotFound, fmt.Errorf("method not found: %s", methodName))
 			vs.writeConnectError(w, connectErr, serviceName, methodName)
 			return
 		}
 
+		// A GraphQL operation takes exactly one set of variables, so client streams can't be mapped
+		if methodDef.IsClientStreaming {
+			connectErr := connect.NewError(connect.CodeUnimplemented, fmt.Errorf("client-streaming methods are not supported: %s", methodName))
+			vs.writeStreamError(w, connectErr, serviceName, methodName)
+			return
+		}
+
+		// Server-streaming methods are backed by subscription operations
+		if methodDef.IsServerStreaming {
+			vs.handleServerStream(w, r, serviceName, methodName)
+			return
+		}
+
 		// For GET requests (Connect protocol), extract message from query parameter
 		// For POST requests, read from body
 		var requestBody []byte
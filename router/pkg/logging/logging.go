@@ -132,7 +132,7 @@ type BufferedLogger struct {
 }
 
 type BufferedLoggerOptions struct {
-	WS            *os.File
+	WS            zapcore.WriteSyncer
 	BufferSize    int
 	FlushInterval time.Duration
 	Development   bool
@@ -155,6 +155,7 @@ func NewJSONZapBufferedLogger(options BufferedLoggerOptions) (*BufferedLogger, e
 	return fl, nil
 }
 
+// Close flushes the buffered lines and stops the periodic flush
 func (f *BufferedLogger) Close() error {
 	return f.bufferedWriteSyncer.Stop()
 }
@@ -0,0 +1,390 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/wundergraph/cosmo/router/pkg/otel/otelconfig"
+)
+
+const (
+	DefaultLogsPath = "/v1/logs"
+
+	contextFieldKey = "otel_context"
+
+	// syncTimeout bounds the export of the buffered log records on Sync, so that an unreachable
+	// collector doesn't block the caller, e.g. before the process exits
+	syncTimeout = 5 * time.Second
+)
+
+type OTLPExporter struct {
+	Exporter otelconfig.Exporter
+	// Endpoint is the URL of the collector, e.g. http://localhost:4318
+	Endpoint string
+	// HTTPPath is the path for OTLP HTTP transport. The default value is /v1/logs.
+	HTTPPath string
+	Headers  map[string]string
+}
+
+type OTLPOptions struct {
+	Exporters []*OTLPExporter
+
+	ServiceName        string
+	ServiceVersion     string
+	ServiceInstanceID  string
+	ResourceAttributes []attribute.KeyValue
+
+	// FlushInterval is the maximum time a log record is buffered before it is exported. The default
+	// value of the OpenTelemetry SDK applies when it is zero.
+	FlushInterval time.Duration
+	// BufferSize is the approximate size in bytes of the buffered log records that triggers an export.
+	// Zero disables it.
+	BufferSize int
+}
+
+// OTLPLogger exports zap logs over OTLP
+type OTLPLogger struct {
+	provider   *sdklog.LoggerProvider
+	bufferSize int
+	// buffered is the approximate size of the log records written since the last size triggered export
+	buffered atomic.Int64
+	flushing atomic.Bool
+}
+
+func NewOTLPLogger(ctx context.Context, opts OTLPOptions) (*OTLPLogger, error) {
+	if len(opts.Exporters) == 0 {
+		return nil, errors.New("at least one OTLP logs exporter is required")
+	}
+
+	r, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceNameKey.String(opts.ServiceName)),
+		resource.WithAttributes(semconv.ServiceVersionKey.String(opts.ServiceVersion)),
+		resource.WithAttributes(semconv.ServiceInstanceID(opts.ServiceInstanceID)),
+		resource.WithAttributes(opts.ResourceAttributes...),
+		resource.WithProcessPID(),
+		resource.WithOSType(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	providerOpts := []sdklog.LoggerProviderOption{
+		sdklog.WithResource(r),
+	}
+
+	var batchOpts []sdklog.BatchProcessorOption
+	if opts.FlushInterval > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportInterval(opts.FlushInterval))
+	}
+
+	for _, exp := range opts.Exporters {
+		exporter, err := createOTLPLogExporter(ctx, exp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP logs exporter for endpoint '%s': %w", exp.Endpoint, err)
+		}
+		providerOpts = append(providerOpts, sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, batchOpts...)))
+	}
+
+	return &OTLPLogger{
+		provider:   sdklog.NewLoggerProvider(providerOpts...),
+		bufferSize: opts.BufferSize,
+	}, nil
+}
+
+func createOTLPLogExporter(ctx context.Context, exp *OTLPExporter) (sdklog.Exporter, error) {
+	u, err := url.Parse(exp.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Host == "" {
+		return nil, errors.New("endpoint must be a URL with a host")
+	}
+
+	switch exp.Exporter {
+	case otelconfig.ExporterOTLPGRPC:
+		opts := []otlploggrpc.Option{
+			// Includes host and port
+			otlploggrpc.WithEndpoint(u.Host),
+			otlploggrpc.WithCompressor("gzip"),
+		}
+		if u.Scheme != "https" {
+			opts = append(opts, otlploggrpc.WithInsecure())
+		}
+		if len(exp.Headers) > 0 {
+			opts = append(opts, otlploggrpc.WithHeaders(exp.Headers))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	case otelconfig.ExporterOTLPHTTP, "":
+		urlPath := exp.HTTPPath
+		if urlPath == "" {
+			urlPath = DefaultLogsPath
+		}
+		opts := []otlploghttp.Option{
+			// Includes host and port
+			otlploghttp.WithEndpoint(u.Host),
+			otlploghttp.WithCompression(otlploghttp.GzipCompression),
+			otlploghttp.WithURLPath(urlPath),
+		}
+		if u.Scheme != "https" {
+			opts = append(opts, otlploghttp.WithInsecure())
+		}
+		if len(exp.Headers) > 0 {
+			opts = append(opts, otlploghttp.WithHeaders(exp.Headers))
+		}
+		return otlploghttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported exporter '%s'", exp.Exporter)
+	}
+}
+
+// Core returns a zap core that writes the entries to the OTLP logger with the given
+// instrumentation scope. The fields of the entries are exported as log attributes. A context added
+// with WithContext correlates the log record with the span of the context.
+func (l *OTLPLogger) Core(name string, level zapcore.LevelEnabler) zapcore.Core {
+	return &otlpCore{
+		LevelEnabler: level,
+		otlp:         l,
+		logger:       l.provider.Logger(name),
+	}
+}
+
+// Tee returns a logger that writes the entries to both the logger and the OTLP logger
+func (l *OTLPLogger) Tee(logger *zap.Logger, name string, level zapcore.LevelEnabler) *zap.Logger {
+	core := l.Core(name, level)
+	return logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	}))
+}
+
+// Shutdown exports the buffered log records and stops the exporters
+func (l *OTLPLogger) Shutdown(ctx context.Context) error {
+	return l.provider.Shutdown(ctx)
+}
+
+// written triggers an export in the background once the buffer size is exceeded
+func (l *OTLPLogger) written(size int) {
+	if l.bufferSize <= 0 {
+		return
+	}
+	if l.buffered.Add(int64(size)) < int64(l.bufferSize) {
+		return
+	}
+	if !l.flushing.CompareAndSwap(false, true) {
+		return
+	}
+	l.buffered.Store(0)
+	go func() {
+		defer l.flushing.Store(false)
+		_ = l.provider.ForceFlush(context.Background())
+	}()
+}
+
+// WithContext adds the context to the log entry, so that the OTLP log record is correlated with
+// the trace and span of the context. The field is ignored by other encoders.
+func WithContext(ctx context.Context) zap.Field {
+	return zap.Field{Key: contextFieldKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+type otlpCore struct {
+	zapcore.LevelEnabler
+
+	otlp   *OTLPLogger
+	logger otellog.Logger
+	ctx    context.Context
+	attrs  []otellog.KeyValue
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.ctx, clone.attrs = c.convertFields(fields, c.attrs)
+	return &clone
+}
+
+func (c *otlpCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *otlpCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	ctx, attrs := c.convertFields(fields, c.attrs)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var record otellog.Record
+	record.SetTimestamp(entry.Time)
+	record.SetObservedTimestamp(time.Now())
+	record.SetBody(otellog.StringValue(entry.Message))
+	record.SetSeverity(otlpSeverity(entry.Level))
+	record.SetSeverityText(entry.Level.CapitalString())
+	if entry.LoggerName != "" {
+		attrs = append(attrs, otellog.String("logger", entry.LoggerName))
+	}
+	if entry.Caller.Defined {
+		attrs = append(attrs, otellog.String("caller", entry.Caller.TrimmedPath()))
+	}
+	if entry.Stack != "" {
+		attrs = append(attrs, otellog.String("stacktrace", entry.Stack))
+	}
+	record.AddAttributes(attrs...)
+
+	c.logger.Emit(ctx, record)
+
+	size := len(entry.Message)
+	for _, attr := range attrs {
+		size += len(attr.Key) + len(attr.Value.String())
+	}
+	c.otlp.written(size)
+
+	// Like the zap cores writing to files, export right away before a panic or exit
+	if entry.Level > zapcore.ErrorLevel {
+		return c.Sync()
+	}
+
+	return nil
+}
+
+// Sync exports the buffered log records of all cores of the OTLP logger
+func (c *otlpCore) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	return c.otlp.provider.ForceFlush(ctx)
+}
+
+// convertFields appends the fields to the attributes. It returns the context of the fields or the
+// context of the core.
+func (c *otlpCore) convertFields(fields []zapcore.Field, attrs []otellog.KeyValue) (context.Context, []otellog.KeyValue) {
+	ctx := c.ctx
+	enc := zapcore.NewMapObjectEncoder()
+
+	result := make([]otellog.KeyValue, len(attrs), len(attrs)+len(fields))
+	copy(result, attrs)
+
+	for _, field := range fields {
+		if field.Type == zapcore.SkipType {
+			if fieldCtx, ok := field.Interface.(context.Context); ok {
+				ctx = fieldCtx
+			}
+			continue
+		}
+		field.AddTo(enc)
+	}
+	// Keep the order of the fields
+	for _, field := range fields {
+		if value, ok := enc.Fields[field.Key]; ok {
+			result = append(result, otellog.KeyValue{Key: field.Key, Value: otlpValue(value)})
+			delete(enc.Fields, field.Key)
+		}
+	}
+
+	return ctx, result
+}
+
+func otlpValue(v any) otellog.Value {
+	switch value := v.(type) {
+	case string:
+		return otellog.StringValue(value)
+	case bool:
+		return otellog.BoolValue(value)
+	case int:
+		return otellog.IntValue(value)
+	case int8:
+		return otellog.Int64Value(int64(value))
+	case int16:
+		return otellog.Int64Value(int64(value))
+	case int32:
+		return otellog.Int64Value(int64(value))
+	case int64:
+		return otellog.Int64Value(value)
+	case uint:
+		return otlpUintValue(uint64(value))
+	case uint8:
+		return otellog.Int64Value(int64(value))
+	case uint16:
+		return otellog.Int64Value(int64(value))
+	case uint32:
+		return otellog.Int64Value(int64(value))
+	case uint64:
+		return otlpUintValue(value)
+	case float32:
+		return otellog.Float64Value(float64(value))
+	case float64:
+		return otellog.Float64Value(value)
+	case []byte:
+		return otellog.BytesValue(value)
+	case time.Duration:
+		return otellog.Float64Value(value.Seconds())
+	case time.Time:
+		return otellog.Int64Value(value.UnixMilli())
+	case error:
+		return otellog.StringValue(value.Error())
+	case []any:
+		values := make([]otellog.Value, 0, len(value))
+		for _, item := range value {
+			values = append(values, otlpValue(item))
+		}
+		return otellog.SliceValue(values...)
+	case map[string]any:
+		kvs := make([]otellog.KeyValue, 0, len(value))
+		for k, item := range value {
+			kvs = append(kvs, otellog.KeyValue{Key: k, Value: otlpValue(item)})
+		}
+		return otellog.MapValue(kvs...)
+	case fmt.Stringer:
+		return otellog.StringValue(value.String())
+	case nil:
+		return otellog.Value{}
+	default:
+		return otellog.StringValue(fmt.Sprint(value))
+	}
+}
+
+func otlpUintValue(v uint64) otellog.Value {
+	if v > math.MaxInt64 {
+		return otellog.StringValue(fmt.Sprint(v))
+	}
+	return otellog.Int64Value(int64(v))
+}
+
+func otlpSeverity(level zapcore.Level) otellog.Severity {
+	switch level {
+	case zapcore.DebugLevel:
+		return otellog.SeverityDebug
+	case zapcore.InfoLevel:
+		return otellog.SeverityInfo
+	case zapcore.WarnLevel:
+		return otellog.SeverityWarn
+	case zapcore.ErrorLevel:
+		return otellog.SeverityError
+	case zapcore.DPanicLevel:
+		return otellog.SeverityFatal1
+	case zapcore.PanicLevel:
+		return otellog.SeverityFatal2
+	case zapcore.FatalLevel:
+		return otellog.SeverityFatal3
+	default:
+		if level < zapcore.DebugLevel {
+			return otellog.SeverityTrace
+		}
+		return otellog.SeverityUndefined
+	}
+}
@@ -0,0 +1,246 @@
+package logging
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/wundergraph/cosmo/router/pkg/otel/otelconfig"
+)
+
+// otlpReceiver stands in for an OTLP collector receiving logs over HTTP
+type otlpReceiver struct {
+	mu      sync.Mutex
+	records []*logspb.LogRecord
+	scopes  []string
+}
+
+func newOTLPReceiver(t *testing.T) (*otlpReceiver, *httptest.Server) {
+	t.Helper()
+
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DefaultLogsPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gr
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req collogspb.ExportLogsServiceRequest
+		if err := proto.Unmarshal(data, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		receiver.mu.Lock()
+		for _, resourceLogs := range req.ResourceLogs {
+			for _, scopeLogs := range resourceLogs.ScopeLogs {
+				for _, record := range scopeLogs.LogRecords {
+					receiver.records = append(receiver.records, record)
+					receiver.scopes = append(receiver.scopes, scopeLogs.Scope.GetName())
+				}
+			}
+		}
+		receiver.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		resp, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return receiver, server
+}
+
+func (r *otlpReceiver) received() ([]*logspb.LogRecord, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*logspb.LogRecord(nil), r.records...), append([]string(nil), r.scopes...)
+}
+
+func attributes(record *logspb.LogRecord) map[string]*commonpb.AnyValue {
+	result := make(map[string]*commonpb.AnyValue, len(record.Attributes))
+	for _, attr := range record.Attributes {
+		result[attr.Key] = attr.Value
+	}
+	return result
+}
+
+func TestOTLPLogger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exports the log entries with their fields and trace context", func(t *testing.T) {
+		t.Parallel()
+
+		receiver, server := newOTLPReceiver(t)
+
+		otlpLogger, err := NewOTLPLogger(t.Context(), OTLPOptions{
+			Exporters:      []*OTLPExporter{{Exporter: otelconfig.ExporterOTLPHTTP, Endpoint: server.URL}},
+			ServiceName:    "cosmo-router",
+			ServiceVersion: "dev",
+		})
+		require.NoError(t, err)
+
+		spanContext := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    oteltrace.TraceID{1, 2, 3},
+			SpanID:     oteltrace.SpanID{4, 5, 6},
+			TraceFlags: oteltrace.FlagsSampled,
+		})
+		ctx := oteltrace.ContextWithSpanContext(context.Background(), spanContext)
+
+		logger := NewZapLoggerWithCore(otlpLogger.Core("access_logs", zapcore.InfoLevel), false, false)
+		logger.With(zap.String("log_type", "request")).Info("/graphql",
+			zap.String("operation_name", "Employees"),
+			zap.Int("status", 200),
+			WithContext(ctx),
+		)
+		// Below the level
+		logger.Debug("ignored")
+
+		require.NoError(t, otlpLogger.Shutdown(t.Context()))
+
+		records, scopes := receiver.received()
+		require.Len(t, records, 1)
+		require.Equal(t, []string{"access_logs"}, scopes)
+
+		record := records[0]
+		require.Equal(t, "/graphql", record.Body.GetStringValue())
+		require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, record.SeverityNumber)
+		require.Equal(t, "INFO", record.SeverityText)
+		require.Equal(t, spanContext.TraceID().String(), oteltrace.TraceID(record.TraceId).String())
+		require.Equal(t, spanContext.SpanID().String(), oteltrace.SpanID(record.SpanId).String())
+
+		attrs := attributes(record)
+		require.Equal(t, "request", attrs["log_type"].GetStringValue())
+		require.Equal(t, "Employees", attrs["operation_name"].GetStringValue())
+		require.Equal(t, int64(200), attrs["status"].GetIntValue())
+		require.NotContains(t, attrs, contextFieldKey)
+	})
+
+	t.Run("tees the entries to the existing logger", func(t *testing.T) {
+		t.Parallel()
+
+		receiver, server := newOTLPReceiver(t)
+
+		otlpLogger, err := NewOTLPLogger(t.Context(), OTLPOptions{
+			Exporters: []*OTLPExporter{{Endpoint: server.URL}},
+		})
+		require.NoError(t, err)
+
+		var written int
+		base := zap.New(zapcore.NewCore(ZapJsonEncoder(), zapcore.AddSync(writerFunc(func(p []byte) (int, error) {
+			written++
+			return len(p), nil
+		})), zapcore.InfoLevel))
+
+		otlpLogger.Tee(base, "router", zapcore.InfoLevel).Warn("Slow subgraph", zap.Duration("latency", 2*time.Second))
+		require.NoError(t, otlpLogger.Shutdown(t.Context()))
+
+		require.Equal(t, 1, written)
+		records, _ := receiver.received()
+		require.Len(t, records, 1)
+		require.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, records[0].SeverityNumber)
+		require.Equal(t, 2.0, attributes(records[0])["latency"].GetDoubleValue())
+	})
+
+	t.Run("exports when the buffer size is exceeded", func(t *testing.T) {
+		t.Parallel()
+
+		receiver, server := newOTLPReceiver(t)
+
+		otlpLogger, err := NewOTLPLogger(t.Context(), OTLPOptions{
+			Exporters:     []*OTLPExporter{{Endpoint: server.URL}},
+			FlushInterval: time.Hour,
+			BufferSize:    100,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = otlpLogger.Shutdown(context.Background())
+		})
+
+		logger := NewZapLoggerWithCore(otlpLogger.Core("access_logs", zapcore.InfoLevel), false, false)
+		for range 10 {
+			logger.Info("/graphql", zap.String("operation_name", "Employees"))
+		}
+
+		require.Eventually(t, func() bool {
+			records, _ := receiver.received()
+			return len(records) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("exports the buffered entries on sync", func(t *testing.T) {
+		t.Parallel()
+
+		receiver, server := newOTLPReceiver(t)
+
+		otlpLogger, err := NewOTLPLogger(t.Context(), OTLPOptions{
+			Exporters:     []*OTLPExporter{{Endpoint: server.URL}},
+			FlushInterval: time.Hour,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = otlpLogger.Shutdown(context.Background())
+		})
+
+		logger := NewZapLoggerWithCore(otlpLogger.Core("access_logs", zapcore.InfoLevel), false, false)
+		logger.Info("/graphql")
+		records, _ := receiver.received()
+		require.Empty(t, records)
+
+		require.NoError(t, logger.Sync())
+		records, _ = receiver.received()
+		require.Len(t, records, 1)
+	})
+
+	t.Run("requires a valid exporter", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewOTLPLogger(t.Context(), OTLPOptions{})
+		require.ErrorContains(t, err, "at least one OTLP logs exporter is required")
+
+		_, err = NewOTLPLogger(t.Context(), OTLPOptions{
+			Exporters: []*OTLPExporter{{Endpoint: "localhost:4318"}},
+		})
+		require.ErrorContains(t, err, "endpoint must be a URL with a host")
+
+		_, err = NewOTLPLogger(t.Context(), OTLPOptions{
+			Exporters: []*OTLPExporter{{Exporter: "kafka", Endpoint: "http://localhost:4318"}},
+		})
+		require.ErrorContains(t, err, "unsupported exporter 'kafka'")
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,365 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotationTimeFormat is the format of the rotation time in the names of the rotated files
+const rotationTimeFormat = "2006-01-02T15-04-05.000"
+
+type RotationOptions struct {
+	// MaxSize rotates the file before a write would exceed the size in bytes. Zero disables it.
+	MaxSize int64
+	// Interval rotates the file on the first write after an interval boundary. The boundaries are
+	// aligned to the Unix epoch in UTC, e.g. 24h rotates at midnight UTC. Zero disables it.
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackups int
+	// MaxAge is the maximum age of the rotated files to keep. Zero keeps all of them.
+	MaxAge time.Duration
+	// Compress compresses the rotated files with gzip
+	Compress bool
+}
+
+// RotatingFile is a log file that is rotated by size and interval. Rotated files are renamed to
+// <name>-<time><ext> next to the file, compressed and removed in the background. Each write goes
+// entirely to one file, so that the lines of a write are never split between two files.
+type RotatingFile struct {
+	path string
+	mode os.FileMode
+	opts RotationOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// mill compresses and removes rotated files in the background
+	mill     chan struct{}
+	millDone chan struct{}
+}
+
+func NewRotatingFile(path string, mode os.FileMode, opts RotationOptions) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:     path,
+		mode:     mode,
+		opts:     opts,
+		now:      time.Now,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	go f.runMill()
+	// Apply the retention to the files rotated before a restart
+	f.triggerMill()
+
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := NewLogFile(f.path, f.mode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	// A file with existing lines belongs to the interval of its last write
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	}
+
+	return nil
+}
+
+// Write writes the lines to the file. The file is rotated before the write if it would exceed
+// the maximum size or if an interval boundary has passed since the file was opened.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	var rotateErr error
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			rotateErr = fmt.Errorf("failed to rotate log file: %w", err)
+			if f.file == nil {
+				return 0, rotateErr
+			}
+		}
+	}
+
+	// When the rotation failed, the lines are still written to the current file
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, errors.Join(err, rotateErr)
+}
+
+func (f *RotatingFile) shouldRotate(size int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSize > 0 && f.size+size > f.opts.MaxSize {
+		return true
+	}
+	if f.opts.Interval > 0 && !f.now().Truncate(f.opts.Interval).Equal(f.openedAt.Truncate(f.opts.Interval)) {
+		return true
+	}
+	return false
+}
+
+// rotate renames the file and opens a new one. The caller must hold the lock.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil {
+		// Continue with the current file
+		return errors.Join(err, f.open())
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.triggerMill()
+
+	return nil
+}
+
+// backupName returns a name for the rotated file that doesn't exist yet
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := f.nameParts()
+	t = t.UTC()
+	for {
+		name := filepath.Join(dir, prefix+t.Format(rotationTimeFormat)+ext)
+		_, err := os.Stat(name)
+		_, gzErr := os.Stat(name + ".gz")
+		if errors.Is(err, os.ErrNotExist) && errors.Is(gzErr, os.ErrNotExist) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func (f *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.path)
+	base := filepath.Base(f.path)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+	return dir, prefix, ext
+}
+
+// Rotates returns true if the file is rotated by size or interval
+func (f *RotatingFile) Rotates() bool {
+	return f.opts.MaxSize > 0 || f.opts.Interval > 0
+}
+
+// Sync commits the written lines to the disk
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Rotate rotates the file regardless of its size and age
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen closes the file and opens the file at the path again. It is used after an external tool
+// like logrotate moved the file.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	return f.open()
+}
+
+// Close closes the file and waits for the background compression to finish
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.file == nil {
+		f.mu.Unlock()
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	close(f.mill)
+	f.mu.Unlock()
+
+	<-f.millDone
+
+	return err
+}
+
+func (f *RotatingFile) triggerMill() {
+	select {
+	case f.mill <- struct{}{}:
+	default:
+	}
+}
+
+func (f *RotatingFile) runMill() {
+	defer close(f.millDone)
+	for range f.mill {
+		_ = f.millRotatedFiles()
+	}
+}
+
+type rotatedFile struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// millRotatedFiles compresses the rotated files and removes the ones beyond the retention
+func (f *RotatingFile) millRotatedFiles() error {
+	files, err := f.rotatedFiles()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	// Newest first
+	slices.SortFunc(files, func(a, b rotatedFile) int {
+		return b.rotatedAt.Compare(a.rotatedAt)
+	})
+
+	kept := files[:0]
+	for i, file := range files {
+		expired := f.opts.MaxAge > 0 && f.now().Sub(file.rotatedAt) > f.opts.MaxAge
+		if (f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups) || expired {
+			errs = append(errs, os.Remove(file.path))
+			continue
+		}
+		kept = append(kept, file)
+	}
+
+	if f.opts.Compress {
+		for _, file := range kept {
+			if strings.HasSuffix(file.path, ".gz") {
+				continue
+			}
+			errs = append(errs, compressFile(file.path))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *RotatingFile) rotatedFiles() ([]rotatedFile, error) {
+	dir, prefix, ext := f.nameParts()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []rotatedFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		timestamp := strings.TrimPrefix(name, prefix)
+		timestamp = strings.TrimSuffix(timestamp, ".gz")
+		if !strings.HasSuffix(timestamp, ext) {
+			continue
+		}
+		rotatedAt, err := time.Parse(rotationTimeFormat, strings.TrimSuffix(timestamp, ext))
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: filepath.Join(dir, name), rotatedAt: rotatedAt})
+	}
+
+	return files, nil
+}
+
+// compressFile compresses the file to <path>.gz and removes it
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The compressed file is renamed once it is complete, so that a partial file is never kept
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,212 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// readLines returns the lines of the log file and its rotated files
+func readLines(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var lines []string
+	for _, entry := range entries {
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+
+		r := io.Reader(f)
+		if strings.HasSuffix(entry.Name(), ".gz") {
+			gr, err := gzip.NewReader(f)
+			require.NoError(t, err)
+			r = gr
+		}
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		require.NoError(t, scanner.Err())
+		require.NoError(t, f.Close())
+	}
+
+	return lines
+}
+
+func fileNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rotates before a write exceeds the max size", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		f, err := NewRotatingFile(filepath.Join(dir, "access.log"), 0, RotationOptions{MaxSize: 25})
+		require.NoError(t, err)
+
+		for range 3 {
+			_, err := f.Write([]byte("0123456789\n"))
+			require.NoError(t, err)
+		}
+		require.NoError(t, f.Close())
+
+		// Two writes fit into a file, the third one goes entirely to a new file
+		require.Len(t, fileNames(t, dir), 2)
+		content, err := os.ReadFile(filepath.Join(dir, "access.log"))
+		require.NoError(t, err)
+		require.Equal(t, "0123456789\n", string(content))
+		require.Len(t, readLines(t, dir), 3)
+	})
+
+	t.Run("rotates on the first write after an interval boundary", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		f, err := NewRotatingFile(filepath.Join(dir, "access.log"), 0, RotationOptions{Interval: time.Hour})
+		require.NoError(t, err)
+
+		now := time.Date(2026, 10, 17, 10, 59, 0, 0, time.UTC)
+		f.now = func() time.Time { return now }
+		f.openedAt = now
+
+		_, err = f.Write([]byte("before\n"))
+		require.NoError(t, err)
+
+		now = now.Add(2 * time.Minute)
+		_, err = f.Write([]byte("after\n"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		require.ElementsMatch(t, []string{"access.log", "access-2026-10-17T11-01-00.000.log"}, fileNames(t, dir))
+		content, err := os.ReadFile(filepath.Join(dir, "access-2026-10-17T11-01-00.000.log"))
+		require.NoError(t, err)
+		require.Equal(t, "before\n", string(content))
+	})
+
+	t.Run("compresses the rotated files and keeps max backups", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		f, err := NewRotatingFile(filepath.Join(dir, "access.log"), 0, RotationOptions{MaxBackups: 2, Compress: true})
+		require.NoError(t, err)
+
+		for range 4 {
+			_, err := f.Write([]byte("line\n"))
+			require.NoError(t, err)
+			require.NoError(t, f.Rotate())
+		}
+		_, err = f.Write([]byte("current\n"))
+		require.NoError(t, err)
+		// Close waits for the pending compression and cleanup
+		require.NoError(t, f.Close())
+
+		names := fileNames(t, dir)
+		require.Len(t, names, 3)
+		for _, name := range names {
+			if name != "access.log" {
+				require.True(t, strings.HasSuffix(name, ".log.gz"), name)
+			}
+		}
+		require.Len(t, readLines(t, dir), 3)
+	})
+
+	t.Run("removes rotated files older than the max age", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		old := filepath.Join(dir, "access-"+time.Now().Add(-48*time.Hour).UTC().Format(rotationTimeFormat)+".log")
+		require.NoError(t, os.WriteFile(old, []byte("old\n"), 0o640))
+		// Not a rotated file of the access log
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "access-other.log"), []byte("other\n"), 0o640))
+
+		f, err := NewRotatingFile(filepath.Join(dir, "access.log"), 0, RotationOptions{MaxAge: 24 * time.Hour})
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		require.ElementsMatch(t, []string{"access.log", "access-other.log"}, fileNames(t, dir))
+	})
+
+	t.Run("reopens the file after an external rotation", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		path := filepath.Join(dir, "access.log")
+		f, err := NewRotatingFile(path, 0, RotationOptions{})
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte("before\n"))
+		require.NoError(t, err)
+		require.NoError(t, os.Rename(path, path+".1"))
+
+		require.NoError(t, f.Reopen())
+		_, err = f.Write([]byte("after\n"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "after\n", string(content))
+		content, err = os.ReadFile(path + ".1")
+		require.NoError(t, err)
+		require.Equal(t, "before\n", string(content))
+
+		_, err = f.Write([]byte("closed\n"))
+		require.ErrorIs(t, err, os.ErrClosed)
+	})
+
+	t.Run("keeps every line of the buffered logger across rotations", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		f, err := NewRotatingFile(filepath.Join(dir, "access.log"), 0, RotationOptions{MaxSize: 1024})
+		require.NoError(t, err)
+
+		bl, err := NewJSONZapBufferedLogger(BufferedLoggerOptions{
+			WS:            f,
+			BufferSize:    512,
+			FlushInterval: time.Millisecond,
+			Level:         zapcore.InfoLevel,
+		})
+		require.NoError(t, err)
+
+		for i := range 200 {
+			bl.Logger.Info("/graphql", zap.Int("request", i))
+			if i == 100 {
+				require.NoError(t, f.Reopen())
+			}
+		}
+		require.NoError(t, bl.Close())
+		require.NoError(t, f.Close())
+
+		require.Greater(t, len(fileNames(t, dir)), 2)
+		lines := readLines(t, dir)
+		require.Len(t, lines, 200)
+		for _, line := range lines {
+			require.True(t, strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}"), line)
+		}
+	})
+}
@@ -13,22 +13,24 @@ import (
 
 // OperationsManager handles the loading and preparation of GraphQL operations
 type OperationsManager struct {
-	schemaDoc        *ast.Document
-	operations       []schemaloader.Operation
-	logger           *zap.Logger
-	excludeMutations bool
+	schemaDoc            *ast.Document
+	operations           []schemaloader.Operation
+	logger               *zap.Logger
+	excludeMutations     bool
+	excludeSubscriptions bool
 }
 
 // NewOperationsManager creates a new operations manager
-func NewOperationsManager(schemaDoc *ast.Document, logger *zap.Logger, excludeMutations bool) *OperationsManager {
+func NewOperationsManager(schemaDoc *ast.Document, logger *zap.Logger, excludeMutations, excludeSubscriptions bool) *OperationsManager {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 
 	return &OperationsManager{
-		schemaDoc:        schemaDoc,
-		logger:           logger,
-		excludeMutations: excludeMutations,
+		schemaDoc:            schemaDoc,
+		logger:               logger,
+		excludeMutations:     excludeMutations,
+		excludeSubscriptions: excludeSubscriptions,
 	}
 }
 
@@ -58,15 +60,15 @@ func (om *OperationsManager) GetOperations() []schemaloader.Operation {
 	return om.operations
 }
 
-// GetFilteredOperations returns operations filtered by excludeMutations setting
+// GetFilteredOperations returns operations filtered by the excludeMutations and excludeSubscriptions settings
 func (om *OperationsManager) GetFilteredOperations() []schemaloader.Operation {
-	if !om.excludeMutations {
+	if !om.excludeMutations && !om.excludeSubscriptions {
 		return om.operations
 	}
 
 	filteredOps := make([]schemaloader.Operation, 0, len(om.operations))
 	for _, op := range om.operations {
-		if op.OperationType != "mutation" {
+		if !om.isExcluded(&op) {
 			filteredOps = append(filteredOps, op)
 		}
 	}
@@ -77,8 +79,8 @@ func (om *OperationsManager) GetFilteredOperations() []schemaloader.Operation {
 func (om *OperationsManager) GetOperation(name string) *schemaloader.Operation {
 	for i := range om.operations {
 		if om.operations[i].Name == name {
-			if om.operations[i].OperationType == "mutation" && om.excludeMutations {
-				return nil // Mutation excluded by configuration
+			if om.isExcluded(&om.operations[i]) {
+				return nil // Operation type excluded by configuration
 			}
 			return &om.operations[i]
 		}
@@ -86,6 +88,17 @@ func (om *OperationsManager) GetOperation(name string) *schemaloader.Operation {
 	return nil
 }
 
+// isExcluded reports whether the type of the operation is excluded by configuration
+func (om *OperationsManager) isExcluded(op *schemaloader.Operation) bool {
+	switch op.OperationType {
+	case "mutation":
+		return om.excludeMutations
+	case "subscription":
+		return om.excludeSubscriptions
+	}
+	return false
+}
+
 // ComputeToolScopes runs the scope extractor against all loaded operations,
 // populating each operation's RequiredScopes from @requiresScopes directives.
 // Returns an error if any operation exceeds the scope combination limit, which
@@ -94,6 +94,8 @@ type Options struct {
 	CorsConfig cors.Config
 	// OAuthConfig is the OAuth/JWKS configuration for authentication
 	OAuthConfig *config.MCPOAuthConfiguration
+	// Subscriptions configures the tools generated from subscription operations
+	Subscriptions config.MCPSubscriptionsConfiguration
 	// ServerBaseURL is the base URL of this MCP server (for resource metadata)
 	ServerBaseURL string
 	// ResourceDocumentation is a URL to a human-readable page describing this resource
@@ -142,6 +144,8 @@ type GraphQLSchemaServer struct {
 	serverBaseURL             string
 	resourceDocumentation     string
 	authMiddleware            *MCPAuthMiddleware
+	subscriptions             config.MCPSubscriptionsConfiguration
+	subscriptionClient        *http.Client
 }
 
 type graphqlRequest struct {
@@ -234,6 +238,10 @@ func NewGraphQLSchemaServer(ctx context.Context, routerGraphQLEndpoint string, o
 		ExposeSchema:   true,
 		Stateless:      true,
 		ServerVersion:  "dev",
+		Subscriptions: config.MCPSubscriptionsConfiguration{
+			MaxEvents:   100,
+			MaxDuration: 25 * time.Second,
+		},
 	}
 
 	// Apply all option functions
@@ -350,6 +358,10 @@ func NewGraphQLSchemaServer(ctx context.Context, routerGraphQLEndpoint string, o
 		serverBaseURL:             options.ServerBaseURL,
 		resourceDocumentation:     options.ResourceDocumentation,
 		authMiddleware:            authMiddleware,
+		subscriptions:             options.Subscriptions,
+		// Subscriptions are bounded by the requested duration instead of a client timeout
+		// and must not be retried once events were received.
+		subscriptionClient: &http.Client{},
 	}
 
 	return gs, nil
@@ -480,6 +492,16 @@ func WithOAuth(oauthCfg *config.MCPOAuthConfiguration) func(*Options) {
 	}
 }
 
+// WithSubscriptions configures the tools generated from subscription operations
+// Zero limits keep the defaults.
+func WithSubscriptions(subscriptions config.MCPSubscriptionsConfiguration) func(*Options) {
+	return func(o *Options) {
+		o.Subscriptions.Enabled = subscriptions.Enabled
+		o.Subscriptions.MaxEvents = cmp.Or(subscriptions.MaxEvents, o.Subscriptions.MaxEvents)
+		o.Subscriptions.MaxDuration = cmp.Or(subscriptions.MaxDuration, o.Subscriptions.MaxDuration)
+	}
+}
+
 // WithServerBaseURL sets the server base URL for OAuth discovery
 func WithServerBaseURL(baseURL string) func(*Options) {
 	return func(o *Options) {
@@ -504,6 +526,12 @@ func (s *GraphQLSchemaServer) Serve() (*http.Server, error) {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Subscription tool calls stay open for up to the maximum duration and need
+	// some headroom to write the collected events afterwards
+	if s.subscriptions.Enabled {
+		httpServer.WriteTimeout = max(httpServer.WriteTimeout, s.subscriptions.MaxDuration+5*time.Second)
+	}
+
 	// Create MCP streamable HTTP handler
 	// The getServer function returns our MCP server instance for each request
 	// Disable the SDK's built-in cross-origin protection (Sec-Fetch-Site check)
@@ -550,6 +578,7 @@ func (s *GraphQLSchemaServer) Serve() (*http.Server, error) {
 		zap.String("operations_dir", s.operationsDir),
 		zap.String("graph_name", s.graphName),
 		zap.Bool("exclude_mutations", s.excludeMutations),
+		zap.Bool("subscriptions", s.subscriptions.Enabled),
 		zap.Bool("enable_arbitrary_operations", s.enableArbitraryOperations),
 		zap.Bool("expose_schema", s.exposeSchema),
 	}
@@ -588,7 +617,7 @@ func (s *GraphQLSchemaServer) Reload(schema *ast.Document, fieldConfigs []*nodev
 	}
 
 	s.schemaCompiler = NewSchemaCompiler(s.logger)
-	s.operationsManager = NewOperationsManager(schema, s.logger, s.excludeMutations)
+	s.operationsManager = NewOperationsManager(schema, s.logger, s.excludeMutations, !s.subscriptions.Enabled)
 
 	if s.operationsDir != "" {
 		if err := s.operationsManager.LoadOperationsFromDirectory(s.operationsDir); err != nil {
@@ -743,6 +772,7 @@ func (s *GraphQLSchemaServer) registerTools() error {
 			operation:      op,
 			compiledSchema: compiledSchema,
 		}
+		isSubscription := op.OperationType == "subscription"
 
 		// Convert the operation name to snake_case for consistent tool naming
 		operationToolName := strcase.ToSnake(op.Name)
@@ -777,12 +807,15 @@ func (s *GraphQLSchemaServer) registerTools() error {
 		} else {
 			inputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
 		}
+		if isSubscription {
+			inputSchema = s.subscriptionInputSchema(inputSchema)
+		}
 
 		// Declare the response envelope of the operation's selection set as the
 		// tool's output schema. A build failure only degrades the tool: it is
 		// registered without an output schema.
 		var outputSchema any
-		if s.outputSchemaEnabled {
+		if s.outputSchemaEnabled && !isSubscription {
 			if outputJSONSchema, err := buildResponseSchema(&op.Document, s.operationsManager.GetSchema()); err != nil {
 				s.logger.Warn("failed to build output schema for operation; registering tool without output schema",
 					zap.String("operation", op.Name),
@@ -801,12 +834,16 @@ func (s *GraphQLSchemaServer) registerTools() error {
 			Annotations: &mcp.ToolAnnotations{
 				IdempotentHint: op.OperationType != "mutation",
 				Title:          fmt.Sprintf("Execute operation %s", op.Name),
-				ReadOnlyHint:   op.OperationType == "query",
+				ReadOnlyHint:   op.OperationType != "mutation",
 				OpenWorldHint:  &openWorld,
 			},
 		}
 
-		s.server.AddTool(tool, s.handleOperation(handler))
+		if isSubscription {
+			s.server.AddTool(tool, s.handleSubscription(handler))
+		} else {
+			s.server.AddTool(tool, s.handleOperation(handler))
+		}
 
 		s.registeredTools = append(s.registeredTools, toolName)
 
@@ -919,6 +956,9 @@ Usage Instructions:
 3. Headers Required:
    - Content-Type: application/json; charset=utf-8
 `, s.routerGraphQLEndpoint)
+		if targetOp.OperationType == "subscription" {
+			usageInstructions += "   - Accept: text/event-stream (events are streamed as Server-Sent Events)\n"
+		}
 
 		// Request format section
 		requestFormat := "\nRequest Format:\n```json\n"
@@ -981,23 +1021,7 @@ func (s *GraphQLSchemaServer) executeGraphQLQuery(ctx context.Context, query str
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Forward all headers from the original MCP request to the GraphQL server
-	// The router's header forwarding rules will then determine what gets sent to subgraphs
-	reqHeaders, err := headersFromContext(ctx)
-	if err != nil {
-		s.logger.Debug("failed to get headers from context", zap.Error(err))
-	} else {
-		// Copy all headers from the MCP request
-		for key, values := range reqHeaders {
-			// Skip headers that should not be forwarded
-			if _, ok := headers.SkippedHeaders[key]; ok {
-				continue
-			}
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
-	}
+	s.forwardRequestHeaders(ctx, req)
 
 	// Override specific headers that must be set for GraphQL requests
 	req.Header.Set("Accept", "application/json")
@@ -1031,12 +1055,7 @@ func (s *GraphQLSchemaServer) executeGraphQLQuery(ctx context.Context, query str
 
 	if len(graphqlResponse.Errors) > 0 {
 		// Concatenate all error messages
-		var errorMessages []string
-		for _, gqlErr := range graphqlResponse.Errors {
-			errorMessages = append(errorMessages, gqlErr.Message)
-		}
-
-		errorMessage := strings.Join(errorMessages, "; ")
+		errorMessage := joinGraphQLErrors(graphqlResponse.Errors)
 
 		// If there are errors but no data, return only the errors
 		if len(graphqlResponse.Data) == 0 || string(graphqlResponse.Data) == "null" {
@@ -1066,6 +1085,26 @@ func (s *GraphQLSchemaServer) executeGraphQLQuery(ctx context.Context, query str
 	return result, nil
 }
 
+// forwardRequestHeaders copies all headers from the original MCP request to the GraphQL request.
+// The router's header forwarding rules will then determine what gets sent to subgraphs.
+func (s *GraphQLSchemaServer) forwardRequestHeaders(ctx context.Context, req *http.Request) {
+	reqHeaders, err := headersFromContext(ctx)
+	if err != nil {
+		s.logger.Debug("failed to get headers from context", zap.Error(err))
+		return
+	}
+
+	for key, values := range reqHeaders {
+		// Skip headers that should not be forwarded
+		if _, ok := headers.SkippedHeaders[key]; ok {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+}
+
 // handleExecuteGraphQL returns a handler function that executes arbitrary GraphQL queries
 func (s *GraphQLSchemaServer) handleExecuteGraphQL() func(ctx context.Context, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
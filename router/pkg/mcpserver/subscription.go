@@ -0,0 +1,305 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/wundergraph/cosmo/router/internal/sse"
+	"go.uber.org/zap"
+)
+
+const (
+	// subscriptionStopMaxEvents is reported when the requested number of events arrived
+	subscriptionStopMaxEvents = "max_events"
+	// subscriptionStopDuration is reported when the requested duration elapsed
+	subscriptionStopDuration = "duration"
+	// subscriptionStopCompleted is reported when the router completed the subscription
+	subscriptionStopCompleted = "completed"
+
+	// maxSubscriptionEventSize limits the size of a single Server-Sent Event
+	maxSubscriptionEventSize = 10 * 1024 * 1024
+)
+
+// SubscriptionInput defines the input structure of tools generated from subscription operations
+type SubscriptionInput struct {
+	Variables       json.RawMessage `json:"variables,omitempty"`
+	MaxEvents       int             `json:"maxEvents,omitempty"`
+	DurationSeconds float64         `json:"durationSeconds,omitempty"`
+}
+
+// SubscriptionResult is the result of a subscription tool call
+type SubscriptionResult struct {
+	Events []json.RawMessage `json:"events"`
+	// StopReason is one of "max_events", "duration" or "completed"
+	StopReason string `json:"stopReason"`
+}
+
+// subscriptionInputSchema wraps the input schema of a subscription operation, so the operation
+// variables don't collide with the arguments bounding the subscription.
+func (s *GraphQLSchemaServer) subscriptionInputSchema(variablesSchema any) any {
+	properties := map[string]any{
+		"maxEvents": map[string]any{
+			"type":        "integer",
+			"minimum":     1,
+			"maximum":     s.subscriptions.MaxEvents,
+			"description": fmt.Sprintf("The number of events to collect before returning. Defaults to %d.", s.subscriptions.MaxEvents),
+		},
+		"durationSeconds": map[string]any{
+			"type":             "number",
+			"exclusiveMinimum": 0,
+			"maximum":          s.subscriptions.MaxDuration.Seconds(),
+			"description":      fmt.Sprintf("The number of seconds to collect events for before returning. Defaults to %g.", s.subscriptions.MaxDuration.Seconds()),
+		},
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"description":          "Subscribes to the operation and collects events until either maxEvents events arrived or durationSeconds elapsed. Every event is also sent as a progress notification if the request carries a progress token.",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	// Operations without variables have an input schema without properties
+	if vs, ok := variablesSchema.(map[string]any); ok {
+		if props, ok := vs["properties"].(map[string]any); ok && len(props) > 0 {
+			properties["variables"] = variablesSchema
+			if required, ok := vs["required"].([]any); ok && len(required) > 0 {
+				schema["required"] = []string{"variables"}
+			}
+		}
+	}
+
+	return schema
+}
+
+// subscriptionBounds returns the number of events and the duration requested by the client, capped
+// by the configured maximums.
+func (s *GraphQLSchemaServer) subscriptionBounds(input SubscriptionInput) (int, time.Duration) {
+	maxEvents := s.subscriptions.MaxEvents
+	if input.MaxEvents > 0 {
+		maxEvents = min(input.MaxEvents, maxEvents)
+	}
+
+	duration := s.subscriptions.MaxDuration
+	if input.DurationSeconds > 0 {
+		duration = min(time.Duration(input.DurationSeconds*float64(time.Second)), duration)
+	}
+
+	return maxEvents, duration
+}
+
+// handleSubscription handles a subscription operation
+func (s *GraphQLSchemaServer) handleSubscription(handler *operationHandler) func(ctx context.Context, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Log authenticated user if OAuth is enabled
+		if claims, ok := GetClaimsFromContext(ctx); ok {
+			s.logger.Debug("subscription called by authenticated user",
+				zap.String("sub", getClaimString(claims, "sub")),
+				zap.String("email", getClaimString(claims, "email")),
+				zap.String("operation", handler.operation.Name))
+		}
+
+		var input SubscriptionInput
+		if len(request.Params.Arguments) > 0 {
+			if err := json.Unmarshal(request.Params.Arguments, &input); err != nil {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Input validation error: %v", err)}},
+					IsError: true,
+				}, nil
+			}
+		}
+
+		// Validate the variables against the pre-compiled schema derived from the operation input type
+		if handler.compiledSchema != nil {
+			variables := input.Variables
+			if len(variables) == 0 {
+				variables = json.RawMessage("{}")
+			}
+			if err := s.schemaCompiler.ValidateInput(variables, handler.compiledSchema); err != nil {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Input validation error: %v", err)}},
+					IsError: true,
+				}, nil
+			}
+		}
+
+		maxEvents, duration := s.subscriptionBounds(input)
+
+		return s.executeGraphQLSubscription(ctx, handler.operation.OperationString, input.Variables, maxEvents, duration, s.progressNotifier(request, maxEvents))
+	}
+}
+
+// progressNotifier returns a function sending every event as a progress notification. It returns nil
+// if the client didn't ask for progress notifications.
+func (s *GraphQLSchemaServer) progressNotifier(request *mcp.CallToolRequest, maxEvents int) func(ctx context.Context, count int, event json.RawMessage) {
+	token := request.Params.GetProgressToken()
+	if token == nil || request.Session == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, count int, event json.RawMessage) {
+		err := request.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(count),
+			Total:         float64(maxEvents),
+			Message:       string(event),
+		})
+		if err != nil {
+			s.logger.Debug("failed to send progress notification", zap.Error(err))
+		}
+	}
+}
+
+// executeGraphQLSubscription subscribes to the router endpoint over Server-Sent Events and collects
+// the data of up to maxEvents events within the given duration. onEvent is called for every event.
+func (s *GraphQLSchemaServer) executeGraphQLSubscription(ctx context.Context, query string, variables json.RawMessage, maxEvents int, duration time.Duration, onEvent func(ctx context.Context, count int, event json.RawMessage)) (*mcp.CallToolResult, error) {
+	graphqlRequestBytes, err := json.Marshal(graphqlRequest{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal GraphQL request: %w", err)
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(subCtx, "POST", s.routerGraphQLEndpoint, bytes.NewReader(graphqlRequestBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	s.forwardRequestHeaders(ctx, req)
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	result := SubscriptionResult{
+		Events: []json.RawMessage{},
+	}
+
+	resp, err := s.subscriptionClient.Do(req)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(subCtx.Err(), context.DeadlineExceeded) {
+			result.StopReason = subscriptionStopDuration
+			return subscriptionToolResult(result)
+		}
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// The router answers with a single JSON response if the subscription could not be started,
+	// e.g. because the operation failed validation
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		var graphqlResponse *GraphQLResponse
+		if err := json.Unmarshal(body, &graphqlResponse); err != nil || graphqlResponse == nil || len(graphqlResponse.Errors) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Response error: unexpected response from GraphQL endpoint: %s", body)}},
+				IsError: true,
+			}, nil
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Response error: %s", joinGraphQLErrors(graphqlResponse.Errors))}},
+			IsError: true,
+		}, nil
+	}
+
+	// toolResult is set when an event ends the subscription with a tool error
+	var toolResult *mcp.CallToolResult
+
+	err = sse.Read(resp.Body, maxSubscriptionEventSize, func(event sse.Event) (bool, error) {
+		if event.Name == "complete" {
+			result.StopReason = subscriptionStopCompleted
+			return false, nil
+		}
+		if event.Data == "" {
+			return true, nil
+		}
+
+		var graphqlResponse *GraphQLResponse
+		if err := json.Unmarshal([]byte(event.Data), &graphqlResponse); err != nil || graphqlResponse == nil {
+			toolResult = &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Response error: unexpected event from GraphQL endpoint: %s", event.Data)}},
+				IsError: true,
+			}
+			return false, nil
+		}
+		if len(graphqlResponse.Errors) > 0 {
+			toolResult = &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Response error after %d events: %s", len(result.Events), joinGraphQLErrors(graphqlResponse.Errors))}},
+				IsError: true,
+			}
+			return false, nil
+		}
+
+		result.Events = append(result.Events, graphqlResponse.Data)
+		if onEvent != nil {
+			onEvent(ctx, len(result.Events), graphqlResponse.Data)
+		}
+
+		if len(result.Events) >= maxEvents {
+			result.StopReason = subscriptionStopMaxEvents
+			return false, nil
+		}
+		return true, nil
+	})
+	if toolResult != nil {
+		return toolResult, nil
+	}
+	if err != nil {
+		// The client cancelled the tool call
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if subCtx.Err() == nil {
+			return nil, fmt.Errorf("failed to read subscription events: %w", err)
+		}
+	}
+	if result.StopReason != "" {
+		return subscriptionToolResult(result)
+	}
+
+	if subCtx.Err() != nil {
+		result.StopReason = subscriptionStopDuration
+	} else {
+		// The router closed the stream without completing it explicitly
+		result.StopReason = subscriptionStopCompleted
+	}
+
+	return subscriptionToolResult(result)
+}
+
+func subscriptionToolResult(result SubscriptionResult) (*mcp.CallToolResult, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal subscription result: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(resultBytes)}},
+	}, nil
+}
+
+// joinGraphQLErrors concatenates the messages of all errors
+func joinGraphQLErrors(errs []GraphQLError) string {
+	errorMessages := make([]string, 0, len(errs))
+	for _, gqlErr := range errs {
+		errorMessages = append(errorMessages, gqlErr.Message)
+	}
+	return strings.Join(errorMessages, "; ")
+}
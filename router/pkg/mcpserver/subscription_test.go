@@ -0,0 +1,257 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/astparser"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/asttransform"
+	"go.uber.org/zap"
+)
+
+const testSubscriptionSchema = `
+schema {
+  query: Query
+  subscription: Subscription
+}
+
+type Query {
+  employees: [Employee!]!
+}
+
+type Subscription {
+  employeeUpdated(id: ID!): Employee!
+}
+
+type Employee {
+  id: ID!
+  name: String!
+}
+`
+
+const employeeUpdatedOp = `
+subscription EmployeeUpdated($id: ID!) {
+  employeeUpdated(id: $id) {
+    id
+    name
+  }
+}
+`
+
+// sseServer writes the given events as Server-Sent Events and keeps the stream open unless
+// complete is set.
+func sseServer(t *testing.T, events []string, complete bool) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, ":heartbeat\n\n")
+		for _, event := range events {
+			_, _ = fmt.Fprintf(w, "event: next\ndata: %s\n\n", event)
+		}
+		if complete {
+			_, _ = fmt.Fprint(w, "event: complete\ndata: \n\n")
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newSubscriptionServer(t *testing.T, endpoint string) *GraphQLSchemaServer {
+	t.Helper()
+
+	srv, err := NewGraphQLSchemaServer(
+		t.Context(),
+		endpoint,
+		WithLogger(zap.NewNop()),
+		WithOperationsDir(t.TempDir()),
+		WithSubscriptions(config.MCPSubscriptionsConfiguration{Enabled: true, MaxEvents: 5, MaxDuration: 2 * time.Second}),
+	)
+	require.NoError(t, err)
+
+	return srv
+}
+
+func decodeSubscriptionResult(t *testing.T, result *mcp.CallToolResult) SubscriptionResult {
+	t.Helper()
+
+	require.False(t, result.IsError, "unexpected tool error: %v", result.Content)
+	require.Len(t, result.Content, 1)
+
+	var res SubscriptionResult
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &res))
+	return res
+}
+
+func TestExecuteGraphQLSubscription(t *testing.T) {
+	t.Parallel()
+
+	events := []string{
+		`{"data":{"employeeUpdated":{"id":"1"}}}`,
+		`{"data":{"employeeUpdated":{"id":"2"}}}`,
+		`{"data":{"employeeUpdated":{"id":"3"}}}`,
+	}
+
+	t.Run("stops after the maximum number of events", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newSubscriptionServer(t, sseServer(t, events, false).URL)
+
+		var progress []int
+		result, err := srv.executeGraphQLSubscription(t.Context(), employeeUpdatedOp, nil, 2, 2*time.Second, func(_ context.Context, count int, _ json.RawMessage) {
+			progress = append(progress, count)
+		})
+		require.NoError(t, err)
+
+		res := decodeSubscriptionResult(t, result)
+		assert.Equal(t, subscriptionStopMaxEvents, res.StopReason)
+		require.Len(t, res.Events, 2)
+		assert.JSONEq(t, `{"employeeUpdated":{"id":"1"}}`, string(res.Events[0]))
+		assert.Equal(t, []int{1, 2}, progress)
+	})
+
+	t.Run("stops when the router completes the subscription", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newSubscriptionServer(t, sseServer(t, events, true).URL)
+
+		result, err := srv.executeGraphQLSubscription(t.Context(), employeeUpdatedOp, nil, 5, 2*time.Second, nil)
+		require.NoError(t, err)
+
+		res := decodeSubscriptionResult(t, result)
+		assert.Equal(t, subscriptionStopCompleted, res.StopReason)
+		assert.Len(t, res.Events, 3)
+	})
+
+	t.Run("stops when the duration elapsed", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newSubscriptionServer(t, sseServer(t, events[:1], false).URL)
+
+		result, err := srv.executeGraphQLSubscription(t.Context(), employeeUpdatedOp, nil, 5, 200*time.Millisecond, nil)
+		require.NoError(t, err)
+
+		res := decodeSubscriptionResult(t, result)
+		assert.Equal(t, subscriptionStopDuration, res.StopReason)
+		assert.Len(t, res.Events, 1)
+	})
+
+	t.Run("errors in an event are a tool error", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newSubscriptionServer(t, sseServer(t, []string{events[0], `{"errors":[{"message":"boom"}]}`}, true).URL)
+
+		result, err := srv.executeGraphQLSubscription(t.Context(), employeeUpdatedOp, nil, 5, 2*time.Second, nil)
+		require.NoError(t, err)
+		require.True(t, result.IsError)
+		assert.Equal(t, "Response error after 1 events: boom", result.Content[0].(*mcp.TextContent).Text)
+	})
+
+	t.Run("a JSON response is a tool error", func(t *testing.T) {
+		t.Parallel()
+
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"errors":[{"message":"invalid operation"}]}`))
+		}))
+		t.Cleanup(upstream.Close)
+
+		srv := newSubscriptionServer(t, upstream.URL)
+
+		result, err := srv.executeGraphQLSubscription(t.Context(), employeeUpdatedOp, nil, 5, 2*time.Second, nil)
+		require.NoError(t, err)
+		require.True(t, result.IsError)
+		assert.Equal(t, "Response error: invalid operation", result.Content[0].(*mcp.TextContent).Text)
+	})
+}
+
+func TestSubscriptionBounds(t *testing.T) {
+	t.Parallel()
+
+	srv := newSubscriptionServer(t, "http://localhost:4000/graphql")
+
+	maxEvents, duration := srv.subscriptionBounds(SubscriptionInput{})
+	assert.Equal(t, 5, maxEvents)
+	assert.Equal(t, 2*time.Second, duration)
+
+	maxEvents, duration = srv.subscriptionBounds(SubscriptionInput{MaxEvents: 3, DurationSeconds: 0.5})
+	assert.Equal(t, 3, maxEvents)
+	assert.Equal(t, 500*time.Millisecond, duration)
+
+	maxEvents, duration = srv.subscriptionBounds(SubscriptionInput{MaxEvents: 1000, DurationSeconds: 60})
+	assert.Equal(t, 5, maxEvents)
+	assert.Equal(t, 2*time.Second, duration)
+}
+
+func TestRegisterTools_Subscriptions(t *testing.T) {
+	t.Parallel()
+
+	schemaDoc, report := astparser.ParseGraphqlDocumentString(testSubscriptionSchema)
+	require.False(t, report.HasErrors())
+	require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&schemaDoc))
+
+	newServer := func(t *testing.T, enabled bool) *GraphQLSchemaServer {
+		t.Helper()
+
+		tempDir := t.TempDir()
+		writeOperationFiles(t, tempDir, map[string]string{
+			"EmployeeUpdated.graphql": employeeUpdatedOp,
+		})
+
+		srv, err := NewGraphQLSchemaServer(
+			t.Context(),
+			"http://localhost:4000/graphql",
+			WithLogger(zap.NewNop()),
+			WithOperationsDir(tempDir),
+			WithSubscriptions(config.MCPSubscriptionsConfiguration{Enabled: enabled}),
+		)
+		require.NoError(t, err)
+		require.NoError(t, srv.Reload(&schemaDoc, nil))
+
+		return srv
+	}
+
+	t.Run("subscriptions are skipped when disabled", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newServer(t, false)
+		assert.NotContains(t, srv.registeredTools, "execute_operation_employee_updated")
+		assert.Nil(t, srv.operationsManager.GetOperation("EmployeeUpdated"))
+	})
+
+	t.Run("subscriptions are registered when enabled", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newServer(t, true)
+		assert.Contains(t, srv.registeredTools, "execute_operation_employee_updated")
+
+		op := srv.operationsManager.GetOperation("EmployeeUpdated")
+		require.NotNil(t, op)
+
+		var variablesSchema any
+		require.NoError(t, json.Unmarshal(op.JSONSchema, &variablesSchema))
+
+		inputSchema := srv.subscriptionInputSchema(variablesSchema).(map[string]any)
+		properties := inputSchema["properties"].(map[string]any)
+		assert.Contains(t, properties, "variables")
+		assert.Contains(t, properties, "maxEvents")
+		assert.Contains(t, properties, "durationSeconds")
+		assert.Equal(t, []string{"variables"}, inputSchema["required"])
+	})
+}
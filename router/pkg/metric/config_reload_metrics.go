@@ -0,0 +1,65 @@
+package metric
+
+import (
+	"context"
+
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+const (
+	cosmoRouterConfigReloadMeterName    = "cosmo.router.config.reload"
+	cosmoRouterConfigReloadMeterVersion = "0.0.1"
+
+	configReloadCounter = "router.config.reloads"
+)
+
+const (
+	// ConfigReloadModeLive is a reload that was applied to the running router
+	ConfigReloadModeLive = "live"
+	// ConfigReloadModeRestart is a reload that restarted the router
+	ConfigReloadModeRestart = "restart"
+)
+
+// ConfigReloadMetrics counts the reloads of the router config file
+type ConfigReloadMetrics struct {
+	counters []otelmetric.Int64Counter
+}
+
+// NewConfigReloadMetrics creates the reload counter on every given meter provider
+func NewConfigReloadMetrics(providers ...*metric.MeterProvider) (*ConfigReloadMetrics, error) {
+	m := &ConfigReloadMetrics{}
+
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+
+		meter := provider.Meter(cosmoRouterConfigReloadMeterName, otelmetric.WithInstrumentationVersion(cosmoRouterConfigReloadMeterVersion))
+		counter, err := meter.Int64Counter(
+			configReloadCounter,
+			otelmetric.WithDescription("Total number of router config reloads"),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		m.counters = append(m.counters, counter)
+	}
+
+	return m, nil
+}
+
+// MeasureReload records a reload with the given mode and outcome
+func (m *ConfigReloadMetrics) MeasureReload(ctx context.Context, mode string, success bool) {
+	attrs := otelmetric.WithAttributes(
+		otel.WgRouterConfigReloadMode.String(mode),
+		otel.WgRouterConfigReloadSuccess.Bool(success),
+	)
+
+	for _, counter := range m.counters {
+		counter.Add(ctx, 1, attrs)
+	}
+}
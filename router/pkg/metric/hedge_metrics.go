@@ -0,0 +1,63 @@
+package metric
+
+import (
+	"context"
+
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+const (
+	cosmoRouterHedgeMeterName    = "cosmo.router.subgraph.hedge"
+	cosmoRouterHedgeMeterVersion = "0.0.1"
+
+	hedgedRequestsCounter = "router.subgraph.hedged_requests"
+)
+
+// HedgeMetrics counts the hedged subgraph requests and whether the hedged request won
+type HedgeMetrics struct {
+	counters []otelmetric.Int64Counter
+}
+
+// NewHedgeMetrics creates the hedged requests counter on every given meter provider
+func NewHedgeMetrics(providers ...*metric.MeterProvider) (*HedgeMetrics, error) {
+	m := &HedgeMetrics{}
+
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+
+		meter := provider.Meter(cosmoRouterHedgeMeterName, otelmetric.WithInstrumentationVersion(cosmoRouterHedgeMeterVersion))
+		counter, err := meter.Int64Counter(
+			hedgedRequestsCounter,
+			otelmetric.WithDescription("Total number of hedged subgraph requests"),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		m.counters = append(m.counters, counter)
+	}
+
+	return m, nil
+}
+
+// MeasureHedge records a hedged request to the subgraph. won is true when the response of the
+// hedged request was used.
+func (m *HedgeMetrics) MeasureHedge(ctx context.Context, subgraph string, won bool) {
+	if m == nil {
+		return
+	}
+
+	attrs := otelmetric.WithAttributes(
+		otel.WgSubgraphName.String(subgraph),
+		otel.WgSubgraphHedgeWon.Bool(won),
+	)
+
+	for _, counter := range m.counters {
+		counter.Add(ctx, 1, attrs)
+	}
+}
@@ -0,0 +1,63 @@
+package metric
+
+import (
+	"context"
+
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+const (
+	cosmoRouterLoadBalancerMeterName    = "cosmo.router.load_balancer"
+	cosmoRouterLoadBalancerMeterVersion = "0.0.1"
+
+	subgraphEndpointEjectedGauge = "router.subgraph.endpoint.ejected"
+)
+
+// EndpointEjection is a subgraph endpoint that doesn't receive requests
+type EndpointEjection struct {
+	Subgraph string
+	Endpoint string
+	// Reason is either health_check or outlier
+	Reason string
+}
+
+// RegisterLoadBalancerMetrics registers the ejected endpoint gauge on every given meter provider.
+// Every ejected endpoint is reported with a value of 1 while it is ejected.
+// The gauges are removed when the meter providers are shut down.
+func RegisterLoadBalancerMetrics(ejections func() []EndpointEjection, providers ...*metric.MeterProvider) error {
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+
+		meter := provider.Meter(cosmoRouterLoadBalancerMeterName, otelmetric.WithInstrumentationVersion(cosmoRouterLoadBalancerMeterVersion))
+		gauge, err := meter.Int64ObservableGauge(
+			subgraphEndpointEjectedGauge,
+			otelmetric.WithDescription("Subgraph endpoints ejected from load balancing"),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+			for _, ejection := range ejections() {
+				o.ObserveInt64(gauge, 1,
+					otelmetric.WithAttributes(
+						otel.WgSubgraphName.String(ejection.Subgraph),
+						otel.WgSubgraphEndpoint.String(ejection.Endpoint),
+						otel.WgSubgraphEjectionReason.String(ejection.Reason),
+					),
+				)
+			}
+			return nil
+		}, gauge)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package metric
+
+import (
+	"context"
+	"time"
+
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+const (
+	cosmoRouterTLSMeterName    = "cosmo.router.tls"
+	cosmoRouterTLSMeterVersion = "0.0.1"
+
+	tlsCertificateExpirationGauge = "router.tls.certificate.expiration"
+)
+
+// CertificateExpiration is the expiration time of a certificate loaded from a file
+type CertificateExpiration struct {
+	File     string
+	NotAfter time.Time
+}
+
+// RegisterTLSMetrics registers the certificate expiration gauge on every given meter provider.
+// The expirations are observed on every collection, so reloaded certificates are reported as well.
+// The gauges are removed when the meter providers are shut down.
+func RegisterTLSMetrics(expirations func() []CertificateExpiration, providers ...*metric.MeterProvider) error {
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+
+		meter := provider.Meter(cosmoRouterTLSMeterName, otelmetric.WithInstrumentationVersion(cosmoRouterTLSMeterVersion))
+		gauge, err := meter.Int64ObservableGauge(
+			tlsCertificateExpirationGauge,
+			otelmetric.WithDescription("Expiration time of the server TLS certificates as a Unix timestamp"),
+			otelmetric.WithUnit("s"),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+			for _, expiration := range expirations() {
+				o.ObserveInt64(gauge, expiration.NotAfter.Unix(),
+					otelmetric.WithAttributes(otel.WgTLSCertificateFile.String(expiration.File)),
+				)
+			}
+			return nil
+		}, gauge)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
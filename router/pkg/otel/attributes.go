@@ -19,9 +19,15 @@ const (
 	WgClientVersion              = attribute.Key("wg.client.version")
 	WgRouterVersion              = attribute.Key("wg.router.version")
 	WgRouterConfigVersion        = attribute.Key("wg.router.config.version")
+	WgRouterConfigReloadMode     = attribute.Key("wg.router.config.reload.mode")
+	WgRouterConfigReloadSuccess  = attribute.Key("wg.router.config.reload.success")
+	WgTLSCertificateFile         = attribute.Key("wg.tls.certificate.file")
 	WgFederatedGraphID           = attribute.Key("wg.federated_graph.id")
 	WgSubgraphID                 = attribute.Key("wg.subgraph.id")
 	WgSubgraphName               = attribute.Key("wg.subgraph.name")
+	WgSubgraphEndpoint           = attribute.Key("wg.subgraph.endpoint")
+	WgSubgraphEjectionReason     = attribute.Key("wg.subgraph.endpoint.ejection_reason")
+	WgSubgraphHedgeWon           = attribute.Key("wg.subgraph.hedge.won")
 	// WgRequestError is only used to annotate the request count metric to easily identify errored and non-errored requests
 	// with the same metric. This has simplified the query for the error and request count metric in Cloud.
 	WgRequestError                     = attribute.Key("wg.request.error")
@@ -34,6 +40,7 @@ const (
 	WgSubgraphErrorExtendedCode        = attribute.Key("wg.subgraph.error.extended_code")
 	WgSubgraphErrorMessage             = attribute.Key("wg.subgraph.error.message")
 	WgFeatureFlag                      = attribute.Key("wg.feature_flag")
+	WgFeatureFlagReason                = attribute.Key("wg.feature_flag.reason")
 	WgAcquireResolverWaitTimeMs        = attribute.Key("wg.engine.resolver.wait_time_ms")
 	WgResolverDeduplicatedRequest      = attribute.Key("wg.engine.resolver.deduplicated_request")
 	WgNormalizationCacheHit            = attribute.Key("wg.engine.normalization_cache_hit")
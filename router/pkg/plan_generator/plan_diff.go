@@ -0,0 +1,690 @@
+package plan_generator
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/core"
+	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation/pqlmanifest"
+	"github.com/wundergraph/cosmo/router/pkg/execution_config"
+)
+
+const (
+	DiffReportFileName = "diff_report.json"
+	DiffJUnitFileName  = "diff_report.xml"
+)
+
+type PlanDiffConfig struct {
+	// BaseExecutionConfig is the execution config the operations are planned with today
+	BaseExecutionConfig string
+	// TargetExecutionConfig is the execution config compared with the base. Defaults to the base execution config.
+	TargetExecutionConfig string
+	// FeatureFlag compares with the graph of the feature flag in the target execution config
+	FeatureFlag string
+	// SourceDir is the directory of the operation files. Either SourceDir or Manifest must be set.
+	SourceDir string
+	// Manifest is the path of a PQL manifest file. The operations are named by their hash.
+	Manifest    string
+	OutDir      string
+	Concurrency int
+	Filter      string
+	Timeout     string
+	// PlanTimeRegressionFactor reports a regression when the planning time with the target is
+	// higher than the planning time with the base times the factor. Zero disables it.
+	PlanTimeRegressionFactor float64
+	// PlanTimeRegressionMinDelta ignores planning time increases below the delta, as short planning times are noisy
+	PlanTimeRegressionMinDelta time.Duration
+	// FailOnDiff returns an error if an operation plans differently, fails to plan only with the target or regressed
+	FailOnDiff                         bool
+	Logger                             *zap.Logger
+	MaxDataSourceCollectorsConcurrency uint
+}
+
+type PlanDiffStatus string
+
+const (
+	// PlanDiffStatusUnchanged means the operation is planned with the same fetches
+	PlanDiffStatusUnchanged PlanDiffStatus = "unchanged"
+	// PlanDiffStatusChanged means the fetches of the operation are different
+	PlanDiffStatusChanged PlanDiffStatus = "changed"
+	// PlanDiffStatusNewError means the operation can only be planned with the base
+	PlanDiffStatusNewError PlanDiffStatus = "new_error"
+	// PlanDiffStatusFixed means the operation can only be planned with the target
+	PlanDiffStatusFixed PlanDiffStatus = "fixed"
+	// PlanDiffStatusFailing means the operation can neither be planned with the base nor the target
+	PlanDiffStatusFailing PlanDiffStatus = "failing"
+)
+
+type PlanDiffReport struct {
+	Summary    PlanDiffSummary     `json:"summary"`
+	Operations []OperationPlanDiff `json:"operations,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+type PlanDiffSummary struct {
+	Operations          int `json:"operations"`
+	Unchanged           int `json:"unchanged"`
+	Changed             int `json:"changed"`
+	NewErrors           int `json:"new_errors"`
+	Fixed               int `json:"fixed"`
+	Failing             int `json:"failing"`
+	PlanTimeRegressions int `json:"plan_time_regressions"`
+}
+
+type OperationPlanDiff struct {
+	// Name is the file name of the operation or its hash in the manifest
+	Name                string                  `json:"name"`
+	Status              PlanDiffStatus          `json:"status"`
+	AddedFetches        []FetchSummary          `json:"added_fetches,omitempty"`
+	RemovedFetches      []FetchSummary          `json:"removed_fetches,omitempty"`
+	ChangedQueries      []FetchQueryChange      `json:"changed_queries,omitempty"`
+	ChangedDependencies []FetchDependencyChange `json:"changed_dependencies,omitempty"`
+	BaseError           string                  `json:"base_error,omitempty"`
+	TargetError         string                  `json:"target_error,omitempty"`
+	BaseTimings         core.OperationTimes     `json:"base_timings"`
+	TargetTimings       core.OperationTimes     `json:"target_timings"`
+	PlanTimeRegression  bool                    `json:"plan_time_regression,omitempty"`
+}
+
+// Failed returns true if the operation plans differently, fails to plan only with the target or regressed
+func (d *OperationPlanDiff) Failed() bool {
+	return d.Status == PlanDiffStatusChanged || d.Status == PlanDiffStatusNewError || d.PlanTimeRegression
+}
+
+// FetchRef identifies a fetch by the subgraph and the response path it is made for
+type FetchRef struct {
+	Subgraph string `json:"subgraph"`
+	Path     string `json:"path,omitempty"`
+}
+
+func (r FetchRef) String() string {
+	if r.Path == "" {
+		return r.Subgraph
+	}
+	return r.Subgraph + " at " + r.Path
+}
+
+type FetchSummary struct {
+	FetchRef
+	Kind      string     `json:"kind"`
+	Query     string     `json:"query,omitempty"`
+	DependsOn []FetchRef `json:"depends_on,omitempty"`
+}
+
+type FetchQueryChange struct {
+	FetchRef
+	BaseQuery   string `json:"base_query"`
+	TargetQuery string `json:"target_query"`
+}
+
+type FetchDependencyChange struct {
+	FetchRef
+	Base   []FetchRef `json:"base"`
+	Target []FetchRef `json:"target"`
+}
+
+type diffOperation struct {
+	name    string
+	content []byte
+}
+
+type plannedOperation struct {
+	fetches []FetchSummary
+	timings core.OperationTimes
+	err     error
+}
+
+// PlanDiff plans the operations of cfg.SourceDir or cfg.Manifest with the base and the target
+// execution config and writes the differences per operation to cfg.OutDir as a JSON report
+// and a JUnit report.
+func PlanDiff(ctx context.Context, cfg PlanDiffConfig) error {
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	if cfg.TargetExecutionConfig == "" && cfg.FeatureFlag == "" {
+		return errors.New("either a target execution config or a feature flag is required")
+	}
+	if cfg.TargetExecutionConfig == "" {
+		cfg.TargetExecutionConfig = cfg.BaseExecutionConfig
+	}
+
+	outPath, err := filepath.Abs(cfg.OutDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %w", err)
+	}
+	if err := os.MkdirAll(outPath, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	operations, err := loadDiffOperations(cfg)
+	if err != nil {
+		return err
+	}
+
+	duration, parseErr := time.ParseDuration(cfg.Timeout)
+	if parseErr != nil {
+		return fmt.Errorf("failed to parse timeout: %w", parseErr)
+	}
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	ctxError, cancelError := context.WithCancelCause(ctx)
+	defer cancelError(nil)
+
+	basePg, err := newDiffPlanGenerator(cfg.BaseExecutionConfig, "", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create plan generator for base execution config: %w", err)
+	}
+	targetPg, err := newDiffPlanGenerator(cfg.TargetExecutionConfig, cfg.FeatureFlag, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create plan generator for target execution config: %w", err)
+	}
+
+	operationsQueue := make(chan diffOperation, len(operations))
+	for _, operation := range operations {
+		operationsQueue <- operation
+	}
+	close(operationsQueue)
+
+	var results []OperationPlanDiff
+	var resultsMux sync.Mutex
+
+	wg := sync.WaitGroup{}
+	wg.Add(cfg.Concurrency)
+	for i := 0; i < cfg.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctxError.Done():
+					return
+				case operation, ok := <-operationsQueue:
+					if !ok {
+						return
+					}
+
+					base, err := planOperationForDiff(basePg, operation.content)
+					if err != nil {
+						cancelError(err)
+						return
+					}
+					target, err := planOperationForDiff(targetPg, operation.content)
+					if err != nil {
+						cancelError(err)
+						return
+					}
+
+					res := diffPlannedOperations(operation.name, base, target, cfg)
+
+					resultsMux.Lock()
+					results = append(results, res)
+					resultsMux.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(results, func(a, b OperationPlanDiff) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	report := PlanDiffReport{
+		Summary:    summarizePlanDiffs(results),
+		Operations: results,
+	}
+	if ctxError.Err() != nil {
+		report.Error = context.Cause(ctxError).Error()
+	}
+
+	if err := writePlanDiffReport(filepath.Join(outPath, DiffReportFileName), report); err != nil {
+		return err
+	}
+	if err := writePlanDiffJUnit(filepath.Join(outPath, DiffJUnitFileName), report); err != nil {
+		return err
+	}
+
+	if err := context.Cause(ctxError); err != nil {
+		return err
+	}
+
+	if cfg.FailOnDiff && slices.ContainsFunc(results, func(d OperationPlanDiff) bool { return d.Failed() }) {
+		return fmt.Errorf("some operations plan differently with the target execution config")
+	}
+
+	return nil
+}
+
+func newDiffPlanGenerator(executionConfigPath, featureFlag string, cfg PlanDiffConfig) (*core.PlanGenerator, error) {
+	routerConfig, err := execution_config.FromFile(executionConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if featureFlag != "" {
+		ffConfig, ok := routerConfig.GetFeatureFlagConfigs().GetConfigByFeatureFlagName()[featureFlag]
+		if !ok {
+			return nil, fmt.Errorf("feature flag %q not found in execution config", featureFlag)
+		}
+		routerConfig = &nodev1.RouterConfig{
+			EngineConfig: ffConfig.GetEngineConfig(),
+			Version:      ffConfig.GetVersion(),
+			Subgraphs:    ffConfig.GetSubgraphs(),
+		}
+	}
+
+	return core.NewPlanGeneratorFromConfig(routerConfig, cfg.Logger, cfg.MaxDataSourceCollectorsConcurrency)
+}
+
+// loadDiffOperations reads the operations from the source directory or the manifest
+func loadDiffOperations(cfg PlanDiffConfig) ([]diffOperation, error) {
+	if (cfg.SourceDir == "") == (cfg.Manifest == "") {
+		return nil, errors.New("either an operations directory or a manifest is required")
+	}
+
+	var filter []string
+	if cfg.Filter != "" {
+		filterContent, err := os.ReadFile(cfg.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read filter file: %w", err)
+		}
+
+		filter = strings.Split(string(filterContent), "\n")
+	}
+	included := func(name string) bool {
+		return len(filter) == 0 || slices.Contains(filter, name)
+	}
+
+	var operations []diffOperation
+
+	if cfg.Manifest != "" {
+		data, err := os.ReadFile(cfg.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest file: %w", err)
+		}
+		manifest, err := pqlmanifest.ParseManifest(data)
+		if err != nil {
+			return nil, err
+		}
+		for hash, body := range manifest.Operations {
+			if included(hash) {
+				operations = append(operations, diffOperation{name: hash, content: []byte(body)})
+			}
+		}
+		return operations, nil
+	}
+
+	queries, err := os.ReadDir(cfg.SourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries directory: %w", err)
+	}
+	for _, queryFile := range queries {
+		if !slices.Contains([]string{".graphql", ".gql", ".graphqls"}, filepath.Ext(queryFile.Name())) {
+			continue
+		}
+		if !included(queryFile.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(cfg.SourceDir, queryFile.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read operation file: %w", err)
+		}
+		operations = append(operations, diffOperation{name: queryFile.Name(), content: content})
+	}
+
+	return operations, nil
+}
+
+// planOperationForDiff plans the operation and collects its fetches. Planning errors are
+// returned as part of the planned operation, only a failure to create the planner is returned.
+func planOperationForDiff(pg *core.PlanGenerator, content []byte) (*plannedOperation, error) {
+	// Planners should not be reused.
+	planner, err := pg.GetPlanner()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a planner: %w", err)
+	}
+
+	operation, opTimes, err := planner.ParseAndPrepareOperationContent(content)
+	if err != nil {
+		return &plannedOperation{timings: opTimes, err: err}, nil
+	}
+
+	rawPlan, planTimes, err := planner.PlanPreparedOperation(operation)
+	opTimes = opTimes.Merge(planTimes)
+	if err != nil {
+		return &plannedOperation{timings: opTimes, err: fmt.Errorf("failed to plan operation: %w", err)}, nil
+	}
+
+	queryPlan, err := rawPlan.QueryPlan()
+	if err != nil {
+		return &plannedOperation{timings: opTimes, err: err}, nil
+	}
+
+	return &plannedOperation{fetches: collectFetches(queryPlan), timings: opTimes}, nil
+}
+
+// collectFetches returns the fetches of the query plan in execution order
+func collectFetches(node *resolve.FetchTreeQueryPlanNode) []FetchSummary {
+	var fetches []*resolve.FetchTreeQueryPlan
+	var walk func(node *resolve.FetchTreeQueryPlanNode)
+	walk = func(node *resolve.FetchTreeQueryPlanNode) {
+		if node == nil {
+			return
+		}
+		if node.Trigger != nil {
+			fetches = append(fetches, node.Trigger)
+		}
+		if node.Fetch != nil {
+			fetches = append(fetches, node.Fetch)
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	refs := make(map[int]FetchRef, len(fetches))
+	for _, fetch := range fetches {
+		refs[fetch.FetchID] = FetchRef{Subgraph: fetch.SubgraphName, Path: fetch.Path}
+	}
+
+	summaries := make([]FetchSummary, 0, len(fetches))
+	for _, fetch := range fetches {
+		summary := FetchSummary{
+			FetchRef: FetchRef{Subgraph: fetch.SubgraphName, Path: fetch.Path},
+			Kind:     fetch.Kind,
+			Query:    fetch.Query,
+		}
+		// Fetch IDs differ between plans, so the dependencies are compared by subgraph and path
+		for _, id := range fetch.DependsOnFetchIDs {
+			if ref, ok := refs[id]; ok {
+				summary.DependsOn = append(summary.DependsOn, ref)
+			}
+		}
+		slices.SortFunc(summary.DependsOn, compareFetchRefs)
+		summary.DependsOn = slices.Compact(summary.DependsOn)
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+func compareFetchRefs(a, b FetchRef) int {
+	return cmp.Or(strings.Compare(a.Subgraph, b.Subgraph), strings.Compare(a.Path, b.Path))
+}
+
+func diffPlannedOperations(name string, base, target *plannedOperation, cfg PlanDiffConfig) OperationPlanDiff {
+	res := OperationPlanDiff{
+		Name:          name,
+		Status:        PlanDiffStatusUnchanged,
+		BaseTimings:   base.timings,
+		TargetTimings: target.timings,
+	}
+	if base.err != nil {
+		res.BaseError = base.err.Error()
+	}
+	if target.err != nil {
+		res.TargetError = target.err.Error()
+	}
+
+	switch {
+	case base.err != nil && target.err != nil:
+		res.Status = PlanDiffStatusFailing
+		return res
+	case base.err != nil:
+		res.Status = PlanDiffStatusFixed
+		return res
+	case target.err != nil:
+		res.Status = PlanDiffStatusNewError
+		return res
+	}
+
+	diffFetches(&res, base.fetches, target.fetches)
+	if len(res.AddedFetches) > 0 || len(res.RemovedFetches) > 0 || len(res.ChangedQueries) > 0 || len(res.ChangedDependencies) > 0 {
+		res.Status = PlanDiffStatusChanged
+	}
+
+	if cfg.PlanTimeRegressionFactor > 0 {
+		basePlanTime, targetPlanTime := base.timings.PlanTime, target.timings.PlanTime
+		res.PlanTimeRegression = targetPlanTime-basePlanTime >= cfg.PlanTimeRegressionMinDelta &&
+			float64(targetPlanTime) > float64(basePlanTime)*cfg.PlanTimeRegressionFactor
+	}
+
+	return res
+}
+
+// diffFetches matches the fetches of both plans by subgraph and path. Fetches with the same
+// query are matched first, the remaining ones in execution order.
+func diffFetches(res *OperationPlanDiff, base, target []FetchSummary) {
+	matched := make([]bool, len(target))
+	// pairs holds the index of the matched target fetch for each base fetch
+	pairs := make([]int, len(base))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+
+	match := func(sameQuery bool) {
+		for i, b := range base {
+			if pairs[i] != -1 {
+				continue
+			}
+			for j, t := range target {
+				if matched[j] || b.FetchRef != t.FetchRef || (sameQuery && b.Query != t.Query) {
+					continue
+				}
+				matched[j] = true
+				pairs[i] = j
+				break
+			}
+		}
+	}
+	match(true)
+	match(false)
+
+	for i, b := range base {
+		if pairs[i] == -1 {
+			res.RemovedFetches = append(res.RemovedFetches, b)
+			continue
+		}
+		t := target[pairs[i]]
+		if b.Query != t.Query {
+			res.ChangedQueries = append(res.ChangedQueries, FetchQueryChange{
+				FetchRef:    b.FetchRef,
+				BaseQuery:   b.Query,
+				TargetQuery: t.Query,
+			})
+		}
+		if !slices.Equal(b.DependsOn, t.DependsOn) {
+			res.ChangedDependencies = append(res.ChangedDependencies, FetchDependencyChange{
+				FetchRef: b.FetchRef,
+				Base:     b.DependsOn,
+				Target:   t.DependsOn,
+			})
+		}
+	}
+
+	for j, t := range target {
+		if !matched[j] {
+			res.AddedFetches = append(res.AddedFetches, t)
+		}
+	}
+}
+
+func summarizePlanDiffs(results []OperationPlanDiff) PlanDiffSummary {
+	summary := PlanDiffSummary{Operations: len(results)}
+	for _, res := range results {
+		switch res.Status {
+		case PlanDiffStatusUnchanged:
+			summary.Unchanged++
+		case PlanDiffStatusChanged:
+			summary.Changed++
+		case PlanDiffStatusNewError:
+			summary.NewErrors++
+		case PlanDiffStatusFixed:
+			summary.Fixed++
+		case PlanDiffStatusFailing:
+			summary.Failing++
+		}
+		if res.PlanTimeRegression {
+			summary.PlanTimeRegressions++
+		}
+	}
+	return summary
+}
+
+func writePlanDiffReport(path string, report PlanDiffReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal diff report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write diff report: %w", err)
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writePlanDiffJUnit writes a test case per operation. Changed plans and regressions are failures,
+// new errors are errors and operations failing with both execution configs are skipped.
+func writePlanDiffJUnit(path string, report PlanDiffReport) error {
+	suite := junitTestSuite{
+		Name:      "plan-diff",
+		TestCases: make([]junitTestCase, 0, len(report.Operations)),
+	}
+
+	for _, res := range report.Operations {
+		tc := junitTestCase{
+			Name:      res.Name,
+			ClassName: "plan-diff",
+			Time:      fmt.Sprintf("%.6f", res.TargetTimings.TotalTime().Seconds()),
+		}
+
+		switch {
+		case res.Status == PlanDiffStatusNewError:
+			tc.Error = &junitMessage{Message: "operation fails to plan with the target execution config", Text: res.TargetError}
+			suite.Errors++
+		case res.Failed():
+			tc.Failure = &junitMessage{Message: failureMessage(res), Text: describePlanDiff(res)}
+			suite.Failures++
+		case res.Status == PlanDiffStatusFailing:
+			tc.Skipped = &junitMessage{Message: "operation fails to plan with both execution configs", Text: res.TargetError}
+			suite.Skipped++
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	if report.Error != "" {
+		// Operations that were not planned in time are missing, so the run itself is reported as an error
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      "plan-diff",
+			ClassName: "plan-diff",
+			Time:      "0",
+			Error:     &junitMessage{Message: report.Error},
+		})
+		suite.Tests++
+		suite.Errors++
+	}
+
+	suites := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal junit report: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+	return nil
+}
+
+func failureMessage(res OperationPlanDiff) string {
+	if res.Status == PlanDiffStatusChanged {
+		return "operation plans differently with the target execution config"
+	}
+	return "planning time regressed with the target execution config"
+}
+
+// describePlanDiff returns a human-readable line per difference
+func describePlanDiff(res OperationPlanDiff) string {
+	var lines []string
+	for _, fetch := range res.AddedFetches {
+		lines = append(lines, "added fetch: "+fetch.String())
+	}
+	for _, fetch := range res.RemovedFetches {
+		lines = append(lines, "removed fetch: "+fetch.String())
+	}
+	for _, change := range res.ChangedQueries {
+		lines = append(lines, "changed query: "+change.String())
+	}
+	for _, change := range res.ChangedDependencies {
+		lines = append(lines, fmt.Sprintf("changed dependencies: %s depends on [%s] instead of [%s]",
+			change.String(), joinFetchRefs(change.Target), joinFetchRefs(change.Base)))
+	}
+	if res.PlanTimeRegression {
+		lines = append(lines, fmt.Sprintf("planning time: %s instead of %s", res.TargetTimings.PlanTime, res.BaseTimings.PlanTime))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func joinFetchRefs(refs []FetchRef) string {
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.String())
+	}
+	return strings.Join(names, ", ")
+}
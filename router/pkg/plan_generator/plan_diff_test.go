@@ -0,0 +1,325 @@
+package plan_generator
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/wundergraph/cosmo/router/core"
+	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	"github.com/wundergraph/cosmo/router/pkg/execution_config"
+)
+
+// removeRootField removes the field from the root nodes of the data source
+func removeRootField(engineConfig *nodev1.EngineConfiguration, dataSourceID, typeName, fieldName string) {
+	for _, ds := range engineConfig.DatasourceConfigurations {
+		if ds.Id != dataSourceID {
+			continue
+		}
+		for _, node := range ds.RootNodes {
+			if node.TypeName == typeName {
+				node.FieldNames = slices.DeleteFunc(node.FieldNames, func(name string) bool {
+					return name == fieldName
+				})
+			}
+		}
+	}
+}
+
+// targetEngineConfig moves Employee.details from the employees to the family subgraph
+// and removes Employee.currentMood from the graph
+func targetEngineConfig(t *testing.T) (*nodev1.RouterConfig, *nodev1.EngineConfiguration) {
+	t.Helper()
+
+	routerConfig, err := execution_config.FromFile(path.Join(getTestDataDir(), "execution_config", "base.json"))
+	require.NoError(t, err)
+
+	engineConfig := proto.Clone(routerConfig.EngineConfig).(*nodev1.EngineConfiguration)
+	removeRootField(engineConfig, "1", "Employee", "details")
+	removeRootField(engineConfig, "5", "Employee", "currentMood")
+	engineConfig.GraphqlSchema = strings.Replace(engineConfig.GraphqlSchema, "  currentMood: Mood!\n", "", 1)
+	clientSchema := strings.Replace(engineConfig.GetGraphqlClientSchema(), "  currentMood: Mood!\n", "", 1)
+	engineConfig.GraphqlClientSchema = &clientSchema
+
+	return routerConfig, engineConfig
+}
+
+func writeExecutionConfig(t *testing.T, routerConfig *nodev1.RouterConfig) string {
+	t.Helper()
+
+	data, err := protojson.Marshal(routerConfig)
+	require.NoError(t, err)
+
+	configPath := path.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, data, 0644))
+	return configPath
+}
+
+func readDiffReport(t *testing.T, outDir string) PlanDiffReport {
+	t.Helper()
+
+	data, err := os.ReadFile(path.Join(outDir, DiffReportFileName))
+	require.NoError(t, err)
+
+	var report PlanDiffReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	return report
+}
+
+func readJUnitReport(t *testing.T, outDir string) junitTestSuites {
+	t.Helper()
+
+	data, err := os.ReadFile(path.Join(outDir, DiffJUnitFileName))
+	require.NoError(t, err)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &suites))
+	return suites
+}
+
+func TestPlanDiff(t *testing.T) {
+	baseConfig := path.Join(getTestDataDir(), "execution_config", "base.json")
+
+	t.Run("requires a target execution config or a feature flag", func(t *testing.T) {
+		err := PlanDiff(context.Background(), PlanDiffConfig{
+			BaseExecutionConfig: baseConfig,
+			SourceDir:           path.Join(getTestDataDir(), "queries", "diff"),
+			OutDir:              t.TempDir(),
+			Timeout:             "30s",
+		})
+		assert.ErrorContains(t, err, "either a target execution config or a feature flag is required")
+	})
+
+	t.Run("requires either an operations directory or a manifest", func(t *testing.T) {
+		err := PlanDiff(context.Background(), PlanDiffConfig{
+			BaseExecutionConfig:   baseConfig,
+			TargetExecutionConfig: baseConfig,
+			OutDir:                t.TempDir(),
+			Timeout:               "30s",
+		})
+		assert.ErrorContains(t, err, "either an operations directory or a manifest is required")
+	})
+
+	t.Run("fails if the feature flag does not exist", func(t *testing.T) {
+		err := PlanDiff(context.Background(), PlanDiffConfig{
+			BaseExecutionConfig: baseConfig,
+			FeatureFlag:         "missing",
+			SourceDir:           path.Join(getTestDataDir(), "queries", "diff"),
+			OutDir:              t.TempDir(),
+			Timeout:             "30s",
+		})
+		assert.ErrorContains(t, err, `feature flag "missing" not found in execution config`)
+	})
+
+	t.Run("reports no differences for the same execution config", func(t *testing.T) {
+		outDir := t.TempDir()
+
+		err := PlanDiff(context.Background(), PlanDiffConfig{
+			BaseExecutionConfig:   baseConfig,
+			TargetExecutionConfig: baseConfig,
+			SourceDir:             path.Join(getTestDataDir(), "queries", "base"),
+			OutDir:                outDir,
+			Timeout:               "30s",
+			FailOnDiff:            true,
+		})
+		require.NoError(t, err)
+
+		report := readDiffReport(t, outDir)
+		assert.Equal(t, PlanDiffSummary{Operations: 5, Unchanged: 4, Failing: 1}, report.Summary)
+		assert.Equal(t, PlanDiffStatusFailing, report.Operations[1].Status)
+		assert.NotEmpty(t, report.Operations[1].TargetError)
+
+		suites := readJUnitReport(t, outDir)
+		assert.Equal(t, 5, suites.Tests)
+		assert.Equal(t, 0, suites.Failures)
+		assert.Equal(t, 0, suites.Errors)
+		assert.Equal(t, 1, suites.Skipped)
+	})
+
+	t.Run("reports changed fetches and new errors of the target execution config", func(t *testing.T) {
+		routerConfig, engineConfig := targetEngineConfig(t)
+		routerConfig.EngineConfig = engineConfig
+		targetConfig := writeExecutionConfig(t, routerConfig)
+		outDir := t.TempDir()
+
+		err := PlanDiff(context.Background(), PlanDiffConfig{
+			BaseExecutionConfig:   baseConfig,
+			TargetExecutionConfig: targetConfig,
+			SourceDir:             path.Join(getTestDataDir(), "queries", "diff"),
+			OutDir:                outDir,
+			Timeout:               "30s",
+			FailOnDiff:            true,
+		})
+		assert.ErrorContains(t, err, "some operations plan differently with the target execution config")
+
+		report := readDiffReport(t, outDir)
+		assert.Equal(t, PlanDiffSummary{Operations: 2, Changed: 1, NewErrors: 1}, report.Summary)
+
+		details := report.Operations[0]
+		assert.Equal(t, "details.graphql", details.Name)
+		assert.Equal(t, PlanDiffStatusChanged, details.Status)
+		require.Len(t, details.AddedFetches, 1)
+		assert.Equal(t, FetchRef{Subgraph: "family", Path: "employees"}, details.AddedFetches[0].FetchRef)
+		assert.Equal(t, []FetchRef{{Subgraph: "employees"}}, details.AddedFetches[0].DependsOn)
+		assert.Empty(t, details.RemovedFetches)
+		assert.Empty(t, details.ChangedDependencies)
+		require.Len(t, details.ChangedQueries, 1)
+		assert.Equal(t, FetchRef{Subgraph: "employees"}, details.ChangedQueries[0].FetchRef)
+		assert.Contains(t, details.ChangedQueries[0].BaseQuery, "forename")
+		assert.NotContains(t, details.ChangedQueries[0].TargetQuery, "forename")
+
+		mood := report.Operations[1]
+		assert.Equal(t, "mood.graphql", mood.Name)
+		assert.Equal(t, PlanDiffStatusNewError, mood.Status)
+		assert.Empty(t, mood.BaseError)
+		assert.NotEmpty(t, mood.TargetError)
+
+		suites := readJUnitReport(t, outDir)
+		require.Len(t, suites.Suites, 1)
+		testCases := suites.Suites[0].TestCases
+		require.Len(t, testCases, 2)
+		require.NotNil(t, testCases[0].Failure)
+		assert.Contains(t, testCases[0].Failure.Text, "added fetch: family at employees")
+		assert.Contains(t, testCases[0].Failure.Text, "changed query: employees")
+		require.NotNil(t, testCases[1].Error)
+		assert.Equal(t, mood.TargetError, testCases[1].Error.Text)
+	})
+
+	t.Run("compares the operations of a manifest with a feature flag", func(t *testing.T) {
+		routerConfig, engineConfig := targetEngineConfig(t)
+		routerConfig.FeatureFlagConfigs = &nodev1.FeatureFlagRouterExecutionConfigs{
+			ConfigByFeatureFlagName: map[string]*nodev1.FeatureFlagRouterExecutionConfig{
+				"family": {
+					EngineConfig: engineConfig,
+					Version:      routerConfig.Version,
+					Subgraphs:    routerConfig.Subgraphs,
+				},
+			},
+		}
+		configPath := writeExecutionConfig(t, routerConfig)
+
+		details, err := os.ReadFile(path.Join(getTestDataDir(), "queries", "diff", "details.graphql"))
+		require.NoError(t, err)
+		manifest, err := json.Marshal(map[string]any{
+			"version":     1,
+			"revision":    "1",
+			"generatedAt": "2026-10-17T10:00:00Z",
+			"operations": map[string]string{
+				"details-hash": string(details),
+			},
+		})
+		require.NoError(t, err)
+		manifestPath := path.Join(t.TempDir(), "manifest.json")
+		require.NoError(t, os.WriteFile(manifestPath, manifest, 0644))
+
+		outDir := t.TempDir()
+		err = PlanDiff(context.Background(), PlanDiffConfig{
+			BaseExecutionConfig: configPath,
+			FeatureFlag:         "family",
+			Manifest:            manifestPath,
+			OutDir:              outDir,
+			Timeout:             "30s",
+		})
+		require.NoError(t, err)
+
+		report := readDiffReport(t, outDir)
+		require.Len(t, report.Operations, 1)
+		assert.Equal(t, "details-hash", report.Operations[0].Name)
+		assert.Equal(t, PlanDiffStatusChanged, report.Operations[0].Status)
+		require.Len(t, report.Operations[0].AddedFetches, 1)
+		assert.Equal(t, "family", report.Operations[0].AddedFetches[0].Subgraph)
+	})
+}
+
+func TestDiffPlannedOperations(t *testing.T) {
+	employees := FetchSummary{FetchRef: FetchRef{Subgraph: "employees"}, Kind: "Single", Query: "{ employees { id } }"}
+	mood := FetchSummary{
+		FetchRef:  FetchRef{Subgraph: "mood", Path: "employees"},
+		Kind:      "BatchEntity",
+		Query:     "{ _entities { currentMood } }",
+		DependsOn: []FetchRef{{Subgraph: "employees"}},
+	}
+
+	t.Run("reports changed fetch dependencies", func(t *testing.T) {
+		availability := FetchSummary{
+			FetchRef:  FetchRef{Subgraph: "availability", Path: "employees"},
+			Kind:      "BatchEntity",
+			Query:     "{ _entities { isAvailable } }",
+			DependsOn: []FetchRef{{Subgraph: "employees"}},
+		}
+		targetMood := mood
+		targetMood.DependsOn = []FetchRef{{Subgraph: "availability", Path: "employees"}, {Subgraph: "employees"}}
+
+		res := diffPlannedOperations("op",
+			&plannedOperation{fetches: []FetchSummary{employees, mood, availability}},
+			&plannedOperation{fetches: []FetchSummary{employees, availability, targetMood}},
+			PlanDiffConfig{},
+		)
+
+		assert.Equal(t, PlanDiffStatusChanged, res.Status)
+		assert.Empty(t, res.AddedFetches)
+		assert.Empty(t, res.RemovedFetches)
+		assert.Empty(t, res.ChangedQueries)
+		assert.Equal(t, []FetchDependencyChange{{
+			FetchRef: mood.FetchRef,
+			Base:     []FetchRef{{Subgraph: "employees"}},
+			Target:   []FetchRef{{Subgraph: "availability", Path: "employees"}, {Subgraph: "employees"}},
+		}}, res.ChangedDependencies)
+	})
+
+	t.Run("reports removed fetches", func(t *testing.T) {
+		res := diffPlannedOperations("op",
+			&plannedOperation{fetches: []FetchSummary{employees, mood}},
+			&plannedOperation{fetches: []FetchSummary{employees}},
+			PlanDiffConfig{},
+		)
+
+		assert.Equal(t, PlanDiffStatusChanged, res.Status)
+		assert.Equal(t, []FetchSummary{mood}, res.RemovedFetches)
+	})
+
+	t.Run("reports planning time regressions above the factor and delta", func(t *testing.T) {
+		cfg := PlanDiffConfig{PlanTimeRegressionFactor: 2, PlanTimeRegressionMinDelta: time.Millisecond}
+		plan := func(planTime time.Duration) *plannedOperation {
+			return &plannedOperation{
+				fetches: []FetchSummary{employees},
+				timings: core.OperationTimes{PlanTime: planTime},
+			}
+		}
+
+		res := diffPlannedOperations("op", plan(2*time.Millisecond), plan(5*time.Millisecond), cfg)
+		assert.Equal(t, PlanDiffStatusUnchanged, res.Status)
+		assert.True(t, res.PlanTimeRegression)
+		assert.True(t, res.Failed())
+
+		// Below the factor
+		res = diffPlannedOperations("op", plan(2*time.Millisecond), plan(3*time.Millisecond), cfg)
+		assert.False(t, res.PlanTimeRegression)
+
+		// Below the delta
+		res = diffPlannedOperations("op", plan(100*time.Microsecond), plan(500*time.Microsecond), cfg)
+		assert.False(t, res.PlanTimeRegression)
+	})
+
+	t.Run("reports fixed operations", func(t *testing.T) {
+		res := diffPlannedOperations("op",
+			&plannedOperation{err: assert.AnError},
+			&plannedOperation{fetches: []FetchSummary{employees}},
+			PlanDiffConfig{},
+		)
+
+		assert.Equal(t, PlanDiffStatusFixed, res.Status)
+		assert.False(t, res.Failed())
+	})
+}
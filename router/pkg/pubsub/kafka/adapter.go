@@ -44,11 +44,77 @@ type ProviderAdapter struct {
 	// skipUnavailable mirrors events.skip_unavailable_providers. When true, Startup probes
 	// connectivity (kgo connects lazily otherwise) so an unreachable broker surfaces a
 	// distinct "could not connect" error, consistent with the NATS and Redis adapters.
-	skipUnavailable bool
+	skipUnavailable  bool
+	hostName         string
+	routerListenAddr string
+	// consumerGroup is nil unless the provider consumes with consumer groups
+	consumerGroup *ConsumerGroupOpts
+}
+
+// ConsumerGroupOpts switches the subscriptions of the adapter to consumer groups.
+type ConsumerGroupOpts struct {
+	// GroupIDTemplate is expanded for every subscription. The placeholders {provider_id},
+	// {topics}, {field_name} and {hostname} are supported.
+	GroupIDTemplate string
+	// StartOffset is used for partitions without a committed offset
+	StartOffset    kgo.Offset
+	CommitInterval time.Duration
 }
 
 type PollerOpts struct {
 	providerId string
+	// markCommit marks every record delivered to the subscription for the next commit
+	markCommit bool
+}
+
+func (p *ProviderAdapter) getInstanceIdentifier() string {
+	return fmt.Sprintf("%s-%s", p.hostName, p.routerListenAddr)
+}
+
+// groupID expands the consumer group ID template for the given subscription.
+func (p *ProviderAdapter) groupID(conf *SubscriptionEventConfiguration) string {
+	return strings.NewReplacer(
+		"{provider_id}", conf.ProviderID(),
+		"{topics}", strings.Join(conf.Topics, "-"),
+		"{field_name}", conf.RootFieldName(),
+		"{hostname}", p.hostName,
+	).Replace(p.consumerGroup.GroupIDTemplate)
+}
+
+// consumerOpts returns the consume options of a subscription.
+func (p *ProviderAdapter) consumerOpts(conf *SubscriptionEventConfiguration, log *zap.Logger) []kgo.Opt {
+	opts := []kgo.Opt{
+		kgo.ConsumeTopics(conf.Topics...),
+		// The client ID is only used for observability, the instance identifier
+		// tells apart the consumers of different router instances
+		kgo.ClientID(fmt.Sprintf("cosmo.router.consumer.%s.%s", strings.Join(conf.Topics, "-"), p.getInstanceIdentifier())),
+	}
+
+	if p.consumerGroup == nil {
+		return append(opts,
+			// We want to consume the events produced after the first subscription was created
+			// Messages are shared among all subscriptions, therefore old events are not redelivered
+			// This replicates a stateless publish-subscribe model
+			kgo.ConsumeResetOffset(kgo.NewOffset().AfterMilli(time.Now().UnixMilli())),
+		)
+	}
+
+	return append(opts,
+		kgo.ConsumerGroup(p.groupID(conf)),
+		// Only used for partitions the group has not committed an offset for yet
+		kgo.ConsumeResetOffset(p.consumerGroup.StartOffset),
+		// Records are marked once they were handed to the subscription. Only marked
+		// records are committed, so nothing polled but undelivered gets lost on a restart.
+		kgo.AutoCommitMarks(),
+		kgo.AutoCommitInterval(p.consumerGroup.CommitInterval),
+		// Partitions are not revoked while a polled batch is delivered
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsRevoked(func(ctx context.Context, client *kgo.Client, _ map[string][]int32) {
+			if err := client.CommitMarkedOffsets(ctx); err != nil {
+				log.Error("failed to commit offsets on partition revocation", zap.Error(err))
+			}
+		}),
+	)
 }
 
 // topicPoller polls the Kafka topic for new records and calls the updateTriggers function.
@@ -118,6 +184,15 @@ func (p *ProviderAdapter) topicPoller(ctx context.Context, client *kgo.Client, u
 						},
 					},
 				})
+
+				if pollerOpts.markCommit {
+					client.MarkCommitRecords(r)
+				}
+			}
+
+			if pollerOpts.markCommit {
+				// The batch was delivered, the group may rebalance now
+				client.AllowRebalance()
 			}
 		}
 	}
@@ -137,22 +212,16 @@ func (p *ProviderAdapter) Subscribe(ctx context.Context, conf datasource.Subscri
 		zap.Strings("topics", subConf.Topics),
 	)
 
+	if p.consumerGroup != nil {
+		log = log.With(zap.String("group_id", p.groupID(subConf)))
+	}
+
 	// Create a new client for the topic
 	// Copy opts to avoid data race when multiple goroutines call Subscribe concurrently
-	opts := make([]kgo.Opt, len(p.opts), len(p.opts)+3)
+	consumerOpts := p.consumerOpts(subConf, log)
+	opts := make([]kgo.Opt, len(p.opts), len(p.opts)+len(consumerOpts))
 	copy(opts, p.opts)
-	client, err := kgo.NewClient(append(opts,
-		kgo.ConsumeTopics(subConf.Topics...),
-		// We want to consume the events produced after the first subscription was created
-		// Messages are shared among all subscriptions, therefore old events are not redelivered
-		// This replicates a stateless publish-subscribe model
-		kgo.ConsumeResetOffset(kgo.NewOffset().AfterMilli(time.Now().UnixMilli())),
-		// For observability, we set the client ID to "router"
-		kgo.ClientID(fmt.Sprintf("cosmo.router.consumer.%s", strings.Join(subConf.Topics, "-"))),
-		// FIXME: the client id should have some unique identifier, like in nats
-		// What if we have multiple subscriptions for the same topics?
-		// What if we have more router instances?
-	)...)
+	client, err := kgo.NewClient(append(opts, consumerOpts...)...)
 	if err != nil {
 		log.Error("failed to create client", zap.Error(err))
 		return err
@@ -162,7 +231,9 @@ func (p *ProviderAdapter) Subscribe(ctx context.Context, conf datasource.Subscri
 		// The consumer client owns background goroutines, broker connections and buffered
 		// fetches, so it must be closed when the poller stops, otherwise every ended
 		// subscription leaks a full client for the lifetime of the process.
-		defer client.Close()
+		// Allowing the rebalance first lets a consumer group member leave the group
+		// and commit its marked offsets even if the poller stopped mid batch.
+		defer client.CloseAllowingRebalance()
 
 		// Drive the poller with a context that is cancelled when EITHER the subscription
 		// context (ctx) or the adapter/application context (p.ctx) is cancelled. This makes
@@ -173,7 +244,10 @@ func (p *ProviderAdapter) Subscribe(ctx context.Context, conf datasource.Subscri
 		stop := context.AfterFunc(p.ctx, cancel)
 		defer stop()
 
-		err := p.topicPoller(pollerCtx, client, updater, PollerOpts{providerId: conf.ProviderID()})
+		err := p.topicPoller(pollerCtx, client, updater, PollerOpts{
+			providerId: conf.ProviderID(),
+			markCommit: p.consumerGroup != nil,
+		})
 		if err != nil {
 			if errors.Is(err, errClientClosed) || errors.Is(err, context.Canceled) {
 				log.Debug("poller canceled", zap.Error(err))
@@ -342,7 +416,7 @@ func (p *ProviderAdapter) Shutdown(ctx context.Context) error {
 	return nil
 }
 
-func NewProviderAdapter(ctx context.Context, logger *zap.Logger, opts []kgo.Opt, providerOpts datasource.ProviderOpts) (*ProviderAdapter, error) {
+func NewProviderAdapter(ctx context.Context, logger *zap.Logger, opts []kgo.Opt, hostName string, routerListenAddr string, consumerGroup *ConsumerGroupOpts, providerOpts datasource.ProviderOpts) (*ProviderAdapter, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	if logger == nil {
 		logger = zap.NewNop()
@@ -363,6 +437,9 @@ func NewProviderAdapter(ctx context.Context, logger *zap.Logger, opts []kgo.Opt,
 		cancel:            cancel,
 		streamMetricStore: store,
 		skipUnavailable:   providerOpts.SkipUnavailableProviders,
+		hostName:          hostName,
+		routerListenAddr:  routerListenAddr,
+		consumerGroup:     consumerGroup,
 	}, nil
 }
 
@@ -0,0 +1,91 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/wundergraph/cosmo/router/pkg/pubsub/datasource"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestConsumerGroupSubscription(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "orders"))
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+
+	adapter, err := NewProviderAdapter(context.Background(), zaptest.NewLogger(t),
+		[]kgo.Opt{kgo.SeedBrokers(cluster.ListenAddrs()...)}, "host", "addr",
+		&ConsumerGroupOpts{
+			GroupIDTemplate: "cosmo.router.{provider_id}.{topics}.{field_name}",
+			StartOffset:     kgo.NewOffset().AtStart(),
+			CommitInterval:  100 * time.Millisecond,
+		}, datasource.ProviderOpts{})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Startup(context.Background()))
+	t.Cleanup(func() {
+		require.NoError(t, adapter.Shutdown(context.Background()))
+	})
+
+	publish := func(data string) {
+		t.Helper()
+		err := adapter.Publish(context.Background(), &PublishEventConfiguration{
+			Provider: "my-kafka",
+			Topic:    "orders",
+		}, []datasource.StreamEvent{&Event{evt: &MutableEvent{Data: []byte(data)}}})
+		require.NoError(t, err)
+	}
+
+	subscribe := func(ctx context.Context) <-chan string {
+		t.Helper()
+		received := make(chan string, 10)
+		updater := datasource.NewMockSubscriptionEventUpdater(t)
+		updater.EXPECT().Update(mock.Anything).Run(func(events []datasource.StreamEvent) {
+			for _, event := range events {
+				received <- string(event.GetData())
+			}
+		}).Maybe()
+		err := adapter.Subscribe(ctx, &SubscriptionEventConfiguration{
+			Provider:  "my-kafka",
+			Topics:    []string{"orders"},
+			FieldName: "orderUpdated",
+		}, updater)
+		require.NoError(t, err)
+		return received
+	}
+
+	next := func(received <-chan string) string {
+		t.Helper()
+		select {
+		case data := <-received:
+			return data
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "timed out waiting for the event")
+			return ""
+		}
+	}
+
+	// The record published before the subscription is read because the group starts at the earliest offset
+	publish("first")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := subscribe(ctx)
+	require.Equal(t, "first", next(received))
+
+	publish("second")
+	require.Equal(t, "second", next(received))
+
+	// Leaving the group commits the delivered records
+	cancel()
+
+	publish("third")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	received = subscribe(ctx)
+	require.Equal(t, "third", next(received))
+}
@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"time"
 
@@ -57,7 +58,7 @@ func (p *ProviderBuilder) BuildEngineDataSourceFactory(data *nodev1.KafkaEventCo
 }
 
 func (p *ProviderBuilder) BuildProvider(provider config.KafkaEventSource, providerOpts datasource.ProviderOpts) (datasource.Provider, error) {
-	pubSubProvider, err := buildProvider(p.ctx, provider, p.logger, providerOpts)
+	pubSubProvider, err := buildProvider(p.ctx, provider, p.logger, p.hostName, p.routerListenAddr, providerOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -148,13 +149,58 @@ func buildKafkaOptions(eventSource config.KafkaEventSource, logger *zap.Logger)
 	return opts, nil
 }
 
-func buildProvider(ctx context.Context, provider config.KafkaEventSource, logger *zap.Logger, providerOpts datasource.ProviderOpts) (datasource.Provider, error) {
+// buildConsumerGroupOptions returns the consumer group options of the event source,
+// or nil if the provider does not consume with consumer groups.
+func buildConsumerGroupOptions(eventSource config.KafkaEventSource) (*ConsumerGroupOpts, error) {
+	cg := eventSource.ConsumerGroup
+	if cg == nil || !cg.Enabled {
+		return nil, nil
+	}
+
+	opts := &ConsumerGroupOpts{
+		GroupIDTemplate: cg.GroupID,
+		CommitInterval:  cg.CommitInterval,
+	}
+
+	if opts.GroupIDTemplate == "" {
+		// Subscriptions of different fields on the same topics must not share a group,
+		// otherwise the group splits the partitions among them and each field misses events
+		opts.GroupIDTemplate = "cosmo.router.{provider_id}.{topics}.{field_name}"
+	}
+
+	if opts.CommitInterval <= 0 {
+		opts.CommitInterval = 5 * time.Second
+	}
+
+	switch cg.StartOffset {
+	case config.KafkaStartOffsetLatest, "":
+		opts.StartOffset = kgo.NewOffset().AtEnd()
+	case config.KafkaStartOffsetEarliest:
+		opts.StartOffset = kgo.NewOffset().AtStart()
+	case config.KafkaStartOffsetTimestamp:
+		if cg.StartTimestamp.IsZero() {
+			return nil, errors.New("start_timestamp is required when start_offset is \"timestamp\"")
+		}
+		opts.StartOffset = kgo.NewOffset().AfterMilli(cg.StartTimestamp.UnixMilli())
+	default:
+		return nil, fmt.Errorf("unsupported start offset: %s", cg.StartOffset)
+	}
+
+	return opts, nil
+}
+
+func buildProvider(ctx context.Context, provider config.KafkaEventSource, logger *zap.Logger, hostName string, routerListenAddr string, providerOpts datasource.ProviderOpts) (datasource.Provider, error) {
 	kafkaOpts, err := buildKafkaOptions(provider, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build options for Kafka provider with ID \"%s\": %w", provider.ID, err)
 	}
 
-	adapter, err := NewProviderAdapter(ctx, logger, kafkaOpts, providerOpts)
+	consumerGroupOpts, err := buildConsumerGroupOptions(provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build consumer group options for Kafka provider with ID \"%s\": %w", provider.ID, err)
+	}
+
+	adapter, err := NewProviderAdapter(ctx, logger, kafkaOpts, hostName, routerListenAddr, consumerGroupOpts, providerOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create adapter for Kafka provider with ID \"%s\": %w", provider.ID, err)
 	}
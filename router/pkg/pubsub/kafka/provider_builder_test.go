@@ -3,9 +3,11 @@ package kafka
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
 	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/cosmo/router/pkg/pubsub/datasource"
 	"go.uber.org/zap/zaptest"
@@ -78,6 +80,78 @@ func TestBuildKafkaOptions(t *testing.T) {
 	})
 }
 
+func TestBuildConsumerGroupOptions(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		opts, err := buildConsumerGroupOptions(config.KafkaEventSource{})
+		require.NoError(t, err)
+		require.Nil(t, opts)
+
+		opts, err = buildConsumerGroupOptions(config.KafkaEventSource{
+			ConsumerGroup: &config.KafkaConsumerGroupConfiguration{},
+		})
+		require.NoError(t, err)
+		require.Nil(t, opts)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		opts, err := buildConsumerGroupOptions(config.KafkaEventSource{
+			ConsumerGroup: &config.KafkaConsumerGroupConfiguration{Enabled: true},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "cosmo.router.{provider_id}.{topics}.{field_name}", opts.GroupIDTemplate)
+		require.Equal(t, 5*time.Second, opts.CommitInterval)
+		require.Equal(t, kgo.NewOffset().AtEnd(), opts.StartOffset)
+	})
+
+	t.Run("earliest", func(t *testing.T) {
+		opts, err := buildConsumerGroupOptions(config.KafkaEventSource{
+			ConsumerGroup: &config.KafkaConsumerGroupConfiguration{
+				Enabled:     true,
+				StartOffset: config.KafkaStartOffsetEarliest,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, kgo.NewOffset().AtStart(), opts.StartOffset)
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		opts, err := buildConsumerGroupOptions(config.KafkaEventSource{
+			ConsumerGroup: &config.KafkaConsumerGroupConfiguration{
+				Enabled:        true,
+				StartOffset:    config.KafkaStartOffsetTimestamp,
+				StartTimestamp: ts,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, kgo.NewOffset().AfterMilli(ts.UnixMilli()), opts.StartOffset)
+	})
+
+	t.Run("timestamp without start timestamp", func(t *testing.T) {
+		_, err := buildConsumerGroupOptions(config.KafkaEventSource{
+			ConsumerGroup: &config.KafkaConsumerGroupConfiguration{
+				Enabled:     true,
+				StartOffset: config.KafkaStartOffsetTimestamp,
+			},
+		})
+		require.ErrorContains(t, err, "start_timestamp is required")
+	})
+}
+
+func TestConsumerGroupID(t *testing.T) {
+	adapter, err := NewProviderAdapter(context.Background(), zaptest.NewLogger(t), nil, "host", "addr", &ConsumerGroupOpts{
+		GroupIDTemplate: "cosmo.{provider_id}.{topics}.{field_name}.{hostname}",
+	}, datasource.ProviderOpts{})
+	require.NoError(t, err)
+
+	groupID := adapter.groupID(&SubscriptionEventConfiguration{
+		Provider:  "my-kafka",
+		Topics:    []string{"orders", "payments"},
+		FieldName: "orderUpdated",
+	})
+	require.Equal(t, "cosmo.my-kafka.orders-payments.orderUpdated.host", groupID)
+}
+
 func TestPubSubProviderBuilderFactory(t *testing.T) {
 	t.Run("creates provider with configured adapters", func(t *testing.T) {
 		providerId := "test-provider"
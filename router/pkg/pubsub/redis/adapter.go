@@ -22,7 +22,7 @@ const (
 // Ensure ProviderAdapter implements ProviderSubscriptionHooks
 var _ datasource.Adapter = (*ProviderAdapter)(nil)
 
-func NewProviderAdapter(ctx context.Context, logger *zap.Logger, urls []string, clusterEnabled bool, opts datasource.ProviderOpts) datasource.Adapter {
+func NewProviderAdapter(ctx context.Context, logger *zap.Logger, urls []string, clusterEnabled bool, streams *StreamsOpts, opts datasource.ProviderOpts) datasource.Adapter {
 	ctx, cancel := context.WithCancel(ctx)
 	if logger == nil {
 		logger = zap.NewNop()
@@ -43,6 +43,7 @@ func NewProviderAdapter(ctx context.Context, logger *zap.Logger, urls []string,
 		clusterEnabled:    clusterEnabled,
 		streamMetricStore: store,
 		skipUnavailable:   opts.SkipUnavailableProviders,
+		streams:           streams,
 	}
 }
 
@@ -59,6 +60,8 @@ type ProviderAdapter struct {
 	// the resilient client even if the initial connection check fails, so go-redis can
 	// reconnect on a later command and the provider recovers without a restart.
 	skipUnavailable bool
+	// streams is nil unless the provider uses Redis Streams instead of Pub/Sub
+	streams *StreamsOpts
 }
 
 func (p *ProviderAdapter) Startup(ctx context.Context) error {
@@ -128,6 +131,10 @@ func (p *ProviderAdapter) Subscribe(ctx context.Context, conf datasource.Subscri
 		return datasource.NewError("redis connection not initialized", nil)
 	}
 
+	if p.streams != nil {
+		return p.subscribeStreams(ctx, subConf, updater, log)
+	}
+
 	sub := p.conn.PSubscribe(ctx, subConf.Channels...)
 	msgChan := sub.Channel()
 
@@ -218,6 +225,13 @@ func (p *ProviderAdapter) Publish(ctx context.Context, conf datasource.PublishEv
 			continue
 		}
 
+		if p.streams != nil {
+			if err := p.publishStream(ctx, pubConf.Channel, data); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
 		intCmd := p.conn.Publish(ctx, pubConf.Channel, data)
 		if intCmd.Err() != nil {
 			errs = append(errs, intCmd.Err())
@@ -70,7 +70,7 @@ func (b *ProviderBuilder) BuildEngineDataSourceFactory(data *nodev1.RedisEventCo
 
 // Providers returns the Redis PubSub providers for the given provider IDs
 func (b *ProviderBuilder) BuildProvider(provider config.RedisEventSource, providerOpts datasource.ProviderOpts) (datasource.Provider, error) {
-	adapter := NewProviderAdapter(b.ctx, b.logger, provider.URLs, provider.ClusterEnabled, providerOpts)
+	adapter := NewProviderAdapter(b.ctx, b.logger, provider.URLs, provider.ClusterEnabled, b.buildStreamsOptions(provider), providerOpts)
 	eventBuilder := func(data []byte) datasource.MutableStreamEvent {
 		return &MutableEvent{Data: data}
 	}
@@ -79,3 +79,34 @@ func (b *ProviderBuilder) BuildProvider(provider config.RedisEventSource, provid
 
 	return pubSubProvider, nil
 }
+
+// buildStreamsOptions returns the Redis Streams options of the provider with defaults applied,
+// or nil if the provider uses Pub/Sub.
+func (b *ProviderBuilder) buildStreamsOptions(provider config.RedisEventSource) *StreamsOpts {
+	streams := provider.Streams
+	if streams == nil || !streams.Enabled {
+		return nil
+	}
+
+	opts := &StreamsOpts{
+		ConsumerGroupTemplate: streams.ConsumerGroup,
+		InstanceID:            fmt.Sprintf("%s-%s", b.hostName, b.routerListenAddr),
+		HostName:              b.hostName,
+		StartID:               streams.StartID,
+		MaxLen:                streams.MaxLen,
+		BatchSize:             streams.BatchSize,
+		BlockTimeout:          streams.BlockTimeout,
+	}
+
+	if opts.StartID == "" {
+		opts.StartID = defaultStreamStartID
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultStreamBatchSize
+	}
+	if opts.BlockTimeout <= 0 {
+		opts.BlockTimeout = defaultStreamBlockTimeout
+	}
+
+	return opts
+}
@@ -11,6 +11,31 @@ import (
 	"go.uber.org/zap/zaptest"
 )
 
+func TestBuildStreamsOptions(t *testing.T) {
+	builder := NewProviderBuilder(context.Background(), zaptest.NewLogger(t), "host", "addr")
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, builder.buildStreamsOptions(config.RedisEventSource{}))
+		require.Nil(t, builder.buildStreamsOptions(config.RedisEventSource{
+			Streams: &config.RedisStreamsConfiguration{},
+		}))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		opts := builder.buildStreamsOptions(config.RedisEventSource{
+			Streams: &config.RedisStreamsConfiguration{Enabled: true, ConsumerGroup: "group"},
+		})
+		require.Equal(t, &StreamsOpts{
+			ConsumerGroupTemplate: "group",
+			InstanceID:            "host-addr",
+			HostName:              "host",
+			StartID:               defaultStreamStartID,
+			BatchSize:             defaultStreamBatchSize,
+			BlockTimeout:          defaultStreamBlockTimeout,
+		}, opts)
+	})
+}
+
 func TestBuildRedisOptions(t *testing.T) {
 	t.Run("basic configuration", func(t *testing.T) {
 		cfg := config.RedisEventSource{
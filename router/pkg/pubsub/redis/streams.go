@@ -0,0 +1,273 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/wundergraph/cosmo/router/pkg/metric"
+	"github.com/wundergraph/cosmo/router/pkg/pubsub/datasource"
+	"go.uber.org/zap"
+)
+
+const (
+	// streamDataField is the field of a stream entry holding the event data
+	streamDataField = "data"
+
+	defaultStreamStartID      = "$"
+	defaultStreamBatchSize    = 100
+	defaultStreamBlockTimeout = 5 * time.Second
+
+	// streamReadRetryInterval is the pause after a failed read before the next attempt
+	streamReadRetryInterval = time.Second
+)
+
+// StreamsOpts switches the adapter from Pub/Sub to Redis Streams.
+type StreamsOpts struct {
+	// ConsumerGroupTemplate enables consumer groups when set. The placeholders {provider_id},
+	// {channels}, {field_name} and {hostname} are replaced for every subscription.
+	ConsumerGroupTemplate string
+	// InstanceID identifies the router instance. It is part of the consumer name, so a restarted
+	// router picks up the entries it had read but not acknowledged before.
+	InstanceID   string
+	HostName     string
+	StartID      string
+	MaxLen       int64
+	BatchSize    int64
+	BlockTimeout time.Duration
+}
+
+// consumerGroup expands the consumer group template for the given subscription. It returns an
+// empty string if consumer groups are not enabled.
+func (p *ProviderAdapter) consumerGroup(conf *SubscriptionEventConfiguration) string {
+	if p.streams.ConsumerGroupTemplate == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{provider_id}", conf.ProviderID(),
+		"{channels}", strings.Join(conf.Channels, "-"),
+		"{field_name}", conf.RootFieldName(),
+		"{hostname}", p.streams.HostName,
+	).Replace(p.streams.ConsumerGroupTemplate)
+}
+
+// consumerName is stable across restarts and unique per subscription of a router instance.
+func (p *ProviderAdapter) consumerName(conf *SubscriptionEventConfiguration) string {
+	return fmt.Sprintf("%s-%s-%s", p.streams.InstanceID, conf.RootFieldName(), strings.Join(conf.Channels, "-"))
+}
+
+func (p *ProviderAdapter) subscribeStreams(ctx context.Context, conf *SubscriptionEventConfiguration, updater datasource.SubscriptionEventUpdater, log *zap.Logger) error {
+	group := p.consumerGroup(conf)
+
+	var lastIDs []string
+	if group != "" {
+		log = log.With(zap.String("consumer_group", group))
+		for _, stream := range conf.Channels {
+			err := p.conn.XGroupCreateMkStream(ctx, stream, group, p.streams.StartID).Err()
+			// The group already exists when another router instance or an earlier run created it
+			if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				log.Error("failed to create consumer group", zap.Error(err), zap.String("stream", stream))
+				return datasource.NewError(fmt.Sprintf("error creating consumer group %s for Redis stream %s", group, stream), err)
+			}
+		}
+	} else {
+		var err error
+		lastIDs, err = p.resolveStartIDs(ctx, conf.Channels)
+		if err != nil {
+			log.Error("failed to resolve start IDs", zap.Error(err))
+			return datasource.NewError("error resolving the start IDs of Redis streams", err)
+		}
+	}
+
+	// A single XREAD or XREADGROUP call must only read keys of the same hash slot in cluster
+	// mode, so every stream gets its own reader
+	readers := [][]string{conf.Channels}
+	if p.clusterEnabled && len(conf.Channels) > 1 {
+		readers = make([][]string, len(conf.Channels))
+		for i, stream := range conf.Channels {
+			readers[i] = []string{stream}
+		}
+	}
+
+	for i, streams := range readers {
+		ids := lastIDs
+		if group == "" && len(readers) > 1 {
+			ids = lastIDs[i : i+1]
+		}
+
+		p.closeWg.Go(func() {
+			// Stop reading when either the subscription or the adapter context is cancelled
+			readCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			stop := context.AfterFunc(p.ctx, cancel)
+			defer stop()
+
+			if group != "" {
+				p.readGroup(readCtx, conf, streams, group, updater, log)
+			} else {
+				p.readStreams(readCtx, conf, streams, ids, updater, log)
+			}
+		})
+	}
+
+	return nil
+}
+
+// resolveStartIDs replaces "$" with the ID of the last entry of each stream. XREAD with "$" on
+// every call would skip the entries added between two calls.
+func (p *ProviderAdapter) resolveStartIDs(ctx context.Context, streams []string) ([]string, error) {
+	ids := make([]string, len(streams))
+	for i, stream := range streams {
+		if p.streams.StartID != defaultStreamStartID {
+			ids[i] = p.streams.StartID
+			continue
+		}
+
+		entries, err := p.conn.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			ids[i] = "0-0"
+			continue
+		}
+		ids[i] = entries[0].ID
+	}
+	return ids, nil
+}
+
+// readStreams reads the streams with XREAD. The last seen ID of every stream is tracked, so a
+// failed read resumes where the previous one stopped.
+func (p *ProviderAdapter) readStreams(ctx context.Context, conf *SubscriptionEventConfiguration, streams, lastIDs []string, updater datasource.SubscriptionEventUpdater, log *zap.Logger) {
+	for ctx.Err() == nil {
+		res, err := p.conn.XRead(ctx, &redis.XReadArgs{
+			Streams: slices.Concat(streams, lastIDs),
+			Count:   p.streams.BatchSize,
+			Block:   p.streams.BlockTimeout,
+		}).Result()
+		if err != nil {
+			if !p.handleStreamReadError(ctx, err, log) {
+				return
+			}
+			continue
+		}
+
+		for _, stream := range res {
+			idx := slices.Index(streams, stream.Stream)
+			for _, msg := range stream.Messages {
+				p.dispatchStreamEntry(ctx, conf, stream.Stream, msg, updater, log)
+				if idx >= 0 {
+					lastIDs[idx] = msg.ID
+				}
+			}
+		}
+	}
+}
+
+// readGroup reads the streams with XREADGROUP and acknowledges every entry after it was dispatched.
+// Entries this consumer had read but not acknowledged, e.g. before a restart, are dispatched first.
+func (p *ProviderAdapter) readGroup(ctx context.Context, conf *SubscriptionEventConfiguration, streams []string, group string, updater datasource.SubscriptionEventUpdater, log *zap.Logger) {
+	consumer := p.consumerName(conf)
+
+	// An ID returns the pending entries of the consumer after it, ">" entries never delivered to
+	// the group. The pending IDs move past the dispatched entries, so an entry whose acknowledgement
+	// failed stays pending, but isn't dispatched again by this reader.
+	ids := make([]string, len(streams))
+	for i := range ids {
+		ids[i] = "0"
+	}
+	pending := true
+
+	for ctx.Err() == nil {
+		res, err := p.conn.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  slices.Concat(streams, ids),
+			Count:    p.streams.BatchSize,
+			Block:    p.streams.BlockTimeout,
+		}).Result()
+		if err != nil {
+			if !p.handleStreamReadError(ctx, err, log) {
+				return
+			}
+			continue
+		}
+
+		read := 0
+		for _, stream := range res {
+			idx := slices.Index(streams, stream.Stream)
+			for _, msg := range stream.Messages {
+				read++
+				if pending && idx >= 0 {
+					ids[idx] = msg.ID
+				}
+				// Pending entries that were deleted from the stream meanwhile have no values
+				if msg.Values != nil {
+					p.dispatchStreamEntry(ctx, conf, stream.Stream, msg, updater, log)
+				}
+				if err := p.conn.XAck(ctx, stream.Stream, group, msg.ID).Err(); err != nil {
+					log.Error("failed to acknowledge stream entry", zap.Error(err), zap.String("stream", stream.Stream), zap.String("id", msg.ID))
+				}
+			}
+		}
+
+		if pending && read == 0 {
+			pending = false
+			for i := range ids {
+				ids[i] = ">"
+			}
+		}
+	}
+}
+
+// handleStreamReadError reports whether reading should continue.
+func (p *ProviderAdapter) handleStreamReadError(ctx context.Context, err error, log *zap.Logger) bool {
+	// No entries arrived within the block timeout
+	if errors.Is(err, redis.Nil) {
+		return true
+	}
+	if ctx.Err() != nil {
+		log.Debug("subscription context done, stopping stream reader")
+		return false
+	}
+
+	log.Error("failed to read from redis streams, retrying", zap.Error(err))
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(streamReadRetryInterval):
+		return true
+	}
+}
+
+func (p *ProviderAdapter) dispatchStreamEntry(ctx context.Context, conf *SubscriptionEventConfiguration, stream string, msg redis.XMessage, updater datasource.SubscriptionEventUpdater, log *zap.Logger) {
+	data, _ := msg.Values[streamDataField].(string)
+
+	log.Debug("subscription update", zap.String("stream", stream), zap.String("id", msg.ID), zap.String("data", data))
+	p.streamMetricStore.Consume(ctx, metric.StreamsEvent{
+		ProviderId:          conf.ProviderID(),
+		StreamOperationName: redisReceive,
+		ProviderType:        metric.ProviderTypeRedis,
+		DestinationName:     stream,
+	})
+	updater.Update([]datasource.StreamEvent{
+		&Event{evt: &MutableEvent{
+			Data: []byte(data),
+		}},
+	})
+}
+
+// publishStream appends the data to the stream and trims it if a maximum length is configured.
+func (p *ProviderAdapter) publishStream(ctx context.Context, stream string, data []byte) error {
+	return p.conn.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: p.streams.MaxLen,
+		Approx: p.streams.MaxLen > 0,
+		Values: map[string]any{streamDataField: data},
+	}).Err()
+}
@@ -0,0 +1,259 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/cosmo/router/pkg/pubsub/datasource"
+	"go.uber.org/zap/zaptest"
+)
+
+type channelUpdater struct {
+	noopUpdater
+	events chan []byte
+}
+
+func (c *channelUpdater) Update(events []datasource.StreamEvent) {
+	for _, event := range events {
+		c.events <- event.GetData()
+	}
+}
+
+func newStreamsAdapter(t *testing.T, mr *miniredis.Miniredis, streams *StreamsOpts) datasource.Adapter {
+	t.Helper()
+	return newStreamsAdapterWithCluster(t, mr, streams, false)
+}
+
+func newStreamsAdapterWithCluster(t *testing.T, mr *miniredis.Miniredis, streams *StreamsOpts, clusterEnabled bool) datasource.Adapter {
+	t.Helper()
+
+	ctx := context.Background()
+	adapter := NewProviderAdapter(ctx, zaptest.NewLogger(t), []string{fmt.Sprintf("redis://%s", mr.Addr())}, clusterEnabled, streams, datasource.ProviderOpts{})
+	require.NoError(t, adapter.Startup(ctx))
+	t.Cleanup(func() {
+		_ = adapter.Shutdown(ctx)
+	})
+
+	return adapter
+}
+
+func defaultStreamsOpts() *StreamsOpts {
+	return &StreamsOpts{
+		InstanceID:   "host-addr",
+		HostName:     "host",
+		StartID:      defaultStreamStartID,
+		BatchSize:    defaultStreamBatchSize,
+		BlockTimeout: 50 * time.Millisecond,
+	}
+}
+
+func publishStreamEvent(t *testing.T, adapter datasource.Adapter, stream string, data string) {
+	t.Helper()
+
+	err := adapter.Publish(context.Background(), &PublishEventConfiguration{
+		Provider: "test-provider",
+		Channel:  stream,
+	}, []datasource.StreamEvent{&MutableEvent{Data: json.RawMessage(data)}})
+	require.NoError(t, err)
+}
+
+func receiveStreamEvent(t *testing.T, updater *channelUpdater) string {
+	t.Helper()
+
+	select {
+	case data := <-updater.events:
+		return string(data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for stream event")
+		return ""
+	}
+}
+
+func TestProviderAdapter_Streams(t *testing.T) {
+	t.Parallel()
+
+	t.Run("publish trims the stream", func(t *testing.T) {
+		t.Parallel()
+
+		mr := miniredis.RunT(t)
+		opts := defaultStreamsOpts()
+		opts.MaxLen = 2
+		adapter := newStreamsAdapter(t, mr, opts)
+
+		for i := range 5 {
+			publishStreamEvent(t, adapter, "orders", fmt.Sprintf(`{"id":%d}`, i))
+		}
+
+		entries, err := mr.Stream("orders")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, []string{streamDataField, `{"id":4}`}, entries[1].Values)
+	})
+
+	t.Run("subscribe only receives new entries", func(t *testing.T) {
+		t.Parallel()
+
+		mr := miniredis.RunT(t)
+		adapter := newStreamsAdapter(t, mr, defaultStreamsOpts())
+
+		publishStreamEvent(t, adapter, "orders", `{"id":1}`)
+
+		subCtx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		updater := &channelUpdater{events: make(chan []byte, 10)}
+		require.NoError(t, adapter.Subscribe(subCtx, &SubscriptionEventConfiguration{
+			Provider:  "test-provider",
+			Channels:  []string{"orders"},
+			FieldName: "orderUpdated",
+		}, updater))
+
+		publishStreamEvent(t, adapter, "orders", `{"id":2}`)
+		publishStreamEvent(t, adapter, "orders", `{"id":3}`)
+
+		require.Equal(t, `{"id":2}`, receiveStreamEvent(t, updater))
+		require.Equal(t, `{"id":3}`, receiveStreamEvent(t, updater))
+	})
+
+	t.Run("subscribe resumes from the start ID", func(t *testing.T) {
+		t.Parallel()
+
+		mr := miniredis.RunT(t)
+		_, err := mr.XAdd("orders", "1-0", []string{streamDataField, `{"id":1}`})
+		require.NoError(t, err)
+		_, err = mr.XAdd("orders", "2-0", []string{streamDataField, `{"id":2}`})
+		require.NoError(t, err)
+
+		opts := defaultStreamsOpts()
+		opts.StartID = "1-0"
+		adapter := newStreamsAdapter(t, mr, opts)
+
+		subCtx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		updater := &channelUpdater{events: make(chan []byte, 10)}
+		require.NoError(t, adapter.Subscribe(subCtx, &SubscriptionEventConfiguration{
+			Provider:  "test-provider",
+			Channels:  []string{"orders"},
+			FieldName: "orderUpdated",
+		}, updater))
+
+		require.Equal(t, `{"id":2}`, receiveStreamEvent(t, updater))
+	})
+
+	t.Run("consumer group acknowledges dispatched entries", func(t *testing.T) {
+		t.Parallel()
+
+		mr := miniredis.RunT(t)
+		opts := defaultStreamsOpts()
+		opts.ConsumerGroupTemplate = "cosmo.{provider_id}.{field_name}"
+		opts.StartID = "0"
+		adapter := newStreamsAdapter(t, mr, opts)
+
+		publishStreamEvent(t, adapter, "orders", `{"id":1}`)
+
+		subCtx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		updater := &channelUpdater{events: make(chan []byte, 10)}
+		require.NoError(t, adapter.Subscribe(subCtx, &SubscriptionEventConfiguration{
+			Provider:  "test-provider",
+			Channels:  []string{"orders"},
+			FieldName: "orderUpdated",
+		}, updater))
+
+		require.Equal(t, `{"id":1}`, receiveStreamEvent(t, updater))
+
+		publishStreamEvent(t, adapter, "orders", `{"id":2}`)
+		require.Equal(t, `{"id":2}`, receiveStreamEvent(t, updater))
+
+		client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() {
+			_ = client.Close()
+		})
+
+		require.Eventually(t, func() bool {
+			pending, err := client.XPending(context.Background(), "orders", "cosmo.test-provider.orderUpdated").Result()
+			return err == nil && pending.Count == 0
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("consumer group dispatches the pending entries once", func(t *testing.T) {
+		t.Parallel()
+
+		mr := miniredis.RunT(t)
+		opts := defaultStreamsOpts()
+		opts.ConsumerGroupTemplate = "cosmo.{provider_id}.{field_name}"
+		opts.StartID = "0"
+		adapter := newStreamsAdapter(t, mr, opts)
+
+		client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() {
+			_ = client.Close()
+		})
+
+		// The consumer read two entries before a restart, but didn't acknowledge them
+		publishStreamEvent(t, adapter, "orders", `{"id":1}`)
+		publishStreamEvent(t, adapter, "orders", `{"id":2}`)
+		require.NoError(t, client.XGroupCreate(context.Background(), "orders", "cosmo.test-provider.orderUpdated", "0").Err())
+		require.NoError(t, client.XReadGroup(context.Background(), &goredis.XReadGroupArgs{
+			Group:    "cosmo.test-provider.orderUpdated",
+			Consumer: "host-addr-orderUpdated-orders",
+			Streams:  []string{"orders", ">"},
+		}).Err())
+
+		subCtx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		updater := &channelUpdater{events: make(chan []byte, 10)}
+		require.NoError(t, adapter.Subscribe(subCtx, &SubscriptionEventConfiguration{
+			Provider:  "test-provider",
+			Channels:  []string{"orders"},
+			FieldName: "orderUpdated",
+		}, updater))
+
+		require.Equal(t, `{"id":1}`, receiveStreamEvent(t, updater))
+		require.Equal(t, `{"id":2}`, receiveStreamEvent(t, updater))
+
+		publishStreamEvent(t, adapter, "orders", `{"id":3}`)
+		require.Equal(t, `{"id":3}`, receiveStreamEvent(t, updater))
+
+		select {
+		case data := <-updater.events:
+			t.Fatalf("unexpected stream event %s", data)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("cluster mode reads every stream on its own", func(t *testing.T) {
+		t.Parallel()
+
+		mr := miniredis.RunT(t)
+		opts := defaultStreamsOpts()
+		opts.StartID = "0"
+		adapter := newStreamsAdapterWithCluster(t, mr, opts, true)
+
+		subCtx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		// The streams are in different hash slots, a single XREAD would fail with CROSSSLOT
+		updater := &channelUpdater{events: make(chan []byte, 10)}
+		require.NoError(t, adapter.Subscribe(subCtx, &SubscriptionEventConfiguration{
+			Provider:  "test-provider",
+			Channels:  []string{"orders", "payments"},
+			FieldName: "updates",
+		}, updater))
+
+		publishStreamEvent(t, adapter, "orders", `{"id":1}`)
+		publishStreamEvent(t, adapter, "payments", `{"id":2}`)
+
+		received := []string{receiveStreamEvent(t, updater), receiveStreamEvent(t, updater)}
+		require.ElementsMatch(t, []string{`{"id":1}`, `{"id":2}`}, received)
+	})
+}
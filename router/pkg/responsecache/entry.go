@@ -0,0 +1,125 @@
+package responsecache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/pquerna/cachecontrol/cacheobject"
+)
+
+// Entry is a cached response
+type Entry struct {
+	Body []byte `json:"body"`
+	// Header holds the response headers that are replayed on a cache hit
+	Header   http.Header `json:"header"`
+	ETag     string      `json:"etag"`
+	StoredAt time.Time   `json:"stored_at"`
+	// MaxAge is the time the entry is fresh after it was stored
+	MaxAge time.Duration `json:"max_age"`
+	// StaleWhileRevalidate is the time after MaxAge in which the stale entry can still be served
+	// while it is revalidated
+	StaleWhileRevalidate time.Duration `json:"stale_while_revalidate"`
+}
+
+// NewEntry creates an entry for the response body and computes its ETag
+func NewEntry(body []byte, header http.Header, directives Directives, now time.Time) *Entry {
+	return &Entry{
+		Body:                 body,
+		Header:               header,
+		ETag:                 ETag(body),
+		StoredAt:             now,
+		MaxAge:               directives.MaxAge,
+		StaleWhileRevalidate: directives.StaleWhileRevalidate,
+	}
+}
+
+// TTL is the time the entry has to be kept in the store
+func (e *Entry) TTL() time.Duration {
+	return e.MaxAge + e.StaleWhileRevalidate
+}
+
+// Age returns how long ago the entry was stored
+func (e *Entry) Age(now time.Time) time.Duration {
+	return max(now.Sub(e.StoredAt), 0)
+}
+
+// Fresh reports whether the entry can be served without revalidation
+func (e *Entry) Fresh(now time.Time) bool {
+	return e.Age(now) < e.MaxAge
+}
+
+// Usable reports whether the entry can be served, either fresh or stale while it is revalidated
+func (e *Entry) Usable(now time.Time) bool {
+	return e.Age(now) < e.TTL()
+}
+
+// ETag returns a strong entity tag for the response body
+func ETag(body []byte) string {
+	return `"` + strconv.FormatUint(xxhash.Sum64(body), 16) + `"`
+}
+
+// MatchesETag reports whether the If-None-Match header value matches the entity tag.
+// Weak comparison is used, as defined for If-None-Match.
+func MatchesETag(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Directives are the Cache-Control directives relevant for caching a response
+type Directives struct {
+	MaxAge               time.Duration
+	StaleWhileRevalidate time.Duration
+	NoStore              bool
+	NoCache              bool
+	Private              bool
+}
+
+// Cacheable reports whether a response with these directives can be stored. Private responses are
+// only cacheable when the cache entries are scoped, e.g. per user.
+func (d Directives) Cacheable(scoped bool) bool {
+	if d.NoStore || d.NoCache || d.MaxAge <= 0 {
+		return false
+	}
+	return !d.Private || scoped
+}
+
+// ParseCacheControl parses the directives of a Cache-Control header value. As the router is a
+// shared cache, s-maxage takes precedence over max-age. Invalid directives make the response
+// uncacheable.
+func ParseCacheControl(value string) Directives {
+	parsed, err := cacheobject.ParseResponseCacheControl(value)
+	if err != nil {
+		return Directives{NoStore: true}
+	}
+
+	maxAge := parsed.MaxAge
+	if parsed.SMaxAge >= 0 {
+		maxAge = parsed.SMaxAge
+	}
+
+	return Directives{
+		MaxAge:               seconds(maxAge),
+		StaleWhileRevalidate: seconds(parsed.StaleWhileRevalidate),
+		NoStore:              parsed.NoStore,
+		NoCache:              parsed.NoCachePresent,
+		Private:              parsed.PrivatePresent,
+	}
+}
+
+func seconds(value cacheobject.DeltaSeconds) time.Duration {
+	if value <= 0 {
+		return 0
+	}
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,74 @@
+package responsecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parses the caching directives", func(t *testing.T) {
+		t.Parallel()
+
+		d := ParseCacheControl("public, max-age=60, stale-while-revalidate=30")
+		require.Equal(t, Directives{MaxAge: 60 * time.Second, StaleWhileRevalidate: 30 * time.Second}, d)
+		require.True(t, d.Cacheable(false))
+	})
+
+	t.Run("prefers s-maxage over max-age", func(t *testing.T) {
+		t.Parallel()
+
+		d := ParseCacheControl(`s-maxage="10", max-age=60`)
+		require.Equal(t, 10*time.Second, d.MaxAge)
+	})
+
+	t.Run("does not cache responses that forbid it", func(t *testing.T) {
+		t.Parallel()
+
+		require.False(t, ParseCacheControl("no-store, no-cache, must-revalidate").Cacheable(true))
+		require.False(t, ParseCacheControl("max-age=60, no-cache").Cacheable(true))
+		require.False(t, ParseCacheControl("max-age=0").Cacheable(true))
+		require.False(t, ParseCacheControl("max-age=invalid").Cacheable(true))
+		require.False(t, ParseCacheControl("").Cacheable(true))
+	})
+
+	t.Run("caches private responses only when the entries are scoped", func(t *testing.T) {
+		t.Parallel()
+
+		d := ParseCacheControl("private, max-age=60")
+		require.False(t, d.Cacheable(false))
+		require.True(t, d.Cacheable(true))
+	})
+}
+
+func TestEntry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	entry := NewEntry([]byte(`{"data":{}}`), nil, Directives{MaxAge: time.Minute, StaleWhileRevalidate: 30 * time.Second}, now)
+
+	require.Equal(t, 90*time.Second, entry.TTL())
+	require.True(t, entry.Fresh(now.Add(59*time.Second)))
+	require.False(t, entry.Fresh(now.Add(time.Minute)))
+	require.True(t, entry.Usable(now.Add(time.Minute)))
+	require.False(t, entry.Usable(now.Add(90*time.Second)))
+
+	require.Equal(t, ETag([]byte(`{"data":{}}`)), entry.ETag)
+	require.NotEqual(t, ETag([]byte(`{"data":null}`)), entry.ETag)
+}
+
+func TestMatchesETag(t *testing.T) {
+	t.Parallel()
+
+	etag := ETag([]byte("body"))
+
+	require.True(t, MatchesETag(etag, etag))
+	require.True(t, MatchesETag(`"other", `+etag, etag))
+	require.True(t, MatchesETag("W/"+etag, etag))
+	require.True(t, MatchesETag("*", etag))
+	require.False(t, MatchesETag(`"other"`, etag))
+	require.False(t, MatchesETag("", etag))
+}
@@ -0,0 +1,80 @@
+package responsecache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const maxMemoryEntries = 100_000
+
+// MemoryStore keeps the entries in memory. When it is full, the least recently used entry is evicted.
+type MemoryStore struct {
+	cache *lru.Cache[string, *Entry]
+	now   func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a store holding at most maxEntries entries
+func NewMemoryStore(maxEntries int) (*MemoryStore, error) {
+	if maxEntries <= 0 {
+		return nil, fmt.Errorf("in memory response cache needs a positive size, got %d", maxEntries)
+	}
+	if maxEntries > maxMemoryEntries {
+		return nil, fmt.Errorf("in memory response cache size is too large: %d", maxEntries)
+	}
+
+	cache, err := lru.New[string, *Entry](maxEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create in memory response cache: %w", err)
+	}
+
+	return &MemoryStore{cache: cache, now: time.Now}, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, bool, error) {
+	entry, ok := s.cache.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+
+	if !entry.Usable(s.now()) {
+		s.cache.Remove(key)
+		return nil, false, nil
+	}
+
+	return entry, true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, entry *Entry) error {
+	if entry.TTL() <= 0 {
+		return fmt.Errorf("response cache entry %q has no TTL", key)
+	}
+
+	s.cache.Add(key, entry)
+	return nil
+}
+
+func (s *MemoryStore) InvalidateOperation(_ context.Context, operationName string) error {
+	prefix := Key(operationName, "")
+	for _, key := range s.cache.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			s.cache.Remove(key)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Clear(_ context.Context) error {
+	s.cache.Purge()
+	return nil
+}
+
+// Len returns the number of entries, including the ones that are expired but not yet removed
+func (s *MemoryStore) Len() int {
+	return s.cache.Len()
+}
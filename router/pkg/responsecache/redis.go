@@ -0,0 +1,103 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanCount is the number of keys a SCAN looks at per call while invalidating entries
+const scanCount = 1000
+
+// RedisStore stores the entries in Redis, so they are shared between router instances
+type RedisStore struct {
+	// client is owned by the caller and never closed here
+	client redis.UniversalClient
+	// prefix is put in front of every key, so the entries can be invalidated without touching
+	// other keys of the instance
+	prefix string
+	now    func() time.Time
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore returns a store backed by client, namespacing every key with prefix
+func NewRedisStore(ctx context.Context, client redis.UniversalClient, prefix string) (*RedisStore, error) {
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("unable to connect to redis: %w", err)
+	}
+
+	return &RedisStore{client: client, prefix: prefix, now: time.Now}, nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (*Entry, bool, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("failed to decode response cache entry %q: %w", key, err)
+	}
+
+	// Redis expires the key on its own, but the clocks of the router instances can differ
+	if !entry.Usable(s.now()) {
+		return nil, false, nil
+	}
+
+	return &entry, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, entry *Entry) error {
+	ttl := entry.TTL()
+	if ttl <= 0 {
+		return fmt.Errorf("response cache entry %q has no TTL", key)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode response cache entry %q: %w", key, err)
+	}
+
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+func (s *RedisStore) InvalidateOperation(ctx context.Context, operationName string) error {
+	return s.deleteMatching(ctx, s.prefix+Key(operationName, "")+"*")
+}
+
+func (s *RedisStore) Clear(ctx context.Context) error {
+	if s.prefix == "" {
+		return errors.New("the redis response cache can't be cleared without a key prefix")
+	}
+	return s.deleteMatching(ctx, s.prefix+"*")
+}
+
+// deleteMatching deletes the keys matching the pattern. In a cluster, every master node is scanned.
+func (s *RedisStore) deleteMatching(ctx context.Context, pattern string) error {
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return deleteMatchingOnNode(ctx, node, pattern)
+		})
+	}
+	return deleteMatchingOnNode(ctx, s.client, pattern)
+}
+
+func deleteMatchingOnNode(ctx context.Context, client redis.Cmdable, pattern string) error {
+	iter := client.Scan(ctx, 0, pattern, scanCount).Iterator()
+	for iter.Next(ctx) {
+		// Keys are deleted one by one, because the keys of a batch can belong to different cluster slots
+		if err := client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
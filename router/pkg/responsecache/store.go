@@ -0,0 +1,25 @@
+package responsecache
+
+import (
+	"context"
+)
+
+// Store stores the cached responses. The keys are built with Key, so the entries of an operation
+// can be invalidated by the operation name.
+type Store interface {
+	// Get returns the entry of the key. ok is false if there is no usable entry.
+	Get(ctx context.Context, key string) (entry *Entry, ok bool, err error)
+	// Set stores the entry for its TTL
+	Set(ctx context.Context, key string, entry *Entry) error
+	// InvalidateOperation removes all entries of the operation with the given name.
+	// An empty name removes the entries of anonymous operations.
+	InvalidateOperation(ctx context.Context, operationName string) error
+	// Clear removes all entries
+	Clear(ctx context.Context) error
+}
+
+// Key returns the key of a cached response. GraphQL operation names can't contain a colon, so the
+// operation name is always the part before the first colon.
+func Key(operationName, hash string) string {
+	return operationName + ":" + hash
+}
@@ -0,0 +1,182 @@
+package responsecache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEntry(body string, maxAge time.Duration) *Entry {
+	return NewEntry([]byte(body), http.Header{"Content-Type": {"application/json"}}, Directives{MaxAge: maxAge}, time.Now())
+}
+
+func TestStores(t *testing.T) {
+	t.Parallel()
+
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			s, err := NewMemoryStore(100)
+			require.NoError(t, err)
+			return s
+		},
+		"redis": func(t *testing.T) Store {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() {
+				require.NoError(t, client.Close())
+			})
+
+			// A foreign key must survive every invalidation
+			require.NoError(t, client.Set(t.Context(), "other", "value", 0).Err())
+			t.Cleanup(func() {
+				require.True(t, mr.Exists("other"))
+			})
+
+			s, err := NewRedisStore(t.Context(), client, "response:")
+			require.NoError(t, err)
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			t.Run("returns a stored entry", func(t *testing.T) {
+				t.Parallel()
+
+				s := newStore(t)
+				entry := newTestEntry(`{"data":{}}`, time.Minute)
+				require.NoError(t, s.Set(t.Context(), Key("Employees", "1"), entry))
+
+				got, ok, err := s.Get(t.Context(), Key("Employees", "1"))
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, entry.Body, got.Body)
+				require.Equal(t, entry.ETag, got.ETag)
+				require.Equal(t, entry.Header, got.Header)
+				require.Equal(t, entry.MaxAge, got.MaxAge)
+
+				_, ok, err = s.Get(t.Context(), Key("Employees", "2"))
+				require.NoError(t, err)
+				require.False(t, ok)
+			})
+
+			t.Run("rejects entries without TTL", func(t *testing.T) {
+				t.Parallel()
+
+				s := newStore(t)
+				require.Error(t, s.Set(t.Context(), Key("Employees", "1"), newTestEntry(`{}`, 0)))
+			})
+
+			t.Run("does not return expired entries", func(t *testing.T) {
+				t.Parallel()
+
+				s := newStore(t)
+				entry := newTestEntry(`{}`, time.Minute)
+				entry.StoredAt = time.Now().Add(-2 * time.Minute)
+				// Stored directly, so only the age of the entry decides
+				require.NoError(t, s.Set(t.Context(), Key("Employees", "1"), &Entry{
+					Body: entry.Body, StoredAt: entry.StoredAt, MaxAge: 3 * time.Minute,
+				}))
+				require.NoError(t, s.Set(t.Context(), Key("Employees", "2"), entry))
+
+				_, ok, err := s.Get(t.Context(), Key("Employees", "1"))
+				require.NoError(t, err)
+				require.True(t, ok)
+
+				_, ok, err = s.Get(t.Context(), Key("Employees", "2"))
+				require.NoError(t, err)
+				require.False(t, ok)
+			})
+
+			t.Run("invalidates the entries of an operation", func(t *testing.T) {
+				t.Parallel()
+
+				s := newStore(t)
+				require.NoError(t, s.Set(t.Context(), Key("Employees", "1"), newTestEntry(`{}`, time.Minute)))
+				require.NoError(t, s.Set(t.Context(), Key("Employees", "2"), newTestEntry(`{}`, time.Minute)))
+				require.NoError(t, s.Set(t.Context(), Key("EmployeesWithTeam", "1"), newTestEntry(`{}`, time.Minute)))
+
+				require.NoError(t, s.InvalidateOperation(t.Context(), "Employees"))
+
+				for key, expected := range map[string]bool{
+					Key("Employees", "1"):         false,
+					Key("Employees", "2"):         false,
+					Key("EmployeesWithTeam", "1"): true,
+				} {
+					_, ok, err := s.Get(t.Context(), key)
+					require.NoError(t, err)
+					require.Equal(t, expected, ok, key)
+				}
+			})
+
+			t.Run("clears all entries", func(t *testing.T) {
+				t.Parallel()
+
+				s := newStore(t)
+				require.NoError(t, s.Set(t.Context(), Key("Employees", "1"), newTestEntry(`{}`, time.Minute)))
+				require.NoError(t, s.Set(t.Context(), Key("", "1"), newTestEntry(`{}`, time.Minute)))
+
+				require.NoError(t, s.Clear(t.Context()))
+
+				for _, key := range []string{Key("Employees", "1"), Key("", "1")} {
+					_, ok, err := s.Get(t.Context(), key)
+					require.NoError(t, err)
+					require.False(t, ok, key)
+				}
+			})
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rejects invalid sizes", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewMemoryStore(0)
+		require.Error(t, err)
+
+		_, err = NewMemoryStore(maxMemoryEntries + 1)
+		require.Error(t, err)
+	})
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := NewMemoryStore(2)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Set(t.Context(), Key("A", "1"), newTestEntry(`{}`, time.Minute)))
+		require.NoError(t, s.Set(t.Context(), Key("B", "1"), newTestEntry(`{}`, time.Minute)))
+		_, ok, _ := s.Get(t.Context(), Key("A", "1"))
+		require.True(t, ok)
+		require.NoError(t, s.Set(t.Context(), Key("C", "1"), newTestEntry(`{}`, time.Minute)))
+
+		_, ok, _ = s.Get(t.Context(), Key("B", "1"))
+		require.False(t, ok)
+		_, ok, _ = s.Get(t.Context(), Key("A", "1"))
+		require.True(t, ok)
+		require.Equal(t, 2, s.Len())
+	})
+}
+
+func TestRedisStoreRequiresPrefixToClear(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	s, err := NewRedisStore(t.Context(), client, "")
+	require.NoError(t, err)
+	require.Error(t, s.Clear(t.Context()))
+}
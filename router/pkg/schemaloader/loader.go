@@ -101,12 +101,6 @@ func (l *OperationLoader) LoadOperationsFromDirectory(dirPath string) ([]Operati
 			return nil
 		}
 
-		// Check if the operation type is supported
-		if opType == "subscription" {
-			l.Logger.Error("Subscriptions in MCP are not supported yet", zap.String("operation", opName), zap.String("file", path))
-			return nil
-		}
-
 		// Validate operation against schema
 		validationReport := operationreport.Report{}
 		validationState := validator.Validate(&opDoc, l.SchemaDocument, &validationReport)
@@ -187,7 +181,7 @@ func GetOperationNameAndType(doc *ast.Document) (string, string, error) {
 			case ast.OperationTypeMutation:
 				opType = "mutation"
 			case ast.OperationTypeSubscription:
-				opType = "subscription" // Not supported yet
+				opType = "subscription"
 			default:
 				return "", "", fmt.Errorf("unknown operation type %d", opDef.OperationType)
 			}
@@ -19,6 +19,7 @@ type setRequest[V any] struct {
 	value  V
 	dur    time.Duration
 	waitCh chan struct{} // if non-nil, will be closed after previous requests in the buffer are processed
+	clear  bool          // if true, all entries are removed
 }
 
 // Cache is a bounded map that holds expensive-to-compute values
@@ -71,6 +72,10 @@ func (c *Cache[V]) processWrites() {
 				close(req.waitCh)
 				continue
 			}
+			if req.clear {
+				c.applyClear()
+				continue
+			}
 			c.applySet(req.key, req.value, req.dur)
 		case <-c.stop:
 			return
@@ -126,6 +131,27 @@ func (c *Cache[V]) Wait() {
 	}
 }
 
+// Clear enqueues the removal of all entries. Like Set, it is applied asynchronously,
+// so writes enqueued before Clear are removed as well.
+func (c *Cache[V]) Clear() {
+	if c == nil || c.closed.Load() {
+		return
+	}
+
+	select {
+	case c.writeCh <- setRequest[V]{clear: true}:
+	case <-c.done:
+	}
+}
+
+// applyClear removes all entries. Must only be called from processWrites.
+func (c *Cache[V]) applyClear() {
+	c.entries.Clear()
+	c.size = 0
+	c.minKey = 0
+	c.minDur = 0
+}
+
 // applySet performs the actual cache mutation. Must only be called from processWrites.
 func (c *Cache[V]) applySet(key uint64, value V, duration time.Duration) {
 	entry := &Entry[V]{value: value, duration: duration}
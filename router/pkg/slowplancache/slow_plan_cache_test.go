@@ -162,6 +162,34 @@ func TestCache_Values_EarlyStop(t *testing.T) {
 	require.Equal(t, 1, count)
 }
 
+func TestCache_Clear(t *testing.T) {
+	t.Parallel()
+	c, err := New[*testPlan](2, 0)
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.Set(1, &testPlan{content: "q1"}, 10*time.Millisecond)
+	c.Set(2, &testPlan{content: "q2"}, 20*time.Millisecond)
+	c.Clear()
+	c.Wait()
+
+	_, ok := c.Get(1)
+	require.False(t, ok)
+	_, ok = c.Get(2)
+	require.False(t, ok)
+
+	// The capacity is available again, so cheaper entries are accepted
+	c.Set(3, &testPlan{content: "q3"}, 5*time.Millisecond)
+	c.Set(4, &testPlan{content: "q4"}, 5*time.Millisecond)
+	c.Wait()
+
+	var contents []string
+	for v := range c.Values() {
+		contents = append(contents, v.content)
+	}
+	require.ElementsMatch(t, []string{"q3", "q4"}, contents)
+}
+
 func TestCache_Close(t *testing.T) {
 	t.Parallel()
 	c, err := New[*testPlan](10, 0)
@@ -77,6 +77,8 @@ type Config struct {
 	Attributes          []config.CustomAttribute
 	// SanitizeUTF8 configures sanitization of invalid UTF-8 sequences in span attribute values
 	SanitizeUTF8 *attributeprocessor.SanitizeUTF8Config
+	// Sampling defers the sampling decision to the end of the request when enabled
+	Sampling *DeferredSamplingConfig
 }
 
 func DefaultExporter(cfg *Config) *ExporterConfig {
@@ -0,0 +1,314 @@
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+const (
+	// staleTraceTimeout is the time after which a buffered trace whose local root span never ended
+	// is dropped, e.g. for spans that ended after the request
+	staleTraceTimeout = 5 * time.Minute
+	// staleTraceSweepInterval is the minimum time between two sweeps of the stale traces
+	staleTraceSweepInterval = 10 * time.Second
+)
+
+// DeferredSamplingConfig configures the sampling decision at the end of a request
+type DeferredSamplingConfig struct {
+	// Rules set the sampling ratio of the requests matching their expression. They are evaluated
+	// by the router, which reports the ratio with DeferredSampler.SetRatio.
+	Rules []config.TracingSamplingRule
+	// KeepOnError keeps the traces with a span that ended with an error
+	KeepOnError bool
+	// LatencyThreshold keeps the traces whose local root span took at least the threshold
+	LatencyThreshold time.Duration
+	// MaxBufferedTraces caps the number of traces buffered until their local root span ends.
+	// Together with MaxSpansPerTrace, it bounds the number of spans held in memory.
+	MaxBufferedTraces int
+	// MaxSpansPerTrace caps the number of buffered spans of a trace
+	MaxSpansPerTrace int
+	// PropagateSampled propagates the traces whose decision is deferred as sampled, see Propagator
+	PropagateSampled bool
+}
+
+// IsEnabled reports whether the sampling decision is deferred
+func (c *DeferredSamplingConfig) IsEnabled() bool {
+	if c == nil {
+		return false
+	}
+	return len(c.Rules) > 0 || c.KeepOnError || c.LatencyThreshold > 0
+}
+
+// DeferredSampler decides whether a trace is exported when its local root span ends, instead of
+// when it starts. The spans of a trace are recorded but not sampled, so that the exporters ignore
+// them, and buffered until the decision is made. Kept traces are passed to the exporters as sampled.
+//
+// The processor must be registered after the processors that modify the span attributes, so the
+// buffered spans are complete.
+type DeferredSampler struct {
+	config DeferredSamplingConfig
+	// ratio applies to the traces without a ratio set with SetRatio
+	ratio float64
+	// exporters are the span processors of the exporters
+	exporters []sdktrace.SpanProcessor
+
+	mu        sync.Mutex
+	traces    map[oteltrace.TraceID]*deferredTrace
+	lastSweep time.Time
+}
+
+type deferredTrace struct {
+	spans     []sdktrace.ReadOnlySpan
+	ratio     float64
+	hasRatio  bool
+	updatedAt time.Time
+}
+
+var _ sdktrace.SpanProcessor = (*DeferredSampler)(nil)
+
+func NewDeferredSampler(config DeferredSamplingConfig, ratio float64) *DeferredSampler {
+	if config.MaxBufferedTraces <= 0 {
+		config.MaxBufferedTraces = 10000
+	}
+	if config.MaxSpansPerTrace <= 0 {
+		config.MaxSpansPerTrace = 512
+	}
+	return &DeferredSampler{
+		config:    config,
+		ratio:     ratio,
+		traces:    make(map[oteltrace.TraceID]*deferredTrace),
+		lastSweep: time.Now(),
+	}
+}
+
+// Sampler returns the head sampler of the tracer provider. It records the spans without sampling
+// them. With parentBased, the decision of a remote parent is respected.
+func (d *DeferredSampler) Sampler(parentBased bool) sdktrace.Sampler {
+	return deferredHeadSampler{parentBased: parentBased}
+}
+
+// Propagator returns the propagator of the trace context sent to the subgraphs. The spans of a
+// trace whose decision is deferred are not sampled, so the subgraphs would not sample the trace
+// either and a kept trace would lack their spans. With PropagateSampled, such traces are
+// propagated as sampled, at the cost of the subgraphs sampling every one of them.
+func (d *DeferredSampler) Propagator(next propagation.TextMapPropagator) propagation.TextMapPropagator {
+	if !d.config.PropagateSampled {
+		return next
+	}
+	return sampledPropagator{TextMapPropagator: next}
+}
+
+// SetRatio sets the sampling ratio of a trace whose decision is deferred. It must be called before
+// the local root span of the trace ends.
+func (d *DeferredSampler) SetRatio(traceID oteltrace.TraceID, ratio float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t := d.bufferedTrace(traceID, time.Now())
+	if t == nil {
+		return
+	}
+	t.ratio = ratio
+	t.hasRatio = true
+}
+
+// bufferedTrace returns the buffered trace or starts buffering it. It returns nil when the limit of
+// buffered traces is reached. The caller must hold the lock.
+func (d *DeferredSampler) bufferedTrace(traceID oteltrace.TraceID, now time.Time) *deferredTrace {
+	if t, ok := d.traces[traceID]; ok {
+		t.updatedAt = now
+		return t
+	}
+
+	if now.Sub(d.lastSweep) >= staleTraceSweepInterval {
+		d.lastSweep = now
+		for id, t := range d.traces {
+			if now.Sub(t.updatedAt) >= staleTraceTimeout {
+				delete(d.traces, id)
+			}
+		}
+	}
+
+	if len(d.traces) >= d.config.MaxBufferedTraces {
+		return nil
+	}
+
+	t := &deferredTrace{updatedAt: now}
+	d.traces[traceID] = t
+	return t
+}
+
+func (d *DeferredSampler) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	for _, exporter := range d.exporters {
+		exporter.OnStart(parent, s)
+	}
+}
+
+// OnEnd exports sampled spans right away and buffers the others until the local root span of
+// their trace ends
+func (d *DeferredSampler) OnEnd(s sdktrace.ReadOnlySpan) {
+	spanContext := s.SpanContext()
+	if spanContext.IsSampled() {
+		d.export(s)
+		return
+	}
+
+	isLocalRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+
+	d.mu.Lock()
+	t := d.bufferedTrace(spanContext.TraceID(), time.Now())
+	if t == nil {
+		d.mu.Unlock()
+		return
+	}
+	// The local root span is always kept, it carries the request
+	if len(t.spans) < d.config.MaxSpansPerTrace || isLocalRoot {
+		t.spans = append(t.spans, s)
+	}
+	if !isLocalRoot {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.traces, spanContext.TraceID())
+	d.mu.Unlock()
+
+	if !d.keep(t, s) {
+		return
+	}
+	for _, span := range t.spans {
+		d.export(sampledSpan{ReadOnlySpan: span})
+	}
+}
+
+// keep decides whether the trace is exported
+func (d *DeferredSampler) keep(t *deferredTrace, root sdktrace.ReadOnlySpan) bool {
+	if d.config.KeepOnError {
+		for _, span := range t.spans {
+			if span.Status().Code == codes.Error {
+				return true
+			}
+		}
+	}
+
+	if d.config.LatencyThreshold > 0 && root.EndTime().Sub(root.StartTime()) >= d.config.LatencyThreshold {
+		return true
+	}
+
+	ratio := d.ratio
+	if t.hasRatio {
+		ratio = t.ratio
+	}
+	return sampledByRatio(root.SpanContext().TraceID(), ratio)
+}
+
+func (d *DeferredSampler) export(s sdktrace.ReadOnlySpan) {
+	for _, exporter := range d.exporters {
+		exporter.OnEnd(s)
+	}
+}
+
+// Shutdown drops the buffered traces and shuts down the exporters
+func (d *DeferredSampler) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	clear(d.traces)
+	d.mu.Unlock()
+
+	var errs []error
+	for _, exporter := range d.exporters {
+		errs = append(errs, exporter.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DeferredSampler) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, exporter := range d.exporters {
+		errs = append(errs, exporter.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// sampledByRatio samples the trace ID like sdktrace.TraceIDRatioBased, so that the decision is
+// consistent with the head sampling of other services
+func sampledByRatio(traceID oteltrace.TraceID, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	upperBound := uint64(ratio * (1 << 63))
+	x := binary.BigEndian.Uint64(traceID[8:16]) >> 1
+	return x < upperBound
+}
+
+// sampledSpan marks a buffered span as sampled, so that the exporters don't ignore it
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() oteltrace.SpanContext {
+	spanContext := s.ReadOnlySpan.SpanContext()
+	return spanContext.WithTraceFlags(spanContext.TraceFlags().WithSampled(true))
+}
+
+// sampledPropagator injects the trace context of recorded but unsampled spans as sampled. Only
+// the spans of traces whose decision is deferred are recorded without being sampled.
+type sampledPropagator struct {
+	propagation.TextMapPropagator
+}
+
+func (p sampledPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	span := oteltrace.SpanFromContext(ctx)
+	if spanContext := span.SpanContext(); span.IsRecording() && !spanContext.IsSampled() {
+		ctx = oteltrace.ContextWithSpanContext(ctx, spanContext.WithTraceFlags(spanContext.TraceFlags().WithSampled(true)))
+	}
+	p.TextMapPropagator.Inject(ctx, carrier)
+}
+
+// deferredHeadSampler records the spans of the traces whose decision is deferred
+type deferredHeadSampler struct {
+	parentBased bool
+}
+
+func (s deferredHeadSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := oteltrace.SpanFromContext(p.ParentContext)
+	parentContext := parent.SpanContext()
+
+	result := sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordOnly,
+		Tracestate: parentContext.TraceState(),
+	}
+
+	switch {
+	case !parentContext.IsValid():
+	case parentContext.IsSampled() && (s.parentBased || !parentContext.IsRemote()):
+		result.Decision = sdktrace.RecordAndSample
+	case !parentContext.IsRemote():
+		// Only the children of deferred spans are recorded
+		if !parent.IsRecording() {
+			result.Decision = sdktrace.Drop
+		}
+	case s.parentBased:
+		result.Decision = sdktrace.Drop
+	}
+
+	return result
+}
+
+func (s deferredHeadSampler) Description() string {
+	return "DeferredSampler"
+}
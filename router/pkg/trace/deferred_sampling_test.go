@@ -0,0 +1,191 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/trace/tracetest"
+)
+
+func TestDeferredSampler(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T, config DeferredSamplingConfig, ratio float64) (*DeferredSampler, oteltrace.Tracer, func() int) {
+		t.Helper()
+
+		exporter := tracetest.NewInMemoryExporter(t)
+		sampler := NewDeferredSampler(config, ratio)
+		tp, err := NewTracerProvider(t.Context(), &ProviderConfig{
+			Logger: zap.NewNop(),
+			Config: &Config{
+				Enabled:            true,
+				Sampler:            ratio,
+				ParentBasedSampler: true,
+			},
+			MemoryExporter:  exporter,
+			DeferredSampler: sampler,
+		})
+		require.NoError(t, err)
+
+		exported := func() int {
+			spans := exporter.GetSpans()
+			for _, span := range spans {
+				require.True(t, span.SpanContext.IsSampled())
+			}
+			return len(spans)
+		}
+		return sampler, tp.Tracer("test"), exported
+	}
+
+	t.Run("drops the traces that are not sampled by the ratio", func(t *testing.T) {
+		t.Parallel()
+
+		_, tracer, exported := setup(t, DeferredSamplingConfig{KeepOnError: true}, 0)
+
+		ctx, root := tracer.Start(t.Context(), "root")
+		_, child := tracer.Start(ctx, "child")
+		require.True(t, root.IsRecording())
+		require.False(t, root.SpanContext().IsSampled())
+		child.End()
+		root.End()
+
+		require.Zero(t, exported())
+	})
+
+	t.Run("keeps the traces with an error", func(t *testing.T) {
+		t.Parallel()
+
+		_, tracer, exported := setup(t, DeferredSamplingConfig{KeepOnError: true}, 0)
+
+		ctx, root := tracer.Start(t.Context(), "root")
+		_, child := tracer.Start(ctx, "child")
+		child.SetStatus(codes.Error, "subgraph error")
+		child.End()
+		// Spans are buffered until the local root span ends
+		require.Zero(t, exported())
+		root.End()
+
+		require.Equal(t, 2, exported())
+	})
+
+	t.Run("keeps the traces over the latency threshold", func(t *testing.T) {
+		t.Parallel()
+
+		_, tracer, exported := setup(t, DeferredSamplingConfig{LatencyThreshold: time.Second}, 0)
+
+		start := time.Now()
+		_, fast := tracer.Start(t.Context(), "fast", oteltrace.WithTimestamp(start))
+		fast.End(oteltrace.WithTimestamp(start.Add(100 * time.Millisecond)))
+		require.Zero(t, exported())
+
+		_, slow := tracer.Start(t.Context(), "slow", oteltrace.WithTimestamp(start))
+		slow.End(oteltrace.WithTimestamp(start.Add(2 * time.Second)))
+		require.Equal(t, 1, exported())
+	})
+
+	t.Run("applies the ratio set for the trace", func(t *testing.T) {
+		t.Parallel()
+
+		sampler, tracer, exported := setup(t, DeferredSamplingConfig{KeepOnError: true}, 0)
+
+		_, root := tracer.Start(t.Context(), "root")
+		sampler.SetRatio(root.SpanContext().TraceID(), 1)
+		root.End()
+
+		require.Equal(t, 1, exported())
+	})
+
+	t.Run("exports the traces of a sampled remote parent right away", func(t *testing.T) {
+		t.Parallel()
+
+		_, tracer, exported := setup(t, DeferredSamplingConfig{KeepOnError: true}, 0)
+
+		parent := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    oteltrace.TraceID{1},
+			SpanID:     oteltrace.SpanID{1},
+			TraceFlags: oteltrace.FlagsSampled,
+			Remote:     true,
+		})
+		ctx, root := tracer.Start(oteltrace.ContextWithRemoteSpanContext(t.Context(), parent), "root")
+		_, child := tracer.Start(ctx, "child")
+		require.True(t, child.SpanContext().IsSampled())
+		child.End()
+
+		require.Equal(t, 1, exported())
+		root.End()
+		require.Equal(t, 2, exported())
+	})
+
+	t.Run("limits the buffered spans of a trace", func(t *testing.T) {
+		t.Parallel()
+
+		_, tracer, exported := setup(t, DeferredSamplingConfig{KeepOnError: true, MaxSpansPerTrace: 2}, 0)
+
+		ctx, root := tracer.Start(t.Context(), "root")
+		for range 5 {
+			_, child := tracer.Start(ctx, "child")
+			child.End()
+		}
+		root.SetStatus(codes.Error, "request error")
+		root.End()
+
+		// The local root span is kept in addition to the limit
+		require.Equal(t, 3, exported())
+	})
+
+	t.Run("limits the buffered traces", func(t *testing.T) {
+		t.Parallel()
+
+		_, tracer, exported := setup(t, DeferredSamplingConfig{KeepOnError: true, MaxBufferedTraces: 1}, 0)
+
+		first, firstRoot := tracer.Start(t.Context(), "first")
+		_, firstChild := tracer.Start(first, "child")
+		firstChild.End()
+
+		_, secondRoot := tracer.Start(t.Context(), "second")
+		secondRoot.SetStatus(codes.Error, "request error")
+		secondRoot.End()
+		require.Zero(t, exported())
+
+		firstRoot.SetStatus(codes.Error, "request error")
+		firstRoot.End()
+		require.Equal(t, 2, exported())
+	})
+
+	t.Run("propagates the deferred traces as sampled", func(t *testing.T) {
+		t.Parallel()
+
+		sampler, tracer, _ := setup(t, DeferredSamplingConfig{KeepOnError: true, PropagateSampled: true}, 0)
+
+		ctx, root := tracer.Start(t.Context(), "root")
+		defer root.End()
+		require.False(t, root.SpanContext().IsSampled())
+
+		carrier := propagation.MapCarrier{}
+		sampler.Propagator(propagation.TraceContext{}).Inject(ctx, carrier)
+		require.True(t, strings.HasSuffix(carrier.Get("traceparent"), "-01"))
+
+		carrier = propagation.MapCarrier{}
+		NewDeferredSampler(DeferredSamplingConfig{KeepOnError: true}, 0).Propagator(propagation.TraceContext{}).Inject(ctx, carrier)
+		require.True(t, strings.HasSuffix(carrier.Get("traceparent"), "-00"))
+	})
+}
+
+func TestSampledByRatio(t *testing.T) {
+	t.Parallel()
+
+	low := oteltrace.TraceID{8: 0x10}
+	high := oteltrace.TraceID{8: 0xf0}
+
+	require.True(t, sampledByRatio(low, 1))
+	require.False(t, sampledByRatio(low, 0))
+	require.True(t, sampledByRatio(low, 0.5))
+	require.False(t, sampledByRatio(high, 0.5))
+}
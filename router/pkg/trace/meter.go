@@ -32,6 +32,8 @@ type (
 		SanitizeUTF8 *attributeprocessor.SanitizeUTF8Config
 		// MemoryExporter is used for testing purposes
 		MemoryExporter sdktrace.SpanExporter
+		// DeferredSampler defers the sampling decision to the end of the request when set
+		DeferredSampler *DeferredSampler
 	}
 )
 
@@ -129,7 +131,11 @@ func NewTracerProvider(ctx context.Context, config *ProviderConfig) (*sdktrace.T
 		sdktrace.WithResource(r),
 	}
 
-	if config.Config.ParentBasedSampler {
+	if config.DeferredSampler != nil {
+		opts = append(opts,
+			sdktrace.WithSampler(config.DeferredSampler.Sampler(config.Config.ParentBasedSampler)),
+		)
+	} else if config.Config.ParentBasedSampler {
 		opts = append(opts,
 			sdktrace.WithSampler(
 				sdktrace.ParentBased(
@@ -171,6 +177,8 @@ func NewTracerProvider(ctx context.Context, config *ProviderConfig) (*sdktrace.T
 		opts = append(opts, attributeprocessor.NewAttributeProcessorOption(transformers...))
 	}
 
+	var exporters []sdktrace.SpanProcessor
+
 	if config.Config.Enabled {
 
 		// Either memory exporter or the configured exporters are used.
@@ -182,7 +190,7 @@ func NewTracerProvider(ctx context.Context, config *ProviderConfig) (*sdktrace.T
 					handler: config.Config.TestErrorHandler,
 				}
 			}
-			opts = append(opts, sdktrace.WithSyncer(exporter))
+			exporters = append(exporters, sdktrace.NewSimpleSpanProcessor(exporter))
 		} else {
 			for _, exp := range config.Config.Exporters {
 				if exp.Disabled {
@@ -211,8 +219,8 @@ func NewTracerProvider(ctx context.Context, config *ProviderConfig) (*sdktrace.T
 				}
 
 				// Always be sure to batch in production.
-				opts = append(opts,
-					sdktrace.WithBatcher(exporter,
+				exporters = append(exporters,
+					sdktrace.NewBatchSpanProcessor(exporter,
 						sdktrace.WithBatchTimeout(batchTimeout),
 						sdktrace.WithExportTimeout(exportTimeout),
 						sdktrace.WithMaxExportBatchSize(512),
@@ -224,6 +232,17 @@ func NewTracerProvider(ctx context.Context, config *ProviderConfig) (*sdktrace.T
 
 	}
 
+	// The deferred sampler is registered last, so that it buffers the spans after the attributes
+	// have been renamed and transformed, and passes the kept spans to the exporters.
+	if config.DeferredSampler != nil {
+		config.DeferredSampler.exporters = exporters
+		opts = append(opts, sdktrace.WithSpanProcessor(config.DeferredSampler))
+	} else {
+		for _, exporter := range exporters {
+			opts = append(opts, sdktrace.WithSpanProcessor(exporter))
+		}
+	}
+
 	tp := sdktrace.NewTracerProvider(opts...)
 
 	// Don't set globals when we use the router in tests.